@@ -2,21 +2,273 @@ package main
 
 // TODO wkpo check all imports
 import (
+	"context"
+	goflag "flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/wk8/go-conversion-gen/pkg/converter"
 	"k8s.io/klog/v2"
 )
 
+// a command is one of the CLI's subcommands.
+type command struct {
+	description string
+	// addFlags registers the command's specific flags, and returns the function to run the command.
+	addFlags func(fs *pflag.FlagSet) func(c *converter.Converter) error
+	// converterFlags are the names of the converter's flags that the command uses; all of them if
+	// nil, as is the case of all the commands that generate code, be it into a temporary directory.
+	converterFlags []string
+}
+
+// defaultCommand is the command that runs when no command is given, so that invocations without
+// a subcommand keep working.
+const defaultCommand = "generate"
+
+var commands = map[string]command{
+	"generate": {
+		description: "Generates conversion functions.",
+		addFlags: func(*pflag.FlagSet) func(c *converter.Converter) error {
+			return (*converter.Converter).Run
+		},
+	},
+	"verify": {
+		description: "Checks that generated files are up to date, without writing anything.",
+		addFlags: func(*pflag.FlagSet) func(c *converter.Converter) error {
+			return (*converter.Converter).Verify
+		},
+	},
+	"plan": {
+		description: "Lists the files and functions that would be generated, without writing anything.",
+		addFlags: func(*pflag.FlagSet) func(c *converter.Converter) error {
+			return func(c *converter.Converter) error {
+				plan, err := c.Plan(context.Background())
+				if err != nil {
+					return err
+				}
+				return converter.WritePlan(os.Stdout, plan)
+			}
+		},
+	},
+	"diff": {
+		description: "Lists the functions that would be added, removed or changed in generated files, without writing anything.",
+		addFlags: func(*pflag.FlagSet) func(c *converter.Converter) error {
+			return func(c *converter.Converter) error {
+				diffs, err := c.DiffPlan(context.Background())
+				if err != nil {
+					return err
+				}
+				return converter.WritePlanDiff(os.Stdout, diffs)
+			}
+		},
+	},
+	"clean": {
+		description: "Removes previously generated files from the input packages.",
+		converterFlags: []string{"input-dirs", "output-file-base", "build-tags", "use-packages-driver", "package-files-manifest",
+			"output-packages-by-group", "equality-functions", "fail-fast", "force"},
+		addFlags: func(fs *pflag.FlagSet) func(c *converter.Converter) error {
+			dryRun := fs.Bool("dry-run", false, "If true, only lists the files that would be removed.")
+			return func(c *converter.Converter) error {
+				removed, err := c.Clean(*dryRun)
+				for _, path := range removed {
+					fmt.Println(path)
+				}
+				return err
+			}
+		},
+	},
+	"report": {
+		description: "Reports the conversions that require manual work, without writing anything.",
+		addFlags: func(*pflag.FlagSet) func(c *converter.Converter) error {
+			return func(c *converter.Converter) error {
+				diagnostics, err := c.Report()
+				if err != nil {
+					return err
+				}
+				return converter.WriteReport(os.Stdout, diagnostics)
+			}
+		},
+	},
+	"compat": {
+		description: "Reports the fields added, removed, retyped and renamed between the input packages' types and their peers, without writing anything.",
+		addFlags: func(fs *pflag.FlagSet) func(c *converter.Converter) error {
+			failOnBreaking := fs.Bool("fail-on-breaking", false, "If true, fails if any change is breaking, i.e. has error severity.")
+			return func(c *converter.Converter) error {
+				changes, err := c.CompatReport()
+				if err != nil {
+					return err
+				}
+				if err := converter.WriteCompatReport(os.Stdout, changes); err != nil {
+					return err
+				}
+				if breaking := converter.BreakingCompatChanges(changes); *failOnBreaking && breaking != 0 {
+					return fmt.Errorf("%d breaking change(s) found", breaking)
+				}
+				return nil
+			}
+		},
+	},
+	"suggest-tags": {
+		description: "Suggests the tags and field mappings that would let the generator take over the input packages' manual conversion functions, e.g. to migrate legacy hand-written conversions.",
+		addFlags: func(fs *pflag.FlagSet) func(c *converter.Converter) error {
+			fieldMappingsFile := fs.String("suggested-field-mappings", "", "If set, the field mappings file to add the suggested field mappings to, created if it doesn't exist; see --field-mappings-file.")
+			return func(c *converter.Converter) error {
+				suggestions, err := c.TagSuggestions()
+				if err != nil {
+					return err
+				}
+				if err := converter.WriteTagSuggestions(os.Stdout, suggestions); err != nil {
+					return err
+				}
+				if *fieldMappingsFile != "" {
+					return converter.WriteSuggestedFieldMappings(*fieldMappingsFile, suggestions)
+				}
+				return nil
+			}
+		},
+	},
+	"crd-skeletons": {
+		description:    "Writes manual conversion function skeletons for a CRD's Go types, from the diff of two versions' OpenAPI schemas.",
+		converterFlags: []string{"tag-name", "private-function-prefix"},
+		addFlags: func(fs *pflag.FlagSet) func(c *converter.Converter) error {
+			options := converter.CRDSkeletonOptions{}
+			fs.StringVar(&options.CRDFile, "crd", "", "YAML file defining the CustomResourceDefinition.")
+			fs.StringVar(&options.FromVersion, "from-version", "", "The CRD version to convert from, e.g. v1alpha1.")
+			fs.StringVar(&options.ToVersion, "to-version", "", "The CRD version to convert to, e.g. v1beta1.")
+			fs.StringVar(&options.FromPackage, "from-package", "", "Import path of the Go package defining the types of --from-version.")
+			fs.StringVar(&options.ToPackage, "to-package", "", "Import path of the Go package defining the types of --to-version.")
+			fs.StringVar(&options.OutputPackage, "skeletons-package", "", "Import path of the package the skeletons are for, either --from-package (the default) or --to-package.")
+			output := fs.String("skeletons-file", "", "File to write the skeletons to; standard output by default.")
+			return func(c *converter.Converter) error {
+				options.TagName = c.Options.GeneratorOptions.TagName
+				options.PrivateFunctionPrefix = c.Options.GeneratorOptions.PrivateFunctionPrefix
+				source, changes, err := converter.CRDSkeletons(options)
+				if err != nil {
+					return err
+				}
+				for _, change := range changes {
+					klog.V(2).Infof("%v", change)
+				}
+				if *output == "" {
+					_, err = os.Stdout.Write(source)
+					return err
+				}
+				return ioutil.WriteFile(*output, source, 0644)
+			}
+		},
+	},
+	"init-example": {
+		description:    "Writes a sample pair of packages with conversion tags and a go:generate directive, to adapt or to use as a fixture.",
+		converterFlags: []string{"tag-name", "force"},
+		addFlags: func(fs *pflag.FlagSet) func(c *converter.Converter) error {
+			options := converter.ExampleOptions{}
+			fs.StringVar(&options.Dir, "example-dir", "conversion-example", "Directory to write the example to.")
+			fs.StringVar(&options.Package, "example-package", "", "Import path of --example-dir; derived from the enclosing go.mod file by default.")
+			return func(c *converter.Converter) error {
+				options.TagName = c.Options.GeneratorOptions.TagName
+				options.Force = c.Options.Force
+				written, err := converter.WriteExample(options)
+				for _, path := range written {
+					fmt.Println(path)
+				}
+				return err
+			}
+		},
+	},
+	"selftest": {
+		description: "Generates into a temporary directory, and checks that the generated code builds.",
+		addFlags: func(fs *pflag.FlagSet) func(c *converter.Converter) error {
+			runTests := fs.Bool("run-tests", false,
+				"If true, also runs the affected packages' own tests with `go test` against the generated code; no tests get generated.")
+			return func(c *converter.Converter) error {
+				return c.SelfTest(*runTests)
+			}
+		},
+	},
+}
+
 func main() {
 	klog.InitFlags(nil)
 
-	converter := converter.NewConverterFromCLIFlags()
-
-	if err := converter.Run(); err != nil {
+	if err := newRootCommand().Execute(); err != nil {
 		klog.Fatalf("Error: %v", err)
 	}
 	klog.Infof("Completed successfully")
 }
 
+// newRootCommand returns the CLI's root command, with a subcommand per command; the root command
+// itself runs the default command, so that invocations without a subcommand keep working.
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "go-conversion-gen",
+		Short: "Generates conversion functions between Go types.",
+		Long: fmt.Sprintf("Generates conversion functions between Go types.\n\n"+
+			"Without a command, runs the %q command, with its flags.", defaultCommand),
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().AddGoFlagSet(goflag.CommandLine)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := commands[name]
+		short := cmd.description
+		if name == defaultCommand {
+			short += " (default)"
+		}
+		subcommand := &cobra.Command{
+			Use:   name,
+			Short: short,
+			Args:  cobra.NoArgs,
+		}
+		setRun(subcommand, cmd)
+		root.AddCommand(subcommand)
+	}
+	setRun(root, commands[defaultCommand])
+
+	return root
+}
+
+// setRun registers the command's specific flags on the cobra command, along with the converter's
+// that it uses, and makes the cobra command run it.
+func setRun(cobraCommand *cobra.Command, cmd command) {
+	// -h is gengo's shorthand for --go-header-file
+	cobraCommand.Flags().Bool("help", false, "help for "+cobraCommand.Name())
+	run := cmd.addFlags(cobraCommand.Flags())
+
+	// the converter registers all its flags on a flag set of its own, that the cobra command
+	// then shares those it uses from
+	converterFlags := pflag.NewFlagSet(cobraCommand.Name(), pflag.ContinueOnError)
+	c := converter.NewConverterFromFlags(converterFlags, nil)
+	used := make(map[string]bool, len(cmd.converterFlags))
+	for _, name := range cmd.converterFlags {
+		used[name] = true
+	}
+	converterFlags.VisitAll(func(flag *pflag.Flag) {
+		if cmd.converterFlags == nil || used[flag.Name] {
+			cobraCommand.Flags().AddFlag(flag)
+		}
+	})
+
+	cobraCommand.RunE = func(*cobra.Command, []string) error {
+		// cobra has already parsed the shared flags; this keeps the converter from parsing
+		// the process' arguments itself
+		if err := converterFlags.Parse(nil); err != nil {
+			return err
+		}
+		return run(c)
+	}
+}
+
 //func oldmain() { // TODO wkpo
 //	klog.InitFlags(nil)
 //
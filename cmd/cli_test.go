@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/wk8/go-conversion-gen/pkg/converter"
+)
+
+func TestCommands(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "bare invocation",
+			expected: defaultCommand,
+		},
+		{
+			name:     "bare invocation with flags",
+			args:     []string{"--output-file-base", "zz_generated.conversion"},
+			expected: defaultCommand,
+		},
+		{
+			name:     "explicit default command",
+			args:     []string{defaultCommand},
+			expected: defaultCommand,
+		},
+		{
+			name:     "other command",
+			args:     []string{"verify"},
+			expected: "verify",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ran := ""
+			defer func(original map[string]command) { commands = original }(commands)
+			commands = make(map[string]command)
+			for _, name := range []string{defaultCommand, "verify"} {
+				name := name
+				commands[name] = command{
+					addFlags: func(*pflag.FlagSet) func(c *converter.Converter) error {
+						return func(*converter.Converter) error {
+							ran = name
+							return nil
+						}
+					},
+				}
+			}
+
+			root := newRootCommand()
+			root.SetArgs(tc.args)
+			if err := root.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if ran != tc.expected {
+				t.Errorf("expected %q to run, got %q", tc.expected, ran)
+			}
+		})
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	root := newRootCommand()
+
+	all := pflag.NewFlagSet("all", pflag.ContinueOnError)
+	converter.NewConverterFromFlags(all, nil)
+	for name, cmd := range commands {
+		for _, flag := range cmd.converterFlags {
+			if all.Lookup(flag) == nil {
+				t.Errorf("command %q uses unknown converter flag %q", name, flag)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		command    string
+		present    []string
+		notPresent []string
+	}{
+		{
+			command: "",
+			present: []string{"input-dirs", "skip-unsafe", "equality-functions"},
+		},
+		{
+			command: defaultCommand,
+			present: []string{"input-dirs", "skip-unsafe", "equality-functions"},
+		},
+		{
+			command: "plan",
+			present: []string{"input-dirs", "skip-unsafe", "equality-functions"},
+		},
+		{
+			command:    "clean",
+			present:    []string{"input-dirs", "equality-functions", "force", "dry-run"},
+			notPresent: []string{"skip-unsafe", "output-base", "trace-build-tag"},
+		},
+		{
+			command:    "init-example",
+			present:    []string{"tag-name", "force", "example-dir"},
+			notPresent: []string{"input-dirs", "skip-unsafe"},
+		},
+	} {
+		cmd := root
+		if tc.command != "" {
+			var err error
+			if cmd, _, err = root.Find([]string{tc.command}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, flag := range tc.present {
+			if cmd.Flags().Lookup(flag) == nil {
+				t.Errorf("expected command %q to have flag %q", tc.command, flag)
+			}
+		}
+		for _, flag := range tc.notPresent {
+			if cmd.Flags().Lookup(flag) != nil {
+				t.Errorf("expected command %q not to have flag %q", tc.command, flag)
+			}
+		}
+	}
+}
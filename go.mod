@@ -4,14 +4,18 @@ go 1.17
 
 require (
 	github.com/pkg/errors v0.9.1
+	github.com/spf13/cobra v1.5.0
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/mod v0.2.0
+	golang.org/x/tools v0.0.0-20200505023115-26f46d2f7ef8
 	k8s.io/gengo v0.0.0-20211129171323-c02415ce4185
 	k8s.io/klog/v2 v2.2.0
+	sigs.k8s.io/yaml v1.2.0
 )
 
 require (
 	github.com/go-logr/logr v0.2.0 // indirect
-	golang.org/x/mod v0.2.0 // indirect
-	golang.org/x/tools v0.0.0-20200505023115-26f46d2f7ef8 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -0,0 +1,191 @@
+package converter
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/klog/v2"
+)
+
+// Backend decides how generated files get assembled.
+type Backend string
+
+const (
+	// BackendSnippets concatenates the generated code as is, and formats it; files that don't
+	// parse still get written, to help debugging. This is the default.
+	BackendSnippets Backend = "snippets"
+	// BackendAST builds generated files as syntax trees, from their imports and from the parsed
+	// generated declarations, and prints them with go/printer: files are only ever written if
+	// they're syntactically valid, and ASTPostProcessors can modify them in a structured way.
+	BackendAST Backend = "ast"
+	// BackendStreaming formats and writes generated files one top-level declaration at a time,
+	// instead of parsing and formatting them as a whole: this saves the syntax tree and formatted
+	// copy of the whole file, but not its generated code, that gengo buffers in full beforehand;
+	// so memory usage still grows with the size of packages. Imports are grouped like with
+	// BackendSnippets, unused ones being left out.
+	BackendStreaming Backend = "streaming"
+)
+
+// An ASTPostProcessor modifies a generated file's syntax tree before it gets printed, e.g. to
+// rewrite imports or to remove declarations; see BackendAST. Post-processors run in order, and
+// positions of the nodes they add don't matter.
+type ASTPostProcessor func(fileSet *token.FileSet, file *ast.File) error
+
+// RewriteImports returns a post-processor replacing imports of the given packages, by import
+// path, with the packages they map to; e.g. to point generated code to forks.
+func RewriteImports(rewrites map[string]string) ASTPostProcessor {
+	return func(fileSet *token.FileSet, file *ast.File) error {
+		for from, to := range rewrites {
+			astutil.RewriteImport(fileSet, file, from, to)
+		}
+		return nil
+	}
+}
+
+// an astFileType assembles Go files for BackendAST.
+type astFileType struct {
+	postProcessors []ASTPostProcessor
+}
+
+func newASTFileType(postProcessors []ASTPostProcessor) *astFileType {
+	return &astFileType{postProcessors: postProcessors}
+}
+
+func (ft *astFileType) AssembleFile(f *gengogenerator.File, path string) error {
+	klog.V(5).Infof("Assembling file %q", path)
+	content, err := ft.render(f)
+	if err != nil {
+		return errors.Wrapf(err, "unable to assemble %q", path)
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, content, 0644), "unable to write %q", path)
+}
+
+func (ft *astFileType) VerifyFile(f *gengogenerator.File, path string) error {
+	klog.V(5).Infof("Verifying file %q", path)
+	content, err := ft.render(f)
+	if err != nil {
+		return errors.Wrapf(err, "unable to assemble %q", path)
+	}
+	existing, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return errors.Errorf("%q does not exist", filepath.Join(f.PackageName, f.Name))
+	} else if err != nil {
+		return errors.Wrapf(err, "unable to read %q for comparison", path)
+	}
+	if !bytes.Equal(content, existing) {
+		return errors.Errorf("output for %q differs", filepath.Join(f.PackageName, f.Name))
+	}
+	return nil
+}
+
+// render returns the content of f: its header, followed by the printed syntax tree.
+func (ft *astFileType) render(f *gengogenerator.File) ([]byte, error) {
+	fileSet := token.NewFileSet()
+	file, err := buildASTFile(fileSet, f)
+	if err != nil {
+		return nil, err
+	}
+	for _, postProcess := range ft.postProcessors {
+		if err := postProcess(fileSet, file); err != nil {
+			return nil, errors.Wrap(err, "post-processing failed")
+		}
+	}
+
+	printed := bytes.NewBuffer(append([]byte{}, f.Header...))
+	if err := format.Node(printed, fileSet, file); err != nil {
+		return nil, errors.Wrap(err, "unable to print syntax tree")
+	}
+	// groups imports the same way as BackendSnippets
+	result, err := imports.Process("", printed.Bytes(), nil)
+	return result, errors.Wrap(err, "unable to process imports")
+}
+
+// buildASTFile builds the syntax tree of f, without its header.
+func buildASTFile(fileSet *token.FileSet, f *gengogenerator.File) (*ast.File, error) {
+	file := &ast.File{Name: ast.NewIdent(f.PackageName)}
+
+	// declarations get parsed on their own, with their comments
+	source := &bytes.Buffer{}
+	source.WriteString("package " + f.PackageName + "\n\n")
+	if f.Vars.Len() != 0 {
+		source.WriteString("var (\n" + f.Vars.String() + ")\n\n")
+	}
+	if f.Consts.Len() != 0 {
+		source.WriteString("const (\n" + f.Consts.String() + ")\n\n")
+	}
+	source.Write(f.Body.Bytes())
+	parsed, err := parser.ParseFile(fileSet, f.Name, source.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "generated code is not valid Go")
+	}
+	file.Package = parsed.Package
+	file.Name.NamePos = parsed.Name.NamePos
+	file.Comments = parsed.Comments
+
+	if len(f.Imports) != 0 {
+		// imports are positioned right after the package clause, so that no comment ends up in there
+		position := parsed.Name.End()
+		importDecl := &ast.GenDecl{TokPos: position, Tok: token.IMPORT, Lparen: position, Rparen: position}
+		for _, importLine := range sortedImportLines(f.Imports) {
+			spec, err := importSpec(importLine, position)
+			if err != nil {
+				return nil, err
+			}
+			importDecl.Specs = append(importDecl.Specs, spec)
+			file.Imports = append(file.Imports, spec)
+		}
+		file.Decls = append(file.Decls, importDecl)
+	}
+	file.Decls = append(file.Decls, parsed.Decls...)
+	return file, nil
+}
+
+// sortedImportLines returns the import lines in imports, sorted by import path.
+func sortedImportLines(imports map[string]struct{}) []string {
+	lines := make([]string, 0, len(imports))
+	for line := range imports {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return importLinePath(lines[i]) < importLinePath(lines[j])
+	})
+	return lines
+}
+
+// importLinePath returns the quoted import path of importLine, of the form either `path`,
+// `"path"` or `name "path"`.
+func importLinePath(importLine string) string {
+	if space := strings.LastIndex(importLine, " "); space != -1 {
+		return importLine[space+1:]
+	}
+	return importLine
+}
+
+// importSpec builds the import spec for importLine, see importLinePath, at the given position.
+func importSpec(importLine string, position token.Pos) (*ast.ImportSpec, error) {
+	spec := &ast.ImportSpec{}
+	path := importLinePath(importLine)
+	if path != importLine {
+		spec.Name = &ast.Ident{NamePos: position, Name: strings.TrimSpace(strings.TrimSuffix(importLine, path))}
+	}
+	if !strings.HasPrefix(path, `"`) {
+		path = strconv.Quote(path)
+	} else if _, err := strconv.Unquote(path); err != nil {
+		return nil, errors.Wrapf(err, "invalid import %q", importLine)
+	}
+	spec.Path = &ast.BasicLit{ValuePos: position, Kind: token.STRING, Value: path}
+	return spec, nil
+}
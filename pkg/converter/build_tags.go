@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"go/build"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/args"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+)
+
+// buildTags returns the build tags that packages get loaded with: the generated build tag, and
+// Options.BuildTags.
+func (c *Converter) buildTags() []string {
+	return append([]string{c.args.GeneratedBuildTag}, c.Options.BuildTags...)
+}
+
+// installBuildTags makes packages get loaded with Options.BuildTags, on top of the generated build
+// tag, by gengo's parser as well as when checking syntax. The returned function must be called
+// once done.
+func (c *Converter) installBuildTags() (restore func()) {
+	previous := build.Default.BuildTags
+	build.Default.BuildTags = append(append([]string{}, previous...), c.Options.BuildTags...)
+	return func() { build.Default.BuildTags = previous }
+}
+
+// A constrainedGenerator generates the conversions of an input package that need a given build
+// constraint, into a file of their own with that constraint; see Options.ConstrainedFiles.
+type constrainedGenerator struct {
+	buildConstraint string
+	generator       *generator.Generator
+	// fileName is the name of the file it generates.
+	fileName string
+}
+
+// constrainedGenerators returns the generators for the conversions of pkg that need build
+// constraints, if configured and if there are any; conversionGenerator then no longer generates
+// them.
+func (c *Converter) constrainedGenerators(context *gengogenerator.Context, pkg *types.Package, outputPackage string, conversionGenerator *generator.Generator) ([]constrainedGenerator, error) {
+	if !c.Options.ConstrainedFiles {
+		return nil, nil
+	}
+	buildConstraints, err := conversionGenerator.BuildConstraints(context)
+	if err != nil || len(buildConstraints) == 0 {
+		return nil, err
+	}
+
+	var generators []constrainedGenerator
+	for _, buildConstraint := range buildConstraints {
+		baseName := c.args.OutputFileBaseName + "." + constraintFileSuffix(buildConstraint)
+		constrained, err := generator.NewConversionGenerator(
+			context,
+			baseName,
+			pkg.Path,
+			outputPackage,
+			c.Options.BasePeerPackages,
+			c.Options.GeneratorOptions,
+		)
+		if err != nil {
+			return nil, err
+		}
+		constrained.RestrictToBuildConstraint(buildConstraint)
+		generators = append(generators, constrainedGenerator{
+			buildConstraint: buildConstraint,
+			generator:       constrained,
+			fileName:        baseName + ".go",
+		})
+	}
+	conversionGenerator.RestrictToBuildConstraint("")
+	return generators, nil
+}
+
+// constraintFileSuffix turns a build constraint into a suffix for file names, e.g.
+// "linux-and-not-arm" for "linux && !arm"; without underscores, as these would make file names
+// imply build constraints of their own.
+func constraintFileSuffix(buildConstraint string) string {
+	return strings.NewReplacer(
+		"&&", "-and-",
+		"||", "-or-",
+		"!", "not-",
+		"(", "",
+		")", "",
+		" ", "",
+		"_", "-",
+	).Replace(buildConstraint)
+}
+
+// filesHeaderPackage is a package whose files can have headers of their own, e.g. with additional
+// build constraints.
+type filesHeaderPackage struct {
+	*gengogenerator.DefaultPackage
+	// fileHeaders are the headers of the files that don't get the package's default one, by name.
+	fileHeaders map[string][]byte
+}
+
+func (p *filesHeaderPackage) Header(fileName string) []byte {
+	if header, found := p.fileHeaders[fileName]; found {
+		return header
+	}
+	return p.DefaultPackage.Header(fileName)
+}
+
+// constrainedFileName returns the name to give a file named fileName, written for a constrained
+// generator, see Options.ConstrainedFiles; e.g. "conversion_manual_todo.linux.go".
+func constrainedFileName(fileName, buildConstraint string) string {
+	if buildConstraint == "" {
+		return fileName
+	}
+	extension := filepath.Ext(fileName)
+	return strings.TrimSuffix(fileName, extension) + "." + constraintFileSuffix(buildConstraint) + extension
+}
+
+// constrainedFileHeaders returns the headers of the files written by the given constrained
+// generators, by name: with their build constraints on top of the usual ones.
+func (c *Converter) constrainedFileHeaders(arguments *args.GeneratorArgs, outputPkg *types.Package, constrained []constrainedGenerator, boilerplate []byte) (map[string][]byte, error) {
+	headers := make(map[string][]byte)
+	for _, constrainedGenerator := range constrained {
+		if err := c.checkOverwrite(filepath.Join(arguments.OutputBase, outputPkg.Path, constrainedGenerator.fileName)); err != nil {
+			return nil, err
+		}
+
+		constraints := append(append([]string{}, c.Options.BuildConstraints...), constrainedGenerator.buildConstraint)
+		constraintsHeader, err := buildConstraintsHeader(arguments.GeneratedBuildTag, constraints, c.Options.OmitLegacyBuildLines)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed building header")
+		}
+		headers[constrainedGenerator.fileName] = appendGeneratedFileMarker(append(constraintsHeader, boilerplate...))
+	}
+	return headers, nil
+}
@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConstrainedFileName(t *testing.T) {
+	for _, testCase := range []struct {
+		fileName, buildConstraint, expected string
+	}{
+		{"zz_generated.conversion.go", "", "zz_generated.conversion.go"},
+		{"zz_generated.conversion.go", "linux", "zz_generated.conversion.linux.go"},
+		{"conversion_manual_todo.md", "linux && !arm", "conversion_manual_todo.linux-and-not-arm.md"},
+		{"equality.go", "(darwin || freebsd) && cgo", "equality.darwin-or-freebsd-and-cgo.go"},
+		{"conversion.go", "my_tag", "conversion.my-tag.go"},
+	} {
+		if actual := constrainedFileName(testCase.fileName, testCase.buildConstraint); actual != testCase.expected {
+			t.Errorf("expected %q for %q with %q, got %q", testCase.expected, testCase.fileName, testCase.buildConstraint, actual)
+		}
+	}
+}
+
+func constrainedOptions(t *testing.T) *Options {
+	options := DefaultOptions()
+	options.ConstrainedFiles = true
+	options.BuildTags = []string{"special"}
+	options.PackageFiles = PackageFiles{}
+	for _, pkg := range []string{"v1", "v2"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "constrained", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			if files[i], err = filepath.Abs(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		options.PackageFiles["example.com/constrained/"+pkg] = files
+	}
+	return options
+}
+
+func TestConstrainedFiles(t *testing.T) {
+	options := constrainedOptions(t)
+	outputBase := t.TempDir()
+	converter := NewConverter([]string{"example.com/constrained/v1"}, options)
+	converter.outputBaseOverride = outputBase
+
+	if err := converter.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(outputBase, "example.com", "constrained", "v1")
+	read := func(fileName string) string {
+		content, err := ioutil.ReadFile(filepath.Join(outputDir, fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	main := read(options.OutputFileBaseName + ".go")
+	constrained := read(options.OutputFileBaseName + ".special.go")
+
+	if !strings.Contains(main, "func Convert_v1_Foo_To_v2_Foo(") || strings.Contains(main, "Bar") {
+		t.Errorf("expected only Foo's conversions in the main file, got:\n%s", main)
+	}
+	if !strings.Contains(constrained, "func Convert_v1_Bar_To_v2_Bar(") || strings.Contains(constrained, "Foo") {
+		t.Errorf("expected only Bar's conversions in the constrained file, got:\n%s", constrained)
+	}
+	if !strings.Contains(constrained, "//go:build !ignore_autogenerated && special\n") {
+		t.Errorf("expected the constrained file to have the special build constraint, got:\n%s", constrained)
+	}
+}
+
+func TestConstrainedFilesRejectGlobalGeneratorOptions(t *testing.T) {
+	options := constrainedOptions(t)
+	options.GeneratorOptions.DeduplicateLoops = true
+	converter := NewConverter([]string{"example.com/constrained/v1"}, options)
+	converter.outputBaseOverride = t.TempDir()
+
+	err := converter.Run()
+	if err == nil || !strings.Contains(err.Error(), "constrained files can't be combined with loop deduplication") {
+		t.Errorf("expected constrained files to be rejected with loop deduplication, got %v", err)
+	}
+}
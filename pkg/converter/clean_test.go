@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	// copy the fixture where it gets generated into, so that generated files land next to the sources
+	outputBase := t.TempDir()
+	options := DefaultOptions()
+	options.EqualityFunctions = true
+	options.GeneratorOptions.TraceBuildTag = "conversion_trace"
+	options.PackageFiles = PackageFiles{}
+	for _, pkg := range []string{"v1", "v2"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "equality", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := filepath.Join(outputBase, "example.com", "equality", pkg)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[i] = filepath.Join(dir, filepath.Base(file))
+			if err := ioutil.WriteFile(files[i], content, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		options.PackageFiles["example.com/equality/"+pkg] = files
+	}
+	dir := filepath.Join(outputBase, "example.com", "equality", "v1")
+
+	converter := NewConverter([]string{"example.com/equality/v1"}, options)
+	converter.outputBaseOverride = outputBase
+	if err := converter.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a generated file with a custom name, and a hand-written file named like a generated one
+	renamed := filepath.Join(dir, "renamed_generated.go")
+	if err := os.Rename(filepath.Join(dir, options.EqualityFileBaseName+".go"), renamed); err != nil {
+		t.Fatal(err)
+	}
+	handWritten := filepath.Join(dir, options.EqualityFileBaseName+".go")
+	if err := ioutil.WriteFile(handWritten, []byte("package v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, options.OutputFileBaseName+".go"),
+		filepath.Join(dir, options.OutputFileBaseName+TraceFileSuffix),
+		renamed,
+	}
+	removed, err := NewConverter([]string{"example.com/equality/v1"}, options).Clean(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("expected a dry run to list %v, got %v", expected, removed)
+	}
+	for _, path := range expected {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected a dry run to leave %q alone: %v", path, err)
+		}
+	}
+
+	removed, err = NewConverter([]string{"example.com/equality/v1"}, options).Clean(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("expected %v to be removed, got %v", expected, removed)
+	}
+	for _, path := range expected {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(handWritten); err != nil {
+		t.Errorf("expected %q to be left alone: %v", handWritten, err)
+	}
+
+	options.Force = true
+	removed, err = NewConverter([]string{"example.com/equality/v1"}, options).Clean(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{handWritten}; !reflect.DeepEqual(removed, expected) {
+		t.Errorf("expected %v to be removed when forced, got %v", expected, removed)
+	}
+}
@@ -0,0 +1,408 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+	"k8s.io/gengo/args"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Verify checks that the generated files are up to date, without writing anything.
+func (c *Converter) Verify() error {
+	c.args.VerifyOnly = true
+	defer func() { c.args.VerifyOnly = false }()
+	return c.Run()
+}
+
+// A PlannedFile is a file that the converter would generate.
+type PlannedFile struct {
+	// Package is the import path of the package the file would be generated for.
+	Package string
+	// Path is where the file would be generated.
+	Path string
+	// Functions are the names of the functions the file would contain.
+	Functions []string
+}
+
+// A Plan is what the converter would generate.
+type Plan struct {
+	// Files are the files that would be generated.
+	Files []PlannedFile
+	// Conversions are the conversions that would be generated, with how, and the problems found
+	// when generating them; in generation order.
+	Conversions []generator.PlannedConversion
+}
+
+// Plan returns the files, the functions in them, and the conversions that the converter would
+// generate; without writing anything.
+// ctx is only checked before generating, as generation can't be interrupted.
+func (c *Converter) Plan(ctx context.Context) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	err := c.runInTempDir(func(tmpDir string) error {
+		files, err := c.generatedFiles(tmpDir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			functions, err := declaredFunctions(file.GeneratedPath)
+			if err != nil {
+				return err
+			}
+			plan.Files = append(plan.Files, PlannedFile{
+				Package:   file.Package,
+				Path:      file.SourcePath,
+				Functions: functions,
+			})
+		}
+
+		for _, generated := range c.generatedPackages {
+			plan.Conversions = append(plan.Conversions, generated.generator.PlannedConversions()...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GeneratedSources returns the contents of the files that the converter would generate, indexed
+// by the import paths of the packages they would be generated for; without writing anything.
+func (c *Converter) GeneratedSources() (map[string][]byte, error) {
+	sources := make(map[string][]byte)
+	err := c.runInTempDir(func(tmpDir string) error {
+		files, err := c.generatedFiles(tmpDir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			source, err := ioutil.ReadFile(file.GeneratedPath)
+			if err != nil {
+				return errors.Wrapf(err, "unable to read %q", file.GeneratedPath)
+			}
+			sources[file.Package] = source
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// GeneratedEqualitySources is the same as GeneratedSources, for the files of equality functions
+// that the converter would generate, see Options.EqualityFunctions; indexed by the import paths
+// of the packages whose types they compare, as packages sharing an output package get a file
+// each.
+func (c *Converter) GeneratedEqualitySources() (map[string][]byte, error) {
+	sources := make(map[string][]byte)
+	err := c.runInTempDir(func(tmpDir string) error {
+		if !c.Options.EqualityFunctions {
+			return nil
+		}
+		for _, generated := range c.generatedPackages {
+			if generated.buildConstraint != "" {
+				// equality functions aren't restricted to build constraints
+				continue
+			}
+			path := filepath.Join(tmpDir, generated.output.Path, generated.perPackageFileName(c.Options.EqualityFileBaseName+".go"))
+			source, err := ioutil.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return errors.Wrapf(err, "unable to read %q", path)
+			}
+			sources[generated.pkg.Path] = source
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// declaredFunctions returns the names of the functions declared in the given Go file.
+func declaredFunctions(path string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %q", path)
+	}
+
+	var functions []string
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			functions = append(functions, funcDecl.Name.Name)
+		}
+	}
+	return functions, nil
+}
+
+// WritePlan writes a human-readable version of the plan.
+func WritePlan(w io.Writer, plan *Plan) error {
+	buffer := &bytes.Buffer{}
+	for _, file := range plan.Files {
+		fmt.Fprintf(buffer, "%s (%s):\n", file.Package, file.Path)
+		for _, function := range file.Functions {
+			fmt.Fprintf(buffer, "  %s\n", function)
+		}
+	}
+	if len(plan.Conversions) != 0 {
+		fmt.Fprintf(buffer, "conversions:\n")
+	}
+	for _, conversion := range plan.Conversions {
+		fmt.Fprintf(buffer, "  %v -> %v (%s): %s", conversion.InType, conversion.OutType, conversion.Direction, conversion.Strategy)
+		if conversion.Function != "" {
+			fmt.Fprintf(buffer, ", %s", conversion.Function)
+		}
+		buffer.WriteString("\n")
+		for _, warning := range conversion.Warnings {
+			fmt.Fprintf(buffer, "    warning: %s\n", warning)
+		}
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// Clean removes previously generated files from the input packages, or from the packages that
+// their conversions get generated into, and returns their paths. Generated files are those listed
+// by ListGeneratedFiles, which includes e.g. trace and equality files, whatever their names.
+// If Options.Force is set, it also removes files named like the ones it generates even if they
+// don't look generated.
+// If dryRun is true, it only returns the paths of the files it would remove.
+func (c *Converter) Clean(dryRun bool) (removed []string, err error) {
+	var sourcePaths []string
+	seen := make(map[string]bool)
+	if err := c.execute(func(context *gengogenerator.Context, arguments *args.GeneratorArgs) gengogenerator.Packages {
+		for _, input := range context.Inputs {
+			pkg := context.Universe[input]
+			if pkg == nil {
+				continue
+			}
+			// see Options.OutputPackagesByGroup
+			if outputPackage := c.outputPackageFor(pkg); outputPackage != pkg.Path {
+				if pkg, _ = context.AddDirectory(outputPackage); pkg == nil {
+					continue
+				}
+			}
+			if pkg.SourcePath != "" && !seen[pkg.SourcePath] {
+				seen[pkg.SourcePath] = true
+				sourcePaths = append(sourcePaths, pkg.SourcePath)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(sourcePaths)
+	var paths []string
+	for _, sourcePath := range sourcePaths {
+		generated, err := ListGeneratedFiles(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range generated {
+			// subdirectories are other packages
+			if filepath.Dir(path) == filepath.Clean(sourcePath) {
+				paths = append(paths, path)
+			}
+		}
+
+		if c.Options.Force {
+			forced := []string{c.args.OutputFileBaseName + ".go", c.args.OutputFileBaseName + TraceFileSuffix}
+			if c.Options.EqualityFunctions {
+				forced = append(forced, c.Options.EqualityFileBaseName+".go")
+			}
+			for _, name := range forced {
+				path := filepath.Join(sourcePath, name)
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					continue
+				} else if err != nil {
+					return removed, errors.Wrapf(err, "unable to stat %q", path)
+				}
+				if !IsGeneratedFile(path) {
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if !dryRun {
+			klog.V(2).Infof("Removing %q", path)
+			if err := os.Remove(path); err != nil {
+				return removed, errors.Wrapf(err, "unable to remove %q", path)
+			}
+		}
+		removed = append(removed, path)
+	}
+	return
+}
+
+// Report runs the converter without writing anything, and returns the diagnostics about
+// conversions that need manual work.
+func (c *Converter) Report() ([]*generator.Diagnostic, error) {
+	if err := c.parseCLIFlags(); err != nil {
+		return nil, err
+	}
+	if c.Options.GeneratorOptions.Diagnostics == nil {
+		c.Options.GeneratorOptions.Diagnostics = generator.NewDiagnosticsCollector()
+	}
+	collector := c.Options.GeneratorOptions.Diagnostics
+
+	if err := c.runInTempDir(func(string) error { return nil }); err != nil {
+		return nil, err
+	}
+
+	collector.ResolvePositions()
+	return collector.Diagnostics(), nil
+}
+
+// WriteReport writes a human-readable version of the diagnostics.
+func WriteReport(w io.Writer, diagnostics []*generator.Diagnostic) error {
+	buffer := &bytes.Buffer{}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Position.IsValid() {
+			fmt.Fprintf(buffer, "%s: ", diagnostic.Position)
+		}
+		fmt.Fprintf(buffer, "%s [%s]: %s\n", diagnostic.Severity, diagnostic.Category, diagnostic.Message)
+	}
+	fmt.Fprintf(buffer, "%d problem(s) found\n", len(diagnostics))
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// CompatReport returns the differences between the structs of the input packages and their peer
+// types, as an API compatibility check; without writing anything.
+func (c *Converter) CompatReport() ([]generator.CompatChange, error) {
+	var changes []generator.CompatChange
+	err := c.runInTempDir(func(string) error {
+		for _, generated := range c.generatedPackages {
+			changes = append(changes, generated.generator.CompatChanges()...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// WriteCompatReport writes a human-readable version of the changes.
+func WriteCompatReport(w io.Writer, changes []generator.CompatChange) error {
+	buffer := &bytes.Buffer{}
+	for _, change := range changes {
+		fmt.Fprintf(buffer, "%s [%s]: %s\n", change.Severity, change.Kind, change.Message)
+	}
+	fmt.Fprintf(buffer, "%d change(s) found, %d breaking\n", len(changes), BreakingCompatChanges(changes))
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// TagSuggestions returns the tags and field mappings that would let the generator take over the
+// manual conversion functions of the input packages, without writing anything; see
+// generator.Generator.TagSuggestions.
+func (c *Converter) TagSuggestions() ([]generator.TagSuggestion, error) {
+	var suggestions []generator.TagSuggestion
+	err := c.runInTempDir(func(string) error {
+		for _, generated := range c.generatedPackages {
+			pkgSuggestions, err := generated.generator.TagSuggestions()
+			if err != nil {
+				return err
+			}
+			suggestions = append(suggestions, pkgSuggestions...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// WriteTagSuggestions writes a human-readable version of the suggestions.
+func WriteTagSuggestions(w io.Writer, suggestions []generator.TagSuggestion) error {
+	buffer := &bytes.Buffer{}
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(buffer, "%s: %s [%s]: %s\n", suggestion.Position, suggestion.Function.Name.Name, suggestion.Kind, suggestion.Message)
+		switch {
+		case suggestion.Tag != "":
+			fmt.Fprintf(buffer, "    add \"// %s\" to %s\n", suggestion.Tag, suggestion.Target)
+		case suggestion.Mapping != nil:
+			for _, field := range suggestion.Mapping.Fields {
+				fmt.Fprintf(buffer, "    add field mapping %s from %s to %s\n", field, suggestion.Mapping.From, suggestion.Mapping.To)
+			}
+		}
+	}
+	fmt.Fprintf(buffer, "%d suggestion(s)\n", len(suggestions))
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// WriteSuggestedFieldMappings adds the field mappings of the given suggestions to the field
+// mappings file at path, see generator.LoadFieldMappings; creating it if it doesn't exist.
+func WriteSuggestedFieldMappings(path string, suggestions []generator.TagSuggestion) error {
+	mappings := &generator.FieldMappings{}
+	if _, err := os.Stat(path); err == nil {
+		if mappings, err = generator.LoadFieldMappings(path); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "unable to stat %q", path)
+	}
+
+	for _, suggestion := range suggestions {
+		if suggestion.Mapping == nil {
+			continue
+		}
+		added := false
+		for i := range mappings.Conversions {
+			conversion := &mappings.Conversions[i]
+			if conversion.From == suggestion.Mapping.From && conversion.To == suggestion.Mapping.To {
+				conversion.Fields = append(conversion.Fields, suggestion.Mapping.Fields...)
+				added = true
+				break
+			}
+		}
+		if !added {
+			mappings.Conversions = append(mappings.Conversions, *suggestion.Mapping)
+		}
+	}
+
+	raw, err := yaml.Marshal(mappings)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize field mappings")
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, raw, 0644), "unable to write field mappings %q", path)
+}
+
+// BreakingCompatChanges returns how many of the changes are breaking, i.e. have error severity.
+func BreakingCompatChanges(changes []generator.CompatChange) (breaking int) {
+	for _, change := range changes {
+		if change.Severity == generator.SeverityError {
+			breaking++
+		}
+	}
+	return
+}
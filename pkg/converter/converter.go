@@ -2,9 +2,20 @@ package converter
 
 // TODO wkpo lint and goimports...
 import (
+	"bytes"
+	goflag "flag"
 	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	"github.com/wk8/go-conversion-gen/pkg/generator"
+	"golang.org/x/tools/imports"
 	"k8s.io/gengo/args"
 	gengogenerator "k8s.io/gengo/generator"
 	"k8s.io/gengo/namer"
@@ -17,8 +28,56 @@ type Converter struct {
 	Options *Options
 
 	args *args.GeneratorArgs
+	// flagSet is the flag set that flags are registered on, if built from flags.
+	flagSet *pflag.FlagSet
+
+	// generatedPackages are the packages that conversion code was generated for during the last run.
+	generatedPackages []generatedPackage
+	// outputSourcePaths are the source directories of the packages that files were generated into
+	// during the last run, indexed by import path: output packages, and facade packages.
+	outputSourcePaths map[string]string
+	// outputBase is the output base used during the last run.
+	outputBase string
+	// staleFiles are the files previously generated for the input packages skipped during the last
+	// run as they had nothing left to convert, with their output packages; see removeStaleFiles.
+	staleFiles map[string]string
+	// cliFlagsParsed is true once CLI flags have been parsed.
+	cliFlagsParsed bool
+	// outputBaseOverride, if set, overrides the output base; see runInTempDir.
+	outputBaseOverride string
+	// results are what happened to each input package during the last run.
+	results []PackageResult
+	// manualConversionsCache is the cache loaded from Options.ManualConversionsCacheFile, if set.
+	manualConversionsCache *generator.ManualConversionsCache
+	// changedDirs are the directories that changed since Options.Since during the last run, if set.
+	changedDirs map[string]bool
+	// importedPackages caches the packages that input and peer packages import, transitively, as
+	// go/build finds them, indexed by import path; see changedSince.
+	importedPackages map[string]*build.Package
+	// deferredFiles holds the files generated during the last run until checks over all of them
+	// pass, if any such checks are enabled; see writeDeferredFiles.
+	deferredFiles *deferringFileType
 }
 
+// a generatedPackage is a package that conversion code was generated for.
+type generatedPackage struct {
+	pkg       *types.Package
+	generator *generator.Generator
+	// output is the package that the package's conversions end up in, see
+	// Options.OutputPackagesByGroup.
+	output *types.Package
+	// boilerplate is the package's header, without build constraints.
+	boilerplate []byte
+	// fileName is the name of the file that the package's conversions end up in.
+	fileName string
+	// buildConstraint is the build constraint of the conversions that generator generates, if
+	// restricted to these; see Options.ConstrainedFiles.
+	buildConstraint string
+}
+
+// NewConverter builds a converter for the given target packages, which can also be patterns
+// such as "./..." or "example.com/api/*/v1". Options get validated when running, see
+// Options.Validate.
 func NewConverter(targetPackages []string, options *Options) *Converter {
 	args := defaultGenericArgs()
 	args.WithoutDefaultFlagParsing()
@@ -45,7 +104,79 @@ type customCLIArgs struct {
 	functionTagName                   string
 	peerPackagesTagName               string
 	basePeerPackages                  []string
+	readOnlyPeerPackages              []string
 	noPublicConversionFunctionOnError bool
+	publicConversionFunctionOnError   bool
+	diagnosticsFile                   string
+	diagnosticsFormat                 string
+	usePackagesDriver                 bool
+	packageFilesManifest              string
+	targetPlatforms                   []string
+	valueInputMaxSize                 int64
+	builtinConversionPolicy           string
+	funcChanPolicy                    string
+	since                             string
+	optionalScalars                   bool
+	optionalScalarsKeepZeros          bool
+	nilElementsPolicy                 string
+	buildConstraints                  []string
+	omitLegacyBuildLines              bool
+	buildTags                         []string
+	constrainedFiles                  bool
+	headerTemplateFile                string
+	templatesFile                     string
+	fieldMappingsFile                 string
+	genericHelpers                    bool
+	deduplicateLoops                  bool
+	traceBuildTag                     string
+	traceVerbosity                    int
+	sizeReport                        bool
+	interfaceConversionFunction       string
+	interfaceAdapterFunction          string
+	localImportPrefix                 string
+	majorVersionEquivalence           bool
+	pruneCastableConversions          bool
+	viewFunctions                     bool
+	privatePeerTypes                  bool
+	force                             bool
+	spliceFileBaseName                string
+	dynamicValuesPolicy               string
+	dynamicValuesDeepCopyFunction     string
+	errorWrappingPolicy               string
+	errorWrappingFunction             string
+	sizeBudget                        int
+	strictSizeBudget                  bool
+	genericHelpersPackage             string
+	scaffold                          bool
+	equalityFunctions                 bool
+	todoReportFormat                  string
+	graphFormat                       string
+	profile                           string
+	profileFile                       string
+	graphFile                         string
+	fieldDocsDir                      string
+	fieldDocsFormat                   string
+	detectCycles                      bool
+	explain                           []string
+	registryFunction                  string
+	metricsFunction                   string
+	privateFunctionPrefix             string
+	noPrivateFunctions                bool
+	implementersOf                    string
+	files                             []string
+	handlerPlugin                     string
+	backend                           string
+	rewriteImports                    map[string]string
+	outputPackagesByGroup             map[string]string
+	missingFieldsHandlers             []string
+	inconvertibleFieldsHandlers       []string
+	missingFieldsPolicy               string
+	inconvertibleFieldsPolicy         string
+	failFast                          bool
+	failOnIncompleteConversions       bool
+	manualConversionsCacheFile        string
+	unsupportedTypesPolicy            string
+	externalConversionsPolicy         string
 }
 
 // TODO wkpo makes sense? should it be called on
@@ -53,6 +184,23 @@ type customCLIArgs struct {
 func (ca *customCLIArgs) addFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&ca.noUnsafeConversions, "skip-unsafe", ca.noUnsafeConversions,
 		"If true, will not generate code using unsafe pointer conversions; resulting code may be slower.")
+	fs.StringSliceVar(&ca.targetPlatforms, "target-platforms", ca.targetPlatforms,
+		"Comma-separated list of <GOOS>/<GOARCH> platforms the generated code targets; if set, unsafe conversions will also be used between builtin types that have the same memory layout on all of them (e.g. int and int64 on 64-bit platforms).")
+	fs.Int64Var(&ca.valueInputMaxSize, "value-input-max-size", ca.valueInputMaxSize,
+		"If positive, public conversion functions generated from structs of at most that many bytes take their input by value rather than by pointer.")
+	fs.StringVar(&ca.builtinConversionPolicy, "builtin-conversion-policy", ca.builtinConversionPolicy,
+		"How to convert between different builtin types, e.g. int32 and int64: either \""+string(generator.BuiltinConversionCast)+"\" (default), \""+
+			string(generator.BuiltinConversionCheck)+"\" to return an error when values don't fit in integer destination types, or \""+string(generator.BuiltinConversionForbid)+"\".")
+	fs.StringVar(&ca.funcChanPolicy, "func-chan-policy", ca.funcChanPolicy,
+		"How to handle values of function and channel types: either \""+string(generator.FuncChanUnsupported)+"\" (default) to handle them like other unsupported values, \""+
+			string(generator.FuncChanSkip)+"\" to leave them unconverted silently, \""+string(generator.FuncChanCopy)+"\" to copy them as is, or \""+string(generator.FuncChanError)+"\" to fail.")
+	fs.BoolVar(&ca.optionalScalars, "optional-scalars", ca.optionalScalars,
+		"If true, pointers to builtin types (e.g. proto3 optional fields) will be converted to and from plain builtin types: nil pointers to zero values (or to the value of a \"+<tag-name>=default:<value>\" field tag), and zero values to nil pointers.")
+	fs.BoolVar(&ca.optionalScalarsKeepZeros, "optional-scalars-keep-zeros", ca.optionalScalarsKeepZeros,
+		"If true along with --optional-scalars, zero values will be converted to pointers to zero values rather than to nil pointers.")
+	fs.StringVar(&ca.nilElementsPolicy, "nil-elements-policy", ca.nilElementsPolicy,
+		"How to convert nil elements of slices of pointers to slices of values: either \""+string(generator.NilElementsToZero)+"\" (default) to convert them to zero values, or \""+
+			string(generator.NilElementsToError)+"\" to return an error.")
 	fs.StringVar(&ca.tagName, "tag-name", ca.tagName,
 		"comment tag. \"+<tag-name>=false\" in a type's comment will skip that type; \"+<tag-name>=no-public\" will skip generating public conversion functions either to or from it - it will still generate private conversion functions")
 	fs.StringVar(&ca.functionTagName, "function-tag-name", ca.functionTagName,
@@ -62,14 +210,183 @@ func (ca *customCLIArgs) addFlags(fs *pflag.FlagSet) {
 		"\"+<tag-name>=<peer-pkg-1>,<peer-pkg-2>\" in an input package's doc.go file will instruct the converter to look for that package's peer types in the specified peer packages")
 	fs.StringSliceVar(&ca.basePeerPackages, "base-peer-packages", ca.basePeerPackages,
 		"Comma-separated list of peer packages to be shared between all inputs - that's where the converter looks for peer types to generate conversion functions.")
+	fs.StringSliceVar(&ca.readOnlyPeerPackages, "read-only-peer-packages", ca.readOnlyPeerPackages,
+		"Comma-separated list of peer packages that conversions must not write into, e.g. vendored third-party packages; only conversions from their types are generated.")
 	fs.BoolVar(&ca.noPublicConversionFunctionOnError, "no-public-conversion-function-on-error", ca.noPublicConversionFunctionOnError,
-		"If true, will not generate a public conversion function if it's unable to generate conversion code for any field - it will still generate a private conversion function that you can then wrap in your own public function.")
+		"If true, will not generate a public conversion function if it's unable to generate conversion code for any field - it will still generate a private conversion function that you can then wrap in your own public function. Same as setting both --missing-fields-policy and --inconvertible-fields-policy to \""+string(HandlerPolicyError)+"\".")
+	fs.StringVar(&ca.missingFieldsPolicy, "missing-fields-policy", ca.missingFieldsPolicy,
+		"What happens with fields that don't exist in peer types: either "+"\""+string(HandlerPolicyIgnore)+"\", \""+string(HandlerPolicyWarn)+"\" (default), \""+string(HandlerPolicyComment)+"\" or \""+string(HandlerPolicyError)+"\".")
+	fs.StringVar(&ca.inconvertibleFieldsPolicy, "inconvertible-fields-policy", ca.inconvertibleFieldsPolicy,
+		"What happens with fields with inconvertible types: either "+"\""+string(HandlerPolicyIgnore)+"\", \""+string(HandlerPolicyWarn)+"\" (default), \""+string(HandlerPolicyComment)+"\" or \""+string(HandlerPolicyError)+"\".")
+	fs.StringVar(&ca.unsupportedTypesPolicy, "unsupported-types-policy", ca.unsupportedTypesPolicy,
+		"What happens with types that the generator doesn't know how to convert: either "+"\""+string(HandlerPolicyIgnore)+"\", \""+string(HandlerPolicyWarn)+"\" (default), \""+string(HandlerPolicyComment)+"\" or \""+string(HandlerPolicyError)+"\".")
+	fs.StringVar(&ca.externalConversionsPolicy, "external-conversions-policy", ca.externalConversionsPolicy,
+		"What happens with conversions to or from types of packages that conversions aren't generated for: either "+"\""+string(HandlerPolicyIgnore)+"\", \""+string(HandlerPolicyWarn)+"\" (default), \""+string(HandlerPolicyComment)+"\" or \""+string(HandlerPolicyError)+"\".")
+	fs.StringSliceVar(&ca.missingFieldsHandlers, "missing-fields-handlers", ca.missingFieldsHandlers,
+		"Comma-separated built-in handlers for fields that don't exist in peer types, tried in order until one handles them: \""+
+			FieldsHandlerError+"\", \""+FieldsHandlerWarn+"\" or \""+FieldsHandlerReport+"\". Overrides --no-public-conversion-function-on-error.")
+	fs.StringSliceVar(&ca.inconvertibleFieldsHandlers, "inconvertible-fields-handlers", ca.inconvertibleFieldsHandlers,
+		"Comma-separated built-in handlers for fields with inconvertible types, tried in order until one handles them: \""+
+			FieldsHandlerError+"\", \""+FieldsHandlerWarn+"\", \""+FieldsHandlerZero+"\", \""+FieldsHandlerDefault+"\" or \""+FieldsHandlerReport+
+			"\"; e.g. \""+FieldsHandlerDefault+","+FieldsHandlerZero+"\". Overrides --no-public-conversion-function-on-error.")
+	fs.BoolVar(&ca.publicConversionFunctionOnError, "public-conversion-function-on-error", ca.publicConversionFunctionOnError,
+		"If true along with --no-public-conversion-function-on-error or --scaffold, will still generate public conversion functions when unable to generate conversion code for some fields, listing the problems in their doc comments; \"+<tag-name>=public-on-error\" in a type's comment does the same for that type only.")
+	fs.StringVar(&ca.registryFunction, "registry-function", ca.registryFunction,
+		"If set, e.g. to \"example.com/mypkg.Registry.Add\", generated files also get an init function registering their public conversion functions by calling it as f((*A)(nil), (*B)(nil), func(in, out interface{}) error).")
+	fs.StringVar(&ca.implementersOf, "implementers-of", ca.implementersOf,
+		"If set, e.g. to \"k8s.io/apimachinery/pkg/runtime.Object\", only generates conversions for the types implementing that interface, and for the types their fields need; instead of for all the types that have peers.")
+	fs.StringSliceVar(&ca.files, "files", ca.files,
+		"Comma-separated patterns of file names, e.g. \"types.go\", to only generate conversions for the types declared in matching files of input packages; packages can set their own with +"+generator.DefaultTagName+"-files tags in their doc.go files.")
+	fs.StringVar(&ca.handlerPlugin, "handler-plugin", ca.handlerPlugin,
+		"If set, the command line of an executable to call before the other handlers, for missing or inconvertible fields and for unsupported types or external conversions; it gets a JSON request describing the fields or types on its standard input, and must reply with code to write or a skip or error decision, see generator.HandlerPlugin.")
+	fs.StringVar(&ca.metricsFunction, "metrics-function", ca.metricsFunction,
+		"If set, e.g. to \"example.com/conversionmetrics.Observe\", public conversion functions report each conversion by calling it as f(from, to string, err error, d time.Duration), e.g. to feed Prometheus metrics.")
+	fs.StringVar(&ca.privateFunctionPrefix, "private-function-prefix", ca.privateFunctionPrefix,
+		"What the names of private conversion functions start with, instead of the public ones' \"Convert_\"; defaults to \""+generator.DefaultPrivateFunctionPrefix+"\".")
+	fs.BoolVar(&ca.noPrivateFunctions, "no-private-functions", ca.noPrivateFunctions,
+		"If true, writes conversion code directly in public conversion functions, only generating private functions for manual conversion functions to call.")
+	fs.BoolVar(&ca.usePackagesDriver, "use-packages-driver", ca.usePackagesDriver,
+		"If true, resolves packages' source files through go/packages, which honors GOPACKAGESDRIVER (e.g. for Bazel), instead of scanning GOPATH or module directories.")
+	fs.StringVar(&ca.packageFilesManifest, "package-files-manifest", ca.packageFilesManifest,
+		"Path to a JSON file mapping import paths to lists of source files; if set, packages are loaded solely from those files.")
+	fs.StringArrayVar(&ca.buildConstraints, "build-constraint", ca.buildConstraints,
+		"Additional build constraint expression, e.g. \"linux && amd64\", for generated files; can be repeated, constraints are ANDed together.")
+	fs.BoolVar(&ca.omitLegacyBuildLines, "omit-legacy-build-lines", ca.omitLegacyBuildLines,
+		"If true, generated files will only have \"//go:build\" constraint lines, without the legacy \"// +build\" ones.")
+	fs.StringSliceVar(&ca.buildTags, "build-tags", ca.buildTags,
+		"Comma-separated build tags to satisfy when loading packages, e.g. \"linux,integration\"; files whose build constraints they don't satisfy are ignored.")
+	fs.BoolVar(&ca.constrainedFiles, "constrained-files", ca.constrainedFiles,
+		"If true, generates the conversions of types declared in files with build constraints into files of their own, with the same constraints.")
+	fs.StringVar(&ca.headerTemplateFile, "go-header-template-file", ca.headerTemplateFile,
+		"File containing a text/template for generated files' header, used instead of --go-header-file; available variables are .Year, .ToolName, .ToolVersion, .Package, .PackageName and .PeerPackages.")
+	fs.StringVar(&ca.templatesFile, "templates-file", ca.templatesFile,
+		"File defining text/templates overriding how some of the generated code is written, e.g. {{define \"ConversionCall\"}}...{{end}}; see generator.Templates for the available templates and their data.")
+	fs.StringVar(&ca.fieldMappingsFile, "field-mappings-file", ca.fieldMappingsFile,
+		"YAML file mapping the fields of given pairs of types, e.g. renamed fields, constants for dropped fields or expressions transforming them, instead of pairing them up by name; see generator.LoadFieldMappings for its format.")
+	fs.BoolVar(&ca.genericHelpers, "generic-helpers", ca.genericHelpers,
+		"If true, slices and maps will be converted by calling generic helpers rather than with loops, reducing the size of generated files; requires Go 1.18 or later.")
+	fs.StringVar(&ca.genericHelpersPackage, "generic-helpers-package", ca.genericHelpersPackage,
+		"With --generic-helpers, the package providing the generic helpers (ConvertSlice and ConvertMap) instead of writing them to each generated file.")
+	fs.BoolVar(&ca.deduplicateLoops, "deduplicate-loops", ca.deduplicateLoops,
+		"If true, identical code converting maps, slices and pointers element by element will be factored into private helper functions.")
+	fs.StringVar(&ca.traceBuildTag, "trace-build-tag", ca.traceBuildTag,
+		"If set, generated conversion functions will log with klog when called and when converting a field fails, in binaries built with this build tag only; enabled by a file written next to each generated file.")
+	fs.IntVar(&ca.traceVerbosity, "trace-verbosity", ca.traceVerbosity,
+		"With --trace-build-tag, the klog verbosity that calls to generated conversion functions are logged at.")
+	fs.BoolVar(&ca.sizeReport, "size-report", ca.sizeReport,
+		"If true, prints the number of generated lines and functions in each package.")
+	fs.IntVar(&ca.sizeBudget, "size-budget", ca.sizeBudget,
+		"If positive, the maximum number of lines to generate in each package; packages over budget are warned about.")
+	fs.BoolVar(&ca.strictSizeBudget, "strict-size-budget", ca.strictSizeBudget,
+		"If true, fails if any package goes over --size-budget.")
+	fs.StringVar(&ca.dynamicValuesPolicy, "dynamic-values-policy", ca.dynamicValuesPolicy,
+		"How to convert fields of type interface{}, or maps or slices of interface{} values: either \""+string(generator.DynamicValuesPassThrough)+"\", \""+string(generator.DynamicValuesDeepCopy)+"\" (see --dynamic-values-deep-copy-function), or \""+string(generator.DynamicValuesJSON)+"\"; unhandled by default.")
+	fs.StringVar(&ca.dynamicValuesDeepCopyFunction, "dynamic-values-deep-copy-function", ca.dynamicValuesDeepCopyFunction,
+		"The function deep-copying dynamic values, of the form \"<pkg-path>.<expression>\", with the signature func(interface{}) interface{}.")
+	fs.StringVar(&ca.errorWrappingPolicy, "error-wrapping-policy", ca.errorWrappingPolicy,
+		"How generated code returns the errors of the conversions it calls: either \""+string(generator.ErrorWrappingFmt)+"\" to wrap them with fmt.Errorf and the path of the field being converted, or \""+string(generator.ErrorWrappingFunction)+"\" (see --error-wrapping-function); as is by default.")
+	fs.StringVar(&ca.errorWrappingFunction, "error-wrapping-function", ca.errorWrappingFunction,
+		"The function wrapping errors, of the form \"<pkg-path>.<expression>\", with the signature func(err error, field string) error.")
+	fs.BoolVar(&ca.majorVersionEquivalence, "major-version-equivalence", ca.majorVersionEquivalence,
+		"If true, converts types with the same name and memory layout, from different major versions of the same module (e.g. example.com/api and example.com/api/v2), with unsafe casts.")
+	fs.BoolVar(&ca.pruneCastableConversions, "prune-castable-conversions", ca.pruneCastableConversions,
+		"If true, generates no conversion functions between types with the same memory layout, converting them with unsafe casts and listing them in a table instead.")
+	fs.BoolVar(&ca.viewFunctions, "view-functions", ca.viewFunctions,
+		"If true, also generates View_<type> functions returning pointers to values as pointers to their peer types, with unsafe casts and without copying, for types that share the same memory layout; their results alias their inputs, and must only be read.")
+	fs.BoolVar(&ca.privatePeerTypes, "private-peer-types", ca.privatePeerTypes,
+		"If true, generates conversions involving private peer types, as long as they belong to the input package itself, e.g. internal hub types.")
+	fs.StringVar(&ca.localImportPrefix, "local-import-prefix", ca.localImportPrefix,
+		"Comma-separated import path prefixes, e.g. the current module's path; generated files import matching packages in their own group, after third-party packages.")
+	fs.StringVar(&ca.interfaceConversionFunction, "interface-conversion-function", ca.interfaceConversionFunction,
+		"If set, the function converting fields of interface types, of the form \"<pkg-path>.<expression>\"; called as F(&in.Field, &out.Field, <additional arguments>) error.")
+	fs.StringVar(&ca.interfaceAdapterFunction, "interface-adapter-function", ca.interfaceAdapterFunction,
+		"If set, the function converting fields of interface types whose input type doesn't implement their output type, of the form \"<pkg-path>.<expression>\"; called as F(&in.Field, &out.Field, <additional arguments>) error.")
+	fs.StringVar(&ca.manualConversionsCacheFile, "manual-conversions-cache", ca.manualConversionsCacheFile,
+		"If set, the file where to cache which manual conversion functions packages have across runs, keyed by package path and file hashes.")
+	fs.BoolVar(&ca.failFast, "fail-fast", ca.failFast,
+		"If true, stops at the first input package that fails, e.g. because of a syntax error or of an unresolved peer package, instead of carrying on with the other ones.")
+	fs.BoolVar(&ca.failOnIncompleteConversions, "fail-on-incomplete-conversions", ca.failOnIncompleteConversions,
+		"If true, input packages with conversions that no public function could be generated for, for lack of manual conversion functions, fail.")
+	fs.BoolVar(&ca.force, "force", ca.force,
+		"If true, overwrites existing files where files are generated even if they don't look generated, e.g. hand-written files with the same name.")
+	fs.StringVar(&ca.backend, "backend", ca.backend,
+		"How generated files are assembled: either \""+string(BackendSnippets)+"\" (default), \""+string(BackendAST)+"\" to build them as syntax trees, which are only written if valid, or \""+string(BackendStreaming)+"\" to format and write them one declaration at a time, instead of as a whole.")
+	fs.StringToStringVar(&ca.rewriteImports, "rewrite-imports", ca.rewriteImports,
+		"With --backend="+string(BackendAST)+", comma-separated <import-path>=<replacement-import-path> pairs of imports to rewrite in generated files, e.g. to point to forks.")
+	fs.StringToStringVar(&ca.outputPackagesByGroup, "output-packages-by-group", ca.outputPackagesByGroup,
+		"Comma-separated <api-group>=<package-path> pairs of packages to generate all the conversions of input packages from these API groups into, rather than into each input package; groups are set by +groupName tags, or else are the names of packages' parent directories.")
+	fs.StringVar(&ca.spliceFileBaseName, "splice-file-base-name", ca.spliceFileBaseName,
+		"If set, the name of existing files in input packages, without the .go extension, that generated code gets spliced into, between \""+generator.SpliceBeginMarker+"\" and \""+generator.SpliceEndMarker+"\" lines, preserving hand-written code around them.")
+	fs.BoolVar(&ca.scaffold, "scaffold", ca.scaffold,
+		"If true, writes correctly named and signed stub functions for conversions that need to be written manually to a separate file in each package, unless that file already exists. Also sets --missing-fields-policy and --inconvertible-fields-policy to \""+string(HandlerPolicyError)+"\" unless they're set, so that public conversion functions don't get generated when stubs are needed.")
+	fs.StringVar(&ca.since, "since", ca.since,
+		"If set, a git ref: only the input packages with Go files changed since then, committed or not, or whose peer packages or imported packages have, get regenerated; e.g. for pre-commit hooks.")
+	fs.BoolVar(&ca.equalityFunctions, "equality-functions", ca.equalityFunctions,
+		"If true, also writes functions telling whether values of converted struct types are semantically equal to values of their peer types, e.g. Equal_v1_Foo_v2_Foo, to a separate file in each package.")
+	fs.StringVar(&ca.todoReportFormat, "todo-report", ca.todoReportFormat,
+		"If set, writes a report of the conversions left to do manually, with the signatures of the functions needed, in each package; either \""+TodoReportMarkdown+"\" or \""+TodoReportJSON+"\".")
+	fs.BoolVar(&ca.detectCycles, "detect-cycles", ca.detectCycles,
+		"If true, fails if conversion functions call each other on the same object in a cycle, that would recurse infinitely at runtime.")
+	fs.StringVar(&ca.graphFormat, "graph", ca.graphFormat,
+		"If set, writes the graph of generated and manual conversion functions, and of which of them call which; the only supported format is \""+GraphFormatDOT+"\".")
+	fs.StringVar(&ca.fieldDocsDir, "field-docs-dir", ca.fieldDocsDir,
+		"If set, writes a document for each pair of types converted to that directory, showing how each field is converted (direct, cast, unsafe, manual function, dropped, etc...), e.g. for API reviews or upgrade notes.")
+	fs.StringVar(&ca.fieldDocsFormat, "field-docs-format", ca.fieldDocsFormat,
+		"Format of the documents written to --field-docs-dir: either \""+FieldDocsFormatMarkdown+"\" (the default) or \""+FieldDocsFormatHTML+"\".")
+	fs.StringVar(&ca.graphFile, "graph-file", ca.graphFile,
+		"File to write the conversion graph to, if --graph is set; defaults to stdout.")
+	fs.StringVar(&ca.profile, "profile", ca.profile,
+		"If set, profiles the run: either \""+ProfileCPU+"\", \""+ProfileMem+"\" or \""+ProfileTrace+"\", for respectively a pprof CPU or memory profile, or an execution trace.")
+	fs.StringVar(&ca.profileFile, "profile-file", ca.profileFile,
+		"File to write the profile to, if --profile is set; defaults to <profile>.pprof, or trace.out for traces.")
+	fs.StringArrayVar(&ca.explain, "explain", ca.explain,
+		"Name of a type, either qualified (\"<pkg-path>.<name>\") or not, for which to print the full trace of decisions made when generating conversion code; can be repeated.")
+	fs.StringVar(&ca.diagnosticsFile, "diagnostics-file", ca.diagnosticsFile,
+		"If set, diagnostics about types that need manual conversions will be written to that file, with the positions of the offending declarations.")
+	fs.StringVar(&ca.diagnosticsFormat, "diagnostics-format", ca.diagnosticsFormat,
+		"Format of the diagnostics file, either \""+DiagnosticsFormatSARIF+"\" (default) or \""+DiagnosticsFormatLSP+"\".")
 }
 
-func (ca *customCLIArgs) populateOptions(options *Options) {
+func (ca *customCLIArgs) populateOptions(options *Options) error {
 	if ca.noUnsafeConversions {
 		options.GeneratorOptions.NoUnsafeConversions = true
 	}
+	if len(ca.targetPlatforms) != 0 {
+		options.GeneratorOptions.TargetPlatforms = ca.targetPlatforms
+	}
+	if ca.valueInputMaxSize > 0 {
+		options.GeneratorOptions.ValueInputMaxSize = ca.valueInputMaxSize
+	}
+	if ca.builtinConversionPolicy != "" {
+		switch policy := generator.BuiltinConversionPolicy(ca.builtinConversionPolicy); policy {
+		case generator.BuiltinConversionCast, generator.BuiltinConversionCheck, generator.BuiltinConversionForbid:
+			options.GeneratorOptions.BuiltinConversionPolicy = policy
+		default:
+			return fmt.Errorf("unknown builtin conversion policy %q", ca.builtinConversionPolicy)
+		}
+	}
+	if ca.funcChanPolicy != "" {
+		switch policy := generator.FuncChanPolicy(ca.funcChanPolicy); policy {
+		case generator.FuncChanUnsupported, generator.FuncChanSkip, generator.FuncChanCopy, generator.FuncChanError:
+			options.GeneratorOptions.FuncChanPolicy = policy
+		default:
+			return fmt.Errorf("unknown function and channel policy %q", ca.funcChanPolicy)
+		}
+	}
+	if ca.optionalScalars {
+		options.GeneratorOptions.OptionalScalars = true
+	}
+	if ca.optionalScalarsKeepZeros {
+		options.GeneratorOptions.OptionalScalarsKeepZeros = true
+	}
+	if ca.nilElementsPolicy != "" {
+		switch policy := generator.NilElementsPolicy(ca.nilElementsPolicy); policy {
+		case generator.NilElementsToZero, generator.NilElementsToError:
+			options.GeneratorOptions.NilElementsPolicy = policy
+		default:
+			return fmt.Errorf("unknown nil elements policy %q", ca.nilElementsPolicy)
+		}
+	}
 	if ca.tagName != "" {
 		options.GeneratorOptions.TagName = ca.tagName
 	}
@@ -82,75 +399,812 @@ func (ca *customCLIArgs) populateOptions(options *Options) {
 	if len(ca.basePeerPackages) != 0 {
 		options.BasePeerPackages = ca.basePeerPackages
 	}
+	if len(ca.readOnlyPeerPackages) != 0 {
+		options.GeneratorOptions.ReadOnlyPeerPackages = ca.readOnlyPeerPackages
+	}
 	if ca.noPublicConversionFunctionOnError {
-		options.GeneratorOptions.MissingFieldsHandler = ErrorMissingFieldHandler
-		options.GeneratorOptions.InconvertibleFieldsHandler = ErrorInconvertibleFieldsHandler
-
-		// TODO wkpo UnsupportedTypesHandler and ExternalConversionsHandler?
+		options.MissingFieldsPolicy = HandlerPolicyError
+		options.InconvertibleFieldsPolicy = HandlerPolicyError
+	}
+	for _, policy := range []struct {
+		value  string
+		option *HandlerPolicy
+	}{
+		{ca.missingFieldsPolicy, &options.MissingFieldsPolicy},
+		{ca.inconvertibleFieldsPolicy, &options.InconvertibleFieldsPolicy},
+		{ca.unsupportedTypesPolicy, &options.UnsupportedTypesPolicy},
+		{ca.externalConversionsPolicy, &options.ExternalConversionsPolicy},
+	} {
+		if policy.value == "" {
+			continue
+		}
+		if err := validateHandlerPolicy(HandlerPolicy(policy.value)); err != nil {
+			return err
+		}
+		*policy.option = HandlerPolicy(policy.value)
+	}
+	if len(ca.missingFieldsHandlers) != 0 {
+		if _, err := MissingFieldsHandlerFor(ca.missingFieldsHandlers...); err != nil {
+			return err
+		}
+		options.MissingFieldsHandlers = ca.missingFieldsHandlers
+	}
+	if len(ca.inconvertibleFieldsHandlers) != 0 {
+		if _, err := InconvertibleFieldsHandlerFor(ca.inconvertibleFieldsHandlers...); err != nil {
+			return err
+		}
+		options.InconvertibleFieldsHandlers = ca.inconvertibleFieldsHandlers
+	}
+	if ca.publicConversionFunctionOnError {
+		options.GeneratorOptions.PublicFunctionOnError = true
+	}
+	if ca.diagnosticsFile != "" {
+		options.DiagnosticsFile = ca.diagnosticsFile
+	}
+	if ca.diagnosticsFormat != "" {
+		options.DiagnosticsFormat = ca.diagnosticsFormat
+	}
+	if len(ca.buildConstraints) != 0 {
+		options.BuildConstraints = ca.buildConstraints
+	}
+	if ca.omitLegacyBuildLines {
+		options.OmitLegacyBuildLines = true
+	}
+	if len(ca.buildTags) != 0 {
+		options.BuildTags = ca.buildTags
+	}
+	if ca.constrainedFiles {
+		options.ConstrainedFiles = true
+	}
+	if ca.headerTemplateFile != "" {
+		headerTemplate, err := ioutil.ReadFile(ca.headerTemplateFile)
+		if err != nil {
+			return errors.Wrapf(err, "unable to read header template file %q", ca.headerTemplateFile)
+		}
+		options.HeaderTemplate = string(headerTemplate)
+	}
+	if ca.templatesFile != "" {
+		set, err := template.ParseFiles(ca.templatesFile)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse templates file %q", ca.templatesFile)
+		}
+		options.GeneratorOptions.Templates = generator.TemplatesFrom(set)
+	}
+	if ca.fieldMappingsFile != "" {
+		mappings, err := generator.LoadFieldMappings(ca.fieldMappingsFile)
+		if err != nil {
+			return err
+		}
+		options.GeneratorOptions.FieldMappings = mappings
+	}
+	if ca.genericHelpers {
+		options.GeneratorOptions.GenericHelpers = true
+	}
+	if ca.genericHelpersPackage != "" {
+		options.GeneratorOptions.GenericHelpersPackage = ca.genericHelpersPackage
+	}
+	if ca.deduplicateLoops {
+		options.GeneratorOptions.DeduplicateLoops = true
+	}
+	if ca.traceBuildTag != "" {
+		options.GeneratorOptions.TraceBuildTag = ca.traceBuildTag
+		options.GeneratorOptions.TraceVerbosity = ca.traceVerbosity
+	}
+	if ca.sizeReport {
+		options.SizeReport = true
+	}
+	if ca.sizeBudget > 0 {
+		options.SizeBudget = ca.sizeBudget
+	}
+	if ca.strictSizeBudget {
+		options.StrictSizeBudget = true
+	}
+	if ca.dynamicValuesPolicy != "" {
+		switch policy := generator.DynamicValuesPolicy(ca.dynamicValuesPolicy); policy {
+		case generator.DynamicValuesPassThrough, generator.DynamicValuesDeepCopy, generator.DynamicValuesJSON:
+			options.GeneratorOptions.DynamicValuesPolicy = policy
+		default:
+			return fmt.Errorf("unknown dynamic values policy %q", ca.dynamicValuesPolicy)
+		}
+	}
+	if ca.dynamicValuesDeepCopyFunction != "" {
+		options.GeneratorOptions.DynamicValuesDeepCopyFunction = ca.dynamicValuesDeepCopyFunction
+	}
+	if ca.errorWrappingPolicy != "" {
+		switch policy := generator.ErrorWrappingPolicy(ca.errorWrappingPolicy); policy {
+		case generator.ErrorWrappingFmt, generator.ErrorWrappingFunction:
+			options.GeneratorOptions.ErrorWrappingPolicy = policy
+		default:
+			return fmt.Errorf("unknown error wrapping policy %q", ca.errorWrappingPolicy)
+		}
+	}
+	if ca.errorWrappingFunction != "" {
+		options.GeneratorOptions.ErrorWrappingFunction = ca.errorWrappingFunction
+	}
+	if ca.majorVersionEquivalence {
+		options.GeneratorOptions.MajorVersionEquivalence = true
+	}
+	if ca.pruneCastableConversions {
+		options.GeneratorOptions.PruneCastableConversions = true
+	}
+	if ca.viewFunctions {
+		options.GeneratorOptions.ViewFunctions = true
+	}
+	if ca.privatePeerTypes {
+		options.GeneratorOptions.PrivatePeerTypes = true
+	}
+	if ca.localImportPrefix != "" {
+		options.GeneratorOptions.LocalImportPrefix = ca.localImportPrefix
+	}
+	if ca.interfaceConversionFunction != "" {
+		options.GeneratorOptions.InterfaceConversionFunction = ca.interfaceConversionFunction
+	}
+	if ca.interfaceAdapterFunction != "" {
+		options.GeneratorOptions.InterfaceAdapterFunction = ca.interfaceAdapterFunction
+	}
+	if ca.force {
+		options.Force = true
+	}
+	if ca.failFast {
+		options.FailFast = true
+	}
+	if ca.failOnIncompleteConversions {
+		options.FailOnIncompleteConversions = true
+	}
+	if ca.manualConversionsCacheFile != "" {
+		options.ManualConversionsCacheFile = ca.manualConversionsCacheFile
+	}
+	if ca.backend != "" {
+		switch backend := Backend(ca.backend); backend {
+		case BackendSnippets, BackendAST, BackendStreaming:
+			options.Backend = backend
+		default:
+			return fmt.Errorf("unknown backend %q", ca.backend)
+		}
+	}
+	if len(ca.rewriteImports) != 0 {
+		if options.Backend != BackendAST {
+			return fmt.Errorf("rewriting imports requires the %q backend", BackendAST)
+		}
+		options.ASTPostProcessors = append(options.ASTPostProcessors, RewriteImports(ca.rewriteImports))
+	}
+	if len(ca.outputPackagesByGroup) != 0 {
+		options.OutputPackagesByGroup = ca.outputPackagesByGroup
+	}
+	if ca.spliceFileBaseName != "" {
+		options.SpliceFileBaseName = ca.spliceFileBaseName
+	}
+	if ca.scaffold {
+		options.Scaffold = true
+	}
+	if ca.equalityFunctions {
+		options.EqualityFunctions = true
+	}
+	if ca.since != "" {
+		options.Since = ca.since
+	}
+	if ca.todoReportFormat != "" {
+		options.TodoReportFormat = ca.todoReportFormat
+	}
+	if ca.detectCycles {
+		options.DetectCycles = true
+	}
+	if ca.graphFormat != "" {
+		options.GraphFormat = ca.graphFormat
+	}
+	if ca.graphFile != "" {
+		options.GraphFile = ca.graphFile
+	}
+	if ca.profile != "" {
+		switch ca.profile {
+		case ProfileCPU, ProfileMem, ProfileTrace:
+			options.Profile = ca.profile
+		default:
+			return fmt.Errorf("unknown profile %q", ca.profile)
+		}
+	}
+	if ca.profileFile != "" {
+		options.ProfileFile = ca.profileFile
 	}
+	if ca.fieldDocsDir != "" {
+		options.FieldDocsDir = ca.fieldDocsDir
+	}
+	if ca.fieldDocsFormat != "" {
+		options.FieldDocsFormat = ca.fieldDocsFormat
+	}
+	if ca.registryFunction != "" {
+		options.GeneratorOptions.RegistryFunction = ca.registryFunction
+	}
+	if ca.implementersOf != "" {
+		options.GeneratorOptions.ImplementersOf = ca.implementersOf
+	}
+	if len(ca.files) != 0 {
+		options.GeneratorOptions.Files = ca.files
+	}
+	if ca.handlerPlugin != "" {
+		plugin, err := generator.NewHandlerPlugin(ca.handlerPlugin)
+		if err != nil {
+			return err
+		}
+		options.GeneratorOptions.HandlerPlugin = plugin
+	}
+	if ca.metricsFunction != "" {
+		options.GeneratorOptions.MetricsFunction = ca.metricsFunction
+	}
+	if ca.privateFunctionPrefix != "" {
+		options.GeneratorOptions.PrivateFunctionPrefix = ca.privateFunctionPrefix
+	}
+	if ca.noPrivateFunctions {
+		options.GeneratorOptions.NoPrivateFunctions = true
+	}
+	if len(ca.explain) != 0 {
+		options.Explain = ca.explain
+	}
+	if ca.usePackagesDriver {
+		options.UsePackagesDriver = true
+	}
+	if ca.packageFilesManifest != "" {
+		packageFiles, err := LoadPackageFilesManifest(ca.packageFilesManifest)
+		if err != nil {
+			return err
+		}
+		options.PackageFiles = packageFiles
+	}
+	return nil
 }
 
 // ErrorMissingFieldHandler is a missing field handler that will prevent the generation of public conversion functions for structs that have one or more field
 // that are missing conversion functions.
-func ErrorMissingFieldHandler(inVar, outVar generator.NamedVariable, member *types.Member, sw *gengogenerator.SnippetWriter) error {
+func ErrorMissingFieldHandler(handlerContext *generator.HandlerContext, inVar, outVar generator.NamedVariable, member *types.Member, sw *gengogenerator.SnippetWriter) generator.HandlerResult {
 	sw.Do("// WARNING: in."+member.Name+" requires manual conversion: does not exist in peer-type\n", nil)
-	return fmt.Errorf("field " + member.Name + " requires manual conversion")
+	return generator.Failedf("field %s requires manual conversion", member.Name)
 }
 
 // ErrorInconvertibleFieldsHandler is a missing field handler that will prevent the generation of public conversion functions for structs that have one or more field
 // that are inconvertible.
-func ErrorInconvertibleFieldsHandler(inVar, outVar generator.NamedVariable, inMember, outMember *types.Member, sw *gengogenerator.SnippetWriter) error {
+func ErrorInconvertibleFieldsHandler(handlerContext *generator.HandlerContext, inVar, outVar generator.NamedVariable, inMember, outMember *types.Member, sw *gengogenerator.SnippetWriter) generator.HandlerResult {
 	sw.Do("// WARNING: in."+inMember.Name+" requires manual conversion: inconvertible types ("+
 		inMember.Type.String()+" vs "+outMember.Type.String()+")\n", nil)
-	return fmt.Errorf("field " + inMember.Name + " requires manual conversion")
+	return generator.Failedf("field %s requires manual conversion", inMember.Name)
 }
 
+// NewConverterFromCLIFlags builds a converter configured from command line flags, registered
+// on pflag.CommandLine.
 func NewConverterFromCLIFlags() *Converter {
+	return NewConverterFromFlags(pflag.CommandLine, nil)
+}
+
+// NewConverterFromFlags builds a converter configured from flags, that it registers on the given
+// flag set. Flags are parsed when running the converter if the flag set hasn't been parsed yet,
+// from the process' arguments; this allows embedding tools (e.g. cobra commands) to parse the
+// flag set themselves.
+// Base options, if not nil, are used as the starting point that flags then override.
+func NewConverterFromFlags(fs *pflag.FlagSet, base *Options) *Converter {
+	if base == nil {
+		base = DefaultOptions()
+	}
+	if base.GeneratorOptions == nil {
+		base.GeneratorOptions = generator.DefaultOptions()
+	}
+
 	args := defaultGenericArgs()
+	args.WithoutDefaultFlagParsing()
+	args.OutputFileBaseName = base.OutputFileBaseName
+	args.AddFlags(fs)
 
 	customArgs := &customCLIArgs{}
-	customArgs.addFlags(pflag.CommandLine)
+	customArgs.addFlags(fs)
 	args.CustomArgs = customArgs
 
 	return &Converter{
-		Options: DefaultOptions(),
+		Options: base,
 		args:    args,
+		flagSet: fs,
 	}
 }
 
 // Run runs the converter
-func (c *Converter) Run() error {
-	return c.args.Execute(
-		namer.NameSystems{
-			"conversion": generator.ConversionNamer(),
-		},
-		"conversion",
-		c.packages,
-	)
+func (c *Converter) Run() (err error) {
+	// options can come from CLI flags
+	if err := c.parseCLIFlags(); err != nil {
+		return err
+	}
+	stopProfile, err := c.startProfile()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if stopErr := stopProfile(); err == nil {
+			err = stopErr
+		}
+	}()
+
+	if err := c.generate(); err != nil {
+		return err
+	}
+	if err := c.writeSideOutputs(); err != nil {
+		return err
+	}
+	return c.failedPackagesError()
 }
 
-func (c *Converter) packages(context *gengogenerator.Context, arguments *args.GeneratorArgs) (packages gengogenerator.Packages) {
-	var boilerplate []byte
+// generate generates code, and the files that go along with it, into the output base.
+func (c *Converter) generate() error {
+	var packagesErr error
+	if err := c.execute(func(context *gengogenerator.Context, arguments *args.GeneratorArgs) (packages gengogenerator.Packages) {
+		packages, packagesErr = c.packages(context, arguments)
+		return
+	}); err != nil {
+		return err
+	}
+	if packagesErr != nil {
+		return packagesErr
+	}
+	if err := c.checkCycles(); err != nil {
+		return err
+	}
+	if err := c.writeDeferredFiles(); err != nil {
+		return err
+	}
+	if err := c.removeStaleFiles(); err != nil {
+		return err
+	}
+	c.failIncompleteConversions()
+
+	if err := c.checkSizes(); err != nil {
+		return err
+	}
 
-	customArgs, fromCLI := arguments.CustomArgs.(*customCLIArgs)
-	if fromCLI {
-		customArgs.populateOptions(c.Options)
+	if err := c.writeScaffolds(); err != nil {
+		return err
+	}
+	if err := c.writeTraceFiles(); err != nil {
+		return err
+	}
+	return c.writeTodoReports()
+}
 
-		if arguments.GoHeaderFilePath != "" {
-			var err error
-			boilerplate, err = arguments.LoadGoBoilerplate()
+// writeSideOutputs writes what runs produce outside of the output base, if so configured: the
+// manual conversions cache, the conversion graph, field docs, explanations and diagnostics.
+func (c *Converter) writeSideOutputs() error {
+	if c.manualConversionsCache != nil {
+		if err := c.manualConversionsCache.Save(); err != nil {
+			return err
+		}
+	}
+	if err := c.writeGraph(); err != nil {
+		return err
+	}
+	if err := c.writeFieldDocs(); err != nil {
+		return err
+	}
+	if err := c.writeExplanations(); err != nil {
+		return err
+	}
+	return c.writeDiagnostics()
+}
+
+// writeFieldDocs writes a document for each pair of types converted, if so configured; see
+// Options.FieldDocsDir.
+func (c *Converter) writeFieldDocs() error {
+	if c.Options.FieldDocsDir == "" || c.Options.GeneratorOptions.FieldDocs == nil {
+		return nil
+	}
+
+	extension, write := ".md", (*generator.TypePairDoc).WriteMarkdown
+	switch c.Options.FieldDocsFormat {
+	case "", FieldDocsFormatMarkdown:
+	case FieldDocsFormatHTML:
+		extension, write = ".html", (*generator.TypePairDoc).WriteHTML
+	default:
+		return fmt.Errorf("unknown field docs format %q", c.Options.FieldDocsFormat)
+	}
+
+	if err := os.MkdirAll(c.Options.FieldDocsDir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create field docs directory %q", c.Options.FieldDocsDir)
+	}
+	for _, pair := range c.Options.GeneratorOptions.FieldDocs.Pairs() {
+		name := strings.TrimPrefix(generator.ConversionFunctionName(pair.InType, pair.OutType), "Convert_") + extension
+		buffer := &bytes.Buffer{}
+		if err := write(pair, buffer); err != nil {
+			return errors.Wrapf(err, "unable to render field docs for %v to %v", pair.InType, pair.OutType)
+		}
+		path := filepath.Join(c.Options.FieldDocsDir, name)
+		if err := ioutil.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "unable to write field docs %q", path)
+		}
+	}
+	return nil
+}
+
+// writeExplanations writes the decisions made about the types to explain to stdout, if any;
+// see Options.Explain.
+func (c *Converter) writeExplanations() error {
+	if len(c.Options.Explain) == 0 || c.Options.GeneratorOptions.Explainer == nil {
+		return nil
+	}
+	return c.Options.GeneratorOptions.Explainer.Write(os.Stdout)
+}
+
+// writeScaffolds writes manual conversion stub files, if so configured; see Options.Scaffold.
+// Existing stub files are left untouched, so as to never overwrite manual work.
+func (c *Converter) writeScaffolds() error {
+	if !c.Options.Scaffold || c.args.VerifyOnly {
+		return nil
+	}
+
+	for _, generated := range c.generatedPackages {
+		header := generated.boilerplate
+		if generated.buildConstraint != "" {
+			// stubs for constrained conversions only build with the same constraint
+			constraintsHeader, err := buildConstraintsHeader("", []string{generated.buildConstraint}, c.Options.OmitLegacyBuildLines)
 			if err != nil {
-				klog.Fatalf("Failed loading boilerplate: %v", err)
+				return err
+			}
+			header = append(constraintsHeader, generated.boilerplate...)
+		}
+		content, err := generated.generator.ScaffoldManualConversions(header)
+		if err != nil {
+			return err
+		}
+		if content == nil {
+			continue
+		}
+
+		path := filepath.Join(c.outputBase, generated.output.Path, generated.perPackageFileName(c.Options.ScaffoldFileBaseName+".go"))
+		if _, err := os.Stat(path); err == nil {
+			klog.Warningf("Not overwriting existing manual conversion stubs file %q", path)
+			continue
+		}
+		klog.Infof("Writing manual conversion stubs to %q", path)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return errors.Wrapf(err, "unable to write manual conversion stubs file %q", path)
+		}
+	}
+	return nil
+}
+
+// TraceFileSuffix is appended to OutputFileBaseName to name the files enabling tracing in generated
+// functions, see generator.Options.TraceBuildTag.
+const TraceFileSuffix = "_trace.go"
+
+// writeTraceFiles writes the files enabling tracing in generated functions when built with
+// GeneratorOptions.TraceBuildTag, if set; see TraceFileSuffix.
+func (c *Converter) writeTraceFiles() error {
+	if c.Options.GeneratorOptions.TraceBuildTag == "" || c.args.VerifyOnly {
+		return nil
+	}
+
+	constraints := append(append([]string{}, c.Options.BuildConstraints...), c.Options.GeneratorOptions.TraceBuildTag)
+	constraintsHeader, err := buildConstraintsHeader(c.args.GeneratedBuildTag, constraints, c.Options.OmitLegacyBuildLines)
+	if err != nil {
+		return err
+	}
+
+	for _, generated := range c.generatedPackages {
+		header := appendGeneratedFileMarker(append(append([]byte{}, constraintsHeader...), generated.boilerplate...))
+		content := generated.generator.TraceFile(header)
+		if content == nil {
+			continue
+		}
+
+		path := filepath.Join(c.outputBase, generated.output.Path, c.args.OutputFileBaseName+TraceFileSuffix)
+		klog.V(2).Infof("Writing trace file %q", path)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return errors.Wrapf(err, "unable to write trace file %q", path)
+		}
+	}
+	return nil
+}
+
+// execute loads the inputs, and runs gengo with the given packages function.
+func (c *Converter) execute(pkgs func(*gengogenerator.Context, *args.GeneratorArgs) gengogenerator.Packages) error {
+	if err := c.parseCLIFlags(); err != nil {
+		return err
+	}
+	if err := c.Options.Validate(); err != nil {
+		return errors.Wrap(err, "invalid options")
+	}
+
+	inputs, err := expandInputPatterns(c.args.InputDirs, c.Options.PackageFiles, c.buildTags()...)
+	if err != nil {
+		return err
+	}
+	c.args.InputDirs = inputs
+	c.results = nil
+
+	c.changedDirs, c.importedPackages = nil, make(map[string]*build.Package)
+	if c.Options.Since != "" {
+		if c.changedDirs, err = changedDirsSince(c.Options.Since, c.args.OutputFileBaseName+".go"); err != nil {
+			return errors.Wrapf(err, "unable to determine changes since %q", c.Options.Since)
+		}
+	}
+
+	defer c.installBuildTags()()
+
+	restore, err := c.installPackageFiles()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	if !c.Options.FailFast {
+		c.excludeUnparseableInputs()
+	}
+
+	// gengo formats generated files with goimports, which only groups local imports separately
+	// through this global
+	defer func(localPrefix string) { imports.LocalPrefix = localPrefix }(imports.LocalPrefix)
+	imports.LocalPrefix = c.Options.GeneratorOptions.LocalImportPrefix
+
+	nameSystems := namer.NameSystems{
+		"conversion": generator.ConversionNamer(),
+	}
+	if c.Options.SharedUniverse != nil {
+		return c.Options.SharedUniverse.execute(c.args, nameSystems, "conversion", pkgs)
+	}
+	return c.args.Execute(nameSystems, "conversion", pkgs)
+}
+
+// runInTempDir runs the converter, generating files into a temporary directory rather than
+// into the actual output base, then calls f with that directory. The directory is removed
+// once done. Nothing gets written outside of it: neither profiles nor side outputs, see
+// writeSideOutputs.
+func (c *Converter) runInTempDir(f func(outputBase string) error) error {
+	if err := c.parseCLIFlags(); err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "go-conversion-gen")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	c.outputBaseOverride = tmpDir
+	defer func() { c.outputBaseOverride = "" }()
+
+	if err := c.generate(); err != nil {
+		return errors.Wrap(err, "generation failed")
+	}
+	if err := c.failedPackagesError(); err != nil {
+		return errors.Wrap(err, "generation failed")
+	}
+	return f(tmpDir)
+}
+
+// parseCLIFlags parses command line flags, if this converter was built from CLI flags.
+// Flags need to be parsed before gengo's parser gets created, see installPackageFiles.
+func (c *Converter) parseCLIFlags() error {
+	customArgs, fromCLI := c.args.CustomArgs.(*customCLIArgs)
+	if !fromCLI || c.cliFlagsParsed {
+		return nil
+	}
+
+	if !c.flagSet.Parsed() {
+		if c.flagSet == pflag.CommandLine {
+			c.flagSet.AddGoFlagSet(goflag.CommandLine)
+		}
+		if err := c.flagSet.Parse(os.Args[1:]); err != nil {
+			return err
+		}
+	}
+	c.cliFlagsParsed = true
+	// gengo's flags set the output file base name on the arguments
+	c.Options.OutputFileBaseName = c.args.OutputFileBaseName
+
+	return customArgs.populateOptions(c.Options)
+}
+
+// installPackageFiles makes gengo load packages from explicit file lists, if so configured.
+// The returned function must be called once done.
+func (c *Converter) installPackageFiles() (restore func(), err error) {
+	var loader *packageFilesLoader
+	switch {
+	case c.Options.PackageFiles != nil:
+		loader = newPackageFilesLoader(nil)
+		err = loader.add(c.Options.PackageFiles)
+	case c.Options.UsePackagesDriver:
+		resolve := func(pkgPath string) (PackageFiles, error) {
+			return LoadPackageFilesFromDriver([]string{pkgPath}, c.buildTags()...)
+		}
+		loader = newPackageFilesLoader(resolve)
+
+		var packageFiles PackageFiles
+		patterns := append(append([]string{}, c.args.InputDirs...), c.Options.BasePeerPackages...)
+		if packageFiles, err = LoadPackageFilesFromDriver(patterns, c.buildTags()...); err == nil {
+			err = loader.add(packageFiles)
+		}
+	default:
+		return func() {}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return loader.install(), nil
+}
+
+// toolName is how this tool identifies itself in diagnostics.
+const toolName = "go-conversion-gen"
+
+func (c *Converter) writeDiagnostics() error {
+	if c.Options.DiagnosticsFile == "" || c.Options.GeneratorOptions.Diagnostics == nil {
+		return nil
+	}
+
+	file, err := os.Create(c.Options.DiagnosticsFile)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create diagnostics file %q", c.Options.DiagnosticsFile)
+	}
+	defer file.Close()
+
+	switch c.Options.DiagnosticsFormat {
+	case DiagnosticsFormatSARIF, "":
+		return c.Options.GeneratorOptions.Diagnostics.WriteSARIF(file, toolName)
+	case DiagnosticsFormatLSP:
+		return c.Options.GeneratorOptions.Diagnostics.WriteLSP(file, toolName)
+	default:
+		return fmt.Errorf("unknown diagnostics format %q", c.Options.DiagnosticsFormat)
+	}
+}
+
+// checkCycles returns an error if conversion functions call each other on the same object in a
+// cycle, if enabled; see Options.DetectCycles.
+func (c *Converter) checkCycles() error {
+	if !c.Options.DetectCycles || c.Options.GeneratorOptions.Graph == nil {
+		return nil
+	}
+
+	cycles := c.Options.GeneratorOptions.Graph.Cycles()
+	if len(cycles) == 0 {
+		return nil
+	}
+	errMsg := "Conversion functions calling each other in a cycle, that would recurse infinitely at runtime:"
+	for _, cycle := range cycles {
+		errMsg += "\n" + strings.Join(cycle, " -> ")
+	}
+	return errors.New(errMsg)
+}
+
+// writeDeferredFiles writes the files generated during the last run that were held back until
+// checks over all of them passed, if any; see deferringFileType.
+func (c *Converter) writeDeferredFiles() error {
+	if c.deferredFiles == nil {
+		return nil
+	}
+	return c.deferredFiles.flush()
+}
+
+// writeGraph writes the conversion graph, if so configured; see Options.GraphFormat.
+func (c *Converter) writeGraph() error {
+	if c.Options.GraphFormat == "" || c.Options.GeneratorOptions.Graph == nil {
+		return nil
+	}
+	if c.Options.GraphFormat != GraphFormatDOT {
+		return fmt.Errorf("unknown graph format %q", c.Options.GraphFormat)
+	}
+
+	if c.Options.GraphFile == "" {
+		return c.Options.GeneratorOptions.Graph.WriteDOT(os.Stdout)
+	}
+	file, err := os.Create(c.Options.GraphFile)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create graph file %q", c.Options.GraphFile)
+	}
+	defer file.Close()
+	return c.Options.GeneratorOptions.Graph.WriteDOT(file)
+}
+
+// packages returns the packages to generate, recording what happens to each input package; see
+// Results. It returns an error, and nothing to generate, if the run can't proceed at all.
+func (c *Converter) packages(context *gengogenerator.Context, arguments *args.GeneratorArgs) (packages gengogenerator.Packages, err error) {
+	var boilerplate []byte
+
+	if _, fromCLI := arguments.CustomArgs.(*customCLIArgs); fromCLI {
+		if arguments.GoHeaderFilePath != "" {
+			if boilerplate, err = arguments.LoadGoBoilerplate(); err != nil {
+				return nil, errors.Wrap(err, "failed loading boilerplate")
 			}
 		}
 	}
 
-	header := append([]byte(fmt.Sprintf("// +build !%s\n\n", arguments.GeneratedBuildTag)), boilerplate...)
+	if c.outputBaseOverride != "" {
+		arguments.OutputBase = c.outputBaseOverride
+	}
+	c.generatedPackages = nil
+	c.outputSourcePaths = make(map[string]string)
+	c.outputBase = arguments.OutputBase
+	c.staleFiles = make(map[string]string)
+
+	constraintsHeader, err := buildConstraintsHeader(arguments.GeneratedBuildTag, c.Options.BuildConstraints, c.Options.OmitLegacyBuildLines)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed building header")
+	}
+
+	var headerTemplate *template.Template
+	if c.Options.HeaderTemplate != "" {
+		if headerTemplate, err = parseHeaderTemplate(c.Options.HeaderTemplate); err != nil {
+			return nil, errors.Wrap(err, "failed building header")
+		}
+	}
+
+	var backendFileType renderingFileType
+	switch c.Options.Backend {
+	case "", BackendSnippets:
+	case BackendAST:
+		backendFileType = newASTFileType(c.Options.ASTPostProcessors)
+	case BackendStreaming:
+		backendFileType = newStreamingFileType()
+	default:
+		return nil, errors.Errorf("unknown backend %q", c.Options.Backend)
+	}
+	if backendFileType != nil {
+		context.FileTypes[gengogenerator.GolangFileType] = backendFileType
+	}
+
+	var splicer *splicingFileType
+	if c.Options.SpliceFileBaseName != "" {
+		splicer = newSplicingFileType(backendFileType)
+		context.FileTypes[gengogenerator.GolangFileType] = splicer
+	}
+
+	// cycles can only be detected once all packages have been generated, so don't write any
+	// file before then
+	c.deferredFiles = nil
+	if c.Options.DetectCycles {
+		c.deferredFiles = newDeferringFileType(context.FileTypes[gengogenerator.GolangFileType])
+		context.FileTypes[gengogenerator.GolangFileType] = c.deferredFiles
+	}
 
 	// share a manual conversion tracker between packages for efficiency
 	if c.Options.GeneratorOptions.ManualConversionsTracker == nil {
 		c.Options.GeneratorOptions.ManualConversionsTracker = generator.NewManualConversionsTracker()
 	}
+	if c.Options.ManualConversionsCacheFile != "" {
+		if c.manualConversionsCache == nil {
+			if c.manualConversionsCache, err = generator.LoadManualConversionsCache(c.Options.ManualConversionsCacheFile); err != nil {
+				return nil, err
+			}
+		}
+		c.Options.GeneratorOptions.ManualConversionsTracker.UseCache(c.manualConversionsCache)
+	}
+	if err := c.setPolicyHandlers(); err != nil {
+		return nil, errors.Wrap(err, "failed setting handlers")
+	}
+	if err := c.setFieldsHandlers(); err != nil {
+		return nil, errors.Wrap(err, "failed setting fields handlers")
+	}
+	if c.Options.Scaffold {
+		// manual conversions are needed whenever some fields can't be converted automatically
+		if c.Options.GeneratorOptions.MissingFieldsHandler == nil {
+			klog.V(2).Infof("Scaffolding: using the %q missing fields policy", HandlerPolicyError)
+			c.Options.GeneratorOptions.MissingFieldsHandler = ErrorMissingFieldHandler
+		}
+		if c.Options.GeneratorOptions.InconvertibleFieldsHandler == nil {
+			klog.V(2).Infof("Scaffolding: using the %q inconvertible fields policy", HandlerPolicyError)
+			c.Options.GeneratorOptions.InconvertibleFieldsHandler = ErrorInconvertibleFieldsHandler
+		}
+	}
+	if c.Options.DiagnosticsFile != "" && c.Options.GeneratorOptions.Diagnostics == nil {
+		c.Options.GeneratorOptions.Diagnostics = generator.NewDiagnosticsCollector()
+	}
+	if len(c.Options.Explain) != 0 && c.Options.GeneratorOptions.Explainer == nil {
+		c.Options.GeneratorOptions.Explainer = generator.NewExplainer(c.Options.Explain...)
+	}
+	if c.Options.FieldDocsDir != "" && c.Options.GeneratorOptions.FieldDocs == nil {
+		c.Options.GeneratorOptions.FieldDocs = generator.NewFieldDocs()
+	}
+	if (c.Options.GraphFormat != "" || c.Options.DetectCycles) && c.Options.GeneratorOptions.Graph == nil {
+		c.Options.GeneratorOptions.Graph = generator.NewConversionGraph()
+	}
+
+	// output packages shared by several input packages or generators, and facade packages, are
+	// only generated once all their input packages have been processed
+	groups := map[string]*outputGroup{}
+	var groupPaths []string
+	facades := map[string]*facadeGroup{}
+	var facadePaths []string
 
 	processed := map[string]bool{}
 	for _, i := range context.Inputs {
@@ -164,52 +1218,236 @@ func (c *Converter) packages(context *gengogenerator.Context, arguments *args.Ge
 		pkg := context.Universe[i]
 		if pkg == nil {
 			// if the input had no Go files, for example.
+			c.recordResult(i, PackageSkipped, nil)
+			continue
+		}
+		if len(pkg.Types) == 0 {
+			klog.V(5).Infof("skipping pkg %q: no types", i)
+			c.recordResult(i, PackageSkipped, nil)
+			c.recordStaleFile(arguments, pkg)
 			continue
 		}
 
 		// TODO wkpo all that stuff about external types...?
 
+		outputPackage := c.outputPackageFor(pkg)
 		conversionGenerator, err := generator.NewConversionGenerator(
 			context,
 			arguments.OutputFileBaseName,
 			pkg.Path,
-			pkg.Path, // TODO wkpo why the 2 args???
+			outputPackage,
 			c.Options.BasePeerPackages,
 			c.Options.GeneratorOptions,
 		)
 		if err != nil {
-			klog.Fatalf("unable to build conversion generator for %v: %v", pkg, err)
-		}
-
-		packages = append(packages,
-			&gengogenerator.DefaultPackage{
-				PackageName: filepath.Base(pkg.Path),
-				PackagePath: pkg.Path,
-				HeaderText:  header,
-				GeneratorFunc: func(context *gengogenerator.Context) []gengogenerator.Generator {
-					generators := []gengogenerator.Generator{conversionGenerator}
-
-					if c.Options.ExtraGenerators != nil {
-						extraGenerators, err := c.Options.ExtraGenerators(context, conversionGenerator)
-						if err != nil {
-							klog.Fatalf("unable to build extra generators for %v: %v", pkg, err)
-						}
-						generators = append(generators, extraGenerators...)
-					}
-
-					return generators
-				},
-				FilterFunc: func(c *gengogenerator.Context, t *types.Type) bool {
-					return t.Name.Package == pkg.Path
-				},
+			c.failPackage(i, errors.Wrap(err, "unable to build conversion generator"))
+			continue
+		}
+		if !c.changedSince(context, pkg, conversionGenerator.PeerPackages()) {
+			klog.V(5).Infof("skipping pkg %q: neither it, its peer packages nor their imports changed since %s", i, c.Options.Since)
+			c.recordResult(i, PackageSkipped, nil)
+			continue
+		}
+		outputPkg := context.Universe[outputPackage]
+		// no need to write a file with just a header, unless extra generators have something to add
+		if c.Options.ExtraGenerators == nil && !conversionGenerator.HasEligibleTypes(context) {
+			klog.V(5).Infof("skipping pkg %q: no types eligible for conversion generation", i)
+			c.recordResult(i, PackageSkipped, nil)
+			c.recordStaleFile(arguments, pkg)
+			continue
+		}
+		fileName := arguments.OutputFileBaseName + ".go"
+		outputPath := filepath.Join(arguments.OutputBase, outputPkg.Path, fileName)
+		if splicer != nil {
+			spliceFileName := c.Options.SpliceFileBaseName + ".go"
+			source := filepath.Join(outputPkg.SourcePath, spliceFileName)
+			if splice, err := hasSpliceMarkers(source); err != nil {
+				c.failPackage(i, err)
+				continue
+			} else if splice {
+				splicer.targets[outputPath] = spliceTarget{
+					source:      source,
+					destination: filepath.Join(arguments.OutputBase, outputPkg.Path, spliceFileName),
+				}
+				fileName = spliceFileName
+			}
+		}
+		if fileName == arguments.OutputFileBaseName+".go" {
+			if err := c.checkOverwrite(outputPath); err != nil {
+				c.failPackage(i, err)
+				continue
+			}
+		}
+
+		var packageBoilerplate []byte
+		if override, found, err := conversionGenerator.HeaderOverride(); err != nil {
+			c.failPackage(i, errors.Wrap(err, "failed building header"))
+			continue
+		} else if found {
+			packageBoilerplate = override
+		} else if headerTemplate == nil {
+			packageBoilerplate = boilerplate
+		} else {
+			rendered, err := renderHeaderTemplate(headerTemplate, &HeaderTemplateData{
+				Year:         time.Now().UTC().Year(),
+				ToolName:     toolName,
+				ToolVersion:  toolVersion(),
+				Package:      pkg.Path,
+				PackageName:  pkg.Name,
+				PeerPackages: conversionGenerator.PeerPackages(),
 			})
+			if err != nil {
+				c.failPackage(i, errors.Wrap(err, "failed building header"))
+				continue
+			}
+			packageBoilerplate = rendered
+		}
+		header := appendGeneratedFileMarker(append(append([]byte{}, constraintsHeader...), packageBoilerplate...))
+
+		facadePackage, err := conversionGenerator.ReExportPackage()
+		if err != nil {
+			c.failPackage(i, errors.Wrap(err, "unable to determine re-export package"))
+			continue
+		}
+
+		constrained, err := c.constrainedGenerators(context, pkg, outputPackage, conversionGenerator)
+		if err != nil {
+			c.failPackage(i, errors.Wrap(err, "unable to build constrained conversion generators"))
+			continue
+		}
+		fileHeaders, err := c.constrainedFileHeaders(arguments, outputPkg, constrained, packageBoilerplate)
+		if err != nil {
+			c.failPackage(i, err)
+			continue
+		}
+
+		generated := generatedPackage{
+			pkg:         pkg,
+			generator:   conversionGenerator,
+			output:      outputPkg,
+			boilerplate: packageBoilerplate,
+			fileName:    fileName,
+		}
+		var equalityFileName string
+		if c.Options.EqualityFunctions {
+			equalityFileName = generated.perPackageFileName(c.Options.EqualityFileBaseName + ".go")
+			if err := c.checkOverwrite(filepath.Join(arguments.OutputBase, outputPkg.Path, equalityFileName)); err != nil {
+				c.failPackage(i, err)
+				continue
+			}
+		}
+		c.generatedPackages = append(c.generatedPackages, generated)
+		c.outputSourcePaths[outputPkg.Path] = outputPkg.SourcePath
+		for _, constrainedGenerator := range constrained {
+			c.generatedPackages = append(c.generatedPackages, generatedPackage{
+				pkg:             pkg,
+				generator:       constrainedGenerator.generator,
+				output:          outputPkg,
+				boilerplate:     packageBoilerplate,
+				fileName:        constrainedGenerator.fileName,
+				buildConstraint: constrainedGenerator.buildConstraint,
+			})
+		}
+		generatorFunc := func(context *gengogenerator.Context) []gengogenerator.Generator {
+			generators := []gengogenerator.Generator{conversionGenerator}
+			if equalityFileName != "" {
+				generators = append(generators, generator.NewEqualityGenerator(strings.TrimSuffix(equalityFileName, ".go"), conversionGenerator))
+			}
+
+			if c.Options.ExtraGenerators != nil {
+				extraGenerators, err := c.Options.ExtraGenerators(context, conversionGenerator, &PackageInfo{
+					Path:         pkg.Path,
+					Package:      pkg,
+					PeerPackages: conversionGenerator.PeerPackages(),
+				}, c.Options)
+				if err != nil {
+					// still generate the package's conversions
+					c.failGeneratedPackage(pkg.Path, errors.Wrap(err, "unable to build extra generators"))
+				} else {
+					generators = append(generators, extraGenerators...)
+				}
+			}
+
+			return generators
+		}
+		// constrained files make for several generators sharing the output package, as with groups
+		grouped := outputPkg.Path != pkg.Path || len(constrained) != 0
+		if !grouped {
+			packages = append(packages,
+				&gengogenerator.DefaultPackage{
+					PackageName:   filepath.Base(pkg.Path),
+					PackagePath:   pkg.Path,
+					HeaderText:    header,
+					GeneratorFunc: generatorFunc,
+					FilterFunc: func(c *gengogenerator.Context, t *types.Type) bool {
+						return t.Name.Package == pkg.Path
+					},
+				})
+		} else {
+			group := groups[outputPkg.Path]
+			if group == nil {
+				// the group's header is that of its first package
+				group = &outputGroup{pkg: outputPkg, header: header, inputs: map[string]bool{}, fileHeaders: map[string][]byte{}}
+				groups[outputPkg.Path] = group
+				groupPaths = append(groupPaths, outputPkg.Path)
+			}
+			// the last generator writes the helpers, which must be in an unconstrained file
+			for _, constrainedGenerator := range constrained {
+				constrainedConversionGenerator := constrainedGenerator.generator
+				group.add(pkg, constrainedConversionGenerator, func(*gengogenerator.Context) []gengogenerator.Generator {
+					return []gengogenerator.Generator{constrainedConversionGenerator}
+				})
+			}
+			for name, fileHeader := range fileHeaders {
+				group.fileHeaders[name] = fileHeader
+			}
+			group.add(pkg, conversionGenerator, generatorFunc)
+		}
+
+		c.recordResult(i, PackageGenerated, nil)
+
+		if facadePackage != "" {
+			facade := facades[facadePackage]
+			if facade == nil {
+				// the facade's header is that of its first package
+				facade = &facadeGroup{path: facadePackage, header: header, inputs: map[string]bool{}}
+				facades[facadePackage] = facade
+				facadePaths = append(facadePaths, facadePackage)
+				if facadePkg, err := context.AddDirectory(facadePackage); err == nil && facadePkg != nil {
+					c.outputSourcePaths[facadePackage] = facadePkg.SourcePath
+				}
+			}
+			facade.add(pkg, conversionGenerator)
+		}
+	}
+
+	for _, groupPath := range groupPaths {
+		group := groups[groupPath]
+		groupPackage, err := group.gengoPackage()
+		if err != nil {
+			c.failGroup(group, errors.Wrapf(err, "unable to generate %s", groupPath))
+			continue
+		}
+		packages = append(packages, groupPackage)
+	}
+	// facade packages must come after the packages they re-export from
+	for _, facadePath := range facadePaths {
+		packages = append(packages, facades[facadePath].gengoPackage(arguments.OutputFileBaseName))
+	}
+	// files of output packages that still get generated for other input packages aren't stale
+	for path, outputPackage := range c.staleFiles {
+		if groups[outputPackage] != nil || facades[outputPackage] != nil {
+			delete(c.staleFiles, path)
+		}
 	}
 
-	return
+	return packages, nil
 }
 
 func defaultGenericArgs() *args.GeneratorArgs {
 	args := args.Default()
 	args.GoHeaderFilePath = ""
+	// generated files get GeneratedFileMarker instead, regardless of the binary's name
+	args.GeneratedByCommentTemplate = ""
 	return args
 }
@@ -0,0 +1,842 @@
+package converter_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/wk8/go-conversion-gen/pkg/converter"
+	"github.com/wk8/go-conversion-gen/pkg/convertertest"
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+	gengogenerator "k8s.io/gengo/generator"
+)
+
+func TestSameNamedTypesInInputPackages(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/samename", ModulePath: "example.com/samename"}
+
+	result := convertertest.Run(t, fixture, nil, "a/v1", "b/v1")
+
+	for pkg, field := range map[string]string{"a/v1": "A", "b/v1": "B"} {
+		result.AssertContains(pkg, `v2 "example.com/samename/`+pkg[:1]+`/v2"`)
+		result.AssertFunction(pkg, "Convert_v1_Foo_To_v2_Foo", "Convert_v2_Foo_To_v1_Foo")
+		if function := result.Function(pkg, "autoConvert_v1_Foo_To_v2_Foo"); !strings.Contains(function, "out."+field+" = in."+field) {
+			t.Errorf("expected %s's Foo to be converted to its own peer, got:\n%s", pkg, function)
+		}
+	}
+}
+
+func TestMajorVersionEquivalence(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/majorversion", ModulePath: "example.com/majorversion"}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.MajorVersionEquivalence = true
+
+	result := convertertest.Run(t, fixture, options, "api/v1")
+
+	function := result.Function("api/v1", "autoConvert_v1_Bar_To_v2_Bar")
+	for _, expected := range []string{
+		"out.Direct = *(*v2.Foo)(unsafe.Pointer(&in.Direct))",
+		"out.Ptr = (*v2.Foo)(unsafe.Pointer(in.Ptr))",
+		"out.Slice = *(*[]v2.Foo)(unsafe.Pointer(&in.Slice))",
+		"out.Map = *(*map[string]*v2.Foo)(unsafe.Pointer(&in.Map))",
+		"out.Nested = *(*map[string][]*v2.Foo)(unsafe.Pointer(&in.Nested))",
+	} {
+		if !strings.Contains(function, expected) {
+			t.Errorf("expected %q in:\n%s", expected, function)
+		}
+	}
+}
+
+func TestScaleTags(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/scale", ModulePath: "example.com/scale"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// named builtins get scaled, but 1000 overflows int8 fields
+	result.AssertContains("v1", "out.D = scaled * 1000")
+	result.AssertContains("v1", "if v2.Dur(Dur(scaled)) != scaled {")
+	result.AssertContains("v1", "out.D = Dur(scaled)")
+	result.AssertNoFunction("v1", "Convert_v1_Foo_To_v2_Foo", "Convert_v2_Foo_To_v1_Foo")
+	// see testdata/scale/v1/scale_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestTemplateErrorsAreReturned(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/scale", ModulePath: "example.com/scale"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := converter.DefaultOptions()
+	options.PackageFiles = files
+	options.GeneratorOptions.Templates = &generator.Templates{
+		Builtin: template.Must(template.New("Builtin").Parse("{{.Nope}}")),
+	}
+
+	_, err = converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options).GeneratedSources()
+	if err == nil || !strings.Contains(err.Error(), `unable to execute template "Builtin"`) {
+		t.Errorf("expected the Builtin template's error, got %v", err)
+	}
+}
+
+func TestSetupErrorsAreReturned(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/scale", ModulePath: "example.com/scale"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := converter.DefaultOptions()
+	options.PackageFiles = files
+	// a directory can't be read as a cache
+	options.ManualConversionsCacheFile = t.TempDir()
+
+	_, err = converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options).GeneratedSources()
+	if err == nil || !strings.Contains(err.Error(), "unable to read manual conversions cache") {
+		t.Errorf("expected the cache's error, got %v", err)
+	}
+}
+
+func TestRunsInTempDirsOnlyWriteThere(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/scale", ModulePath: "example.com/scale"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	options := converter.DefaultOptions()
+	options.PackageFiles = files
+	options.DiagnosticsFile = filepath.Join(dir, "diag.json")
+	options.FieldDocsDir = filepath.Join(dir, "docs")
+	options.GraphFormat = converter.GraphFormatDOT
+	options.GraphFile = filepath.Join(dir, "graph.dot")
+	options.ManualConversionsCacheFile = filepath.Join(dir, "cache.json")
+	options.Profile = converter.ProfileCPU
+	options.ProfileFile = filepath.Join(dir, "cpu.pprof")
+
+	if _, err := converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options).GeneratedSources(); err != nil {
+		t.Fatal(err)
+	}
+	written, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range written {
+		t.Errorf("expected nothing to be written outside of the temporary output base, got %q", file.Name())
+	}
+}
+
+func TestExtraGeneratorsErrorsFailTheirPackage(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/scale", ModulePath: "example.com/scale"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := converter.DefaultOptions()
+	options.PackageFiles = files
+	options.ExtraGenerators = func(*gengogenerator.Context, *generator.Generator, *converter.PackageInfo, *converter.Options) ([]gengogenerator.Generator, error) {
+		return nil, errors.New("nope")
+	}
+
+	c := converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options)
+	_, err = c.GeneratedSources()
+	var failed *converter.FailedPackagesError
+	if !errors.As(err, &failed) || !strings.Contains(err.Error(), "unable to build extra generators: nope") {
+		t.Fatalf("expected the package to fail, got %v", err)
+	}
+	if results := c.Results(); len(results) != 1 || results[0].Outcome != converter.PackageFailed {
+		t.Errorf("expected the package to fail, got %v", results)
+	}
+}
+
+func TestExample(t *testing.T) {
+	fixture := convertertest.ExampleFixture(t)
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	result.AssertGolden("v1", "testdata/example.golden")
+	// conversion.go has a manual one, Cache opts out of conversions
+	result.AssertNoFunction("v1", "Convert_v1_Widget_To_v2_Widget", "autoConvert_v1_Cache_To_v2_Cache")
+
+	// the example builds, with the generated code
+	runGo(t, fixture, result, []string{"v1"}, "vet", "./...")
+}
+
+// TestBackendsOutput checks that all backends generate the same code, that of the default one.
+func TestBackendsOutput(t *testing.T) {
+	fixture := convertertest.ExampleFixture(t)
+
+	for _, backend := range []converter.Backend{converter.BackendSnippets, converter.BackendAST, converter.BackendStreaming} {
+		backend := backend
+		t.Run(string(backend), func(t *testing.T) {
+			options := converter.DefaultOptions()
+			options.Backend = backend
+
+			result := convertertest.Run(t, fixture, options, "v1")
+
+			result.AssertGolden("v1", "testdata/example.golden")
+		})
+	}
+}
+
+func TestNestedContainers(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/nested", ModulePath: "example.com/nested"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	result.AssertFunctionGolden("v1", "autoConvert_v1_Containers_To_v2_Containers", "testdata/nested.golden")
+	// see testdata/nested/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestEqualityFunctions(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/equality", ModulePath: "example.com/equality"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mappings, err := generator.LoadFieldMappings("testdata/equality/mappings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// converters change their options
+	newConverter := func() *converter.Converter {
+		options := converter.DefaultOptions()
+		options.PackageFiles = files
+		options.EqualityFunctions = true
+		options.GeneratorOptions.FieldMappings = mappings
+		return converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options)
+	}
+
+	sources, err := newConverter().GeneratedSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalitySources, err := newConverter().GeneratedEqualitySources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equality := equalitySources[fixture.ModulePath+"/v1"]
+	for _, expected := range []string{
+		"if int64(a.Seconds)*1000 != b.Seconds {",
+		"if int32(a.Millis/1000) != b.Millis {",
+		"if a.Old != b.New {",
+		`// INFO: a.Upper not compared, as its conversion is "transformed"`,
+		`// INFO: a.Ignored not compared, as its conversion is "dropped"`,
+	} {
+		if !strings.Contains(string(equality), expected) {
+			t.Errorf("expected %q in:\n%s", expected, equality)
+		}
+	}
+
+	// see testdata/equality/v1/equality_test.go
+	runGoWithFiles(t, fixture, map[string][]byte{
+		"v1/conversion_generated.go": sources[fixture.ModulePath+"/v1"],
+		"v1/equality_generated.go":   equality,
+	}, "test", "./...")
+}
+
+// TestFunctionOptions checks that the code generated with options naming functions to call builds,
+// whether these functions are local to the output package, or in another one.
+func TestFunctionOptions(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/functions", ModulePath: "example.com/functions"}
+
+	for _, testCase := range []struct {
+		option   string
+		function string
+		set      func(options *generator.Options, function string)
+	}{
+		{"RegistryFunction", "Register", func(options *generator.Options, function string) { options.RegistryFunction = function }},
+		{"ErrorWrappingFunction", "Wrap", func(options *generator.Options, function string) {
+			options.ErrorWrappingPolicy = generator.ErrorWrappingFunction
+			options.ErrorWrappingFunction = function
+		}},
+		{"MetricsFunction", "Observe", func(options *generator.Options, function string) { options.MetricsFunction = function }},
+		{"DynamicValuesDeepCopyFunction", "DeepCopy", func(options *generator.Options, function string) {
+			options.DynamicValuesPolicy = generator.DynamicValuesDeepCopy
+			options.DynamicValuesDeepCopyFunction = function
+		}},
+		{"InterfaceConversionFunction", "ConvertInterface", func(options *generator.Options, function string) {
+			options.InterfaceConversionFunction = function
+		}},
+		{"InterfaceAdapterFunction", "Adapt", func(options *generator.Options, function string) {
+			options.InterfaceAdapterFunction = function
+		}},
+	} {
+		for _, function := range []string{testCase.function, fixture.ModulePath + "/fns." + testCase.function} {
+			testCase, function := testCase, function
+			t.Run(testCase.option+"="+function, func(t *testing.T) {
+				options := converter.DefaultOptions()
+				testCase.set(options.GeneratorOptions, function)
+
+				result := convertertest.Run(t, fixture, options, "v1")
+
+				// e.g. fns.Register( for the package-qualified form
+				result.AssertContains("v1", path.Base(function)+"(")
+				runGo(t, fixture, result, []string{"v1"}, "vet", "./...")
+			})
+		}
+	}
+}
+
+// runGo runs the go command with the given arguments in a copy of the fixture, along with the
+// code generated for pkgs; it skips the test if there's no go command.
+func runGo(t *testing.T, fixture convertertest.Fixture, result *convertertest.Result, pkgs []string, args ...string) {
+	t.Helper()
+
+	generated := make(map[string][]byte)
+	for _, pkg := range pkgs {
+		generated[filepath.Join(pkg, "conversion_generated.go")] = result.Source(pkg)
+	}
+	runGoWithFiles(t, fixture, generated, args...)
+}
+
+// runGoWithFiles is the same as runGo, with the generated files given by their paths relative
+// to the fixture.
+func runGoWithFiles(t *testing.T, fixture convertertest.Fixture, generated map[string][]byte, args ...string) {
+	t.Helper()
+
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("no go command: %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string][]byte{"go.mod": []byte("module " + fixture.ModulePath + "\n\ngo 1.17\n")}
+	for path, content := range generated {
+		files[path] = content
+	}
+	copyFixture(t, fixture, dir, files)
+
+	command := exec.Command(goBinary, args...)
+	command.Dir = dir
+	command.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Errorf("go %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+// copyFixture copies the fixture's files to dir, replacing or adding the given files, keyed by
+// their paths relative to the fixture.
+func copyFixture(t *testing.T, fixture convertertest.Fixture, dir string, overrides map[string][]byte) {
+	t.Helper()
+
+	files := make(map[string][]byte)
+	if err := filepath.Walk(fixture.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relativePath, err := filepath.Rel(fixture.Dir, path)
+		if err != nil {
+			return err
+		}
+		files[relativePath], err = ioutil.ReadFile(path)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range overrides {
+		files[path] = content
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fullPath, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestForbiddenBuiltinConversions(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/nested", ModulePath: "example.com/nested"}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.BuiltinConversionPolicy = generator.BuiltinConversionForbid
+	options.GeneratorOptions.Diagnostics = generator.NewDiagnosticsCollector()
+
+	convertertest.Run(t, fixture, options, "v1")
+
+	var found bool
+	for _, diagnostic := range options.GeneratorOptions.Diagnostics.Diagnostics() {
+		if diagnostic.Type.Name.Name == "Leaf" && diagnostic.Member == "A" {
+			found = true
+			if diagnostic.Category != generator.InconvertibleFieldDiagnostic || !strings.Contains(diagnostic.Message, `forbidden by builtin conversion policy "forbid"`) {
+				t.Errorf("expected Leaf.A's diagnostic to name the builtin conversion policy, got %+v", diagnostic)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected a diagnostic for Leaf.A")
+	}
+}
+
+func TestDeepCopyTags(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/deepcopy", ModulePath: "example.com/deepcopy"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	result.AssertContains("v1", "out.Shared = *(*[]v2.Inner)(unsafe.Pointer(&in.Shared))")
+	result.AssertFunction("v1", "deepConvert_v1_Inner_To_v2_Inner", "deepConvert_v1_Node_To_v2_Node")
+	if function := result.Function("v1", "deepConvert_v1_Node_To_v2_Node"); !strings.Contains(function, "deepConvert_v1_Node_To_v2_Node(&(*in)[i], &(*out)[i])") {
+		t.Errorf("expected Node's children to be deep-copied recursively, got:\n%s", function)
+	}
+	// see testdata/deepcopy/v1/deepcopy_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestFieldMappings(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/fieldmappings", ModulePath: "example.com/fieldmappings"}
+	mappings, err := generator.LoadFieldMappings("testdata/fieldmappings/mappings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.FieldMappings = mappings
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	function := result.Function("v1", "autoConvert_v1_Foo_To_v2_Foo")
+	for _, expected := range []string{
+		"out.Name = in.SpecName",
+		"// INFO: in.Legacy dropped by field mapping",
+		"out.Label = strings.ToUpper(in.Title)",
+		"out.Total = int64(in.Count)",
+		"in, out := &in.Bars, &out.Items",
+		`out.Kind = "Foo"`,
+	} {
+		if !strings.Contains(function, expected) {
+			t.Errorf("expected %q in:\n%s", expected, function)
+		}
+	}
+	result.AssertContains("v1", `out.Legacy = "legacy"`)
+	// see testdata/fieldmappings/v1/mappings_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestFieldMappingsErrors(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/fieldmappings", ModulePath: "example.com/fieldmappings"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		mappings string
+		expected string
+	}{
+		{
+			name: "unknown type",
+			mappings: `
+- from: example.com/fieldmappings/v1.Nope
+  to: example.com/fieldmappings/v2.Foo`,
+			expected: "field mappings for example.com/fieldmappings/v1.Nope to example.com/fieldmappings/v2.Foo: unknown type example.com/fieldmappings/v1.Nope",
+		},
+		{
+			name: "unknown in field",
+			mappings: `
+- from: example.com/fieldmappings/v1.Foo
+  to: example.com/fieldmappings/v2.Foo
+  fields:
+  - from: Nope
+    to: Name`,
+			expected: "example.com/fieldmappings/v1.Foo has no field Nope",
+		},
+		{
+			name: "unknown out field",
+			mappings: `
+- from: example.com/fieldmappings/v1.Foo
+  to: example.com/fieldmappings/v2.Foo
+  fields:
+  - from: SpecName
+    to: Nope`,
+			expected: "example.com/fieldmappings/v2.Foo has no field Nope",
+		},
+		{
+			name: "invalid expression",
+			mappings: `
+- from: example.com/fieldmappings/v1.Foo
+  to: example.com/fieldmappings/v2.Foo
+  fields:
+  - to: Kind
+    value: nope(`,
+			expected: "invalid field mapping",
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			mappingsFile := filepath.Join(t.TempDir(), "mappings.yaml")
+			if err := ioutil.WriteFile(mappingsFile, []byte("conversions:"+testCase.mappings+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mappings, err := generator.LoadFieldMappings(mappingsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options := converter.DefaultOptions()
+			options.PackageFiles = files
+			options.GeneratorOptions.FieldMappings = mappings
+			// errors in mappings of existing types leave their conversions incomplete
+			options.FailOnIncompleteConversions = true
+
+			_, err = converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options).GeneratedSources()
+			if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+				t.Errorf("expected an error containing %q, got %v", testCase.expected, err)
+			}
+		})
+	}
+}
+
+func TestTagSuggestions(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/suggest", ModulePath: "example.com/suggest"}
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := converter.DefaultOptions()
+	options.PackageFiles = files
+
+	suggestions, err := converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options).TagSuggestions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &strings.Builder{}
+	for _, suggestion := range suggestions {
+		if suggestion.Function.Name.Name != "Convert_v1_Foo_To_v2_Foo" {
+			t.Errorf("unexpected suggestion for %v: %s", suggestion.Function, suggestion.Message)
+		}
+		switch suggestion.Kind {
+		case generator.SuggestedTag:
+			fmt.Fprintf(buffer, "tag %s on %s\n", suggestion.Tag, suggestion.Target)
+		case generator.SuggestedFieldMapping:
+			fmt.Fprintf(buffer, "mapping %s from %s to %s\n", suggestion.Mapping.Fields[0], suggestion.Mapping.From, suggestion.Mapping.To)
+		default:
+			fmt.Fprintf(buffer, "%s\n", suggestion.Kind)
+		}
+	}
+
+	expected := `mapping {from: "Name", to: "DisplayName"} from example.com/suggest/v1.Foo to example.com/suggest/v2.Foo
+tag +conversion-gen=scale:1000 on example.com/suggest/v1.Foo.Timeout
+tag +conversion-gen=expr:strings.ToUpper($in$) on example.com/suggest/v2.Foo.Title
+mapping {to: "Kind", value: "\"Foo\""} from example.com/suggest/v1.Foo to example.com/suggest/v2.Foo
+mapping {from: "Legacy", drop: true} from example.com/suggest/v1.Foo to example.com/suggest/v2.Foo
+removal
+`
+	if actual := buffer.String(); actual != expected {
+		t.Errorf("expected suggestions:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestSplicing(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/splice", ModulePath: "example.com/splice"}
+	options := converter.DefaultOptions()
+	options.SpliceFileBaseName = "conversion"
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	spliced := string(result.Source("v1"))
+	for _, expected := range []string{
+		"// Normalize is hand-written, and must survive splicing.",
+		"// ConvertAll is hand-written too, and calls generated functions.",
+		"// +conversion-gen:begin\n\nfunc autoConvert_v1_Foo_To_v2_Foo(",
+		"func Convert_v1_Foo_To_v2_Foo(in *Foo, out *v2.Foo) error {\n\treturn autoConvert_v1_Foo_To_v2_Foo(in, out)\n}",
+		"func Convert_v2_Foo_To_v1_Foo(",
+	} {
+		if !strings.Contains(spliced, expected) {
+			t.Errorf("expected %q in:\n%s", expected, spliced)
+		}
+	}
+	// previously spliced functions get replaced, rather than mistaken for manual ones
+	if strings.Contains(spliced, "is stale") || strings.Count(spliced, "func Convert_v1_Foo_To_v2_Foo(") != 1 {
+		t.Errorf("expected previously spliced code to be replaced, got:\n%s", spliced)
+	}
+
+	// splicing the same code again changes nothing
+	runGoWithFiles(t, fixture, map[string][]byte{"v1/conversion.go": result.Source("v1")}, "vet", "./...")
+	dir := t.TempDir()
+	copyFixture(t, fixture, dir, map[string][]byte{"v1/conversion.go": result.Source("v1")})
+	again := convertertest.Run(t, convertertest.Fixture{Dir: dir, ModulePath: fixture.ModulePath}, options, "v1")
+	if respliced := string(again.Source("v1")); respliced != spliced {
+		t.Errorf("expected splicing to be idempotent, got:\n%s", respliced)
+	}
+}
+
+func TestMapKeyTags(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/mapkey", ModulePath: "example.com/mapkey"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// maps get sorted by key into slices, and keys get stored in the key field, and back
+	result.AssertContains("v1", "sort.Strings(keys)")
+	result.AssertContains("v1", "out.Nodes[i].Name = key")
+	result.AssertContains("v1", "key = in.Nodes[i].Name\n\t\t\tout.Nodes[key] = val")
+	// the key field only exists in v2's Node
+	result.AssertFunction("v1", "Convert_v1_Node_To_v2_Node", "Convert_v2_Node_To_v1_Node")
+	// see testdata/mapkey/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestNoUnsafeTag(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/nounsafe", ModulePath: "example.com/nounsafe"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// "no-unsafe" shares its tag with the peer package, but isn't one
+	result.AssertFunction("v1", "Convert_v1_Foo_To_v2_Foo", "Convert_v2_Foo_To_v1_Foo")
+	if strings.Contains(string(result.Source("v1")), "unsafe.Pointer") {
+		t.Errorf("expected no unsafe conversions, got:\n%s", result.Source("v1"))
+	}
+	result.AssertContains("v1", "if err := Convert_v1_Item_To_v2_Item(&(*in)[i], &(*out)[i]); err != nil {")
+	runGo(t, fixture, result, []string{"v1"}, "vet", "./...")
+
+	// package-level options are still read from the main tag when peer packages have their own
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.PeerPackagesTagName = "conversion-gen-peer"
+	options.BasePeerPackages = []string{"example.com/nounsafe/v2"}
+
+	result = convertertest.Run(t, fixture, options, "v1")
+
+	if strings.Contains(string(result.Source("v1")), "unsafe.Pointer") {
+		t.Errorf("expected no unsafe conversions with a separate peer packages tag, got:\n%s", result.Source("v1"))
+	}
+}
+
+func TestPointerElems(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/pointerelems", ModulePath: "example.com/pointerelems"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// slices of pointers to slices of values, and back, of structs and builtins
+	result.AssertContains("v1", "if (*in)[i] != nil {\n\t\t\t\tif err := Convert_v1_Item_To_v2_Item((*in)[i], &(*out)[i]); err != nil {")
+	result.AssertContains("v1", "(*out)[i] = new(Item)\n\t\t\tif err := Convert_v2_Item_To_v1_Item(&(*in)[i], (*out)[i]); err != nil {")
+	result.AssertContains("v1", "(*out)[i] = new(int64)\n\t\t\t*(*out)[i] = int64((*in)[i])")
+	result.AssertContains("v1", "if (*in)[i] != nil {\n\t\t\t\t(*out)[i] = int32(*(*in)[i])\n\t\t\t}")
+	// see testdata/pointerelems/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.NilElementsPolicy = generator.NilElementsToError
+
+	result = convertertest.Run(t, fixture, options, "v1")
+
+	result.AssertContains("v1", "} else {\n\t\t\t\treturn fmt.Errorf(\"nil element at index %d\", i)\n\t\t\t}")
+	runGo(t, fixture, result, []string{"v1"}, "vet", "./...")
+}
+
+func TestOptionalScalars(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/optional", ModulePath: "example.com/optional"}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.OptionalScalars = true
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	result.AssertContains("v1", "if in.Replicas != nil {\n\t\tout.Replicas = int64(*in.Replicas)\n\t} else {\n\t\tout.Replicas = 0\n\t}")
+	result.AssertContains("v1", "} else {\n\t\tout.Level = \"info\"\n\t}")
+	result.AssertContains("v1", "if in.Replicas != 0 {\n\t\tout.Replicas = new(int32)\n\t\t*out.Replicas = int32(in.Replicas)\n\t} else {\n\t\tout.Replicas = nil\n\t}")
+	// see testdata/optional/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+
+	options.GeneratorOptions.OptionalScalarsKeepZeros = true
+
+	result = convertertest.Run(t, fixture, options, "v1")
+
+	result.AssertContains("v1", "out.Replicas = new(int32)\n\t*out.Replicas = int32(in.Replicas)\n\tout.Level = new(string)")
+}
+
+func TestConvertedMapKeys(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/mapkeys", ModulePath: "example.com/mapkeys"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// with generated functions
+	result.AssertContains("v1", "var newKey v2.Key\n\t\t\tif err := Convert_v1_Key_To_v2_Key(&key, &newKey); err != nil {")
+	result.AssertContains("v1", "var newKey Key\n\t\t\tif err := Convert_v2_Key_To_v1_Key(&key, &newKey); err != nil {")
+	// and manual ones, which take precedence over casts
+	result.AssertContains("v1", "var newKey v2.Color\n\t\t\tif err := Convert_v1_Color_To_v2_Color(&key, &newKey); err != nil {")
+	result.AssertContains("v1", "var newKey Color\n\t\t\tif err := Convert_v2_Color_To_v1_Color(&key, &newKey); err != nil {")
+	// see testdata/mapkeys/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestStructMapKeysFromOtherPackages(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/structkeys", ModulePath: "example.com/structkeys"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// keys with the same memory layout get cast
+	result.AssertContains("v1", "var newKey v2.Ref\n\t\t\tnewKey = *(*v2.Ref)(unsafe.Pointer(&key))")
+	// others get converted field by field, recursively
+	result.AssertContains("v1", "var newKey v2.Coords\n\t\t\tnewKey.X = int64(key.X)\n\t\t\tnewKey.Y = int64(key.Y)\n\t\t\tnewKey.Label.Text = key.Label.Text")
+	result.AssertContains("v1", "newKey.X = int32(key.X)\n\t\t\tnewKey.Y = int32(key.Y)")
+	// unless they'd collide
+	result.AssertContains("v1", "*out = make(map[v2.Point]string, len(*in))\n\t\tfor range *in {\n\t\t\t// FIXME: Converting unassignable keys unsupported")
+	// see testdata/structkeys/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestNilPolicyTags(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/nilpolicy", ModulePath: "example.com/nilpolicy"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	result.AssertContains("v1", "if in.Replicas != nil {\n\t\tout.Replicas = int64(*in.Replicas)\n\t} else {\n\t\tout.Replicas = 0\n\t}")
+	result.AssertContains("v1", "} else {\n\t\treturn fmt.Errorf(\"Template is nil\")\n\t}")
+	result.AssertContains("v1", "} else {\n\t\tout.Mode = DefaultMode\n\t}")
+	// values always convert to new pointers
+	result.AssertContains("v1", "out.Template = new(Template)\n\tif err := Convert_v2_Template_To_v1_Template(&in.Template, out.Template); err != nil {")
+	// see testdata/nilpolicy/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestNilPolicyTagsErrors(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/nilpolicy", ModulePath: "example.com/nilpolicy"}
+
+	for _, testCase := range []struct {
+		name     string
+		tags     string
+		expected string
+	}{
+		{
+			name:     "unknown policy",
+			tags:     "// +conversion-gen=nil:nope",
+			expected: `Spec.Mode: unknown nil policy "nope"`,
+		},
+		{
+			name:     "default without an expression",
+			tags:     "// +conversion-gen=nil:default",
+			expected: `Spec.Mode: nil policy "default" requires a default-expr tag`,
+		},
+		{
+			name:     "invalid default expression",
+			tags:     "// +conversion-gen=nil:default\n\t// +conversion-gen=default-expr:nope(",
+			expected: "invalid default-expr tag on example.com/nilpolicy/v1.Spec.Mode",
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			copyFixture(t, fixture, dir, map[string][]byte{"v1/types.go": []byte(`package v1
+
+type Spec struct {
+	` + testCase.tags + `
+	Mode *string
+}
+`)})
+			options := converter.DefaultOptions()
+			// errors in fields leave their conversions incomplete
+			options.FailOnIncompleteConversions = true
+			files, err := convertertest.Fixture{Dir: dir, ModulePath: fixture.ModulePath}.PackageFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			options.PackageFiles = files
+
+			_, err = converter.NewConverter([]string{fixture.ModulePath + "/v1"}, options).GeneratedSources()
+			if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+				t.Errorf("expected an error containing %q, got %v", testCase.expected, err)
+			}
+		})
+	}
+}
+
+func TestOmitZeroTags(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/omitzero", ModulePath: "example.com/omitzero"}
+
+	result := convertertest.Run(t, fixture, nil, "v1")
+
+	// zero values convert to nil pointers, checked with == if possible, with reflection otherwise
+	result.AssertContains("v1", "if in.Replicas != 0 {\n\t\tout.Replicas = new(int64)")
+	result.AssertContains("v1", "if in.Limits != (Limits{}) {\n\t\tout.Limits = new(v2.Limits)")
+	result.AssertContains("v1", "if !reflect.ValueOf(in.Selector).IsZero() {\n\t\tout.Selector = new(v2.Selector)")
+	// and nil pointers to zero values, unless a nil policy says otherwise
+	result.AssertContains("v1", "} else {\n\t\tout.Limits = Limits{}\n\t}")
+	result.AssertContains("v1", "} else {\n\t\treturn fmt.Errorf(\"Name is nil\")\n\t}")
+	// see testdata/omitzero/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestGenericHelpers(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/generics", ModulePath: "example.com/generics"}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.GenericHelpers = true
+
+	// generics need Go 1.18
+	goMod := []byte("module " + fixture.ModulePath + "\n\ngo 1.18\n")
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	result.AssertContains("v1", "if err := convertSlice(in.Members, &out.Members, Convert_v1_Member_To_v2_Member); err != nil {")
+	result.AssertContains("v1", "if err := convertMap(in.ByName, &out.ByName, Convert_v1_Member_To_v2_Member); err != nil {")
+	// named slices get converted to their underlying types
+	result.AssertContains("v1", "if err := convertSlice([]Member(in.Roster), (*[]v2.Member)(&out.Roster), Convert_v1_Member_To_v2_Member); err != nil {")
+	result.AssertFunction("v1", "convertSlice", "convertMap")
+	// pointers, and elements without conversion functions, still get converted in loops
+	result.AssertContains("v1", "*out = new(v2.Member)")
+	result.AssertContains("v1", "(*out)[key] = int64(val)")
+	// see testdata/generics/v1/roundtrip_test.go
+	runGoWithFiles(t, fixture, map[string][]byte{"go.mod": goMod, "v1/conversion_generated.go": result.Source("v1")}, "test", "./...")
+
+	options.GeneratorOptions.GenericHelpersPackage = fixture.ModulePath + "/helpers"
+
+	result = convertertest.Run(t, fixture, options, "v1")
+
+	result.AssertContains("v1", "if err := helpers.ConvertSlice(in.Members, &out.Members, Convert_v1_Member_To_v2_Member); err != nil {")
+	result.AssertContains("v1", "if err := helpers.ConvertMap(in.ByName, &out.ByName, Convert_v1_Member_To_v2_Member); err != nil {")
+	result.AssertNoFunction("v1", "convertSlice", "convertMap")
+	runGoWithFiles(t, fixture, map[string][]byte{"go.mod": goMod, "v1/conversion_generated.go": result.Source("v1")}, "test", "./...")
+}
+
+func TestDeduplicatedLoops(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/dedup", ModulePath: "example.com/dedup"}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.DeduplicateLoops = true
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	// fields with the same types, in the same or in different structs, share helpers
+	source := string(result.Source("v1"))
+	for helper, expectedCalls := range map[string]int{
+		"autoConvert_Slice_v1_Container_To_Slice_v2_Container":     3,
+		"autoConvert_Map_string_To_int32_To_Map_string_To_int64":   2,
+		"autoConvert_Pointer_v1_Container_To_Pointer_v2_Container": 1,
+		"autoConvert_Slice_v2_Container_To_Slice_v1_Container":     3,
+	} {
+		result.AssertFunction("v1", helper)
+		if calls := strings.Count(source, "if err := "+helper+"(&in."); calls != expectedCalls {
+			t.Errorf("expected %d calls to %s, got %d", expectedCalls, helper, calls)
+		}
+		if definitions := strings.Count(source, "func "+helper+"("); definitions != 1 {
+			t.Errorf("expected %s to be defined once, got %d definitions", helper, definitions)
+		}
+	}
+	result.AssertContains("v1", "func autoConvert_Pointer_v1_Container_To_Pointer_v2_Container(in **Container, out **v2.Container) error {\n\t*out = new(v2.Container)")
+	// see testdata/dedup/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
+
+func TestValueInputs(t *testing.T) {
+	fixture := convertertest.Fixture{Dir: "testdata/valueinputs", ModulePath: "example.com/valueinputs"}
+	options := converter.DefaultOptions()
+	options.GeneratorOptions.ValueInputMaxSize = 16
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	// small structs' public functions take their input by value, and wrap private ones taking pointers
+	result.AssertContains("v1", "func Convert_v1_Point_To_v2_Point(in Point, out *v2.Point) error {\n\treturn autoConvert_v1_Point_To_v2_Point(&in, out)\n}")
+	result.AssertContains("v1", "func autoConvert_v1_Point_To_v2_Point(in *Point, out *v2.Point) error {")
+	result.AssertContains("v1", "if err := Convert_v1_Point_To_v2_Point(in.Origin, &out.Origin); err != nil {")
+	result.AssertContains("v1", "if err := Convert_v1_Point_To_v2_Point((*in)[i], &(*out)[i]); err != nil {")
+	// others' don't
+	result.AssertContains("v1", "func Convert_v1_Shape_To_v2_Shape(in *Shape, out *v2.Shape) error {")
+	// manual functions get called according to their signatures
+	result.AssertContains("v1", "if err := Convert_v1_Color_To_v2_Color(in.Color, &out.Color); err != nil {")
+	result.AssertContains("v1", "if err := Convert_v2_Color_To_v1_Color(&in.Color, &out.Color); err != nil {")
+	// see testdata/valueinputs/v1/roundtrip_test.go
+	runGo(t, fixture, result, []string{"v1"}, "test", "./...")
+}
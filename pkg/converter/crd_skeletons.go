@@ -0,0 +1,414 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+	"k8s.io/gengo/types"
+	"sigs.k8s.io/yaml"
+)
+
+// CRDSkeletonOptions are the options of CRDSkeletons.
+type CRDSkeletonOptions struct {
+	// CRDFile is the path of the YAML file defining the CustomResourceDefinition, with an OpenAPI
+	// schema for each version; if it has several documents, the first CRD is used.
+	CRDFile string
+	// FromVersion and ToVersion are the names of the CRD versions to convert between, e.g.
+	// "v1alpha1" and "v1beta1".
+	FromVersion, ToVersion string
+	// FromPackage and ToPackage are the import paths of the Go packages defining the types of
+	// FromVersion and ToVersion.
+	FromPackage, ToPackage string
+	// OutputPackage is the import path of the package that the skeletons are for, where the
+	// generated conversions are: either FromPackage, the default, or ToPackage.
+	OutputPackage string
+	// TagName is the tag that opts fields out of conversion generation, see
+	// generator.Options.TagName; generator.DefaultTagName by default.
+	TagName string
+	// PrivateFunctionPrefix is what the names of the private conversion functions that skeletons
+	// call start with, see generator.Options.PrivateFunctionPrefix;
+	// generator.DefaultPrivateFunctionPrefix by default.
+	PrivateFunctionPrefix string
+}
+
+// The kinds of the schema changes that CRDSkeletons detects.
+const (
+	SchemaFieldRenamed = "renamed"
+	SchemaFieldMoved   = "moved"
+	SchemaFieldRemoved = "removed"
+	SchemaFieldAdded   = "added"
+)
+
+// A SchemaChange is a difference between two versions of a CRD's schema.
+type SchemaChange struct {
+	// Kind is the kind of change, e.g. SchemaFieldRenamed.
+	Kind string
+	// From and To are the JSON paths of the field in either version, e.g. "spec.template.image";
+	// "[]" denotes the items of arrays, e.g. "spec.containers[].name". From is empty for added
+	// fields, To for removed ones.
+	From, To string
+	// scalar is true iff the field is neither an object nor an array.
+	scalar bool
+}
+
+// crdDocument is the subset of a CustomResourceDefinition that CRDSkeletons needs.
+type crdDocument struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Names struct {
+			Kind string `json:"kind"`
+		} `json:"names"`
+		Versions []struct {
+			Name   string `json:"name"`
+			Schema struct {
+				OpenAPIV3Schema *crdSchema `json:"openAPIV3Schema"`
+			} `json:"schema"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+type crdSchema struct {
+	Type       string                `json:"type,omitempty"`
+	Format     string                `json:"format,omitempty"`
+	Properties map[string]*crdSchema `json:"properties,omitempty"`
+	Items      *crdSchema            `json:"items,omitempty"`
+}
+
+// a schemaField is a field of a flattened schema, see flattenSchema.
+type schemaField struct {
+	path, parent, name string
+	// signature is used to pair up renamed and moved fields, see schemaSignature.
+	signature string
+}
+
+// CRDSkeletons diffs the OpenAPI schemas of two versions of a CRD, and returns the source of a Go
+// file with manual conversion function skeletons between the CRD's Go types in both directions,
+// converting the renamed and moved fields, and with TODOs for the rest; along with the changes it
+// detected. The file also lists the tags to add to the fields that the skeletons take care of, so
+// that generated conversions leave them alone.
+// Go field names are assumed to be the JSON names, capitalized; and moves are assumed not to go
+// through nil pointers. The result is meant as a starting point, to be reviewed and completed.
+func CRDSkeletons(options CRDSkeletonOptions) ([]byte, []SchemaChange, error) {
+	if options.OutputPackage == "" {
+		options.OutputPackage = options.FromPackage
+	}
+	if options.OutputPackage != options.FromPackage && options.OutputPackage != options.ToPackage {
+		return nil, nil, errors.Errorf("skeletons must be in either %q or %q, where the generated conversions they call are", options.FromPackage, options.ToPackage)
+	}
+	if options.TagName == "" {
+		options.TagName = generator.DefaultTagName
+	}
+	if options.PrivateFunctionPrefix == "" {
+		options.PrivateFunctionPrefix = generator.DefaultPrivateFunctionPrefix
+	}
+
+	crd, err := loadCRD(options.CRDFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	fromSchema, err := crd.versionSchema(options.FromVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	toSchema, err := crd.versionSchema(options.ToVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := diffSchemas(flattenSchema(fromSchema), flattenSchema(toSchema))
+	source, err := renderCRDSkeletons(options, crd.Spec.Names.Kind, changes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, changes, nil
+}
+
+// loadCRD loads the first CRD in the YAML file at path.
+func loadCRD(path string) (*crdDocument, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read CRD %q", path)
+	}
+	for _, document := range strings.Split(string(raw), "\n---") {
+		crd := &crdDocument{}
+		if err := yaml.Unmarshal([]byte(document), crd); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse CRD %q", path)
+		}
+		if crd.Kind == "CustomResourceDefinition" {
+			return crd, nil
+		}
+	}
+	return nil, errors.Errorf("no CustomResourceDefinition found in %q", path)
+}
+
+// versionSchema returns the OpenAPI schema of the given version.
+func (crd *crdDocument) versionSchema(name string) (*crdSchema, error) {
+	for _, version := range crd.Spec.Versions {
+		if version.Name != name {
+			continue
+		}
+		if version.Schema.OpenAPIV3Schema == nil {
+			return nil, errors.Errorf("version %s of CRD %s has no OpenAPI schema", name, crd.Spec.Names.Kind)
+		}
+		return version.Schema.OpenAPIV3Schema, nil
+	}
+	return nil, errors.Errorf("CRD %s has no version %s", crd.Spec.Names.Kind, name)
+}
+
+// flattenSchema returns the fields of schema, indexed by path; except the type and object metadata.
+func flattenSchema(schema *crdSchema) map[string]*schemaField {
+	fields := make(map[string]*schemaField)
+	root := &crdSchema{Properties: make(map[string]*crdSchema)}
+	for name, property := range schema.Properties {
+		if name != "apiVersion" && name != "kind" && name != "metadata" {
+			root.Properties[name] = property
+		}
+	}
+	addSchemaFields(root, "", fields)
+	return fields
+}
+
+func addSchemaFields(schema *crdSchema, parent string, fields map[string]*schemaField) {
+	for name, property := range schema.Properties {
+		fieldPath := name
+		if parent != "" {
+			fieldPath = parent + "." + name
+		}
+		fields[fieldPath] = &schemaField{
+			path:      fieldPath,
+			parent:    parent,
+			name:      name,
+			signature: schemaSignature(property),
+		}
+		if property.Type == "array" && property.Items != nil {
+			addSchemaFields(property.Items, fieldPath+"[]", fields)
+		} else {
+			addSchemaFields(property, fieldPath, fields)
+		}
+	}
+}
+
+// schemaSignature sums up the shape of schema: its type for scalars, and the names of their
+// properties for objects.
+func schemaSignature(schema *crdSchema) string {
+	switch {
+	case schema.Type == "array" && schema.Items != nil:
+		return "[]" + schemaSignature(schema.Items)
+	case len(schema.Properties) != 0:
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "object{" + strings.Join(names, ",") + "}"
+	case schema.Format != "":
+		return schema.Type + "/" + schema.Format
+	default:
+		return schema.Type
+	}
+}
+
+func (f *schemaField) scalar() bool {
+	return !strings.HasPrefix(f.signature, "[]") && !strings.HasPrefix(f.signature, "object")
+}
+
+// diffSchemas returns the changes from the from fields to the to fields: fields are moved if a
+// single field with the same name and signature appeared elsewhere, or else renamed if a single
+// field with the same signature appeared under the same parent. Changes to the fields of renamed,
+// moved, removed or added fields aren't listed.
+func diffSchemas(from, to map[string]*schemaField) []SchemaChange {
+	var removed, added []*schemaField
+	for fieldPath, field := range from {
+		if _, present := to[fieldPath]; !present {
+			removed = append(removed, field)
+		}
+	}
+	for fieldPath, field := range to {
+		if _, present := from[fieldPath]; !present {
+			added = append(added, field)
+		}
+	}
+	sortSchemaFields(removed)
+	sortSchemaFields(added)
+
+	// the fields that either got paired up, or are under fields that did
+	covered := make(map[*schemaField]bool)
+	var changes []SchemaChange
+	for _, field := range removed {
+		if covered[field] || isUnder(field, removed, covered) {
+			continue
+		}
+		kind, match := findSchemaMatch(field, added, covered)
+		if match == nil {
+			continue
+		}
+		covered[field], covered[match] = true, true
+		changes = append(changes, SchemaChange{Kind: kind, From: field.path, To: match.path, scalar: field.scalar()})
+	}
+
+	for _, field := range removed {
+		if !covered[field] && !isUnder(field, removed, nil) {
+			changes = append(changes, SchemaChange{Kind: SchemaFieldRemoved, From: field.path, scalar: field.scalar()})
+		}
+	}
+	for _, field := range added {
+		if !covered[field] && !isUnder(field, added, nil) {
+			changes = append(changes, SchemaChange{Kind: SchemaFieldAdded, To: field.path, scalar: field.scalar()})
+		}
+	}
+	return changes
+}
+
+// findSchemaMatch returns the field of candidates that field got renamed or moved to, if any.
+func findSchemaMatch(field *schemaField, candidates []*schemaField, covered map[*schemaField]bool) (string, *schemaField) {
+	var renames, moves []*schemaField
+	for _, candidate := range candidates {
+		if covered[candidate] || candidate.signature != field.signature {
+			continue
+		}
+		if candidate.parent == field.parent {
+			renames = append(renames, candidate)
+		} else if candidate.name == field.name {
+			moves = append(moves, candidate)
+		}
+	}
+	switch {
+	case len(moves) == 1:
+		return SchemaFieldMoved, moves[0]
+	case len(renames) == 1:
+		return SchemaFieldRenamed, renames[0]
+	default:
+		return "", nil
+	}
+}
+
+// isUnder returns true iff field is under one of fields, that are covered if covered isn't nil.
+func isUnder(field *schemaField, fields []*schemaField, covered map[*schemaField]bool) bool {
+	for _, other := range fields {
+		if covered != nil && !covered[other] {
+			continue
+		}
+		if strings.HasPrefix(field.path, other.path+".") || strings.HasPrefix(field.path, other.path+"[]") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortSchemaFields(fields []*schemaField) {
+	sort.Slice(fields, func(i, j int) bool {
+		iDepth, jDepth := strings.Count(fields[i].path, "."), strings.Count(fields[j].path, ".")
+		if iDepth != jDepth {
+			return iDepth < jDepth
+		}
+		return fields[i].path < fields[j].path
+	})
+}
+
+// goFieldPath returns the Go selector for the given JSON path, e.g. "Spec.Template.Image".
+func goFieldPath(jsonPath string) string {
+	parts := strings.Split(jsonPath, ".")
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, ".")
+}
+
+// renderCRDSkeletons renders the skeletons for the given changes.
+func renderCRDSkeletons(options CRDSkeletonOptions, kind string, changes []SchemaChange) ([]byte, error) {
+	fromType, toType := types.Ref(options.FromPackage, kind), types.Ref(options.ToPackage, kind)
+	qualifier := func(pkgPath string) string {
+		if pkgPath == options.OutputPackage {
+			return ""
+		}
+		return path.Base(pkgPath) + "."
+	}
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "// Conversion skeletons for %s, from the OpenAPI schemas of versions %s and %s in %s;\n",
+		kind, options.FromVersion, options.ToVersion, path.Base(options.CRDFile))
+	buffer.WriteString("// to review and complete.\n\n")
+	fmt.Fprintf(buffer, "package %s\n\n", path.Base(options.OutputPackage))
+	buffer.WriteString("import (\n")
+	for _, pkgPath := range []string{options.FromPackage, options.ToPackage} {
+		if pkgPath != options.OutputPackage {
+			fmt.Fprintf(buffer, "%s %q\n", path.Base(pkgPath), pkgPath)
+		}
+	}
+	buffer.WriteString(")\n\n")
+
+	if len(changes) != 0 {
+		fmt.Fprintf(buffer, "// Changes detected from %s to %s:\n", options.FromVersion, options.ToVersion)
+		for _, change := range changes {
+			fmt.Fprintf(buffer, "//   %s\n", change)
+		}
+		buffer.WriteString("//\n")
+		buffer.WriteString("// The conversions below take care of the fields that got renamed or moved, or removed or added;\n")
+		buffer.WriteString("// so that generated conversions leave them alone, tag them with\n")
+		fmt.Fprintf(buffer, "//   +%s=false\n", options.TagName)
+		buffer.WriteString("// in the types of both versions:\n")
+		for _, change := range changes {
+			if change.From != "" {
+				fmt.Fprintf(buffer, "//   %s %s\n", options.FromVersion, change.From)
+			}
+			if change.To != "" {
+				fmt.Fprintf(buffer, "//   %s %s\n", options.ToVersion, change.To)
+			}
+		}
+		buffer.WriteString("\n")
+	}
+
+	writeCRDSkeleton(buffer, fromType, toType, options.FromVersion, options.ToVersion, options.PrivateFunctionPrefix, qualifier, changes, false)
+	writeCRDSkeleton(buffer, toType, fromType, options.ToVersion, options.FromVersion, options.PrivateFunctionPrefix, qualifier, changes, true)
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to format skeletons:\n%s", buffer.String())
+	}
+	return source, nil
+}
+
+// writeCRDSkeleton writes the skeleton of the conversion function from inType to outType, calling
+// the private conversion function whose name starts with privateFunctionPrefix; and applying
+// changes in reverse if reverse is true.
+func writeCRDSkeleton(buffer *bytes.Buffer, inType, outType *types.Type, inVersion, outVersion, privateFunctionPrefix string, qualifier func(string) string, changes []SchemaChange, reverse bool) {
+	fmt.Fprintf(buffer, "func %s(in *%s%s, out *%s%s) error {\n", generator.ConversionFunctionName(inType, outType),
+		qualifier(inType.Name.Package), inType.Name.Name, qualifier(outType.Name.Package), outType.Name.Name)
+	fmt.Fprintf(buffer, "if err := %s(in, out); err != nil {\nreturn err\n}\n", generator.PrivateConversionFunctionName(privateFunctionPrefix, inType, outType))
+
+	for _, change := range changes {
+		from, to := change.From, change.To
+		if reverse {
+			from, to = to, from
+		}
+		switch {
+		case from == "":
+			fmt.Fprintf(buffer, "// TODO: set out.%s, which %s doesn't have\n", goFieldPath(to), inVersion)
+		case to == "":
+			fmt.Fprintf(buffer, "// TODO: in.%s has no equivalent in %s\n", goFieldPath(from), outVersion)
+		case change.scalar && !strings.Contains(from+to, "[]"):
+			fmt.Fprintf(buffer, "out.%s = in.%s\n", goFieldPath(to), goFieldPath(from))
+		default:
+			fmt.Fprintf(buffer, "// TODO: convert in.%s to out.%s\n", goFieldPath(from), goFieldPath(to))
+		}
+	}
+	buffer.WriteString("return nil\n}\n\n")
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case SchemaFieldRemoved:
+		return fmt.Sprintf("%s: %s", c.Kind, c.From)
+	case SchemaFieldAdded:
+		return fmt.Sprintf("%s: %s", c.Kind, c.To)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", c.Kind, c.From, c.To)
+	}
+}
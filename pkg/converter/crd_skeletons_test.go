@@ -0,0 +1,28 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/gengo/types"
+)
+
+func TestCRDSkeletonsCallThePrefixedPrivateFunctions(t *testing.T) {
+	inType := &types.Type{Name: types.Name{Package: "example.com/a/v1", Name: "Foo"}, Kind: types.Struct}
+	outType := &types.Type{Name: types.Name{Package: "example.com/a/v2", Name: "Foo"}, Kind: types.Struct}
+	qualifier := func(pkgPath string) string {
+		return pkgPath[strings.LastIndex(pkgPath, "/")+1:] + "."
+	}
+
+	buffer := &bytes.Buffer{}
+	writeCRDSkeleton(buffer, inType, outType, "v1", "v2", "generatedConvert_", qualifier, nil, false)
+
+	skeleton := buffer.String()
+	if !strings.Contains(skeleton, "func Convert_v1_Foo_To_v2_Foo(in *v1.Foo, out *v2.Foo) error {") {
+		t.Errorf("unexpected public function in skeleton:\n%s", skeleton)
+	}
+	if !strings.Contains(skeleton, "if err := generatedConvert_v1_Foo_To_v2_Foo(in, out); err != nil {") {
+		t.Errorf("expected the skeleton to call the private function with the configured prefix:\n%s", skeleton)
+	}
+}
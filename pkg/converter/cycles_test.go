@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoFileIsWrittenWhenCyclesAreDetected(t *testing.T) {
+	options := DefaultOptions()
+	options.DetectCycles = true
+	options.PackageFiles = PackageFiles{}
+	for _, pkg := range []string{"v1", "v2"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "cycles", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			if files[i], err = filepath.Abs(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		options.PackageFiles["example.com/cycles/"+pkg] = files
+	}
+
+	outputBase := t.TempDir()
+	generated := filepath.Join(outputBase, "example.com", "cycles", "v1", options.OutputFileBaseName+".go")
+
+	converter := NewConverter([]string{"example.com/cycles/v1"}, options)
+	converter.outputBaseOverride = outputBase
+
+	err := converter.Run()
+	if err == nil || !strings.Contains(err.Error(), "Convert_v1_Foo_To_v2_Foo -> example.com/cycles/v1.Convert_v1_Foo_To_v2_Foo") {
+		t.Fatalf("expected the cycle to be detected, got %v", err)
+	}
+	if _, err := os.Stat(generated); !os.IsNotExist(err) {
+		t.Errorf("expected %q not to be written, got %v", generated, err)
+	}
+}
@@ -0,0 +1,41 @@
+package converter
+
+import (
+	gengogenerator "k8s.io/gengo/generator"
+)
+
+// a deferringFileType assembles Go files just like the file type it wraps, except that it only
+// writes them once flushed, so that checks over all the generated code can prevent writing any of
+// it; see Options.DetectCycles.
+type deferringFileType struct {
+	gengogenerator.FileType
+	// pending are the files to write when flushed.
+	pending []pendingFile
+}
+
+// a pendingFile is a file that a deferringFileType has yet to write.
+type pendingFile struct {
+	file *gengogenerator.File
+	path string
+}
+
+func newDeferringFileType(fileType gengogenerator.FileType) *deferringFileType {
+	return &deferringFileType{FileType: fileType}
+}
+
+func (ft *deferringFileType) AssembleFile(f *gengogenerator.File, path string) error {
+	ft.pending = append(ft.pending, pendingFile{file: f, path: path})
+	return nil
+}
+
+// flush writes the files assembled so far.
+func (ft *deferringFileType) flush() error {
+	pending := ft.pending
+	ft.pending = nil
+	for _, p := range pending {
+		if err := ft.FileType.AssembleFile(p.file, p.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
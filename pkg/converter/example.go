@@ -0,0 +1,234 @@
+package converter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"k8s.io/klog/v2"
+)
+
+// ExampleOptions configure WriteExample.
+type ExampleOptions struct {
+	// Dir is the directory to write the example to; it gets a v1 and a v2 package.
+	Dir string
+	// Package is Dir's import path; if empty, it's derived from the go.mod file of the module
+	// containing Dir.
+	Package string
+	// TagName is the tag that the example's packages and types are marked with, see
+	// generator.Options.TagName.
+	TagName string
+	// Force, if true, overwrites existing files.
+	Force bool
+}
+
+// exampleData is the data that example templates get executed with.
+type exampleData struct {
+	Package string
+	TagName string
+	// Generate is the command that the go:generate directive runs.
+	Generate string
+}
+
+// exampleTemplates are the templates of the example's files, indexed by their paths relative to
+// the example's directory.
+var exampleTemplates = map[string]string{
+	"v1/doc.go": `// Package v1 is the version that conversions get generated for: its ` + "`" + `+{{.TagName}}` + "`" + `
+// tag lists the peer packages to generate conversions to and from.
+//
+// +{{.TagName}}={{.Package}}/v2
+//
+//go:generate {{.Generate}}
+package v1
+`,
+	"v1/types.go": `package v1
+
+// Widget gets converted to and from v2.Widget.
+type Widget struct {
+	Name string
+	// Replicas is an int64 in v2: it gets cast.
+	Replicas int32
+	Labels   map[string]string
+	Spec     WidgetSpec
+	// Legacy doesn't exist in v2: it's up to the manual conversion function in conversion.go to
+	// convert it.
+	Legacy string
+}
+
+// WidgetSpec gets converted to and from v2.WidgetSpec.
+type WidgetSpec struct {
+	Image string
+	Ports []Port
+}
+
+// Port gets converted to and from v2.Port.
+type Port struct {
+	Name   string
+	Number int32
+}
+
+// Cache is internal to this version: the tag below opts it out of conversions.
+// +{{.TagName}}=false
+type Cache struct {
+	Entries map[string]string
+}
+`,
+	"v1/conversion.go": `package v1
+
+import (
+	"{{.Package}}/v2"
+)
+
+// Convert_v1_Widget_To_v2_Widget is a manual conversion function: the generated code calls it
+// instead of generating a public function of its own, since v2.Widget doesn't have Legacy.
+func Convert_v1_Widget_To_v2_Widget(in *Widget, out *v2.Widget) error {
+	// autoConvert_v1_Widget_To_v2_Widget gets generated for all the fields that can be converted
+	// automatically.
+	return autoConvert_v1_Widget_To_v2_Widget(in, out)
+}
+`,
+	"v2/types.go": `package v2
+
+// Widget is v1.Widget's peer type: it has a field with the same name for each of v1.Widget's,
+// except Legacy.
+type Widget struct {
+	Name     string
+	Replicas int64
+	Labels   map[string]string
+	Spec     WidgetSpec
+}
+
+// WidgetSpec is v1.WidgetSpec's peer type.
+type WidgetSpec struct {
+	Image string
+	Ports []Port
+}
+
+// Port is v1.Port's peer type.
+type Port struct {
+	Name   string
+	Number int32
+}
+`,
+}
+
+// ExampleFiles returns the files of a sample pair of packages, indexed by their paths relative
+// to the directory they're meant for, whose import path is pkgPath; with a go:generate directive
+// running generateCommand, and tagged with tagName.
+func ExampleFiles(pkgPath, tagName, generateCommand string) (map[string][]byte, error) {
+	data := &exampleData{Package: pkgPath, TagName: tagName, Generate: generateCommand}
+
+	files := make(map[string][]byte, len(exampleTemplates))
+	for path, text := range exampleTemplates {
+		tmpl, err := template.New(path).Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid example template %q", path)
+		}
+		buffer := &bytes.Buffer{}
+		if err := tmpl.Execute(buffer, data); err != nil {
+			return nil, errors.Wrapf(err, "unable to render example file %q", path)
+		}
+		files[path] = buffer.Bytes()
+	}
+	return files, nil
+}
+
+// WriteExample writes a sample pair of packages, along with a go:generate directive generating
+// their conversions; and returns the paths of the files written.
+func WriteExample(options ExampleOptions) ([]string, error) {
+	dir, err := filepath.Abs(options.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve example directory %q", options.Dir)
+	}
+	pkgPath := options.Package
+	if pkgPath == "" {
+		if pkgPath, err = importPathFromGoMod(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := ExampleFiles(pkgPath, options.TagName, exampleGenerateCommand(dir, pkgPath))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if !options.Force {
+		for _, path := range paths {
+			if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+				return nil, errors.Errorf("%q already exists, use --force to overwrite it", filepath.Join(dir, path))
+			} else if !os.IsNotExist(err) {
+				return nil, errors.Wrapf(err, "unable to stat %q", filepath.Join(dir, path))
+			}
+		}
+	}
+
+	written := make([]string, 0, len(paths))
+	for _, path := range paths {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return written, errors.Wrapf(err, "unable to create directory %q", filepath.Dir(fullPath))
+		}
+		if err := ioutil.WriteFile(fullPath, files[path], 0644); err != nil {
+			return written, errors.Wrapf(err, "unable to write %q", fullPath)
+		}
+		written = append(written, fullPath)
+	}
+	return written, nil
+}
+
+// exampleGenerateCommand returns the command generating the conversions of the example in dir,
+// whose import path is pkgPath; go:generate runs it from the v1 package's directory.
+// Generated files are written under the output base, at their packages' import paths: so the
+// output base is the directory that dir's import path is relative to, if dir is laid out that
+// way, as in GOPATH; otherwise it's left to the default, i.e. $GOPATH/src.
+func exampleGenerateCommand(dir, pkgPath string) string {
+	command := "go run github.com/wk8/go-conversion-gen/cmd --input-dirs " + pkgPath + "/v1"
+
+	if filepath.ToSlash(dir) == pkgPath || strings.HasSuffix(filepath.ToSlash(dir), "/"+pkgPath) {
+		// from dir/v1, up to dir, then up once per element of pkgPath
+		command += " --output-base " + strings.Repeat("../", strings.Count(pkgPath, "/")+2)
+	} else {
+		klog.Warningf("%q isn't laid out as its import path %q, the example's go:generate directive will write to $GOPATH/src/%s", dir, pkgPath, pkgPath)
+	}
+	return command
+}
+
+// importPathFromGoMod returns dir's import path, as derived from the go.mod file of the module
+// containing it.
+func importPathFromGoMod(dir string) (string, error) {
+	for moduleDir := dir; ; moduleDir = filepath.Dir(moduleDir) {
+		goMod := filepath.Join(moduleDir, "go.mod")
+		content, err := ioutil.ReadFile(goMod)
+		if err == nil {
+			modulePath := modfile.ModulePath(content)
+			if modulePath == "" {
+				return "", errors.Errorf("no module path found in %q", goMod)
+			}
+			relativeDir, err := filepath.Rel(moduleDir, dir)
+			if err != nil {
+				return "", errors.Wrapf(err, "unable to resolve %q relative to %q", dir, moduleDir)
+			}
+			if relativeDir == "." {
+				return modulePath, nil
+			}
+			return modulePath + "/" + filepath.ToSlash(relativeDir), nil
+		} else if !os.IsNotExist(err) {
+			return "", errors.Wrapf(err, "unable to read %q", goMod)
+		}
+
+		if filepath.Dir(moduleDir) == moduleDir {
+			return "", errors.Errorf("%q isn't in a Go module, its import path must be given explicitly", dir)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+)
+
+const (
+	// FieldsHandlerError fails on the field, see ErrorMissingFieldHandler and
+	// ErrorInconvertibleFieldsHandler.
+	FieldsHandlerError = "error"
+	// FieldsHandlerWarn writes a warning comment, and otherwise ignores the field; see
+	// generator.WarnMissingField and generator.WarnInconvertibleField.
+	FieldsHandlerWarn = "warn"
+	// FieldsHandlerZero assigns the zero value to the out field, see
+	// generator.ZeroInconvertibleField; for inconvertible fields only.
+	FieldsHandlerZero = "zero"
+	// FieldsHandlerDefault assigns the expression from the out field's default-expr tag to it, and skips
+	// fields without one, see generator.DefaultInconvertibleField; for inconvertible fields only.
+	FieldsHandlerDefault = "default"
+	// FieldsHandlerReport ignores the field, but reports it in diagnostics and TODO reports; see
+	// generator.ReportMissingField and generator.ReportInconvertibleField.
+	FieldsHandlerReport = "report"
+)
+
+// MissingFieldsHandlerFor returns a missing fields handler calling the built-in handlers with
+// the given names (see e.g. FieldsHandlerWarn) in order, until one of them doesn't skip.
+func MissingFieldsHandlerFor(names ...string) (generator.MissingFieldsHandlerFunc, error) {
+	handlers := make([]generator.MissingFieldsHandlerFunc, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case FieldsHandlerError:
+			handlers = append(handlers, ErrorMissingFieldHandler)
+		case FieldsHandlerWarn:
+			handlers = append(handlers, generator.WarnMissingField)
+		case FieldsHandlerReport:
+			handlers = append(handlers, generator.ReportMissingField)
+		default:
+			return nil, fmt.Errorf("unknown missing fields handler %q", name)
+		}
+	}
+	return generator.FirstMissingFieldsHandler(handlers...), nil
+}
+
+// InconvertibleFieldsHandlerFor returns an inconvertible fields handler calling the built-in
+// handlers with the given names (see e.g. FieldsHandlerDefault) in order, until one of them
+// doesn't skip; e.g. "default", then "zero".
+func InconvertibleFieldsHandlerFor(names ...string) (generator.InconvertibleFieldsHandlerFunc, error) {
+	handlers := make([]generator.InconvertibleFieldsHandlerFunc, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case FieldsHandlerError:
+			handlers = append(handlers, ErrorInconvertibleFieldsHandler)
+		case FieldsHandlerWarn:
+			handlers = append(handlers, generator.WarnInconvertibleField)
+		case FieldsHandlerZero:
+			handlers = append(handlers, generator.ZeroInconvertibleField)
+		case FieldsHandlerDefault:
+			handlers = append(handlers, generator.DefaultInconvertibleField)
+		case FieldsHandlerReport:
+			handlers = append(handlers, generator.ReportInconvertibleField)
+		default:
+			return nil, fmt.Errorf("unknown inconvertible fields handler %q", name)
+		}
+	}
+	return generator.FirstInconvertibleFieldsHandler(handlers...), nil
+}
+
+// setFieldsHandlers sets the generator's field handlers from Options.MissingFieldsHandlers and
+// Options.InconvertibleFieldsHandlers, if set.
+func (c *Converter) setFieldsHandlers() error {
+	if len(c.Options.MissingFieldsHandlers) != 0 {
+		handler, err := MissingFieldsHandlerFor(c.Options.MissingFieldsHandlers...)
+		if err != nil {
+			return err
+		}
+		c.Options.GeneratorOptions.MissingFieldsHandler = handler
+	}
+	if len(c.Options.InconvertibleFieldsHandlers) != 0 {
+		handler, err := InconvertibleFieldsHandlerFor(c.Options.InconvertibleFieldsHandlers...)
+		if err != nil {
+			return err
+		}
+		c.Options.GeneratorOptions.InconvertibleFieldsHandler = handler
+	}
+	return nil
+}
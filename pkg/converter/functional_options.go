@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+)
+
+// An Option modifies Options, e.g. for programmatic configuration that only sets what it needs
+// on top of DefaultOptions; see Options for what each one does, and NewConverterWith.
+type Option func(*Options)
+
+// Apply applies the given options, in order, and returns o.
+func (o *Options) Apply(options ...Option) *Options {
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// NewConverterWith builds a converter for the given target packages as NewConverter does, with
+// DefaultOptions modified by the given options, e.g.
+//
+//	NewConverterWith(targets, WithBasePeerPackages("example.com/api/v2"), WithStrict())
+func NewConverterWith(targetPackages []string, options ...Option) *Converter {
+	return NewConverter(targetPackages, DefaultOptions().Apply(options...))
+}
+
+// WithGeneratorOptions applies the given options to Options.GeneratorOptions.
+func WithGeneratorOptions(options ...generator.Option) Option {
+	return func(o *Options) {
+		if o.GeneratorOptions == nil {
+			o.GeneratorOptions = generator.DefaultOptions()
+		}
+		o.GeneratorOptions.Apply(options...)
+	}
+}
+
+// WithBasePeerPackages adds to Options.BasePeerPackages.
+func WithBasePeerPackages(peerPackages ...string) Option {
+	return func(o *Options) {
+		o.BasePeerPackages = append(o.BasePeerPackages, peerPackages...)
+	}
+}
+
+// WithOutputFileBaseName sets Options.OutputFileBaseName.
+func WithOutputFileBaseName(baseName string) Option {
+	return func(o *Options) {
+		o.OutputFileBaseName = baseName
+	}
+}
+
+// WithBuildTags adds to Options.BuildTags.
+func WithBuildTags(buildTags ...string) Option {
+	return func(o *Options) {
+		o.BuildTags = append(o.BuildTags, buildTags...)
+	}
+}
+
+// WithBuildConstraints adds to Options.BuildConstraints.
+func WithBuildConstraints(buildConstraints ...string) Option {
+	return func(o *Options) {
+		o.BuildConstraints = append(o.BuildConstraints, buildConstraints...)
+	}
+}
+
+// WithOutputPackageForGroup adds the given group to Options.OutputPackagesByGroup.
+func WithOutputPackageForGroup(group, outputPackage string) Option {
+	return func(o *Options) {
+		if o.OutputPackagesByGroup == nil {
+			o.OutputPackagesByGroup = make(map[string]string)
+		}
+		o.OutputPackagesByGroup[group] = outputPackage
+	}
+}
+
+// WithSharedUniverse sets Options.SharedUniverse.
+func WithSharedUniverse(universe *SharedUniverse) Option {
+	return func(o *Options) {
+		o.SharedUniverse = universe
+	}
+}
+
+// WithForce sets Options.Force.
+func WithForce() Option {
+	return func(o *Options) {
+		o.Force = true
+	}
+}
+
+// WithFailFast sets Options.FailFast.
+func WithFailFast() Option {
+	return func(o *Options) {
+		o.FailFast = true
+	}
+}
+
+// WithHandlerPolicy sets all of Options.MissingFieldsPolicy, InconvertibleFieldsPolicy,
+// UnsupportedTypesPolicy and ExternalConversionsPolicy.
+func WithHandlerPolicy(policy HandlerPolicy) Option {
+	return func(o *Options) {
+		o.MissingFieldsPolicy = policy
+		o.InconvertibleFieldsPolicy = policy
+		o.UnsupportedTypesPolicy = policy
+		o.ExternalConversionsPolicy = policy
+	}
+}
+
+// WithStrict makes anything that the generator can't convert fail: public conversion functions
+// don't get generated for the conversions involved, and their packages fail, see
+// Options.FailOnIncompleteConversions.
+func WithStrict() Option {
+	return func(o *Options) {
+		WithHandlerPolicy(HandlerPolicyError)(o)
+		o.FailOnIncompleteConversions = true
+		if o.GeneratorOptions != nil {
+			o.GeneratorOptions.PublicFunctionOnError = false
+		}
+	}
+}
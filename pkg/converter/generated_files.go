@@ -0,0 +1,79 @@
+package converter
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GeneratedFileMarker is the comment that all files generated by this tool have before their
+// package clause, see https://golang.org/s/generatedcode.
+const GeneratedFileMarker = "// Code generated by " + toolName + ". DO NOT EDIT."
+
+// IsGeneratedFile returns true iff the Go file at path was generated by this tool, i.e. it has
+// GeneratedFileMarker before its package clause. Files that can't be read aren't.
+func IsGeneratedFile(path string) bool {
+	generated, err := hasGeneratedFileMarker(path)
+	return err == nil && generated
+}
+
+func hasGeneratedFileMarker(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to open %q", path)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == GeneratedFileMarker {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false, errors.Wrapf(scanner.Err(), "unable to read %q", path)
+}
+
+// ListGeneratedFiles returns the sorted paths of the Go files generated by this tool, as per
+// IsGeneratedFile, in dir and its subdirectories. Like the go tool, it ignores "vendor" and
+// "testdata" directories, as well as directories whose name starts with "." or "_".
+func ListGeneratedFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name := entry.Name(); path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		generated, err := hasGeneratedFileMarker(path)
+		if err != nil {
+			return err
+		}
+		if generated {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list generated files in %q", dir)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
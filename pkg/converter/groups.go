@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+)
+
+// groupNameTagName is the tag that sets the API group of packages, in their doc.go files, as
+// with Kubernetes APIs.
+const groupNameTagName = "groupName"
+
+// apiGroup returns the API group that pkg belongs to, see Options.OutputPackagesByGroup.
+func apiGroup(pkg *types.Package) string {
+	if values := types.ExtractCommentTags("+", pkg.Comments)[groupNameTagName]; len(values) != 0 {
+		return values[0]
+	}
+	return path.Base(path.Dir(pkg.Path))
+}
+
+// outputPackageFor returns the path of the package that conversions for pkg get generated into.
+func (c *Converter) outputPackageFor(pkg *types.Package) string {
+	if len(c.Options.OutputPackagesByGroup) != 0 {
+		if outputPackage, found := c.Options.OutputPackagesByGroup[apiGroup(pkg)]; found {
+			return outputPackage
+		}
+	}
+	return pkg.Path
+}
+
+// An outputGroup is an output package that conversions for several input packages get generated
+// into, see Options.OutputPackagesByGroup.
+type outputGroup struct {
+	pkg    *types.Package
+	header []byte
+	// fileHeaders are the headers of the files that don't get header, by name, see
+	// Options.ConstrainedFiles.
+	fileHeaders map[string][]byte
+	// inputs are the paths of the input packages in the group.
+	inputs map[string]bool
+	// conversionGenerators are the conversion generators of the input packages, and
+	// generatorFuncs return all their generators, in order.
+	conversionGenerators []*generator.Generator
+	generatorFuncs       []func(*gengogenerator.Context) []gengogenerator.Generator
+}
+
+// add adds an input package to the group, with its conversion generator, and a function returning
+// all its generators.
+func (group *outputGroup) add(pkg *types.Package, conversionGenerator *generator.Generator, generatorFunc func(*gengogenerator.Context) []gengogenerator.Generator) {
+	group.inputs[pkg.Path] = true
+	group.conversionGenerators = append(group.conversionGenerators, conversionGenerator)
+	group.generatorFuncs = append(group.generatorFuncs, generatorFunc)
+}
+
+// gengoPackage returns the package to generate the group's output package with.
+func (group *outputGroup) gengoPackage() (gengogenerator.Package, error) {
+	if err := generator.ShareOutputPackage(group.conversionGenerators...); err != nil {
+		return nil, err
+	}
+	pkg := &gengogenerator.DefaultPackage{
+		PackageName: filepath.Base(group.pkg.Path),
+		PackagePath: group.pkg.Path,
+		HeaderText:  group.header,
+		GeneratorFunc: func(context *gengogenerator.Context) []gengogenerator.Generator {
+			var generators []gengogenerator.Generator
+			for _, generatorFunc := range group.generatorFuncs {
+				generators = append(generators, generatorFunc(context)...)
+			}
+			return generators
+		},
+		FilterFunc: func(c *gengogenerator.Context, t *types.Type) bool {
+			return group.inputs[t.Name.Package]
+		},
+	}
+	if len(group.fileHeaders) == 0 {
+		return pkg, nil
+	}
+	return &filesHeaderPackage{DefaultPackage: pkg, fileHeaders: group.fileHeaders}, nil
+}
+
+// A facadeGroup is a facade package that the public conversion functions of one or more input
+// packages get re-exported from, all in one file; see generator.ReExportGenerator.
+type facadeGroup struct {
+	path   string
+	header []byte
+	// inputs are the paths of the input packages re-exported from the facade package.
+	inputs map[string]bool
+	// conversionGenerators are the conversion generators of the input packages.
+	conversionGenerators []*generator.Generator
+}
+
+// add adds an input package to the facade, with its conversion generator.
+func (facade *facadeGroup) add(pkg *types.Package, conversionGenerator *generator.Generator) {
+	facade.inputs[pkg.Path] = true
+	facade.conversionGenerators = append(facade.conversionGenerators, conversionGenerator)
+}
+
+// gengoPackage returns the package to generate the facade package with, into a file with the
+// given name. It must come after the packages it re-exports from.
+func (facade *facadeGroup) gengoPackage(outputFileName string) gengogenerator.Package {
+	return &gengogenerator.DefaultPackage{
+		PackageName: filepath.Base(facade.path),
+		PackagePath: facade.path,
+		HeaderText:  facade.header,
+		GeneratorFunc: func(context *gengogenerator.Context) []gengogenerator.Generator {
+			// generators writing to the same file get their code concatenated
+			generators := make([]gengogenerator.Generator, len(facade.conversionGenerators))
+			for i, conversionGenerator := range facade.conversionGenerators {
+				generators[i] = generator.NewReExportGenerator(outputFileName, facade.path, conversionGenerator)
+			}
+			return generators
+		},
+		FilterFunc: func(c *gengogenerator.Context, t *types.Type) bool {
+			return facade.inputs[t.Name.Package]
+		},
+	}
+}
+
+// perPackageFileName returns the name to give a file named fileName, written for the package in
+// its output package: suffixed with the package's name if the output package is shared with other
+// packages, e.g. "conversion_manual_todo_v1.go"; and with its build constraint, if any, see
+// constrainedFileName.
+func (p *generatedPackage) perPackageFileName(fileName string) string {
+	if p.output.Path != p.pkg.Path {
+		extension := filepath.Ext(fileName)
+		fileName = strings.TrimSuffix(fileName, extension) + "_" + p.pkg.Name + extension
+	}
+	return constrainedFileName(fileName, p.buildConstraint)
+}
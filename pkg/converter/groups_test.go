@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOutputPackagesByGroup(t *testing.T) {
+	options := DefaultOptions()
+	options.OutputPackagesByGroup = map[string]string{"apps": "example.com/groups/apps/conversions"}
+	options.EqualityFunctions = true
+	options.PackageFiles = PackageFiles{}
+	for _, pkg := range []string{"apps/v1", "apps/v1beta1", "apps/v2", "apps/conversions", "facade"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "groups", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			if files[i], err = filepath.Abs(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		options.PackageFiles["example.com/groups/"+pkg] = files
+	}
+
+	outputBase := t.TempDir()
+	converter := NewConverter([]string{"example.com/groups/apps/v1", "example.com/groups/apps/v1beta1"}, options)
+	converter.outputBaseOverride = outputBase
+	if err := converter.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var written []string
+	if err := filepath.Walk(outputBase, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			written = append(written, strings.TrimPrefix(path, outputBase+"/example.com/groups/"))
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(written)
+	expected := []string{
+		"apps/conversions/" + options.OutputFileBaseName + ".go",
+		"apps/conversions/" + options.EqualityFileBaseName + "_v1.go",
+		"apps/conversions/" + options.EqualityFileBaseName + "_v1beta1.go",
+		"facade/" + options.OutputFileBaseName + ".go",
+	}
+	if strings.Join(written, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected files:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(written, "\n"))
+	}
+
+	facade, err := ioutil.ReadFile(filepath.Join(outputBase, "example.com", "groups", "facade", options.OutputFileBaseName+".go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, function := range []string{"Convert_v1_Foo_To_v2_Foo", "Convert_v1beta1_Foo_To_v2_Foo"} {
+		if !strings.Contains(string(facade), function) {
+			t.Errorf("expected the facade to re-export %s, got:\n%s", function, facade)
+		}
+	}
+
+	// the generated files build, along with the fixture
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("no go command: %v", err)
+	}
+	module := t.TempDir()
+	files := map[string]string{"go.mod": "module example.com/groups\n\ngo 1.17\n"}
+	for _, dir := range []string{filepath.Join("testdata", "groups"), filepath.Join(outputBase, "example.com", "groups")} {
+		if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			content, err := ioutil.ReadFile(path)
+			files[strings.TrimPrefix(path, dir+"/")] = string(content)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(module, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goBinary, "vet", "./...")
+	cmd.Dir = module
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go vet failed: %v\n%s", err, output)
+	}
+}
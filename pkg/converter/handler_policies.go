@@ -0,0 +1,108 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+	gengogenerator "k8s.io/gengo/generator"
+)
+
+// A HandlerPolicy is what happens when the generator can't convert something of a given
+// category, see e.g. Options.MissingFieldsPolicy.
+type HandlerPolicy string
+
+const (
+	// HandlerPolicyIgnore leaves it unconverted, silently.
+	HandlerPolicyIgnore HandlerPolicy = "ignore"
+	// HandlerPolicyWarn leaves it unconverted, logging a warning and reporting it in diagnostics
+	// and TODO reports; that's what happens without a handler.
+	HandlerPolicyWarn HandlerPolicy = "warn"
+	// HandlerPolicyComment leaves it unconverted, with a warning comment in the generated code.
+	HandlerPolicyComment HandlerPolicy = "comment"
+	// HandlerPolicyError writes a warning comment in the generated code, and prevents the
+	// generation of the public conversion function; see
+	// generator.Options.PublicFunctionOnError.
+	HandlerPolicyError HandlerPolicy = "error"
+)
+
+// validateHandlerPolicy returns an error if policy isn't a known policy.
+func validateHandlerPolicy(policy HandlerPolicy) error {
+	switch policy {
+	case HandlerPolicyIgnore, HandlerPolicyWarn, HandlerPolicyComment, HandlerPolicyError:
+		return nil
+	default:
+		return fmt.Errorf("unknown handler policy %q", policy)
+	}
+}
+
+// ErrorUnsupportedTypeHandler is an unsupported types handler that will prevent the generation of
+// public conversion functions for types that the generator doesn't know how to convert.
+func ErrorUnsupportedTypeHandler(handlerContext *generator.HandlerContext, inVar, outVar generator.NamedVariable, sw *gengogenerator.SnippetWriter) generator.HandlerResult {
+	sw.Do("// WARNING: "+inVar.Name+" requires manual conversion: don't know how to convert "+
+		inVar.Type.String()+" to "+outVar.Type.String()+"\n", nil)
+	return generator.Failedf("%v to %v requires manual conversion", inVar.Type, outVar.Type)
+}
+
+// ErrorExternalConversionHandler is an external conversions handler that will prevent the
+// generation of public conversion functions for types that need to be converted to or from
+// types of packages that conversions aren't generated for.
+func ErrorExternalConversionHandler(handlerContext *generator.HandlerContext, inVar, outVar generator.NamedVariable, sw *gengogenerator.SnippetWriter) generator.HandlerResult {
+	sw.Do("// WARNING: "+inVar.Name+" requires manual conversion to external type "+outVar.Type.String()+"\n", nil)
+	return generator.Failedf("%v requires manual conversion to external type %v", inVar.Type, outVar.Type)
+}
+
+// setPolicyHandlers sets the generator's handlers from the policies in Options, if set.
+func (c *Converter) setPolicyHandlers() error {
+	for _, policy := range []HandlerPolicy{c.Options.MissingFieldsPolicy, c.Options.InconvertibleFieldsPolicy,
+		c.Options.UnsupportedTypesPolicy, c.Options.ExternalConversionsPolicy} {
+		if policy == "" {
+			continue
+		}
+		if err := validateHandlerPolicy(policy); err != nil {
+			return err
+		}
+	}
+
+	generatorOptions := c.Options.GeneratorOptions
+	switch c.Options.MissingFieldsPolicy {
+	case HandlerPolicyIgnore:
+		generatorOptions.MissingFieldsHandler = generator.IgnoreMissingField
+	case HandlerPolicyWarn:
+		generatorOptions.MissingFieldsHandler = nil
+	case HandlerPolicyComment:
+		generatorOptions.MissingFieldsHandler = generator.WarnMissingField
+	case HandlerPolicyError:
+		generatorOptions.MissingFieldsHandler = ErrorMissingFieldHandler
+	}
+	switch c.Options.InconvertibleFieldsPolicy {
+	case HandlerPolicyIgnore:
+		generatorOptions.InconvertibleFieldsHandler = generator.IgnoreInconvertibleField
+	case HandlerPolicyWarn:
+		generatorOptions.InconvertibleFieldsHandler = nil
+	case HandlerPolicyComment:
+		generatorOptions.InconvertibleFieldsHandler = generator.WarnInconvertibleField
+	case HandlerPolicyError:
+		generatorOptions.InconvertibleFieldsHandler = ErrorInconvertibleFieldsHandler
+	}
+	switch c.Options.UnsupportedTypesPolicy {
+	case HandlerPolicyIgnore:
+		generatorOptions.UnsupportedTypesHandler = generator.IgnoreConversion
+	case HandlerPolicyWarn:
+		generatorOptions.UnsupportedTypesHandler = nil
+	case HandlerPolicyComment:
+		generatorOptions.UnsupportedTypesHandler = generator.CommentConversion
+	case HandlerPolicyError:
+		generatorOptions.UnsupportedTypesHandler = ErrorUnsupportedTypeHandler
+	}
+	switch c.Options.ExternalConversionsPolicy {
+	case HandlerPolicyIgnore:
+		generatorOptions.ExternalConversionsHandler = generator.IgnoreConversion
+	case HandlerPolicyWarn:
+		generatorOptions.ExternalConversionsHandler = nil
+	case HandlerPolicyComment:
+		generatorOptions.ExternalConversionsHandler = generator.CommentConversion
+	case HandlerPolicyError:
+		generatorOptions.ExternalConversionsHandler = ErrorExternalConversionHandler
+	}
+	return nil
+}
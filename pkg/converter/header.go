@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"go/build/constraint"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// buildConstraintsHeader returns the build constraint lines to put at the top of generated files:
+// generated files are excluded when building with generatedBuildTag, and are subject to any
+// additional constraints, all ANDed together.
+// The "//go:build" form is always emitted, and followed by the legacy "// +build" lines
+// unless omitLegacyLines is true.
+func buildConstraintsHeader(generatedBuildTag string, constraints []string, omitLegacyLines bool) ([]byte, error) {
+	var expressions []string
+	if generatedBuildTag != "" {
+		expressions = append(expressions, "!"+generatedBuildTag)
+	}
+	for _, c := range constraints {
+		expressions = append(expressions, "("+c+")")
+	}
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+
+	expr, err := constraint.Parse("//go:build " + strings.Join(expressions, " && "))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid build constraints %v", constraints)
+	}
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "//go:build %s\n", expr)
+	if !omitLegacyLines {
+		plusBuildLines, err := constraint.PlusBuildLines(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to express build constraints %q as legacy +build lines", expr)
+		}
+		for _, line := range plusBuildLines {
+			buffer.WriteString(line + "\n")
+		}
+	}
+	buffer.WriteString("\n")
+
+	return buffer.Bytes(), nil
+}
+
+// HeaderTemplateData is the data that header templates (see Options.HeaderTemplate) get executed with.
+type HeaderTemplateData struct {
+	// Year is the current 4-digit year.
+	Year int
+	// ToolName is the name of this tool.
+	ToolName string
+	// ToolVersion is this tool's module version, if known, "(devel)" otherwise.
+	ToolVersion string
+	// Package is the import path of the package the file is generated for.
+	Package string
+	// PackageName is the name of the package the file is generated for.
+	PackageName string
+	// PeerPackages are the import paths of that package's peer packages.
+	PeerPackages []string
+}
+
+func parseHeaderTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("header").Parse(text)
+	return tmpl, errors.Wrap(err, "unable to parse header template")
+}
+
+func renderHeaderTemplate(tmpl *template.Template, data *HeaderTemplateData) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return nil, errors.Wrapf(err, "unable to render header template for package %q", data.Package)
+	}
+	if buffer.Len() != 0 && !bytes.HasSuffix(buffer.Bytes(), []byte("\n\n")) {
+		if !bytes.HasSuffix(buffer.Bytes(), []byte("\n")) {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes(), nil
+}
+
+// toolVersion returns this tool's module version, as recorded in the binary's build info.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// appendGeneratedFileMarker appends GeneratedFileMarker to header, in its own paragraph.
+func appendGeneratedFileMarker(header []byte) []byte {
+	if len(header) != 0 && !bytes.HasSuffix(header, []byte("\n\n")) {
+		if !bytes.HasSuffix(header, []byte("\n")) {
+			header = append(header, '\n')
+		}
+		header = append(header, '\n')
+	}
+	return append(header, GeneratedFileMarker+"\n\n"...)
+}
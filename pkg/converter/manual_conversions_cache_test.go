@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManualConversionsCache(t *testing.T) {
+	// copy the fixture, as the test changes its sources
+	sourceDir := t.TempDir()
+	packageFiles := PackageFiles{}
+	for _, pkg := range []string{"v1", "v2"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "cache", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(sourceDir, pkg), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[i] = filepath.Join(sourceDir, pkg, filepath.Base(file))
+			if err := ioutil.WriteFile(files[i], content, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		packageFiles["example.com/cache/"+pkg] = files
+	}
+	cacheFile := filepath.Join(t.TempDir(), "cache", "manual_conversions.json")
+
+	outputBase := t.TempDir()
+	run := func() string {
+		t.Helper()
+
+		// fresh options every time, as converters record their manual conversions tracker in them
+		options := DefaultOptions()
+		options.PackageFiles = packageFiles
+		options.ManualConversionsCacheFile = cacheFile
+		converter := NewConverter([]string{"example.com/cache/v1"}, options)
+		converter.outputBaseOverride = outputBase
+		if err := converter.Run(); err != nil {
+			t.Fatal(err)
+		}
+		generated, err := ioutil.ReadFile(filepath.Join(outputBase, "example.com", "cache", "v1", options.OutputFileBaseName+".go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(generated)
+	}
+	readCache := func() map[string]*cachedEntry {
+		t.Helper()
+
+		raw, err := ioutil.ReadFile(cacheFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cache struct {
+			Packages map[string]*cachedEntry `json:"packages"`
+		}
+		if err := json.Unmarshal(raw, &cache); err != nil {
+			t.Fatal(err)
+		}
+		return cache.Packages
+	}
+	writeCache := func(version int, packages map[string]*cachedEntry) {
+		t.Helper()
+
+		raw, err := json.Marshal(map[string]interface{}{"version": version, "packages": packages})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(cacheFile, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// usesManualBarConversions checks whether the generated code calls the manual Bar conversions,
+	// rather than generating them.
+	usesManualBarConversions := func(generated string) bool {
+		return strings.Contains(generated, "Convert_v1_Bar_To_v2_Bar(&in.Bar, &out.Bar)") &&
+			!strings.Contains(generated, "func Convert_v1_Bar_To_v2_Bar(")
+	}
+
+	// a first run populates the cache
+	if generated := run(); !usesManualBarConversions(generated) {
+		t.Fatalf("expected the manual Bar conversions to be used, got:\n%s", generated)
+	}
+	cache := readCache()
+	entry := cache["example.com/cache/v1"]
+	if entry == nil || entry.Hash == "" ||
+		strings.Join(entry.Functions, ",") != "Convert_v1_Bar_To_v2_Bar,Convert_v2_Bar_To_v1_Bar" {
+		t.Fatalf("expected the v1 package's manual conversions to be cached, got %+v", entry)
+	}
+
+	// the cache gets reused as long as the package doesn't change: make it forget about the manual
+	// functions, which then get generated again
+	entry.Functions = nil
+	writeCache(1, cache)
+	if generated := run(); usesManualBarConversions(generated) {
+		t.Fatalf("expected the cached entry to be used, got:\n%s", generated)
+	}
+
+	// and invalidated when it does
+	conversionFile := filepath.Join(sourceDir, "v1", "conversion.go")
+	content, err := ioutil.ReadFile(conversionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(conversionFile, append(content, []byte("\n// a change\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if generated := run(); !usesManualBarConversions(generated) {
+		t.Fatalf("expected the stale cached entry to be discarded, got:\n%s", generated)
+	}
+	if updated := readCache()["example.com/cache/v1"]; updated == nil || updated.Hash == entry.Hash || len(updated.Functions) != 2 {
+		t.Errorf("expected the stale cached entry to be replaced, got %+v", updated)
+	}
+
+	// caches written by other versions get discarded
+	cache = readCache()
+	cache["example.com/cache/v1"].Functions = nil
+	writeCache(0, cache)
+	if generated := run(); !usesManualBarConversions(generated) {
+		t.Fatalf("expected the cache with another version to be discarded, got:\n%s", generated)
+	}
+}
+
+// cachedEntry is the JSON form of the manual conversions cache's entries.
+type cachedEntry struct {
+	Hash      string          `json:"hash"`
+	Functions []string        `json:"functions,omitempty"`
+	Calls     json.RawMessage `json:"calls,omitempty"`
+}
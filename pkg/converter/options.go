@@ -1,13 +1,38 @@
 package converter
 
 import (
+	"github.com/pkg/errors"
 	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
 
 	"github.com/wk8/go-conversion-gen/pkg/generator"
 )
 
 // TODO wkpo look at all of these, check the comments are accurate and all tested?
 
+const (
+	// DiagnosticsFormatSARIF writes diagnostics as a SARIF 2.1.0 log.
+	DiagnosticsFormatSARIF = "sarif"
+	// DiagnosticsFormatLSP writes diagnostics as a list of LSP PublishDiagnosticsParams.
+	DiagnosticsFormatLSP = "lsp"
+
+	// GraphFormatDOT writes conversion graphs in graphviz's DOT format.
+	GraphFormatDOT = "dot"
+
+	// FieldDocsFormatMarkdown writes field docs as Markdown documents.
+	FieldDocsFormatMarkdown = "markdown"
+	// FieldDocsFormatHTML writes field docs as HTML documents.
+	FieldDocsFormatHTML = "html"
+
+	// ProfileCPU writes a pprof CPU profile of runs.
+	ProfileCPU = "cpu"
+	// ProfileMem writes a pprof memory profile of runs, with both the allocations made during the
+	// run and the memory still in use at its end.
+	ProfileMem = "mem"
+	// ProfileTrace writes an execution trace of runs, see `go tool trace`.
+	ProfileTrace = "trace"
+)
+
 type Options struct {
 	// GeneratorOptions will be passed down to the Generators this converter spawns.
 	GeneratorOptions *generator.Options
@@ -20,14 +45,273 @@ type Options struct {
 
 	// TODO wkpo externalTypesTagName??
 
-	// ExtraGenerators allows adding more gengo generators, if needed.
-	ExtraGenerators func(context *gengogenerator.Context, conversionGenerator *generator.Generator) ([]gengogenerator.Generator, error)
+	// BuildConstraints are additional build constraint expressions (e.g. "linux && amd64") that
+	// generated files are subject to, on top of being excluded by the generated build tag.
+	// They are ANDed together.
+	BuildConstraints []string
+
+	// OmitLegacyBuildLines, if true, only emits "//go:build" lines in generated files, without the
+	// legacy "// +build" equivalents.
+	OmitLegacyBuildLines bool
+
+	// BuildTags are build tags to satisfy when loading packages, on top of the generated build
+	// tag; e.g. "linux" or "integration". Files whose build constraints they don't satisfy are
+	// ignored, as by the go tool.
+	BuildTags []string
+
+	// ConstrainedFiles, if true, generates the conversions of types declared in files with build
+	// constraints, including those implied by their names as with "types_linux.go", into files of
+	// their own with the same constraints, e.g. "zz_generated.conversion.linux.go"; rather than
+	// with the other conversions, into files that wouldn't build without these types. Such
+	// conversions don't get re-exported, see generator.Options.ReExportPackageTagName; and this
+	// can't be combined with generator.Options.DeduplicateLoops or PruneCastableConversions.
+	ConstrainedFiles bool
+
+	// HeaderTemplate, if set, is a text/template used to render the header of each generated file,
+	// instead of the static boilerplate file; see HeaderTemplateData for the available variables.
+	HeaderTemplate string
+
+	// UsePackagesDriver, if true, resolves the source files of inputs and of their dependencies
+	// through golang.org/x/tools/go/packages, which honors GOPACKAGESDRIVER (e.g. for Bazel/rules_go),
+	// instead of scanning GOPATH or module directories.
+	UsePackagesDriver bool
+
+	// PackageFiles, if set, explicitly lists the source files for each package; packages not
+	// listed can't be loaded. Takes precedence over UsePackagesDriver.
+	PackageFiles PackageFiles
+
+	// SharedUniverse, if set, is where to parse packages, so that they get parsed once for all the
+	// converters sharing it, e.g. when running converters with different options over the same
+	// packages; see NewSharedUniverse.
+	SharedUniverse *SharedUniverse
+
+	// Force, if true, overwrites existing files where files are generated even if they don't look
+	// generated, i.e. they have neither the standard "// Code generated ... DO NOT EDIT." comment,
+	// nor a build constraint excluding them with the generated build tag. Otherwise, such files
+	// make the run fail, so that hand-written code never gets lost to file name collisions.
+	Force bool
+
+	// ManualConversionsCacheFile, if set, is where to cache, across runs, the results of scanning
+	// packages for manual conversion functions; see generator.ManualConversionsCache.
+	ManualConversionsCacheFile string
+
+	// FailFast, if true, makes runs stop at the first input package that fails, e.g. because of a
+	// syntax error or of an unresolved peer package. Otherwise, runs carry on with the other
+	// packages, and fail once done; see Converter.Results. Syntax errors in peer packages or in
+	// dependencies still make runs fail right away.
+	FailFast bool
+
+	// FailOnIncompleteConversions, if true, makes input packages with conversions that no public
+	// function could be generated for fail, with generator.IncompleteConversionErrors; see
+	// generator.Generator.IncompleteConversions.
+	FailOnIncompleteConversions bool
+
+	// Since, if set, is a git ref: only the input packages with Go files that changed since then,
+	// whether committed or not, or whose peer packages' files did, or the files of packages that
+	// either imports, transitively, get regenerated; e.g. for fast pre-commit hooks in large
+	// repositories. The current directory must be within the git repository.
+	Since string
+
+	// OutputPackagesByGroup, if set, maps API groups to the packages that conversions for input
+	// packages from these groups get generated into, all in one file, rather than into each input
+	// package; e.g. {"apps": "example.com/pkg/apis/apps/conversions"}. An input package's API
+	// group is the value of the "+groupName" tag in its doc.go file, if any, or else the name of
+	// its parent directory, as in "pkg/apis/<group>/<version>". Output packages must exist, e.g.
+	// with just a doc.go file, and can't be input packages themselves; conversions involving
+	// private types can't be generated there. Input packages from other groups are unaffected.
+	OutputPackagesByGroup map[string]string
+
+	// SpliceFileBaseName, if set, is the name of existing files in input packages that generated
+	// code gets spliced into, between generator.SpliceBeginMarker and generator.SpliceEndMarker
+	// lines, instead of being written to OutputFileBaseName; e.g. for packages keeping generated
+	// and manual conversions in a single curated file. Hand-written code around the markers is
+	// preserved, and packages without such a file get OutputFileBaseName as usual.
+	SpliceFileBaseName string
+
+	// Backend decides how generated files get assembled: either BackendSnippets (the default),
+	// BackendAST, or BackendStreaming.
+	Backend Backend
+
+	// ASTPostProcessors modify generated files before they get printed, in order, with BackendAST;
+	// see e.g. RewriteImports.
+	ASTPostProcessors []ASTPostProcessor
+
+	// Scaffold, if true, writes a file with stub functions for the conversions that need to be
+	// written manually in each package, named ScaffoldFileBaseName; so that developers only need to
+	// fill in the bodies. Stub files that already exist are never overwritten.
+	// It also sets MissingFieldsPolicy and InconvertibleFieldsPolicy to HandlerPolicyError, unless
+	// they, or the corresponding handlers of GeneratorOptions, are set: missing and inconvertible
+	// fields then prevent the generation of public conversion functions, so that the stubs get
+	// called instead.
+	Scaffold bool
+
+	// MissingFieldsPolicy, InconvertibleFieldsPolicy, UnsupportedTypesPolicy and
+	// ExternalConversionsPolicy, if set, are what happens respectively with fields that don't exist
+	// in peer types, fields with inconvertible types, types that the generator doesn't know how to
+	// convert, and conversions to or from types of packages that conversions aren't generated for;
+	// by setting the corresponding handlers in GeneratorOptions, see e.g. HandlerPolicyComment.
+	MissingFieldsPolicy       HandlerPolicy
+	InconvertibleFieldsPolicy HandlerPolicy
+	UnsupportedTypesPolicy    HandlerPolicy
+	ExternalConversionsPolicy HandlerPolicy
+
+	// MissingFieldsHandlers, if set, are the names of the built-in handlers (see e.g.
+	// FieldsHandlerWarn) to set GeneratorOptions.MissingFieldsHandler to, tried in order until one
+	// of them doesn't skip; see MissingFieldsHandlerFor. Takes precedence over MissingFieldsPolicy.
+	MissingFieldsHandlers []string
+
+	// InconvertibleFieldsHandlers, if set, are the names of the built-in handlers (see e.g.
+	// FieldsHandlerDefault) to set GeneratorOptions.InconvertibleFieldsHandler to, tried in order
+	// until one of them doesn't skip; see InconvertibleFieldsHandlerFor. Takes precedence over
+	// InconvertibleFieldsPolicy.
+	InconvertibleFieldsHandlers []string
+
+	// ScaffoldFileBaseName is the name of the stub files written when Scaffold is true.
+	ScaffoldFileBaseName string
+
+	// TodoReportFormat, if set to either TodoReportMarkdown or TodoReportJSON, writes a report of the
+	// conversions left to do manually, with the signatures of the functions needed, in each package;
+	// reports are regenerated on every run, and removed from packages that no longer need them.
+	TodoReportFormat string
+
+	// DiagnosticsFile, if set, is where diagnostics about problems found while generating
+	// conversion code are written to, in DiagnosticsFormat.
+	DiagnosticsFile string
+
+	// DiagnosticsFormat is the format diagnostics are written in: either DiagnosticsFormatSARIF
+	// or DiagnosticsFormatLSP.
+	DiagnosticsFormat string
+
+	// DetectCycles, if true, makes runs fail if conversion functions call each other on the same
+	// object in a cycle - directly, or through generated functions - that would recurse infinitely
+	// at runtime; no file gets written then. This parses manual conversion functions to build the
+	// conversion graph.
+	DetectCycles bool
+
+	// GraphFormat, if set, writes the graph of generated and manual conversion functions, and of
+	// which of them call which, to GraphFile; the only supported format is GraphFormatDOT.
+	GraphFormat string
+
+	// GraphFile is where the conversion graph is written to, if GraphFormat is set;
+	// defaults to stdout.
+	GraphFile string
+
+	// FieldDocsDir, if set, is the directory where to write a document for each pair of types
+	// converted, showing how each field is converted; see generator.FieldDocs.
+	FieldDocsDir string
+
+	// FieldDocsFormat is the format of the documents written to FieldDocsDir: either
+	// FieldDocsFormatMarkdown, the default, or FieldDocsFormatHTML.
+	FieldDocsFormat string
+
+	// Profile, if set, profiles runs: either ProfileCPU, ProfileMem or ProfileTrace; e.g. to find
+	// the hot paths of slow runs. The profile is written to ProfileFile.
+	Profile string
+
+	// ProfileFile is where the profile is written to, if Profile is set; defaults to
+	// "<profile>.pprof", or "trace.out" for ProfileTrace, in the current directory.
+	ProfileFile string
+
+	// Explain are the names of types, either qualified ("<pkg-path>.<name>") or not, for which to
+	// print the full trace of decisions made when generating conversion code to stdout: where
+	// peers were looked for, which filter rejected them, which tags applied, and why each field
+	// got the code it did.
+	Explain []string
+
+	// SizeReport, if true, prints the number of generated lines and functions in each package
+	// to stdout, e.g. to track generated code bloat over time.
+	SizeReport bool
+
+	// SizeBudget, if positive, is the maximum number of lines generated in each package: packages
+	// over the budget are warned about, or make the run fail if StrictSizeBudget is set.
+	SizeBudget int
+
+	// StrictSizeBudget, if true, makes the run fail if any package goes over SizeBudget.
+	StrictSizeBudget bool
+
+	// EqualityFunctions, if true, also generates functions telling whether values of the struct types
+	// converted in each package are semantically equal to values of their peer types, e.g.
+	// Equal_v1_Foo_v2_Foo, into files named EqualityFileBaseName; see generator.EqualityGenerator.
+	EqualityFunctions bool
+
+	// EqualityFileBaseName is the name of the files written when EqualityFunctions is true.
+	EqualityFileBaseName string
+
+	// ExtraGenerators allows adding more gengo generators, if needed; it gets called for each input
+	// package, with the package's conversion generator, and the converter's options.
+	ExtraGenerators func(context *gengogenerator.Context, conversionGenerator *generator.Generator, pkg *PackageInfo, options *Options) ([]gengogenerator.Generator, error)
+}
+
+// PackageInfo describes an input package, see Options.ExtraGenerators.
+type PackageInfo struct {
+	// Path is the package's import path.
+	Path string
+	// Package is the parsed package.
+	Package *types.Package
+	// PeerPackages are the packages that the package's peer types are looked for in.
+	PeerPackages []string
 }
 
 func DefaultOptions() *Options {
 	return &Options{
 		GeneratorOptions: generator.DefaultOptions(),
 
-		OutputFileBaseName: "conversion_generated",
+		OutputFileBaseName:   "conversion_generated",
+		ScaffoldFileBaseName: "conversion_manual_todo",
+		EqualityFileBaseName: "equality_generated",
+		DiagnosticsFormat:    DiagnosticsFormatSARIF,
+	}
+}
+
+// Validate returns an error if the options are inconsistent, including the generator options;
+// runs call it once CLI flags, if any, are parsed, before loading any package.
+func (o *Options) Validate() error {
+	if o.OutputFileBaseName == "" {
+		return errors.New("no output file base name set")
+	}
+	if o.Scaffold && o.ScaffoldFileBaseName == "" {
+		return errors.New("scaffolding needs a scaffold file base name")
+	}
+	if o.EqualityFunctions && o.EqualityFileBaseName == "" {
+		return errors.New("equality functions need an equality file base name")
+	}
+	for _, name := range []string{o.ScaffoldFileBaseName, o.EqualityFileBaseName} {
+		if name == o.OutputFileBaseName {
+			return errors.Errorf("file base name %q is also the output file base name", name)
+		}
+	}
+
+	for _, policy := range []HandlerPolicy{o.MissingFieldsPolicy, o.InconvertibleFieldsPolicy, o.UnsupportedTypesPolicy, o.ExternalConversionsPolicy} {
+		if policy == "" {
+			continue
+		}
+		if err := validateHandlerPolicy(policy); err != nil {
+			return err
+		}
+	}
+	switch o.Backend {
+	case "", BackendSnippets, BackendAST, BackendStreaming:
+	default:
+		return errors.Errorf("unknown backend %q", o.Backend)
+	}
+	if o.DiagnosticsFile != "" && o.DiagnosticsFormat != "" && o.DiagnosticsFormat != DiagnosticsFormatSARIF && o.DiagnosticsFormat != DiagnosticsFormatLSP {
+		return errors.Errorf("unknown diagnostics format %q", o.DiagnosticsFormat)
+	}
+	if o.GraphFormat != "" && o.GraphFormat != GraphFormatDOT {
+		return errors.Errorf("unknown graph format %q", o.GraphFormat)
+	}
+	if o.FieldDocsFormat != "" && o.FieldDocsFormat != FieldDocsFormatMarkdown && o.FieldDocsFormat != FieldDocsFormatHTML {
+		return errors.Errorf("unknown field docs format %q", o.FieldDocsFormat)
+	}
+	if _, known := todoReportFileNames[o.TodoReportFormat]; o.TodoReportFormat != "" && !known {
+		return errors.Errorf("unknown TODO report format %q", o.TodoReportFormat)
+	}
+
+	if o.GeneratorOptions == nil {
+		return nil
+	}
+	if o.ConstrainedFiles && (o.GeneratorOptions.DeduplicateLoops || o.GeneratorOptions.PruneCastableConversions) {
+		return errors.New("constrained files can't be combined with loop deduplication or castable conversion pruning")
 	}
+	return errors.Wrap(o.GeneratorOptions.Validate(), "invalid generator options")
 }
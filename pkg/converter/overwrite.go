@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"bufio"
+	"go/build/constraint"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// generatedCodeComment matches the standard comment marking generated Go files, see
+// https://golang.org/s/generatedcode.
+var generatedCodeComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// looksGenerated returns true iff the Go file at path looks generated: that is, if it has the
+// standard generated code comment, or a build constraint excluding it when building with
+// generatedBuildTag, before its package clause. Files that don't exist look generated.
+func looksGenerated(path, generatedBuildTag string) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "unable to open %q", path)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if generatedCodeComment.MatchString(line) {
+			return true, nil
+		}
+		if generatedBuildTag != "" && constraint.IsGoBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil && excludes(expr, generatedBuildTag) {
+				return true, nil
+			}
+		}
+	}
+	return false, errors.Wrapf(scanner.Err(), "unable to read %q", path)
+}
+
+// excludes returns true iff expr is false whenever tag is set.
+func excludes(expr constraint.Expr, tag string) bool {
+	return !expr.Eval(func(t string) bool { return t == tag })
+}
+
+// checkOverwrite makes sure that the file about to be generated at path can be overwritten,
+// i.e. that it looks generated; unless Options.Force is set.
+func (c *Converter) checkOverwrite(path string) error {
+	if c.Options.Force || c.args.VerifyOnly {
+		return nil
+	}
+	generated, err := looksGenerated(path, c.args.GeneratedBuildTag)
+	if err != nil {
+		return err
+	}
+	if !generated {
+		return errors.Errorf("refusing to overwrite %q, which doesn't look generated; rename it, or force overwriting it", path)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package converter
+
+import (
+	"encoding/json"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+	"k8s.io/klog/v2"
+)
+
+// PackageFiles maps import paths to the absolute paths of the source files making up each package.
+type PackageFiles map[string][]string
+
+// LoadPackageFilesManifest loads a JSON file mapping import paths to lists of source files, e.g.
+//
+//	{"example.com/api/v1": ["/abs/path/to/api/v1/types.go", "/abs/path/to/api/v1/doc.go"]}
+//
+// Relative file paths are resolved relative to the manifest's directory.
+func LoadPackageFilesManifest(path string) (PackageFiles, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read package files manifest %q", path)
+	}
+
+	var files PackageFiles
+	if err := json.Unmarshal(raw, &files); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse package files manifest %q", path)
+	}
+
+	baseDir := filepath.Dir(path)
+	for pkgPath, pkgFiles := range files {
+		for i, file := range pkgFiles {
+			if !filepath.IsAbs(file) {
+				pkgFiles[i] = filepath.Join(baseDir, file)
+			}
+		}
+		files[pkgPath] = pkgFiles
+	}
+	return files, nil
+}
+
+// LoadPackageFilesFromDriver resolves the source files of the packages matching patterns, as well
+// as of all their dependencies, using golang.org/x/tools/go/packages; which means it honors
+// GOPACKAGESDRIVER, making it possible to run hermetically under e.g. Bazel/rules_go.
+func LoadPackageFilesFromDriver(patterns []string, buildTags ...string) (PackageFiles, error) {
+	config := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+	}
+	if len(buildTags) != 0 {
+		config.BuildFlags = []string{"-tags=" + strings.Join(buildTags, ",")}
+	}
+
+	roots, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load packages %v", patterns)
+	}
+
+	files := make(PackageFiles)
+	var loadErrors []packages.Error
+	packages.Visit(roots, nil, func(pkg *packages.Package) {
+		loadErrors = append(loadErrors, pkg.Errors...)
+		if len(pkg.GoFiles) != 0 {
+			files[pkg.PkgPath] = pkg.GoFiles
+		}
+	})
+	if len(loadErrors) != 0 {
+		errMsg := "Errors when loading packages through the packages driver:"
+		for _, loadError := range loadErrors {
+			errMsg += "\n" + loadError.Error()
+		}
+		return nil, errors.New(errMsg)
+	}
+
+	klog.V(5).Infof("Packages driver resolved %d package(s)", len(files))
+	return files, nil
+}
+
+// virtualGopath is the GOPATH that go/build is pointed at when loading packages from explicit
+// file lists; it doesn't exist on disk, see packageFilesLoader.install.
+const virtualGopath = "/go-conversion-gen-package-files"
+
+// a packageFilesLoader makes go/build load packages solely from explicit file lists.
+type packageFilesLoader struct {
+	// packageDirs maps import paths to the directory containing their files.
+	packageDirs map[string]string
+	// dirFiles maps directories to the names of the files that can be loaded from them.
+	dirFiles map[string]map[string]bool
+
+	// resolve, if set, is called to look up the files of packages not known yet
+	// (e.g. peer packages only listed in doc.go files).
+	resolve func(pkgPath string) (PackageFiles, error)
+	// resolved tracks the packages that resolve has been called for.
+	resolved map[string]bool
+}
+
+func newPackageFilesLoader(resolve func(pkgPath string) (PackageFiles, error)) *packageFilesLoader {
+	return &packageFilesLoader{
+		packageDirs: make(map[string]string),
+		dirFiles:    make(map[string]map[string]bool),
+		resolve:     resolve,
+		resolved:    make(map[string]bool),
+	}
+}
+
+// add adds the given files to the loader. All of a package's files must be in the same directory.
+func (l *packageFilesLoader) add(files PackageFiles) error {
+	for pkgPath, pkgFiles := range files {
+		for _, file := range pkgFiles {
+			dir := filepath.Dir(file)
+			if previousDir, present := l.packageDirs[pkgPath]; present && previousDir != dir {
+				return errors.Errorf("files for package %q span several directories: %q and %q", pkgPath, previousDir, dir)
+			}
+			l.packageDirs[pkgPath] = dir
+
+			if l.dirFiles[dir] == nil {
+				l.dirFiles[dir] = make(map[string]bool)
+			}
+			l.dirFiles[dir][filepath.Base(file)] = true
+		}
+	}
+	return nil
+}
+
+func (l *packageFilesLoader) packageDir(pkgPath string) (string, bool) {
+	if dir, present := l.packageDirs[pkgPath]; present {
+		return dir, true
+	}
+	if l.resolve == nil || l.resolved[pkgPath] {
+		return "", false
+	}
+	l.resolved[pkgPath] = true
+
+	files, err := l.resolve(pkgPath)
+	if err == nil {
+		err = l.add(files)
+	}
+	if err != nil {
+		klog.Warningf("unable to resolve files for package %q: %v", pkgPath, err)
+		return "", false
+	}
+
+	dir, present := l.packageDirs[pkgPath]
+	return dir, present
+}
+
+// install overrides go/build's default context so that packages are loaded solely through
+// this loader, instead of from GOPATH or modules; gengo's parser copies that default context
+// when created.
+// It returns a function to restore the previous default context.
+func (l *packageFilesLoader) install() (restore func()) {
+	previous := build.Default
+
+	build.Default.GOPATH = virtualGopath
+	build.Default.JoinPath = func(elem ...string) string {
+		if len(elem) == 3 && elem[0] == virtualGopath && elem[1] == "src" {
+			if dir, present := l.packageDir(elem[2]); present {
+				return dir
+			}
+		}
+		return filepath.Join(elem...)
+	}
+	build.Default.ReadDir = func(dir string) ([]os.FileInfo, error) {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		allowed, present := l.dirFiles[dir]
+		if !present {
+			return infos, nil
+		}
+
+		filtered := make([]os.FileInfo, 0, len(allowed))
+		for _, info := range infos {
+			if allowed[info.Name()] {
+				filtered = append(filtered, info)
+			}
+		}
+		sort.Slice(filtered, func(i, j int) bool { return filtered[i].Name() < filtered[j].Name() })
+		return filtered, nil
+	}
+
+	return func() { build.Default = previous }
+}
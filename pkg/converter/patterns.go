@@ -0,0 +1,165 @@
+package converter
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+	"k8s.io/klog/v2"
+)
+
+// isPackagePattern returns true iff the given input package argument is a pattern matching
+// possibly several packages, e.g. "./..." or "example.com/api/*/v1", rather than a single package.
+func isPackagePattern(pattern string) bool {
+	return strings.Contains(pattern, "...") || strings.ContainsAny(pattern, "*?[")
+}
+
+// isFilesystemPattern returns true iff the given pattern is relative to the current directory,
+// or absolute, rather than an import path pattern.
+func isFilesystemPattern(pattern string) bool {
+	return pattern == "." || pattern == ".." || strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../") || filepath.IsAbs(pattern)
+}
+
+// expandInputPatterns replaces the patterns in the given input package arguments with the import
+// paths of the packages they match; patterns can contain "..." wildcards, as for the go command,
+// as well as glob wildcards (see path.Match) within path elements.
+// Directories without Go files are skipped.
+// When packageFiles is set, patterns are matched against its packages instead of being resolved
+// through the go command, or the packages driver.
+func expandInputPatterns(inputs []string, packageFiles PackageFiles, buildTags ...string) ([]string, error) {
+	var expanded []string
+	seen := make(map[string]bool)
+	add := func(pkgPath string) {
+		if !seen[pkgPath] {
+			seen[pkgPath] = true
+			expanded = append(expanded, pkgPath)
+		}
+	}
+
+	for _, input := range inputs {
+		if !isPackagePattern(input) {
+			add(input)
+			continue
+		}
+
+		var matches []string
+		var err error
+		if packageFiles != nil {
+			matches, err = matchPackageFiles(input, packageFiles)
+		} else {
+			matches, err = loadPackagePattern(input, buildTags...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			klog.Warningf("input pattern %q matched no packages", input)
+		}
+		klog.V(5).Infof("input pattern %q matched %v", input, matches)
+
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	return expanded, nil
+}
+
+// loadPackagePattern resolves a pattern through golang.org/x/tools/go/packages.
+func loadPackagePattern(pattern string, buildTags ...string) ([]string, error) {
+	var (
+		loadPatterns []string
+		matcher      *regexp.Regexp
+	)
+
+	switch {
+	case !strings.ContainsAny(pattern, "*?["):
+		// the go command understands these natively
+		loadPatterns = []string{pattern}
+	case isFilesystemPattern(pattern):
+		globbed, recursive := pattern, false
+		if strings.HasSuffix(globbed, "/...") {
+			globbed, recursive = strings.TrimSuffix(globbed, "/..."), true
+		}
+		dirs, err := filepath.Glob(globbed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid input pattern %q", pattern)
+		}
+		for _, dir := range dirs {
+			if !filepath.IsAbs(dir) {
+				dir = "./" + filepath.ToSlash(dir)
+			}
+			if recursive {
+				dir += "/..."
+			}
+			loadPatterns = append(loadPatterns, dir)
+		}
+	default:
+		// load every package under the pattern's longest wildcard-free prefix, then filter
+		elements := strings.Split(pattern, "/")
+		prefix := elements[:0]
+		for _, element := range elements {
+			if isPackagePattern(element) {
+				break
+			}
+			prefix = append(prefix, element)
+		}
+		loadPatterns = []string{strings.Join(prefix, "/") + "/..."}
+		matcher = patternMatcher(pattern)
+	}
+	if len(loadPatterns) == 0 {
+		return nil, nil
+	}
+
+	config := &packages.Config{Mode: packages.NeedName | packages.NeedFiles}
+	if len(buildTags) != 0 {
+		config.BuildFlags = []string{"-tags=" + strings.Join(buildTags, ",")}
+	}
+	pkgs, err := packages.Load(config, loadPatterns...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve input pattern %q", pattern)
+	}
+
+	var matches []string
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 || (matcher != nil && !matcher.MatchString(pkg.PkgPath)) {
+			continue
+		}
+		matches = append(matches, pkg.PkgPath)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchPackageFiles returns the packages from packageFiles matching the given import path pattern.
+func matchPackageFiles(pattern string, packageFiles PackageFiles) ([]string, error) {
+	if isFilesystemPattern(pattern) {
+		return nil, errors.Errorf("input pattern %q: only import path patterns are supported with explicit package files", pattern)
+	}
+	matcher := patternMatcher(pattern)
+
+	var matches []string
+	for pkgPath, files := range packageFiles {
+		if len(files) != 0 && matcher.MatchString(pkgPath) {
+			matches = append(matches, pkgPath)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// patternMatcher builds a regular expression matching the import paths that the given pattern
+// matches: "..." matches any string, and a trailing "/..." also matches the empty string, as for
+// the go command; "*" and "?" match within a single path element.
+func patternMatcher(pattern string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\.\.\.`, `.*`)
+	if strings.HasSuffix(expr, `/.*`) {
+		expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+	}
+	expr = strings.ReplaceAll(expr, `\*`, `[^/]*`)
+	expr = strings.ReplaceAll(expr, `\?`, `[^/]`)
+	return regexp.MustCompile("^" + expr + "$")
+}
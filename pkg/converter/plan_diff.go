@@ -0,0 +1,163 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// A FunctionChange is how a generated function would change, see FunctionDiff.
+type FunctionChange string
+
+const (
+	// FunctionAdded functions don't exist yet in generated files.
+	FunctionAdded FunctionChange = "added"
+	// FunctionRemoved functions exist in generated files, but wouldn't be generated anymore.
+	FunctionRemoved FunctionChange = "removed"
+	// FunctionChanged functions exist in generated files, but would be generated differently.
+	FunctionChanged FunctionChange = "changed"
+)
+
+// A FunctionDiff is a function that would change if the converter were run.
+type FunctionDiff struct {
+	// Package is the import path of the package the function is generated in.
+	Package string
+	// Path is the path of the generated file.
+	Path     string
+	Function string
+	Change   FunctionChange
+}
+
+// DiffPlan compares what the converter would generate with the existing generated files, and
+// returns the functions that would be added, removed or changed; without writing anything.
+// ctx is only checked before generating, as generation can't be interrupted.
+func (c *Converter) DiffPlan(ctx context.Context) (diffs []FunctionDiff, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = c.runInTempDir(func(tmpDir string) error {
+		files, err := c.generatedFiles(tmpDir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			planned, plannedSources, err := functionSources(file.GeneratedPath)
+			if err != nil {
+				return err
+			}
+
+			var existing []string
+			existingSources := make(map[string]string)
+			if _, err := os.Stat(file.SourcePath); err == nil {
+				if existing, existingSources, err = functionSources(file.SourcePath); err != nil {
+					return err
+				}
+			} else if !os.IsNotExist(err) {
+				return errors.Wrapf(err, "unable to stat %q", file.SourcePath)
+			}
+
+			diff := func(function string, change FunctionChange) {
+				diffs = append(diffs, FunctionDiff{
+					Package:  file.Package,
+					Path:     file.SourcePath,
+					Function: function,
+					Change:   change,
+				})
+			}
+			for _, function := range planned {
+				if existingSource, present := existingSources[function]; !present {
+					diff(function, FunctionAdded)
+				} else if existingSource != plannedSources[function] {
+					diff(function, FunctionChanged)
+				}
+			}
+			for _, function := range existing {
+				if _, present := plannedSources[function]; !present {
+					diff(function, FunctionRemoved)
+				}
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// functionSources returns the names of the functions declared in the given Go file, in order,
+// and their formatted sources, without comments.
+func functionSources(path string) (names []string, sources map[string]string, err error) {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, path, nil, 0)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to parse %q", path)
+	}
+
+	sources = make(map[string]string)
+	buffer := &bytes.Buffer{}
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := funcDecl.Name.Name
+		if funcDecl.Recv != nil {
+			// shouldn't happen in generated files, but methods can share names
+			name = fmt.Sprintf("%s.%s", receiverTypeName(funcDecl.Recv), name)
+		}
+
+		buffer.Reset()
+		if err := format.Node(buffer, fileSet, funcDecl); err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to format function %s in %q", name, path)
+		}
+		names = append(names, name)
+		sources[name] = buffer.String()
+	}
+	return names, sources, nil
+}
+
+func receiverTypeName(receiver *ast.FieldList) string {
+	if len(receiver.List) == 0 {
+		return ""
+	}
+	t := receiver.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if ident, ok := t.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return fmt.Sprintf("%T", t)
+}
+
+// WritePlanDiff writes a human-readable version of the plan diff.
+func WritePlanDiff(w io.Writer, diffs []FunctionDiff) error {
+	buffer := &bytes.Buffer{}
+	markers := map[FunctionChange]string{
+		FunctionAdded:   "+",
+		FunctionRemoved: "-",
+		FunctionChanged: "~",
+	}
+	counts := make(map[FunctionChange]int)
+	currentPackage := ""
+	for _, diff := range diffs {
+		if diff.Package != currentPackage {
+			fmt.Fprintf(buffer, "%s (%s):\n", diff.Package, diff.Path)
+			currentPackage = diff.Package
+		}
+		fmt.Fprintf(buffer, "  %s %s\n", markers[diff.Change], diff.Function)
+		counts[diff.Change]++
+	}
+	fmt.Fprintf(buffer, "%d function(s) added, %d removed, %d changed\n",
+		counts[FunctionAdded], counts[FunctionRemoved], counts[FunctionChanged])
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
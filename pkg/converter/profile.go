@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// startProfile starts profiling the run, if so configured; see Options.Profile. The returned
+// function stops profiling, and writes the profile.
+func (c *Converter) startProfile() (stop func() error, err error) {
+	switch c.Options.Profile {
+	case "":
+		return func() error { return nil }, nil
+	case ProfileCPU, ProfileMem, ProfileTrace:
+	default:
+		return nil, errors.Errorf("unknown profile %q", c.Options.Profile)
+	}
+
+	path := c.Options.ProfileFile
+	if path == "" {
+		path = c.Options.Profile + ".pprof"
+		if c.Options.Profile == ProfileTrace {
+			path = "trace.out"
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create profile file %q", path)
+	}
+	closeFile := func() error {
+		if err := file.Close(); err != nil {
+			return errors.Wrapf(err, "unable to write profile file %q", path)
+		}
+		klog.Infof("Wrote %s profile to %q", c.Options.Profile, path)
+		return nil
+	}
+
+	switch c.Options.Profile {
+	case ProfileCPU:
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, errors.Wrap(err, "unable to start CPU profiling")
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return closeFile()
+		}, nil
+
+	case ProfileMem:
+		return func() error {
+			// so that the profile is up to date, see pprof.WriteHeapProfile
+			runtime.GC()
+			if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+				file.Close()
+				return errors.Wrap(err, "unable to write memory profile")
+			}
+			return closeFile()
+		}, nil
+
+	default: // ProfileTrace
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			return nil, errors.Wrap(err, "unable to start tracing")
+		}
+		return func() error {
+			trace.Stop()
+			return closeFile()
+		}, nil
+	}
+}
@@ -0,0 +1,221 @@
+package converter
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+	"k8s.io/klog/v2"
+)
+
+// A PackageOutcome is what happened to an input package during a run, see PackageResult.
+type PackageOutcome string
+
+const (
+	// PackageGenerated means that conversion code was generated for the package.
+	PackageGenerated PackageOutcome = "generated"
+	// PackageSkipped means that the package had nothing to generate conversion code for.
+	PackageSkipped PackageOutcome = "skipped"
+	// PackageFailed means that the package couldn't be processed, see PackageResult.Err.
+	PackageFailed PackageOutcome = "failed"
+)
+
+// A PackageResult is what happened to an input package during a run.
+type PackageResult struct {
+	// Package is the package's import path.
+	Package string
+	Outcome PackageOutcome
+	// Err is why the package failed, with PackageFailed.
+	Err error
+}
+
+// Results returns what happened to each input package during the last run, in input order.
+func (c *Converter) Results() []PackageResult {
+	return append([]PackageResult{}, c.results...)
+}
+
+// recordResult records what happened to an input package.
+func (c *Converter) recordResult(pkgPath string, outcome PackageOutcome, err error) {
+	c.results = append(c.results, PackageResult{Package: pkgPath, Outcome: outcome, Err: err})
+}
+
+// failPackage records that an input package failed, or exits right away if Options.FailFast
+// is set.
+func (c *Converter) failPackage(pkgPath string, err error) {
+	if c.Options.FailFast {
+		klog.Fatalf("%v", err)
+	}
+	klog.Errorf("Skipping package %q: %v", pkgPath, err)
+	c.recordResult(pkgPath, PackageFailed, err)
+}
+
+// failGeneratedPackage records that an input package failed while generating its code, or exits
+// right away if Options.FailFast is set.
+func (c *Converter) failGeneratedPackage(pkgPath string, err error) {
+	if c.Options.FailFast {
+		klog.Fatalf("%v", err)
+	}
+	klog.Errorf("Package %q failed: %v", pkgPath, err)
+	for i, result := range c.results {
+		if result.Package == pkgPath {
+			c.results[i] = PackageResult{Package: pkgPath, Outcome: PackageFailed, Err: err}
+			return
+		}
+	}
+	c.recordResult(pkgPath, PackageFailed, err)
+}
+
+// failGroup records that all the input packages of an output group failed, as the group can't be
+// generated; or exits right away if Options.FailFast is set.
+func (c *Converter) failGroup(group *outputGroup, err error) {
+	generatedPackages := c.generatedPackages[:0]
+	for _, generated := range c.generatedPackages {
+		if !group.inputs[generated.pkg.Path] {
+			generatedPackages = append(generatedPackages, generated)
+		}
+	}
+	c.generatedPackages = generatedPackages
+
+	for _, result := range c.results {
+		if group.inputs[result.Package] {
+			c.failGeneratedPackage(result.Package, err)
+		}
+	}
+}
+
+// A FailedPackagesError lists the packages that failed during a run; errors.Is and errors.As
+// match any of their errors, e.g. generator.ErrPeerPackageNotFound.
+type FailedPackagesError struct {
+	Results []PackageResult
+}
+
+func (e *FailedPackagesError) Error() string {
+	failed := make([]string, 0, len(e.Results))
+	for _, result := range e.Results {
+		failed = append(failed, result.Package+": "+result.Err.Error())
+	}
+	return fmt.Sprintf("%d package(s) failed:\n  %s", len(failed), strings.Join(failed, "\n  "))
+}
+
+func (e *FailedPackagesError) Is(target error) bool {
+	for _, result := range e.Results {
+		if errors.Is(result.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *FailedPackagesError) As(target interface{}) bool {
+	for _, result := range e.Results {
+		if errors.As(result.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedPackagesError returns a *FailedPackagesError listing the packages that failed during the
+// last run, if any.
+func (c *Converter) failedPackagesError() error {
+	var failed []PackageResult
+	for _, result := range c.results {
+		if result.Outcome == PackageFailed {
+			failed = append(failed, result)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &FailedPackagesError{Results: failed}
+}
+
+// An IncompleteConversionsError lists the conversions of a package that no public function
+// could be generated for, see Options.FailOnIncompleteConversions; errors.As matches any of them.
+type IncompleteConversionsError struct {
+	Conversions []*generator.IncompleteConversionError
+}
+
+func (e *IncompleteConversionsError) Error() string {
+	messages := make([]string, 0, len(e.Conversions))
+	for _, conversion := range e.Conversions {
+		messages = append(messages, conversion.Error())
+	}
+	return strings.Join(messages, "\n  ")
+}
+
+func (e *IncompleteConversionsError) As(target interface{}) bool {
+	for _, conversion := range e.Conversions {
+		if errors.As(conversion, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// failIncompleteConversions marks the generated packages with conversions that no public
+// function could be generated for as failed, if Options.FailOnIncompleteConversions is set.
+// It must be called once code is generated.
+func (c *Converter) failIncompleteConversions() {
+	if !c.Options.FailOnIncompleteConversions {
+		return
+	}
+	incomplete := make(map[string][]*generator.IncompleteConversionError)
+	for _, generated := range c.generatedPackages {
+		incomplete[generated.pkg.Path] = append(incomplete[generated.pkg.Path], generated.generator.IncompleteConversions()...)
+	}
+	for i, result := range c.results {
+		conversions := incomplete[result.Package]
+		if result.Outcome != PackageGenerated || len(conversions) == 0 {
+			continue
+		}
+		err := &IncompleteConversionsError{Conversions: conversions}
+		if c.Options.FailFast {
+			klog.Fatalf("%v", err)
+		}
+		klog.Errorf("Package %q has incomplete conversions: %v", result.Package, err)
+		c.results[i] = PackageResult{Package: result.Package, Outcome: PackageFailed, Err: err}
+	}
+}
+
+// excludeUnparseableInputs removes from the inputs the packages whose source files don't parse,
+// recording them as failed; so that they don't prevent loading the other ones.
+// It must be called once package files are installed, see installPackageFiles.
+func (c *Converter) excludeUnparseableInputs() {
+	context := build.Default
+	context.BuildTags = append(append([]string{}, context.BuildTags...), c.args.GeneratedBuildTag)
+
+	inputs := c.args.InputDirs[:0]
+	for _, input := range c.args.InputDirs {
+		if err := checkPackageSyntax(&context, input); err != nil {
+			c.failPackage(input, err)
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+	c.args.InputDirs = inputs
+}
+
+// checkPackageSyntax returns an error if any of the package's source files doesn't parse.
+// Packages that can't be found are left for the parser to complain about.
+func checkPackageSyntax(context *build.Context, pkgPath string) error {
+	pkg, err := context.Import(pkgPath, ".", build.ImportComment)
+	if _, noGo := err.(*build.NoGoError); noGo || pkg == nil || pkg.Dir == "" {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "unable to load package %q", pkgPath)
+	}
+
+	fileSet := token.NewFileSet()
+	for _, file := range append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...) {
+		if _, err := parser.ParseFile(fileSet, filepath.Join(pkg.Dir, file), nil, 0); err != nil {
+			return errors.Wrapf(err, "unable to parse package %q", pkgPath)
+		}
+	}
+	return nil
+}
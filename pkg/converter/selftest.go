@@ -0,0 +1,169 @@
+package converter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// SelfTest generates conversion code into a temporary directory, then runs `go build` (and,
+// if runTests is true, `go test`) on the affected packages, using a build overlay so that
+// the generated files are seen in place of whatever currently exists on disk.
+// No tests get generated: runTests runs the packages' own tests, e.g. hand-written round-trip
+// or fuzz tests, against the generated code.
+// Nothing is written to the input packages themselves, which makes it well suited as a
+// pre-merge check for API changes.
+func (c *Converter) SelfTest(runTests bool) error {
+	return c.runInTempDir(func(tmpDir string) error {
+		return c.selfTest(tmpDir, runTests)
+	})
+}
+
+func (c *Converter) selfTest(tmpDir string, runTests bool) error {
+	overlay, packagePaths, err := c.selfTestOverlay(tmpDir)
+	if err != nil {
+		return err
+	}
+	if len(packagePaths) == 0 {
+		klog.Infof("Self-test: nothing was generated")
+		return nil
+	}
+
+	overlayPath := filepath.Join(tmpDir, "overlay.json")
+	if err := writeJSONFile(overlayPath, overlay); err != nil {
+		return errors.Wrap(err, "unable to write build overlay")
+	}
+
+	if err := runGoCommand("build", overlayPath, packagePaths); err != nil {
+		return errors.Wrap(err, "generated code does not build")
+	}
+	if runTests {
+		if err := runGoCommand("test", overlayPath, packagePaths); err != nil {
+			return errors.Wrap(err, "tests failed against generated code")
+		}
+	}
+
+	klog.Infof("Self-test passed for %d package(s)", len(packagePaths))
+	return nil
+}
+
+// selfTestOverlay maps all the files written under outputBase to where they would have been
+// written to in the actual source tree, in the format expected by `go build -overlay`.
+func (c *Converter) selfTestOverlay(outputBase string) (overlay map[string]map[string]string, packagePaths []string, err error) {
+	files, err := c.writtenFiles(outputBase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replace := make(map[string]string, len(files))
+	seen := make(map[string]bool)
+	for _, file := range files {
+		replace[file.SourcePath] = file.GeneratedPath
+		if !seen[file.Package] {
+			seen[file.Package] = true
+			packagePaths = append(packagePaths, file.Package)
+		}
+	}
+
+	return map[string]map[string]string{"Replace": replace}, packagePaths, nil
+}
+
+// a generatedFile is a file generated into a temporary output base, see runInTempDir.
+type generatedFile struct {
+	// Package is the import path of the package the file was generated for.
+	Package string
+	// GeneratedPath is where the file was generated.
+	GeneratedPath string
+	// SourcePath is where the file would have been generated, in the actual source tree.
+	SourcePath string
+}
+
+// generatedFiles lists the files that were generated under outputBase during the last run.
+func (c *Converter) generatedFiles(outputBase string) ([]generatedFile, error) {
+	var files []generatedFile
+	// packages can share output packages, see Options.OutputPackagesByGroup
+	seen := make(map[string]bool)
+	for _, generated := range c.generatedPackages {
+		pkg := generated.output
+		generatedPath := filepath.Join(outputBase, pkg.Path, generated.fileName)
+		if seen[generatedPath] {
+			continue
+		}
+		seen[generatedPath] = true
+		if _, err := os.Stat(generatedPath); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to stat %q", generatedPath)
+		}
+
+		sourcePath, err := filepath.Abs(filepath.Join(pkg.SourcePath, generated.fileName))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to resolve source path for %q", pkg.Path)
+		}
+
+		files = append(files, generatedFile{
+			Package:       pkg.Path,
+			GeneratedPath: generatedPath,
+			SourcePath:    sourcePath,
+		})
+	}
+	return files, nil
+}
+
+// writtenFiles lists all the Go files written under outputBase during the last run, which must
+// then be a temporary output base, see runInTempDir: as opposed to generatedFiles, that includes
+// e.g. equality, trace and facade files.
+func (c *Converter) writtenFiles(outputBase string) ([]generatedFile, error) {
+	var files []generatedFile
+	err := filepath.Walk(outputBase, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || filepath.Ext(path) != ".go" {
+			return err
+		}
+		relativeDir, err := filepath.Rel(outputBase, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		pkgPath := filepath.ToSlash(relativeDir)
+		sourceDir, ok := c.outputSourcePaths[pkgPath]
+		if !ok {
+			klog.Warningf("Ignoring %q, generated for unknown package %q", path, pkgPath)
+			return nil
+		}
+
+		sourcePath, err := filepath.Abs(filepath.Join(sourceDir, info.Name()))
+		if err != nil {
+			return errors.Wrapf(err, "unable to resolve source path for %q", pkgPath)
+		}
+		files = append(files, generatedFile{
+			Package:       pkgPath,
+			GeneratedPath: path,
+			SourcePath:    sourcePath,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list written files")
+	}
+	return files, nil
+}
+
+func writeJSONFile(path string, content interface{}) error {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+func runGoCommand(command, overlayPath string, packagePaths []string) error {
+	cmd := exec.Command("go", append([]string{command, "-overlay", overlayPath}, packagePaths...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	klog.V(2).Infof("Self-test: running %v", cmd.Args)
+	return cmd.Run()
+}
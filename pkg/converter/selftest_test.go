@@ -0,0 +1,56 @@
+package converter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSelfTestOverlayIncludesAllWrittenFiles(t *testing.T) {
+	options := DefaultOptions()
+	options.EqualityFunctions = true
+	options.GeneratorOptions.TraceBuildTag = "conversion_trace"
+	options.PackageFiles = PackageFiles{}
+	for _, pkg := range []string{"v1", "v2"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "equality", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			if files[i], err = filepath.Abs(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		options.PackageFiles["example.com/equality/"+pkg] = files
+	}
+
+	outputBase := t.TempDir()
+	converter := NewConverter([]string{"example.com/equality/v1"}, options)
+	converter.outputBaseOverride = outputBase
+	if err := converter.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	overlay, packagePaths, err := converter.selfTestOverlay(outputBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packagePaths) != 1 || packagePaths[0] != "example.com/equality/v1" {
+		t.Errorf("unexpected packages: %v", packagePaths)
+	}
+
+	sourceDir, err := filepath.Abs(filepath.Join("testdata", "equality", "v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range []string{
+		options.OutputFileBaseName + ".go",
+		options.OutputFileBaseName + TraceFileSuffix,
+		options.EqualityFileBaseName + ".go",
+	} {
+		sourcePath := filepath.Join(sourceDir, fileName)
+		expected := filepath.Join(outputBase, "example.com", "equality", "v1", fileName)
+		if generatedPath := overlay["Replace"][sourcePath]; generatedPath != expected {
+			t.Errorf("expected %q to be replaced with %q, got %q", sourcePath, expected, generatedPath)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package converter
+
+import (
+	"bytes"
+	"go/build"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// changedDirsSince returns the absolute paths of the directories with Go files that changed since
+// the given git ref, see Options.Since: committed, staged or not, as well as untracked ones.
+// Changes to generated files named outputFileName don't count, these being regenerated anyway.
+func changedDirsSince(ref, outputFileName string) (map[string]bool, error) {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	changed, err := git("diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range append(strings.Fields(changed), strings.Fields(untracked)...) {
+		name := filepath.Base(file)
+		if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") || name == outputFileName {
+			continue
+		}
+		dirs[filepath.Join(root, filepath.Dir(filepath.FromSlash(file)))] = true
+	}
+	klog.V(2).Infof("directories with changes since %s: %v", ref, dirs)
+	return dirs, nil
+}
+
+// git runs git with the given arguments, and returns its trimmed output.
+func git(arguments ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", arguments...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", strings.Join(arguments, " "), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// changedSince returns true iff pkg needs to be regenerated given the directories that changed
+// since Options.Since, if set: iff it or any of its peer packages changed, or any of the packages
+// that they import, transitively, as these declare the types of their types' fields, e.g. deciding
+// whether unsafe casts are possible. Packages whose directories are unknown are deemed to have
+// changed, unless only imported.
+func (c *Converter) changedSince(context *gengogenerator.Context, pkg *types.Package, peerPackages []string) bool {
+	if c.changedDirs == nil {
+		return true
+	}
+
+	roots := append([]string{pkg.Path}, peerPackages...)
+	visited := make(map[string]bool)
+	for _, pkgPath := range roots {
+		visited[pkgPath] = true
+		if changed, known := c.dirChanged(context, pkgPath); changed || !known {
+			return true
+		}
+	}
+
+	var importsChanged func(importPaths []string, srcDir string) bool
+	importsChanged = func(importPaths []string, srcDir string) bool {
+		for _, importPath := range importPaths {
+			if visited[importPath] {
+				continue
+			}
+			visited[importPath] = true
+
+			imported := c.importedPackage(importPath, srcDir)
+			if imported == nil || imported.Goroot {
+				continue
+			}
+			if c.changedDirs[absDir(imported.Dir)] {
+				klog.V(5).Infof("package %q changed since %s, and is imported by %q or its peer packages", importPath, c.Options.Since, pkg.Path)
+				return true
+			}
+			if importsChanged(imported.Imports, imported.Dir) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, pkgPath := range roots {
+		if root := context.Universe[pkgPath]; root != nil {
+			importPaths := make([]string, 0, len(root.Imports))
+			for importPath := range root.Imports {
+				importPaths = append(importPaths, importPath)
+			}
+			if importsChanged(importPaths, root.SourcePath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importedPackage returns the package with the given import path, as imported from srcDir;
+// or nil if it can't be found. Imported packages only have the types that the packages
+// importing them use in the universe, not their directories nor imports.
+func (c *Converter) importedPackage(importPath, srcDir string) *build.Package {
+	if imported, cached := c.importedPackages[importPath]; cached {
+		return imported
+	}
+	imported, err := build.Import(importPath, srcDir, 0)
+	if err != nil {
+		klog.V(5).Infof("unable to find package %q imported from %q, deeming it unchanged: %v", importPath, srcDir, err)
+		imported = nil
+	}
+	c.importedPackages[importPath] = imported
+	return imported
+}
+
+// dirChanged returns whether the directory of the given package changed since Options.Since, and
+// whether it's known at all.
+func (c *Converter) dirChanged(context *gengogenerator.Context, pkgPath string) (changed bool, known bool) {
+	pkg := context.Universe[pkgPath]
+	if pkg == nil || pkg.SourcePath == "" {
+		return false, false
+	}
+	dir := absDir(pkg.SourcePath)
+	return dir != "" && c.changedDirs[dir], dir != ""
+}
+
+// absDir returns the absolute path of dir, symlinks resolved; or an empty string if that fails.
+func absDir(dir string) string {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err == nil {
+		dir, err = filepath.Abs(dir)
+	}
+	if err != nil {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// packageSize is the size of the code generated for a package.
+type packageSize struct {
+	Package   string
+	Lines     int
+	Functions int
+}
+
+// checkSizes reports the size of the code generated for each package, and checks it against
+// the budget, if so configured; see Options.SizeReport and Options.SizeBudget.
+func (c *Converter) checkSizes() error {
+	if (!c.Options.SizeReport && c.Options.SizeBudget <= 0) || c.args.VerifyOnly {
+		return nil
+	}
+
+	files, err := c.generatedFiles(c.outputBase)
+	if err != nil {
+		return err
+	}
+
+	sizes := make([]packageSize, 0, len(files))
+	for _, file := range files {
+		size, err := generatedFileSize(file)
+		if err != nil {
+			return err
+		}
+		sizes = append(sizes, size)
+	}
+
+	if c.Options.SizeReport {
+		if err := writeSizeReport(sizes); err != nil {
+			return err
+		}
+	}
+
+	if c.Options.SizeBudget > 0 {
+		var overBudget []string
+		for _, size := range sizes {
+			if size.Lines > c.Options.SizeBudget {
+				message := fmt.Sprintf("%s: %d generated lines, over the budget of %d", size.Package, size.Lines, c.Options.SizeBudget)
+				klog.Warning(message)
+				overBudget = append(overBudget, message)
+			}
+		}
+		if c.Options.StrictSizeBudget && len(overBudget) != 0 {
+			return fmt.Errorf("%d package(s) over the generated code size budget", len(overBudget))
+		}
+	}
+	return nil
+}
+
+func generatedFileSize(file generatedFile) (packageSize, error) {
+	content, err := ioutil.ReadFile(file.GeneratedPath)
+	if err != nil {
+		return packageSize{}, errors.Wrapf(err, "unable to read generated file %q", file.GeneratedPath)
+	}
+	parsed, err := parser.ParseFile(token.NewFileSet(), file.GeneratedPath, content, 0)
+	if err != nil {
+		return packageSize{}, errors.Wrapf(err, "unable to parse generated file %q", file.GeneratedPath)
+	}
+
+	functions := 0
+	for _, decl := range parsed.Decls {
+		if _, ok := decl.(*ast.FuncDecl); ok {
+			functions++
+		}
+	}
+	return packageSize{
+		Package:   file.Package,
+		Lines:     bytes.Count(content, []byte("\n")),
+		Functions: functions,
+	}, nil
+}
+
+func writeSizeReport(sizes []packageSize) error {
+	buffer := &bytes.Buffer{}
+	totalLines, totalFunctions := 0, 0
+	for _, size := range sizes {
+		fmt.Fprintf(buffer, "%s: %d lines, %d functions\n", size.Package, size.Lines, size.Functions)
+		totalLines += size.Lines
+		totalFunctions += size.Functions
+	}
+	fmt.Fprintf(buffer, "total: %d lines, %d functions, in %d package(s)\n", totalLines, totalFunctions, len(sizes))
+
+	_, err := os.Stdout.Write(buffer.Bytes())
+	return err
+}
@@ -0,0 +1,193 @@
+package converter
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/klog/v2"
+
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+)
+
+// a spliceTarget is an existing file that generated code gets spliced into.
+type spliceTarget struct {
+	// source is the existing file, with splice markers.
+	source string
+	// destination is where the resulting file gets written to; it only differs from source
+	// when generating into a different output base, see runInTempDir.
+	destination string
+}
+
+// a splicingFileType assembles Go files just like gengo's default Go file type, or the AST
+// backend's, except for those whose content needs to be spliced into existing files, see
+// Options.SpliceFileBaseName.
+type splicingFileType struct {
+	gengogenerator.FileType
+	// render returns the content of generated files, as the file type would write them.
+	render func(f *gengogenerator.File) ([]byte, error)
+	// targets maps the paths that files would be generated to, to the files to splice them into.
+	targets map[string]spliceTarget
+}
+
+// a renderingFileType is a file type that can also return the content of the files it
+// assembles, such as those of non-default backends.
+type renderingFileType interface {
+	gengogenerator.FileType
+	render(f *gengogenerator.File) ([]byte, error)
+}
+
+// newSplicingFileType returns a splicing file type wrapping fileType if not nil, or else
+// gengo's default Go file type.
+func newSplicingFileType(fileType renderingFileType) *splicingFileType {
+	if fileType != nil {
+		return &splicingFileType{
+			FileType: fileType,
+			render:   fileType.render,
+			targets:  make(map[string]spliceTarget),
+		}
+	}
+
+	defaultFileType := gengogenerator.NewGolangFile()
+	return &splicingFileType{
+		FileType: defaultFileType,
+		render: func(f *gengogenerator.File) ([]byte, error) {
+			buffer := &bytes.Buffer{}
+			errorTracker := gengogenerator.NewErrorTracker(buffer)
+			defaultFileType.Assemble(errorTracker, f)
+			if err := errorTracker.Error(); err != nil {
+				return nil, err
+			}
+			return defaultFileType.Format(buffer.Bytes())
+		},
+		targets: make(map[string]spliceTarget),
+	}
+}
+
+func (ft *splicingFileType) AssembleFile(f *gengogenerator.File, path string) error {
+	target, ok := ft.targets[path]
+	if !ok {
+		return ft.FileType.AssembleFile(f, path)
+	}
+
+	content, err := ft.splice(f, target)
+	if err != nil {
+		return err
+	}
+	klog.V(5).Infof("Splicing generated code into %q", target.destination)
+	return errors.Wrapf(ioutil.WriteFile(target.destination, content, 0644), "unable to write %q", target.destination)
+}
+
+func (ft *splicingFileType) VerifyFile(f *gengogenerator.File, path string) error {
+	target, ok := ft.targets[path]
+	if !ok {
+		return ft.FileType.VerifyFile(f, path)
+	}
+
+	content, err := ft.splice(f, target)
+	if err != nil {
+		return err
+	}
+	existing, err := ioutil.ReadFile(target.destination)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read %q for comparison", target.destination)
+	}
+	if !bytes.Equal(content, existing) {
+		return errors.Errorf("generated code spliced into %q differs", target.destination)
+	}
+	return nil
+}
+
+// splice returns the content of target's source file, with f's code spliced in.
+func (ft *splicingFileType) splice(f *gengogenerator.File, target spliceTarget) ([]byte, error) {
+	generated, err := ft.render(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to format code to splice into %q", target.source)
+	}
+
+	existing, err := ioutil.ReadFile(target.source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %q", target.source)
+	}
+	return spliceGeneratedCode(existing, generated, target.source)
+}
+
+// spliceGeneratedCode replaces whatever is between the splice markers in existing, the content
+// of the file at filename, with the declarations from generated, a complete Go file; and adds the
+// imports they need, while removing those no longer needed.
+func spliceGeneratedCode(existing, generated []byte, filename string) ([]byte, error) {
+	start, end, found, err := generator.FindSpliceRegion(existing)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid splice markers in %q", filename)
+	}
+	if !found {
+		return nil, errors.Errorf("%q has no %q and %q markers", filename, generator.SpliceBeginMarker, generator.SpliceEndMarker)
+	}
+
+	fileSet := token.NewFileSet()
+	generatedFile, err := parser.ParseFile(fileSet, "", generated, parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse code to splice into %q", filename)
+	}
+	// the declarations start after the package clause and the imports
+	declarationsStart := fileSet.Position(generatedFile.Name.End()).Offset
+	for _, decl := range generatedFile.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			declarationsStart = fileSet.Position(genDecl.End()).Offset
+		}
+	}
+
+	spliced := &bytes.Buffer{}
+	spliced.Write(existing[:start])
+	spliced.WriteString("\n")
+	spliced.Write(bytes.TrimSpace(generated[declarationsStart:]))
+	spliced.WriteString("\n\n")
+	spliced.Write(existing[end:])
+
+	splicedFile, err := parser.ParseFile(fileSet, filename, spliced.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %q once generated code is spliced in", filename)
+	}
+	for _, spec := range generatedFile.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid import %s", spec.Path.Value)
+		}
+		// gengo names all imports, even when it's not needed
+		name := ""
+		if spec.Name != nil && spec.Name.Name != path.Base(importPath) {
+			name = spec.Name.Name
+		}
+		astutil.AddNamedImport(fileSet, splicedFile, name, importPath)
+	}
+
+	formatted := &bytes.Buffer{}
+	if err := format.Node(formatted, fileSet, splicedFile); err != nil {
+		return nil, errors.Wrapf(err, "unable to format %q once generated code is spliced in", filename)
+	}
+	// removes imports that were only used by previously generated code
+	result, err := imports.Process(filename, formatted.Bytes(), nil)
+	return result, errors.Wrapf(err, "unable to process imports of %q once generated code is spliced in", filename)
+}
+
+// hasSpliceMarkers returns true iff the Go file at path exists, and has valid splice markers.
+func hasSpliceMarkers(path string) (bool, error) {
+	src, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "unable to read %q", path)
+	}
+	_, _, found, err := generator.FindSpliceRegion(src)
+	return found, errors.Wrapf(err, "invalid splice markers in %q", path)
+}
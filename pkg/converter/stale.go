@@ -0,0 +1,54 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/args"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// recordStaleFile records the file previously generated for pkg, if any, as stale: pkg gets
+// skipped, having nothing left to convert. See removeStaleFiles.
+func (c *Converter) recordStaleFile(arguments *args.GeneratorArgs, pkg *types.Package) {
+	outputPackage := c.outputPackageFor(pkg)
+	c.staleFiles[filepath.Join(arguments.OutputBase, outputPackage, arguments.OutputFileBaseName+".go")] = outputPackage
+}
+
+// removeStaleFiles removes the stale files recorded during the last run, that look generated
+// unless Options.Force is set; or fails if only verifying, as they're out of date.
+func (c *Converter) removeStaleFiles() error {
+	paths := make([]string, 0, len(c.staleFiles))
+	for path := range c.staleFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return errors.Wrapf(err, "unable to stat %q", path)
+		}
+		if c.args.VerifyOnly {
+			return errors.Errorf("%q is stale, its package has nothing left to convert", path)
+		}
+		if !c.Options.Force {
+			if generated, err := looksGenerated(path, c.args.GeneratedBuildTag); err != nil {
+				return err
+			} else if !generated {
+				klog.Warningf("Not removing stale %q, which doesn't look generated", path)
+				continue
+			}
+		}
+
+		klog.Infof("Removing stale %q, its package has nothing left to convert", path)
+		if err := os.Remove(path); err != nil {
+			return errors.Wrapf(err, "unable to remove %q", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaleFilesOfSkippedPackagesAreRemoved(t *testing.T) {
+	options := DefaultOptions()
+	options.PackageFiles = PackageFiles{}
+	for _, pkg := range []string{"v1", "v2"} {
+		files, err := filepath.Glob(filepath.Join("testdata", "stale", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, file := range files {
+			if files[i], err = filepath.Abs(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		options.PackageFiles["example.com/stale/"+pkg] = files
+	}
+
+	outputBase := t.TempDir()
+	stale := filepath.Join(outputBase, "example.com", "stale", "v1", options.OutputFileBaseName+".go")
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(stale, []byte("// Code generated by conversion-gen. DO NOT EDIT.\n\npackage v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	converter := NewConverter([]string{"example.com/stale/v1"}, options)
+	converter.outputBaseOverride = outputBase
+
+	if err := converter.Verify(); err == nil {
+		t.Errorf("expected verification to fail, as %q is stale", stale)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("expected verification to leave %q alone: %v", stale, err)
+	}
+
+	if err := converter.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected %q to be removed, got %v", stale, err)
+	}
+}
@@ -0,0 +1,262 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/imports"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/klog/v2"
+)
+
+// a streamingFileType assembles Go files for BackendStreaming.
+type streamingFileType struct{}
+
+func newStreamingFileType() *streamingFileType {
+	return &streamingFileType{}
+}
+
+func (ft *streamingFileType) AssembleFile(f *gengogenerator.File, path string) error {
+	klog.V(5).Infof("Assembling file %q", path)
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create %q", path)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := ft.write(writer, f); err != nil {
+		// still flush what was written, to help debugging
+		writer.Flush()
+		return errors.Wrapf(err, "unable to assemble %q", path)
+	}
+	return errors.Wrapf(writer.Flush(), "unable to write %q", path)
+}
+
+// render returns the content of f, as AssembleFile would write it.
+func (ft *streamingFileType) render(f *gengogenerator.File) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	err := ft.write(buffer, f)
+	return buffer.Bytes(), err
+}
+
+func (ft *streamingFileType) VerifyFile(f *gengogenerator.File, path string) error {
+	klog.V(5).Infof("Verifying file %q", path)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return errors.Errorf("%q does not exist", filepath.Join(f.PackageName, f.Name))
+	} else if err != nil {
+		return errors.Wrapf(err, "unable to read %q for comparison", path)
+	}
+	defer file.Close()
+
+	comparer := &comparingWriter{existing: bufio.NewReader(file)}
+	if err := ft.write(comparer, f); err != nil {
+		return errors.Wrapf(err, "unable to assemble %q", path)
+	}
+	if !comparer.equal() {
+		return errors.Errorf("output for %q differs", filepath.Join(f.PackageName, f.Name))
+	}
+	return nil
+}
+
+// write writes f to w, one top-level declaration at a time: so that, unlike with the other
+// backends, the whole file never gets parsed nor formatted at once. Its generated body is still
+// held in memory in full, by gengo.
+// Imports are grouped the same way as with BackendSnippets, and those that declarations don't
+// use are left out.
+func (ft *streamingFileType) write(w io.Writer, f *gengogenerator.File) error {
+	var declarations [][]byte
+	if f.Vars.Len() != 0 {
+		declarations = append(declarations, []byte("var (\n"+f.Vars.String()+")\n"))
+	}
+	if f.Consts.Len() != 0 {
+		declarations = append(declarations, []byte("const (\n"+f.Consts.String()+")\n"))
+	}
+
+	// first pass, to split the body, and to find which imports are used
+	usedNames := make(map[string]bool)
+	for _, chunk := range declarations {
+		if err := parseDeclarations(chunk, usedNames); err != nil {
+			return err
+		}
+	}
+	chunks, err := splitDeclarations(f.Body.Bytes(), usedNames)
+	if err != nil {
+		return err
+	}
+	declarations = append(declarations, chunks...)
+
+	preamble, err := formattedPreamble(f, usedNames)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(preamble); err != nil {
+		return err
+	}
+
+	// second pass, to write the declarations
+	for _, chunk := range declarations {
+		formatted, err := format.Source(chunk)
+		if err != nil {
+			return errors.Wrap(err, "generated code is not valid Go")
+		}
+		if formatted = bytes.Trim(formatted, "\n"); len(formatted) == 0 {
+			continue
+		}
+		if _, err := w.Write([]byte("\n")); err != nil {
+			return err
+		}
+		if _, err := w.Write(append(formatted, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitDeclarations splits body into chunks of complete top-level declarations, along with the
+// comments preceding them; and adds the names of the packages they refer to to usedNames, see
+// parseDeclarations. Chunks are slices of body.
+func splitDeclarations(body []byte, usedNames map[string]bool) ([][]byte, error) {
+	var chunks [][]byte
+	start := 0
+	for offset := 0; offset < len(body); {
+		end := bytes.IndexByte(body[offset:], '\n')
+		if end == -1 {
+			end = len(body)
+		} else {
+			end += offset + 1
+		}
+		line := bytes.TrimRight(body[offset:end], " \t\r\n")
+		offset = end
+
+		// a closing brace or parenthesis at the beginning of a line ends a top-level declaration,
+		// as generated code is indented; unless it's in a multi-line literal, in which case the
+		// chunk doesn't parse, and gets extended to the next such line
+		if len(line) != 1 || (line[0] != '}' && line[0] != ')') {
+			continue
+		}
+		if parseDeclarations(body[start:end], usedNames) == nil {
+			chunks = append(chunks, body[start:end])
+			start = end
+		}
+	}
+	if start < len(body) {
+		if err := parseDeclarations(body[start:], usedNames); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, body[start:])
+	}
+	return chunks, nil
+}
+
+// parseDeclarations parses declarations, and adds to names the names of the packages they refer
+// to, or might refer to: the unresolved identifiers that selectors are applied to.
+func parseDeclarations(declarations []byte, names map[string]bool) error {
+	file, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), declarations...), 0)
+	if err != nil {
+		return errors.Wrap(err, "generated code is not valid Go")
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+				names[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+// formattedPreamble returns f's header, package clause and imports, formatted; leaving out the
+// imports whose names aren't in usedNames, as long as they're explicitly named.
+func formattedPreamble(f *gengogenerator.File, usedNames map[string]bool) ([]byte, error) {
+	var std, external, local []string
+	for _, importLine := range sortedImportLines(f.Imports) {
+		path := importLinePath(importLine)
+		if path != importLine {
+			if name := strings.TrimSpace(strings.TrimSuffix(importLine, path)); name != "_" && name != "." && !usedNames[name] {
+				continue
+			}
+		}
+		if !strings.HasPrefix(path, `"`) {
+			importLine = strings.TrimSuffix(importLine, path) + `"` + path + `"`
+		}
+
+		unquotedPath := strings.Trim(path, `"`)
+		switch {
+		case isLocalImport(unquotedPath):
+			local = append(local, importLine)
+		case !strings.Contains(strings.SplitN(unquotedPath, "/", 2)[0], "."):
+			std = append(std, importLine)
+		default:
+			external = append(external, importLine)
+		}
+	}
+
+	preamble := bytes.NewBuffer(append([]byte{}, f.Header...))
+	preamble.WriteString("package " + f.PackageName + "\n")
+	if len(std)+len(external)+len(local) != 0 {
+		preamble.WriteString("\nimport (\n")
+		separator := ""
+		for _, group := range [][]string{std, external, local} {
+			if len(group) == 0 {
+				continue
+			}
+			preamble.WriteString(separator)
+			for _, importLine := range group {
+				preamble.WriteString("\t" + importLine + "\n")
+			}
+			separator = "\n"
+		}
+		preamble.WriteString(")\n")
+	}
+
+	formatted, err := format.Source(preamble.Bytes())
+	return formatted, errors.Wrap(err, "unable to format the file's preamble")
+}
+
+// isLocalImport returns true iff path is grouped with local imports, see
+// generator.Options.LocalImportPrefix.
+func isLocalImport(path string) bool {
+	for _, prefix := range strings.Split(imports.LocalPrefix, ",") {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// a comparingWriter compares what gets written to it with existing content.
+type comparingWriter struct {
+	existing *bufio.Reader
+	differs  bool
+}
+
+func (w *comparingWriter) Write(p []byte) (int, error) {
+	if !w.differs {
+		expected := make([]byte, len(p))
+		if n, _ := io.ReadFull(w.existing, expected); n != len(p) || !bytes.Equal(expected, p) {
+			w.differs = true
+		}
+	}
+	return len(p), nil
+}
+
+// equal returns true iff everything written so far matches the existing content, in full.
+func (w *comparingWriter) equal() bool {
+	if w.differs {
+		return false
+	}
+	_, err := w.existing.ReadByte()
+	return err == io.EOF
+}
@@ -0,0 +1,15 @@
+package v1
+
+import (
+	v2 "example.com/cache/v2"
+)
+
+func Convert_v1_Bar_To_v2_Bar(in *Bar, out *v2.Bar) error {
+	out.FullName = in.Name
+	return nil
+}
+
+func Convert_v2_Bar_To_v1_Bar(in *v2.Bar, out *Bar) error {
+	out.Name = in.FullName
+	return nil
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/cache/v2
+
+package v1
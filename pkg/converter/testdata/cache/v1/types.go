@@ -0,0 +1,10 @@
+package v1
+
+type Foo struct {
+	A   string
+	Bar Bar
+}
+
+type Bar struct {
+	Name string
+}
@@ -0,0 +1,10 @@
+package v2
+
+type Foo struct {
+	A   string
+	Bar Bar
+}
+
+type Bar struct {
+	FullName string
+}
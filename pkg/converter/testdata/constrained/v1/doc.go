@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/constrained/v2
+
+package v1
@@ -0,0 +1,5 @@
+package v1
+
+type Foo struct {
+	Name string
+}
@@ -0,0 +1,9 @@
+//go:build special
+// +build special
+
+package v1
+
+// Bar only exists with the special build tag.
+type Bar struct {
+	Name string
+}
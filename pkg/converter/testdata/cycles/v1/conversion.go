@@ -0,0 +1,8 @@
+package v1
+
+import "example.com/cycles/v2"
+
+// Convert_v1_Foo_To_v2_Foo calls itself on its own input, and never returns.
+func Convert_v1_Foo_To_v2_Foo(in *Foo, out *v2.Foo) error {
+	return Convert_v1_Foo_To_v2_Foo(in, out)
+}
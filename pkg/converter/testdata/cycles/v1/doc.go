@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/cycles/v2
+
+package v1
@@ -0,0 +1,9 @@
+package v2
+
+type Foo struct {
+	Name string
+}
+
+type Bar struct {
+	Foo Foo
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/dedup/v2
+
+package v1
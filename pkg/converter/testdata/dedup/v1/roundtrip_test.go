@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/dedup/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Job{
+		Containers: []Container{{Image: "a", Port: 80}},
+		Ports:      map[string]int32{"http": 80},
+		Primary:    &Container{Image: "b", Port: 443},
+	}
+
+	out := &v2.Job{}
+	if err := Convert_v1_Job_To_v2_Job(in, out); err != nil {
+		t.Fatal(err)
+	}
+	back := &Job{}
+	if err := Convert_v2_Job_To_v1_Job(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+
+	deployment := &v2.Deployment{}
+	if err := Convert_v1_Deployment_To_v2_Deployment(&Deployment{InitContainers: []Container{{Port: 1}}}, deployment); err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Containers != nil || len(deployment.InitContainers) != 1 || deployment.InitContainers[0].Port != 1 {
+		t.Errorf("unexpected conversion: %+v", deployment)
+	}
+}
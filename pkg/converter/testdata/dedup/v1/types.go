@@ -0,0 +1,18 @@
+package v1
+
+type Deployment struct {
+	Containers     []Container
+	InitContainers []Container
+	Ports          map[string]int32
+}
+
+type Job struct {
+	Containers []Container
+	Ports      map[string]int32
+	Primary    *Container
+}
+
+type Container struct {
+	Image string
+	Port  int32
+}
@@ -0,0 +1,18 @@
+package v2
+
+type Deployment struct {
+	Containers     []Container
+	InitContainers []Container
+	Ports          map[string]int64
+}
+
+type Job struct {
+	Containers []Container
+	Ports      map[string]int64
+	Primary    *Container
+}
+
+type Container struct {
+	Image string
+	Port  int64
+}
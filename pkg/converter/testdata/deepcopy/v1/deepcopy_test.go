@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/deepcopy/v2"
+)
+
+func newOuter() *Outer {
+	return &Outer{
+		Ptrs:   []*Inner{{S: []string{"a"}, M: map[string]string{"a": "b"}}, nil},
+		Val:    Inner{S: []string{"b"}, M: map[string]string{"c": "d"}},
+		P:      &Inner{S: []string{"c"}, M: map[string]string{"e": "f"}},
+		MM:     map[string][]string{"a": {"d"}},
+		Nodes:  []Node{{Name: "a", Children: []Node{{Name: "b", Children: []Node{{Name: "c"}}}}}},
+		Shared: []Inner{{S: []string{"e"}}},
+	}
+}
+
+func TestDeepCopiesShareNoMemory(t *testing.T) {
+	in := newOuter()
+	out := &v2.Outer{}
+	if err := Convert_v1_Outer_To_v2_Outer(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	in.Ptrs[0].S[0] = "changed"
+	in.Ptrs[0].M["a"] = "changed"
+	in.Val.S[0] = "changed"
+	in.Val.M["c"] = "changed"
+	in.P.S[0] = "changed"
+	in.P.M["e"] = "changed"
+	in.MM["a"][0] = "changed"
+	in.Nodes[0].Children[0].Children[0].Name = "changed"
+	in.Shared[0].S[0] = "changed"
+
+	back := &Outer{}
+	if err := Convert_v2_Outer_To_v1_Outer(out, back); err != nil {
+		t.Fatal(err)
+	}
+	expected := newOuter()
+	// only the field without a deep-copy tag is shared
+	expected.Shared[0].S[0] = "changed"
+	if !reflect.DeepEqual(expected, back) {
+		t.Errorf("conversion shares memory with its input, expected\n%+v\ngot\n%+v", expected, back)
+	}
+}
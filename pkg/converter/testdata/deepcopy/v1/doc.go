@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/deepcopy/v2
+
+package v1
@@ -0,0 +1,30 @@
+package v2
+
+// Outer's fields get deep-copied: in and out share no memory, at any depth.
+type Outer struct {
+	// +conversion-gen=deep-copy
+	Ptrs []*Inner
+	// +conversion-gen=deep-copy
+	Val Inner
+	// +conversion-gen=deep-copy
+	P *Inner
+	// +conversion-gen=deep-copy
+	MM map[string][]string
+	// +conversion-gen=deep-copy
+	Nodes []Node
+
+	// Shared doesn't, and gets cast.
+	Shared []Inner
+}
+
+// Inner has the same memory layout in both versions.
+type Inner struct {
+	S []string
+	M map[string]string
+}
+
+// Node is recursive.
+type Node struct {
+	Name     string
+	Children []Node
+}
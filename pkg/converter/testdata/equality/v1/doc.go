@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/equality/v2
+
+package v1
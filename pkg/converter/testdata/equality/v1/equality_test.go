@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"testing"
+
+	v2 "example.com/equality/v2"
+)
+
+func TestEquality(t *testing.T) {
+	in := &Foo{
+		Same:       "same",
+		Cast:       1,
+		Seconds:    2,
+		Millis:     3500,
+		Old:        "old",
+		Upper:      "upper",
+		Ignored:    "ignored",
+		Bar:        Bar{A: 4},
+		Bars:       []Bar{{A: 5}},
+		BarsByName: map[string]*Bar{"a": {A: 6}, "b": nil},
+	}
+	convert := func() *v2.Foo {
+		out := &v2.Foo{}
+		if err := Convert_v1_Foo_To_v2_Foo(in, out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := convert()
+	if !Equal_v1_Foo_v2_Foo(in, out) {
+		t.Errorf("expected %+v to equal its conversion %+v", in, out)
+	}
+	roundTripped := &Foo{}
+	if err := Convert_v2_Foo_To_v1_Foo(out, roundTripped); err != nil {
+		t.Fatal(err)
+	}
+	if !Equal_v1_Foo_v2_Foo(roundTripped, out) {
+		t.Errorf("expected %+v to equal its round trip %+v", out, roundTripped)
+	}
+
+	for field, change := range map[string]func(out *v2.Foo){
+		"Same":       func(out *v2.Foo) { out.Same = "other" },
+		"Cast":       func(out *v2.Foo) { out.Cast++ },
+		"Seconds":    func(out *v2.Foo) { out.Seconds++ },
+		"Millis":     func(out *v2.Foo) { out.Millis++ },
+		"New":        func(out *v2.Foo) { out.New = "other" },
+		"Bar":        func(out *v2.Foo) { out.Bar.A++ },
+		"Bars":       func(out *v2.Foo) { out.Bars = append(out.Bars, v2.Bar{}) },
+		"BarsByName": func(out *v2.Foo) { out.BarsByName["a"].A++ },
+	} {
+		out := convert()
+		change(out)
+		if Equal_v1_Foo_v2_Foo(in, out) {
+			t.Errorf("expected a change of %s to make a difference", field)
+		}
+	}
+
+	// transformed by an expression, and opted out
+	out = convert()
+	out.Upper, out.Ignored = "other", "other"
+	if !Equal_v1_Foo_v2_Foo(in, out) {
+		t.Errorf("expected Upper and Ignored not to be compared")
+	}
+}
@@ -0,0 +1,22 @@
+package v1
+
+// Foo has fields converted in all sorts of ways, see ../mappings.yaml for Old.
+type Foo struct {
+	Same string
+	Cast int32
+	// +conversion-gen=scale:1000
+	Seconds int32
+	Millis  int64
+	Old     string
+	Upper   string
+	// +conversion-gen=false
+	Ignored    string
+	Bar        Bar
+	Bars       []Bar
+	BarsByName map[string]*Bar
+}
+
+// Bar's field is an int64 in v2.
+type Bar struct {
+	A int32
+}
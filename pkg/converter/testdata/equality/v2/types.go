@@ -0,0 +1,22 @@
+package v2
+
+// Foo has fields converted in all sorts of ways, see ../mappings.yaml for New.
+type Foo struct {
+	Same    string
+	Cast    int64
+	Seconds int64
+	// +conversion-gen=scale:1000
+	Millis int32
+	New    string
+	// +conversion-gen=expr:strings.ToUpper($in$)
+	Upper      string
+	Ignored    string
+	Bar        Bar
+	Bars       []Bar
+	BarsByName map[string]*Bar
+}
+
+// Bar's field is an int32 in v1.
+type Bar struct {
+	A int64
+}
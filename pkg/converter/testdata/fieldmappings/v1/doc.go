@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/fieldmappings/v2
+
+package v1
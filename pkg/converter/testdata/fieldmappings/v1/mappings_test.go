@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/fieldmappings/v2"
+)
+
+func TestFieldMappings(t *testing.T) {
+	in := &Foo{SpecName: "name", Legacy: "old", Title: "title", Count: 2, Bars: []Bar{{A: 3}}}
+
+	out := &v2.Foo{}
+	if err := Convert_v1_Foo_To_v2_Foo(in, out); err != nil {
+		t.Fatal(err)
+	}
+	expected := &v2.Foo{Name: "name", Kind: "Foo", Label: "TITLE", Total: 2, Items: []v2.Bar{{A: 3}}}
+	if !reflect.DeepEqual(expected, out) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	back := &Foo{}
+	if err := Convert_v2_Foo_To_v1_Foo(out, back); err != nil {
+		t.Fatal(err)
+	}
+	// Legacy was dropped, and gets its mapped value back
+	if expected := (&Foo{SpecName: "name", Legacy: "legacy", Title: "title", Count: 2, Bars: []Bar{{A: 3}}}); !reflect.DeepEqual(expected, back) {
+		t.Errorf("expected %+v, got %+v", expected, back)
+	}
+}
@@ -0,0 +1,14 @@
+package v1
+
+// Foo's fields got renamed, dropped or transformed in v2, see ../mappings.yaml.
+type Foo struct {
+	SpecName string
+	Legacy   string
+	Title    string
+	Count    int32
+	Bars     []Bar
+}
+
+type Bar struct {
+	A int32
+}
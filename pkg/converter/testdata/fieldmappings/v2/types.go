@@ -0,0 +1,13 @@
+package v2
+
+type Foo struct {
+	Name  string
+	Kind  string
+	Label string
+	Total int64
+	Items []Bar
+}
+
+type Bar struct {
+	A int64
+}
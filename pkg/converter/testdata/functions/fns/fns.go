@@ -0,0 +1,17 @@
+// Package fns has the functions that function options refer to when in another package than the
+// output one.
+package fns
+
+import "time"
+
+func Register(in, out interface{}, convert func(in, out interface{}) error) {}
+
+func Wrap(err error, field string) error { return err }
+
+func Observe(from, to string, err error, d time.Duration) {}
+
+func DeepCopy(in interface{}) interface{} { return in }
+
+func ConvertInterface(in, out interface{}) error { return nil }
+
+func Adapt(in, out interface{}) error { return nil }
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/functions/v2
+
+package v1
@@ -0,0 +1,17 @@
+package v1
+
+import "time"
+
+// The functions that function options refer to when local to the output package, see ../fns.
+
+func Register(in, out interface{}, convert func(in, out interface{}) error) {}
+
+func Wrap(err error, field string) error { return err }
+
+func Observe(from, to string, err error, d time.Duration) {}
+
+func DeepCopy(in interface{}) interface{} { return in }
+
+func ConvertInterface(in, out interface{}) error { return nil }
+
+func Adapt(in, out interface{}) error { return nil }
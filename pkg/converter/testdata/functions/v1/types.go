@@ -0,0 +1,25 @@
+package v1
+
+import "io"
+
+// Foo's Bar needs converting.
+type Foo struct {
+	Name string
+	Bar  Bar
+}
+
+// Bar's field is an int64 in v2.
+type Bar struct {
+	A int32
+}
+
+// Values holds dynamic values.
+type Values struct {
+	Any    interface{}
+	Config map[string]interface{}
+}
+
+// Streams' reader is an io.ReadCloser in v2.
+type Streams struct {
+	Reader io.Reader
+}
@@ -0,0 +1,25 @@
+package v2
+
+import "io"
+
+// Foo's Bar needs converting.
+type Foo struct {
+	Name string
+	Bar  Bar
+}
+
+// Bar's field is an int32 in v1.
+type Bar struct {
+	A int64
+}
+
+// Values holds dynamic values.
+type Values struct {
+	Any    interface{}
+	Config map[string]interface{}
+}
+
+// Streams' reader is an io.Reader in v1.
+type Streams struct {
+	Reader io.ReadCloser
+}
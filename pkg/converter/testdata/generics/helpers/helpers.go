@@ -0,0 +1,37 @@
+// Package helpers provides the generic helpers that generated code calls with
+// GenericHelpersPackage set; they count calls, so that tests can check they're used.
+package helpers
+
+var Calls int
+
+func ConvertSlice[In, Out any](in []In, out *[]Out, convert func(*In, *Out) error) error {
+	Calls++
+	if in == nil {
+		*out = nil
+		return nil
+	}
+	*out = make([]Out, len(in))
+	for i := range in {
+		if err := convert(&in[i], &(*out)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ConvertMap[K comparable, In, Out any](in map[K]In, out *map[K]Out, convert func(*In, *Out) error) error {
+	Calls++
+	if in == nil {
+		*out = nil
+		return nil
+	}
+	*out = make(map[K]Out, len(in))
+	for key, val := range in {
+		var converted Out
+		if err := convert(&val, &converted); err != nil {
+			return err
+		}
+		(*out)[key] = converted
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/generics/v2
+
+package v1
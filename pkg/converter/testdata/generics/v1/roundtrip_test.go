@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/generics/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Team{
+		Members:  []Member{{Name: "a", Age: 1}},
+		ByName:   map[string]Member{"b": {Name: "b", Age: 2}},
+		Lead:     &Member{Name: "c", Age: 3},
+		Tags:     []string{"x"},
+		Roster:   Roster{{Name: "d", Age: 4}},
+		Counters: map[string]int32{"e": 5},
+	}
+
+	out := &v2.Team{}
+	if err := Convert_v1_Team_To_v2_Team(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Members[0].Age != 1 || out.ByName["b"].Age != 2 || out.Roster[0].Age != 4 {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Team{}
+	if err := Convert_v2_Team_To_v1_Team(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+
+	// nil stays nil
+	out = &v2.Team{Members: []v2.Member{}, ByName: map[string]v2.Member{}}
+	if err := Convert_v1_Team_To_v2_Team(&Team{}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Members != nil || out.ByName != nil {
+		t.Errorf("expected nil slices and maps, got %+v", out)
+	}
+}
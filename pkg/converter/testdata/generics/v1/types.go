@@ -0,0 +1,18 @@
+package v1
+
+type Team struct {
+	Members  []Member
+	ByName   map[string]Member
+	Lead     *Member
+	Tags     []string
+	Roster   Roster
+	Counters map[string]int32
+}
+
+type Member struct {
+	Name string
+	Age  int32
+}
+
+// Roster is a named slice.
+type Roster []Member
@@ -0,0 +1,17 @@
+package v2
+
+type Team struct {
+	Members  []Member
+	ByName   map[string]Member
+	Lead     *Member
+	Tags     []string
+	Roster   Roster
+	Counters map[string]int64
+}
+
+type Member struct {
+	Name string
+	Age  int64
+}
+
+type Roster []Member
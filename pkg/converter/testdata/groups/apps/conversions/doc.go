@@ -0,0 +1,2 @@
+// Package conversions gets the conversions of the apps group.
+package conversions
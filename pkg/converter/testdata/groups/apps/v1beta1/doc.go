@@ -0,0 +1,4 @@
+// +conversion-gen=example.com/groups/apps/v2
+// +conversion-gen-reexport=example.com/groups/facade
+
+package v1beta1
@@ -0,0 +1,5 @@
+package v1beta1
+
+type Foo struct {
+	A int32
+}
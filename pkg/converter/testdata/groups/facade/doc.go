@@ -0,0 +1,2 @@
+// Package facade re-exports the conversions of the apps group.
+package facade
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/majorversion/api/v2
+
+package v1
@@ -0,0 +1,11 @@
+package v2
+
+import ext "example.com/majorversion/ext/v2"
+
+type Bar struct {
+	Direct ext.Foo
+	Ptr    *ext.Foo
+	Slice  []ext.Foo
+	Map    map[string]*ext.Foo
+	Nested map[string][]*ext.Foo
+}
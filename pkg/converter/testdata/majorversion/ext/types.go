@@ -0,0 +1,6 @@
+package ext
+
+type Foo struct {
+	A int
+	B string
+}
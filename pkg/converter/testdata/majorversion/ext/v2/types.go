@@ -0,0 +1,6 @@
+package v2
+
+type Foo struct {
+	A int
+	B string
+}
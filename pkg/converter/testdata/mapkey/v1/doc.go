@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/mapkey/v2
+
+package v1
@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/mapkey/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Cluster{Nodes: map[string]Node{
+		"b": {Address: "10.0.0.2"},
+		"a": {Address: "10.0.0.1"},
+	}}
+
+	out := &v2.Cluster{}
+	if err := Convert_v1_Cluster_To_v2_Cluster(in, out); err != nil {
+		t.Fatal(err)
+	}
+	// sorted by key
+	expected := []v2.Node{{Name: "a", Address: "10.0.0.1"}, {Name: "b", Address: "10.0.0.2"}}
+	if !reflect.DeepEqual(out.Nodes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out.Nodes)
+	}
+
+	back := &Cluster{}
+	if err := Convert_v2_Cluster_To_v1_Cluster(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+
+	// nil stays nil
+	out = &v2.Cluster{Nodes: []v2.Node{}}
+	if err := Convert_v1_Cluster_To_v2_Cluster(&Cluster{}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Nodes != nil {
+		t.Errorf("expected nil nodes, got %+v", out.Nodes)
+	}
+}
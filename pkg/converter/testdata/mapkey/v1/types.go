@@ -0,0 +1,10 @@
+package v1
+
+type Cluster struct {
+	// +conversion-gen=mapKey:Name
+	Nodes map[string]Node
+}
+
+type Node struct {
+	Address string
+}
@@ -0,0 +1,10 @@
+package v2
+
+type Cluster struct {
+	Nodes []Node
+}
+
+type Node struct {
+	Name    string
+	Address string
+}
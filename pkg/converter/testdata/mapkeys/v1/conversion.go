@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"fmt"
+
+	v2 "example.com/mapkeys/v2"
+)
+
+var colors = []Color{"red", "green", "blue"}
+
+func Convert_v1_Color_To_v2_Color(in *Color, out *v2.Color) error {
+	for i, color := range colors {
+		if color == *in {
+			*out = v2.Color(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color %q", *in)
+}
+
+func Convert_v2_Color_To_v1_Color(in *v2.Color, out *Color) error {
+	if *in < 0 || int(*in) >= len(colors) {
+		return fmt.Errorf("unknown color %d", *in)
+	}
+	*out = colors[*in]
+	return nil
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/mapkeys/v2
+
+package v1
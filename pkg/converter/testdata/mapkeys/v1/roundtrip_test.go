@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/mapkeys/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Inventory{
+		ByKey:   map[Key]string{{Name: "a", ID: 1}: "x", {Name: "b", ID: 2}: "y"},
+		ByColor: map[Color]int32{"green": 3, "blue": 4},
+	}
+
+	out := &v2.Inventory{}
+	if err := Convert_v1_Inventory_To_v2_Inventory(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ByKey[v2.Key{Name: "b", ID: 2}] != "y" || out.ByColor[1] != 3 || out.ByColor[2] != 4 {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Inventory{}
+	if err := Convert_v2_Inventory_To_v1_Inventory(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+}
+
+func TestKeyConversionErrorsAreReturned(t *testing.T) {
+	if err := Convert_v1_Inventory_To_v2_Inventory(&Inventory{ByColor: map[Color]int32{"purple": 1}}, &v2.Inventory{}); err == nil {
+		t.Error("expected converting an unknown color to fail")
+	}
+}
@@ -0,0 +1,15 @@
+package v1
+
+type Inventory struct {
+	ByKey   map[Key]string
+	ByColor map[Color]int32
+}
+
+// Key converts with a generated function.
+type Key struct {
+	Name string
+	ID   int32
+}
+
+// Color converts with a manual function, see conversion.go.
+type Color string
@@ -0,0 +1,13 @@
+package v2
+
+type Inventory struct {
+	ByKey   map[Key]string
+	ByColor map[Color]int32
+}
+
+type Key struct {
+	Name string
+	ID   int64
+}
+
+type Color int32
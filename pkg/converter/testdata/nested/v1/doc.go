@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/nested/v2
+
+package v1
@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/nested/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	leaf := &Leaf{A: 1}
+	slice := []Leaf{{A: 2}, {A: 3}}
+	in := &Containers{
+		MapOfSlicesOfPointers:   map[string][]*Leaf{"a": {{A: 4}, nil}, "b": nil},
+		SliceOfMaps:             []map[string]Leaf{{"a": {A: 5}}, nil},
+		PointerToSliceOfMaps:    &[]map[string]*Leaf{{"a": {A: 6}, "b": nil}},
+		SliceOfSlices:           [][]Leaf{{{A: 7}}, nil, {}},
+		MapOfMaps:               map[string]map[int]Leaf{"a": {1: {A: 8}}, "b": nil},
+		SliceOfPointersToSlices: []*[]Leaf{&slice, nil},
+		PointerToPointer:        &leaf,
+		MapOfSlicesOfBuiltins:   map[string][]int32{"a": {9, 10}},
+		SliceOfMapsOfSlices:     []map[string][]Leaf{{"a": {{A: 11}}}},
+	}
+
+	converted := &v2.Containers{}
+	if err := Convert_v1_Containers_To_v2_Containers(in, converted); err != nil {
+		t.Fatal(err)
+	}
+	if (*converted.PointerToPointer).A != 1 || converted.MapOfSlicesOfPointers["a"][0].A != 4 || converted.SliceOfMapsOfSlices[0]["a"][0].A != 11 {
+		t.Errorf("unexpected conversion: %+v", converted)
+	}
+
+	out := &Containers{}
+	if err := Convert_v2_Containers_To_v1_Containers(converted, out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip changed\n%+v\ninto\n%+v", in, out)
+	}
+	// conversions don't alias their inputs
+	(*in.SliceOfPointersToSlices[0])[0].A = 12
+	if (*out.SliceOfPointersToSlices[0])[0].A != 2 {
+		t.Errorf("conversion aliases its input")
+	}
+}
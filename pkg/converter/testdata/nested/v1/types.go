@@ -0,0 +1,19 @@
+package v1
+
+// Containers has containers nested two levels deep or more, of leaves that need converting.
+type Containers struct {
+	MapOfSlicesOfPointers   map[string][]*Leaf
+	SliceOfMaps             []map[string]Leaf
+	PointerToSliceOfMaps    *[]map[string]*Leaf
+	SliceOfSlices           [][]Leaf
+	MapOfMaps               map[string]map[int]Leaf
+	SliceOfPointersToSlices []*[]Leaf
+	PointerToPointer        **Leaf
+	MapOfSlicesOfBuiltins   map[string][]int32
+	SliceOfMapsOfSlices     []map[string][]Leaf
+}
+
+// Leaf's field is an int64 in v2.
+type Leaf struct {
+	A int32
+}
@@ -0,0 +1,19 @@
+package v2
+
+// Containers has containers nested two levels deep or more, of leaves that need converting.
+type Containers struct {
+	MapOfSlicesOfPointers   map[string][]*Leaf
+	SliceOfMaps             []map[string]Leaf
+	PointerToSliceOfMaps    *[]map[string]*Leaf
+	SliceOfSlices           [][]Leaf
+	MapOfMaps               map[string]map[int]Leaf
+	SliceOfPointersToSlices []*[]Leaf
+	PointerToPointer        **Leaf
+	MapOfSlicesOfBuiltins   map[string][]int64
+	SliceOfMapsOfSlices     []map[string][]Leaf
+}
+
+// Leaf's field is an int32 in v1.
+type Leaf struct {
+	A int64
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/nilpolicy/v2
+
+package v1
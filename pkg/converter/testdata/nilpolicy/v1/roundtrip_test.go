@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"testing"
+
+	v2 "example.com/nilpolicy/v2"
+)
+
+func TestNilPolicies(t *testing.T) {
+	out := &v2.Spec{Replicas: 3, Mode: "manual"}
+	if err := Convert_v1_Spec_To_v2_Spec(&Spec{Template: &Template{Image: "nginx"}}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Replicas != 0 || out.Template.Image != "nginx" || out.Mode != DefaultMode {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	if err := Convert_v1_Spec_To_v2_Spec(&Spec{}, &v2.Spec{}); err == nil || err.Error() != "Template is nil" {
+		t.Errorf("expected converting a nil template to fail, got %v", err)
+	}
+}
+
+func TestValuesConvertToPointers(t *testing.T) {
+	out := &Spec{}
+	if err := Convert_v2_Spec_To_v1_Spec(&v2.Spec{}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Replicas == nil || *out.Replicas != 0 || out.Template == nil || out.Mode == nil || *out.Mode != "" {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+}
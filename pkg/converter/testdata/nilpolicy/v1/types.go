@@ -0,0 +1,17 @@
+package v1
+
+type Spec struct {
+	// +conversion-gen=nil:zero
+	Replicas *int32
+	// +conversion-gen=nil:error
+	Template *Template
+	// +conversion-gen=nil:default
+	// +conversion-gen=default-expr:DefaultMode
+	Mode *string
+}
+
+type Template struct {
+	Image string
+}
+
+const DefaultMode = "auto"
@@ -0,0 +1,11 @@
+package v2
+
+type Spec struct {
+	Replicas int64
+	Template Template
+	Mode     string
+}
+
+type Template struct {
+	Image string
+}
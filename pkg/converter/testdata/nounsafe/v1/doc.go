@@ -0,0 +1,4 @@
+// +conversion-gen=example.com/nounsafe/v2
+// +conversion-gen=no-unsafe
+
+package v1
@@ -0,0 +1,9 @@
+package v1
+
+type Foo struct {
+	Items []Item
+}
+
+type Item struct {
+	A int32
+}
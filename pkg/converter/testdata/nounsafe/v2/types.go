@@ -0,0 +1,9 @@
+package v2
+
+type Foo struct {
+	Items []Item
+}
+
+type Item struct {
+	A int32
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/omitzero/v2
+
+package v1
@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/omitzero/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Spec{
+		Replicas: 3,
+		Limits:   Limits{CPU: 1},
+		Selector: Selector{Labels: map[string]string{"app": "foo"}},
+		Name:     "foo",
+	}
+
+	out := &v2.Spec{}
+	if err := Convert_v1_Spec_To_v2_Spec(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Replicas == nil || *out.Replicas != 3 || out.Limits == nil || out.Selector == nil || out.Name == nil {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Spec{}
+	if err := Convert_v2_Spec_To_v1_Spec(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+}
+
+func TestZeroValuesConvertToNilPointers(t *testing.T) {
+	out := &v2.Spec{}
+	if err := Convert_v1_Spec_To_v2_Spec(&Spec{}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, &v2.Spec{}) {
+		t.Errorf("expected nil pointers, got %+v", out)
+	}
+}
+
+func TestNilPointersConvertToZeroValues(t *testing.T) {
+	out := &Spec{Replicas: 1, Limits: Limits{CPU: 1}, Selector: Selector{Labels: map[string]string{}}}
+	name := "foo"
+	if err := Convert_v2_Spec_To_v1_Spec(&v2.Spec{Name: &name}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, &Spec{Name: name}) {
+		t.Errorf("expected zero values, got %+v", out)
+	}
+
+	// unless a nil policy says otherwise
+	if err := Convert_v2_Spec_To_v1_Spec(&v2.Spec{}, out); err == nil {
+		t.Error("expected converting a nil name to fail")
+	}
+}
@@ -0,0 +1,24 @@
+package v1
+
+type Spec struct {
+	// +conversion-gen=omit-zero
+	Replicas int32
+	// +conversion-gen=omit-zero
+	Limits Limits
+	// +conversion-gen=omit-zero
+	Selector Selector
+	// +conversion-gen=omit-zero
+	// +conversion-gen=nil:error
+	Name string
+}
+
+// Limits is comparable.
+type Limits struct {
+	CPU    int32
+	Memory int32
+}
+
+// Selector isn't.
+type Selector struct {
+	Labels map[string]string
+}
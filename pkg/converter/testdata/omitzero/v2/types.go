@@ -0,0 +1,17 @@
+package v2
+
+type Spec struct {
+	Replicas *int64
+	Limits   *Limits
+	Selector *Selector
+	Name     *string
+}
+
+type Limits struct {
+	CPU    int32
+	Memory int32
+}
+
+type Selector struct {
+	Labels map[string]string
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/optional/v2
+
+package v1
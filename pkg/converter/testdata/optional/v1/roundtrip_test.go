@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"testing"
+
+	v2 "example.com/optional/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	replicas, level := int32(3), "debug"
+	in := &Settings{Replicas: &replicas, Level: &level, Name: "foo"}
+
+	out := &v2.Settings{}
+	if err := Convert_v1_Settings_To_v2_Settings(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Replicas != 3 || out.Level != "debug" || out.Name == nil || *out.Name != "foo" {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Settings{}
+	if err := Convert_v2_Settings_To_v1_Settings(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Replicas == nil || *back.Replicas != 3 || back.Level == nil || *back.Level != "debug" || back.Name != "foo" {
+		t.Errorf("unexpected conversion: %+v", back)
+	}
+}
+
+func TestNilAndZeroValues(t *testing.T) {
+	// nil pointers convert to zero values, or defaults
+	out := &v2.Settings{Replicas: 1, Name: new(string)}
+	if err := Convert_v1_Settings_To_v2_Settings(&Settings{}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Replicas != 0 || out.Level != "info" || out.Name != nil {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	// and zero values to nil pointers
+	back := &Settings{}
+	if err := Convert_v2_Settings_To_v1_Settings(&v2.Settings{}, back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Replicas != nil || back.Level != nil || back.Name != "" {
+		t.Errorf("unexpected conversion: %+v", back)
+	}
+}
@@ -0,0 +1,8 @@
+package v1
+
+type Settings struct {
+	Replicas *int32
+	// +conversion-gen=default:info
+	Level *string
+	Name  string
+}
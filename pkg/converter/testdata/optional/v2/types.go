@@ -0,0 +1,7 @@
+package v2
+
+type Settings struct {
+	Replicas int64
+	Level    string
+	Name     *string
+}
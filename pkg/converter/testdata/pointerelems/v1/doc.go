@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/pointerelems/v2
+
+package v1
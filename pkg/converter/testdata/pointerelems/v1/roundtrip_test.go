@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/pointerelems/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Pool{Items: []*Item{{Name: "a"}, {Name: "b"}}, Counts: []int32{1, 2}}
+
+	out := &v2.Pool{}
+	if err := Convert_v1_Pool_To_v2_Pool(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Items) != 2 || out.Items[1].Name != "b" || len(out.Counts) != 2 || *out.Counts[1] != 2 {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Pool{}
+	if err := Convert_v2_Pool_To_v1_Pool(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+	// elements get allocated, rather than shared
+	if back.Items[0] == in.Items[0] {
+		t.Errorf("expected elements to be copied")
+	}
+}
+
+func TestNilElementsConvertToZeroValues(t *testing.T) {
+	out := &v2.Pool{}
+	if err := Convert_v1_Pool_To_v2_Pool(&Pool{Items: []*Item{nil, {Name: "b"}}}, out); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []v2.Item{{}, {Name: "b"}}; !reflect.DeepEqual(out.Items, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out.Items)
+	}
+}
@@ -0,0 +1,10 @@
+package v1
+
+type Pool struct {
+	Items  []*Item
+	Counts []int32
+}
+
+type Item struct {
+	Name string
+}
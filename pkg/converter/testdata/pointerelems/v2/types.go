@@ -0,0 +1,10 @@
+package v2
+
+type Pool struct {
+	Items  []Item
+	Counts []*int64
+}
+
+type Item struct {
+	Name string
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/samename/a/v2
+
+package v1
@@ -0,0 +1,5 @@
+package v2
+
+type Foo struct {
+	A string
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/samename/b/v2
+
+package v1
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/scale/v2
+
+package v1
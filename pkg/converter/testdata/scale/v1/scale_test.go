@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"math"
+	"testing"
+
+	v2 "example.com/scale/v2"
+)
+
+func TestScaleOverflows(t *testing.T) {
+	out := &v2.Bar{}
+	if err := Convert_v1_Bar_To_v2_Bar(&Bar{Seconds: 2, Millis: 3500}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Seconds != 2000 || out.Millis != 3 {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	for _, in := range []*Bar{
+		// multiplied, overflowing int32
+		{Seconds: math.MaxInt32 / 1000 * 2},
+		// narrowed to int32 before being multiplied
+		{Seconds: math.MaxInt32 + 1},
+		// divided, but still overflowing int32
+		{Millis: math.MaxInt32 * 1000 * 2},
+		{Millis: math.MinInt32 * 1000 * 2},
+	} {
+		if err := Convert_v1_Bar_To_v2_Bar(in, &v2.Bar{}); err == nil {
+			t.Errorf("expected converting %+v to overflow", in)
+		}
+	}
+}
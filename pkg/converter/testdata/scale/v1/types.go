@@ -0,0 +1,20 @@
+package v1
+
+type Dur int32
+
+type Foo struct {
+	// +conversion-gen=scale:1000
+	T int32
+	F float64
+	// +conversion-gen=scale:1000
+	D Dur
+	// +conversion-gen=scale:1000
+	Small int64
+}
+
+// Bar's fields can overflow when scaled.
+type Bar struct {
+	// +conversion-gen=scale:1000
+	Seconds int64
+	Millis  int64
+}
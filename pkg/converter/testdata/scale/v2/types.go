@@ -0,0 +1,17 @@
+package v2
+
+type Dur int64
+
+type Foo struct {
+	T     int64
+	F     float64
+	D     Dur
+	Small int8
+}
+
+// Bar's fields can overflow when scaled.
+type Bar struct {
+	Seconds int32
+	// +conversion-gen=scale:1000
+	Millis int32
+}
@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"strings"
+
+	v2 "example.com/splice/v2"
+)
+
+// Normalize is hand-written, and must survive splicing.
+func Normalize(foo *v2.Foo) {
+	foo.B = strings.TrimSpace(foo.B)
+}
+
+// +conversion-gen:begin
+
+// Convert_v1_Foo_To_v2_Foo was generated by a previous run, and is stale.
+func Convert_v1_Foo_To_v2_Foo(in *Foo, out *v2.Foo) error {
+	out.A = int64(in.A)
+	out.B = strings.ToLower(in.B)
+	return nil
+}
+
+// +conversion-gen:end
+
+// ConvertAll is hand-written too, and calls generated functions.
+func ConvertAll(in []Foo) ([]v2.Foo, error) {
+	out := make([]v2.Foo, len(in))
+	for i := range in {
+		if err := Convert_v1_Foo_To_v2_Foo(&in[i], &out[i]); err != nil {
+			return nil, err
+		}
+		Normalize(&out[i])
+	}
+	return out, nil
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/splice/v2
+
+package v1
@@ -0,0 +1,6 @@
+package v1
+
+type Foo struct {
+	A int32
+	B string
+}
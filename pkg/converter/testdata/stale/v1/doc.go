@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/stale/v2
+
+package v1
@@ -0,0 +1,7 @@
+package v1
+
+// Foo used to get conversions, before opting out.
+// +conversion-gen=false
+type Foo struct {
+	A int
+}
@@ -0,0 +1,25 @@
+// Package v1 isn't a peer package of example.com/structkeys/v2, so its types have no conversion
+// functions.
+package v1
+
+// Ref has the same memory layout as its v2 counterpart.
+type Ref struct {
+	Namespace string
+	Name      string
+}
+
+// Coords converts field by field to its v2 counterpart.
+type Coords struct {
+	X     int32
+	Y     int32
+	Label Label
+}
+
+type Label struct {
+	Text string
+}
+
+// Point lacks a field of its v2 counterpart, so converted keys could collide.
+type Point struct {
+	X int32
+}
@@ -0,0 +1,21 @@
+package v2
+
+type Ref struct {
+	Namespace string
+	Name      string
+}
+
+type Coords struct {
+	X     int64
+	Y     int64
+	Label Label
+}
+
+type Label struct {
+	Text string
+}
+
+type Point struct {
+	X int32
+	Z int32
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/structkeys/v2
+
+package v1
@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	refs "example.com/structkeys/refs/v1"
+	refsv2 "example.com/structkeys/refs/v2"
+	v2 "example.com/structkeys/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Index{
+		ByRef:    map[refs.Ref]int32{{Namespace: "ns", Name: "a"}: 1},
+		ByCoords: map[refs.Coords]string{{X: 1, Y: 2, Label: refs.Label{Text: "origin"}}: "a"},
+	}
+
+	out := &v2.Index{}
+	if err := Convert_v1_Index_To_v2_Index(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ByRef[refsv2.Ref{Namespace: "ns", Name: "a"}] != 1 ||
+		out.ByCoords[refsv2.Coords{X: 1, Y: 2, Label: refsv2.Label{Text: "origin"}}] != "a" {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Index{}
+	if err := Convert_v2_Index_To_v1_Index(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+}
@@ -0,0 +1,11 @@
+package v1
+
+import (
+	refs "example.com/structkeys/refs/v1"
+)
+
+type Index struct {
+	ByRef    map[refs.Ref]int32
+	ByCoords map[refs.Coords]string
+	ByPoint  map[refs.Point]string
+}
@@ -0,0 +1,11 @@
+package v2
+
+import (
+	refs "example.com/structkeys/refs/v2"
+)
+
+type Index struct {
+	ByRef    map[refs.Ref]int64
+	ByCoords map[refs.Coords]string
+	ByPoint  map[refs.Point]string
+}
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"strings"
+
+	"example.com/suggest/v2"
+)
+
+// Convert_v1_Foo_To_v2_Foo is a legacy hand-written conversion, that the generator can take over.
+func Convert_v1_Foo_To_v2_Foo(in *Foo, out *v2.Foo) error {
+	if err := autoConvert_v1_Foo_To_v2_Foo(in, out); err != nil {
+		return err
+	}
+	out.DisplayName = in.Name
+	out.Timeout = int64(in.Timeout) * 1000
+	out.Title = strings.ToUpper(in.Title)
+	out.Kind = "Foo"
+	return nil
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/suggest/v2
+
+package v1
@@ -0,0 +1,8 @@
+package v1
+
+type Foo struct {
+	Name    string
+	Timeout int32
+	Title   string
+	Legacy  string
+}
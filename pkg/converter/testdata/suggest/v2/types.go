@@ -0,0 +1,10 @@
+package v2
+
+type Foo struct {
+	// DisplayName is v1's Name, renamed.
+	DisplayName string
+	// Timeout is in milliseconds, and in seconds in v1.
+	Timeout int64
+	Title   string
+	Kind    string
+}
@@ -0,0 +1,15 @@
+package v1
+
+import (
+	v2 "example.com/valueinputs/v2"
+)
+
+func Convert_v1_Color_To_v2_Color(in Color, out *v2.Color) error {
+	out.R, out.G, out.B = uint8(in.RGB>>16), uint8(in.RGB>>8), uint8(in.RGB)
+	return nil
+}
+
+func Convert_v2_Color_To_v1_Color(in *v2.Color, out *Color) error {
+	out.RGB = int32(in.R)<<16 | int32(in.G)<<8 | int32(in.B)
+	return nil
+}
@@ -0,0 +1,3 @@
+// +conversion-gen=example.com/valueinputs/v2
+
+package v1
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "example.com/valueinputs/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &Shape{
+		Name:   "triangle",
+		Origin: Point{X: 1, Y: 2},
+		Points: []Point{{X: 3, Y: 4}, {X: 5, Y: 6}},
+		Color:  Color{RGB: 0x102030},
+	}
+
+	out := &v2.Shape{}
+	if err := Convert_v1_Shape_To_v2_Shape(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Origin.Y != 2 || out.Points[1].X != 5 || out.Color.G != 0x20 {
+		t.Errorf("unexpected conversion: %+v", out)
+	}
+
+	back := &Shape{}
+	if err := Convert_v2_Shape_To_v1_Shape(out, back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, in) {
+		t.Errorf("expected %+v, got %+v", in, back)
+	}
+
+	// no need to take the addresses of temporaries
+	var point v2.Point
+	if err := Convert_v1_Point_To_v2_Point(Point{X: 7}, &point); err != nil || point.X != 7 {
+		t.Errorf("unexpected conversion: %+v, %v", point, err)
+	}
+}
@@ -0,0 +1,19 @@
+package v1
+
+// Point is small enough to be taken by value.
+type Point struct {
+	X, Y int32
+}
+
+// Shape isn't.
+type Shape struct {
+	Name   string
+	Origin Point
+	Points []Point
+	Color  Color
+}
+
+// Color has a manual conversion function taking its input by value, see conversion.go.
+type Color struct {
+	RGB int32
+}
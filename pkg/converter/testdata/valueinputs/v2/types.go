@@ -0,0 +1,16 @@
+package v2
+
+type Point struct {
+	X, Y int64
+}
+
+type Shape struct {
+	Name   string
+	Origin Point
+	Points []Point
+	Color  Color
+}
+
+type Color struct {
+	R, G, B uint8
+}
@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// TodoReportMarkdown writes TODO reports as CONVERSIONS_TODO.md files.
+	TodoReportMarkdown = "markdown"
+	// TodoReportJSON writes TODO reports as conversions_todo.json files.
+	TodoReportJSON = "json"
+)
+
+// todoReportFileNames are the names of TODO report files, by format.
+var todoReportFileNames = map[string]string{
+	TodoReportMarkdown: "CONVERSIONS_TODO.md",
+	TodoReportJSON:     "conversions_todo.json",
+}
+
+// todoReportEntry is the JSON representation of a generator.TodoItem.
+type todoReportEntry struct {
+	Category  string `json:"category"`
+	InType    string `json:"inType"`
+	OutType   string `json:"outType"`
+	Field     string `json:"field,omitempty"`
+	Reason    string `json:"reason"`
+	Signature string `json:"signature"`
+}
+
+// writeTodoReports writes a TODO report in each package that has conversions left to do manually,
+// and removes stale reports from packages that don't; see Options.TodoReportFormat.
+func (c *Converter) writeTodoReports() error {
+	if c.Options.TodoReportFormat == "" || c.args.VerifyOnly {
+		return nil
+	}
+	fileName, known := todoReportFileNames[c.Options.TodoReportFormat]
+	if !known {
+		return fmt.Errorf("unknown TODO report format %q", c.Options.TodoReportFormat)
+	}
+
+	for _, generated := range c.generatedPackages {
+		path := filepath.Join(c.outputBase, generated.output.Path, generated.perPackageFileName(fileName))
+
+		items := generated.generator.TodoItems()
+		if len(items) == 0 {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return errors.Wrapf(err, "unable to remove stale TODO report %q", path)
+			}
+			continue
+		}
+
+		var content []byte
+		var err error
+		switch c.Options.TodoReportFormat {
+		case TodoReportMarkdown:
+			content = todoReportMarkdown(generated.pkg.Path, items)
+		case TodoReportJSON:
+			content, err = todoReportJSON(items)
+		}
+		if err != nil {
+			return err
+		}
+
+		klog.V(2).Infof("Writing TODO report to %q", path)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return errors.Wrapf(err, "unable to write TODO report %q", path)
+		}
+	}
+	return nil
+}
+
+func todoReportMarkdown(pkgPath string, items []generator.TodoItem) []byte {
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "# Conversions TODO for `%s`\n\n", pkgPath)
+	fmt.Fprintf(buffer, "_This file is regenerated by %s on every run, do not edit._\n\n", toolName)
+
+	var previousSignature string
+	for _, item := range items {
+		if item.Signature != previousSignature {
+			fmt.Fprintf(buffer, "## `%v` -> `%v`\n\n", item.InType, item.OutType)
+			fmt.Fprintf(buffer, "```go\n%s\n```\n\n", item.Signature)
+			previousSignature = item.Signature
+		}
+		if item.Field == "" {
+			fmt.Fprintf(buffer, "- %s\n", item.Reason)
+		} else {
+			fmt.Fprintf(buffer, "- `%s`: %s\n", item.Field, item.Reason)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func todoReportJSON(items []generator.TodoItem) ([]byte, error) {
+	entries := make([]todoReportEntry, len(items))
+	for i, item := range items {
+		entries[i] = todoReportEntry{
+			Category:  string(item.Category),
+			InType:    item.InType.String(),
+			OutType:   item.OutType.String(),
+			Field:     item.Field,
+			Reason:    item.Reason,
+			Signature: item.Signature,
+		}
+	}
+	content, err := json.MarshalIndent(entries, "", "  ")
+	return append(content, '\n'), errors.Wrap(err, "unable to marshal TODO report")
+}
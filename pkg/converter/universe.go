@@ -0,0 +1,149 @@
+package converter
+
+import (
+	"go/build"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/args"
+	gengogenerator "k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/parser"
+)
+
+// A SharedUniverse lets several converters share the packages they parse, see
+// Options.SharedUniverse: each package gets parsed and type-checked once, by the first converter
+// that needs it, instead of once per converter.
+// Packages get loaded the way the first converter to run with it loads them, e.g. according to its
+// PackageFiles; and converters sharing a universe must use the same generated build tag and build
+// tags.
+// Converters sharing a universe can run concurrently, but their runs get serialized.
+type SharedUniverse struct {
+	mutex sync.Mutex
+
+	// builder is the parser shared by converters, nil until the first run.
+	builder *parser.Builder
+	// generatedBuildTag, buildTags and includeTestFiles are the settings builder was created with.
+	generatedBuildTag string
+	buildTags         string
+	includeTestFiles  bool
+}
+
+// NewSharedUniverse builds a new, empty SharedUniverse.
+func NewSharedUniverse() *SharedUniverse {
+	return &SharedUniverse{}
+}
+
+// execute is the equivalent of arguments.Execute, parsing with the shared parser.
+func (u *SharedUniverse) execute(arguments *args.GeneratorArgs, nameSystems namer.NameSystems, defaultSystem string,
+	pkgs func(*gengogenerator.Context, *args.GeneratorArgs) gengogenerator.Packages) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if err := u.addInputs(arguments); err != nil {
+		return errors.Wrap(err, "failed making a parser")
+	}
+
+	context, err := gengogenerator.NewContext(u.builder, nameSystems, defaultSystem)
+	if err != nil {
+		return errors.Wrap(err, "failed making a context")
+	}
+	// the shared parser knows about all the inputs of the converters that ran with it so far
+	context.Inputs = runInputs(context.Inputs, arguments.InputDirs)
+	context.TrimPathPrefix = arguments.TrimPathPrefix
+	context.Verify = arguments.VerifyOnly
+
+	packages := pkgs(context, arguments)
+	if err := context.ExecutePackages(arguments.OutputBase, packages); err != nil {
+		return errors.Wrap(err, "failed executing generator")
+	}
+	return nil
+}
+
+// addInputs creates the shared parser, or adds the given arguments' inputs to it.
+func (u *SharedUniverse) addInputs(arguments *args.GeneratorArgs) error {
+	if u.builder == nil {
+		builder, err := arguments.NewBuilder()
+		if err != nil {
+			return err
+		}
+		u.builder = builder
+		u.generatedBuildTag = arguments.GeneratedBuildTag
+		u.buildTags = installedBuildTags()
+		u.includeTestFiles = arguments.IncludeTestFiles
+		return nil
+	}
+
+	if arguments.GeneratedBuildTag != u.generatedBuildTag {
+		return errors.Errorf("generated build tag %q differs from the shared universe's %q", arguments.GeneratedBuildTag, u.generatedBuildTag)
+	}
+	if buildTags := installedBuildTags(); buildTags != u.buildTags {
+		return errors.Errorf("build tags %q differ from the shared universe's %q", buildTags, u.buildTags)
+	}
+	if arguments.IncludeTestFiles != u.includeTestFiles {
+		return errors.Errorf("converters sharing a universe must all include test files, or none")
+	}
+	for _, input := range arguments.InputDirs {
+		var err error
+		if strings.HasSuffix(input, "/...") {
+			err = u.builder.AddDirRecursive(strings.TrimSuffix(input, "/..."))
+		} else {
+			err = u.builder.AddDir(input)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to add directory %q", input)
+		}
+	}
+	return nil
+}
+
+// installedBuildTags returns the build tags that packages currently get loaded with, see
+// Converter.installBuildTags.
+func installedBuildTags() string {
+	return strings.Join(build.Default.BuildTags, ",")
+}
+
+// runInputs returns the packages among allInputs that are inputs of the current run, i.e. that
+// match inputDirs.
+func runInputs(allInputs, inputDirs []string) []string {
+	wd, _ := os.Getwd()
+
+	var exact, recursive []string
+	for _, input := range inputDirs {
+		pkgPath := strings.TrimSuffix(input, "/...")
+		if build.IsLocalImport(pkgPath) {
+			if buildPkg, err := build.Import(pkgPath, wd, build.FindOnly); err == nil && buildPkg.ImportPath != "." {
+				pkgPath = buildPkg.ImportPath
+			}
+		}
+		if strings.HasSuffix(input, "/...") {
+			recursive = append(recursive, pkgPath)
+		} else {
+			exact = append(exact, pkgPath)
+		}
+	}
+
+	var inputs []string
+	for _, pkgPath := range allInputs {
+		if matchesInput(pkgPath, exact, recursive) {
+			inputs = append(inputs, pkgPath)
+		}
+	}
+	return inputs
+}
+
+func matchesInput(pkgPath string, exact, recursive []string) bool {
+	for _, input := range exact {
+		if pkgPath == input {
+			return true
+		}
+	}
+	for _, input := range recursive {
+		if pkgPath == input || strings.HasPrefix(pkgPath, input+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+// Package convertertest helps writing integration tests for code embedding the converter: it runs
+// it on fixture package trees, and checks the generated code.
+package convertertest
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/wk8/go-conversion-gen/pkg/converter"
+	"github.com/wk8/go-conversion-gen/pkg/generator"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a non-empty value, makes golden
+// assertions (re)write golden files instead of comparing against them.
+const UpdateGoldenEnv = "CONVERTERTEST_UPDATE_GOLDEN"
+
+// A Fixture is a tree of Go packages to run the converter on, typically under a testdata
+// directory.
+type Fixture struct {
+	// Dir is the root directory of the tree.
+	Dir string
+	// ModulePath is the import path that Dir maps to: the package in Dir/api/v1 has import path
+	// ModulePath + "/api/v1".
+	ModulePath string
+}
+
+// ExampleModulePath is the ModulePath of ExampleFixture.
+const ExampleModulePath = "example.com/conversion-example"
+
+// ExampleFixture writes the example of the init-example command, i.e. converter.ExampleFiles, to
+// a temporary directory removed at the end of the test; its packages are "v1", which conversions
+// are generated for, and "v2".
+func ExampleFixture(t testing.TB) Fixture {
+	t.Helper()
+
+	files, err := converter.ExampleFiles(ExampleModulePath, generator.DefaultTagName, "true")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	fixture := Fixture{Dir: t.TempDir(), ModulePath: ExampleModulePath}
+	for path, content := range files {
+		fullPath := filepath.Join(fixture.Dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("unable to create directory %q: %v", filepath.Dir(fullPath), err)
+		}
+		if err := ioutil.WriteFile(fullPath, content, 0644); err != nil {
+			t.Fatalf("unable to write %q: %v", fullPath, err)
+		}
+	}
+	return fixture
+}
+
+// PackageFiles returns the source files of the fixture's packages, i.e. of all the directories
+// under Dir that contain Go files; excluding tests, as well as testdata and hidden directories.
+func (f Fixture) PackageFiles() (converter.PackageFiles, error) {
+	root, err := filepath.Abs(f.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve fixture directory %q", f.Dir)
+	}
+
+	files := make(converter.PackageFiles)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if name := info.Name(); path != root && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		relativeDir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		pkgPath := f.ModulePath
+		if relativeDir != "." {
+			pkgPath += "/" + filepath.ToSlash(relativeDir)
+		}
+		files[pkgPath] = append(files[pkgPath], path)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list the files of fixture %q", f.Dir)
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no Go files found in fixture %q", f.Dir)
+	}
+	return files, nil
+}
+
+// Run runs the converter on the given packages of the fixture, which are import paths relative to
+// its ModulePath, e.g. "api/v1"; and returns the generated code. It fails the test if generation
+// fails.
+// options can be nil, in which case default options are used; the converter runs with a copy of
+// them, whose PackageFiles get extended with the fixture's.
+func Run(t testing.TB, fixture Fixture, options *converter.Options, packages ...string) *Result {
+	t.Helper()
+
+	files, err := fixture.PackageFiles()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if options == nil {
+		options = converter.DefaultOptions()
+	} else {
+		options = copyOptions(options)
+	}
+	packageFiles := make(converter.PackageFiles, len(options.PackageFiles)+len(files))
+	for pkgPath, pkgFiles := range options.PackageFiles {
+		packageFiles[pkgPath] = pkgFiles
+	}
+	for pkgPath, pkgFiles := range files {
+		packageFiles[pkgPath] = pkgFiles
+	}
+	options.PackageFiles = packageFiles
+
+	targetPackages := make([]string, len(packages))
+	for i, pkg := range packages {
+		targetPackages[i] = fixture.ModulePath + "/" + strings.TrimPrefix(pkg, "/")
+	}
+
+	sources, err := converter.NewConverter(targetPackages, options).GeneratedSources()
+	if err != nil {
+		t.Fatalf("unable to generate conversions for %v: %v", targetPackages, err)
+	}
+	return &Result{t: t, fixture: fixture, Sources: sources}
+}
+
+// copyOptions returns a copy of options, along with their generator options, that the converter
+// can change without changing the caller's; other pointers are shared, e.g. so that callers can
+// inspect the diagnostics collected.
+func copyOptions(options *converter.Options) *converter.Options {
+	copied := *options
+	if options.GeneratorOptions != nil {
+		generatorOptions := *options.GeneratorOptions
+		copied.GeneratorOptions = &generatorOptions
+	}
+	return &copied
+}
+
+// A Result is the code generated by Run.
+type Result struct {
+	t       testing.TB
+	fixture Fixture
+
+	// Sources are the generated files' contents, indexed by the import paths of their packages.
+	Sources map[string][]byte
+}
+
+// Source returns the code generated for the given package, relative to the fixture's ModulePath;
+// it fails the test if nothing was generated for it.
+func (r *Result) Source(pkg string) []byte {
+	r.t.Helper()
+
+	pkgPath := r.fixture.ModulePath + "/" + strings.TrimPrefix(pkg, "/")
+	source, present := r.Sources[pkgPath]
+	if !present {
+		generated := make([]string, 0, len(r.Sources))
+		for path := range r.Sources {
+			generated = append(generated, path)
+		}
+		sort.Strings(generated)
+		r.t.Fatalf("nothing generated for package %q, only for %v", pkgPath, generated)
+	}
+	return source
+}
+
+// Function returns the source of the given function generated for pkg, including its doc
+// comment; it fails the test if there's no such function.
+func (r *Result) Function(pkg, name string) string {
+	r.t.Helper()
+
+	source := r.Source(pkg)
+	function, found, err := findFunction(source, name)
+	if err != nil {
+		r.t.Fatalf("unable to parse the code generated for package %q: %v", pkg, err)
+	}
+	if !found {
+		r.t.Fatalf("function %s not generated for package %q", name, pkg)
+	}
+	return function
+}
+
+// AssertFunction checks that the given functions were generated for pkg.
+func (r *Result) AssertFunction(pkg string, names ...string) {
+	r.t.Helper()
+
+	source := r.Source(pkg)
+	for _, name := range names {
+		if _, found, err := findFunction(source, name); err != nil {
+			r.t.Fatalf("unable to parse the code generated for package %q: %v", pkg, err)
+		} else if !found {
+			r.t.Errorf("function %s not generated for package %q", name, pkg)
+		}
+	}
+}
+
+// AssertNoFunction checks that none of the given functions were generated for pkg.
+func (r *Result) AssertNoFunction(pkg string, names ...string) {
+	r.t.Helper()
+
+	source := r.Source(pkg)
+	for _, name := range names {
+		if _, found, err := findFunction(source, name); err != nil {
+			r.t.Fatalf("unable to parse the code generated for package %q: %v", pkg, err)
+		} else if found {
+			r.t.Errorf("function %s unexpectedly generated for package %q", name, pkg)
+		}
+	}
+}
+
+// AssertContains checks that the code generated for pkg contains the given snippet.
+func (r *Result) AssertContains(pkg, snippet string) {
+	r.t.Helper()
+
+	if !bytes.Contains(r.Source(pkg), []byte(snippet)) {
+		r.t.Errorf("code generated for package %q does not contain:\n%s", pkg, snippet)
+	}
+}
+
+// AssertGolden checks that the code generated for pkg matches the golden file at goldenPath.
+// See UpdateGoldenEnv to update golden files.
+func (r *Result) AssertGolden(pkg, goldenPath string) {
+	r.t.Helper()
+	assertGolden(r.t, r.Source(pkg), goldenPath)
+}
+
+// AssertFunctionGolden checks that the given function generated for pkg matches the golden file
+// at goldenPath. See UpdateGoldenEnv to update golden files.
+func (r *Result) AssertFunctionGolden(pkg, name, goldenPath string) {
+	r.t.Helper()
+	assertGolden(r.t, []byte(r.Function(pkg, name)+"\n"), goldenPath)
+}
+
+func assertGolden(t testing.TB, actual []byte, goldenPath string) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatalf("unable to create the directory of golden file %q: %v", goldenPath, err)
+		}
+		if err := ioutil.WriteFile(goldenPath, actual, 0644); err != nil {
+			t.Fatalf("unable to write golden file %q: %v", goldenPath, err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("unable to read golden file %q (set %s=1 to create it): %v", goldenPath, UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("generated code does not match golden file %q (set %s=1 to update it):\n%s",
+			goldenPath, UpdateGoldenEnv, lineDiff(string(expected), string(actual)))
+	}
+}
+
+// findFunction returns the source of the given top-level function in source, including its doc
+// comment.
+func findFunction(source []byte, name string) (string, bool, error) {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", source, parser.ParseComments)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || funcDecl.Name.Name != name {
+			continue
+		}
+		start := funcDecl.Pos()
+		if funcDecl.Doc != nil {
+			start = funcDecl.Doc.Pos()
+		}
+		return string(source[fileSet.Position(start).Offset:fileSet.Position(funcDecl.End()).Offset]), true, nil
+	}
+	return "", false, nil
+}
+
+// lineDiff returns a minimal description of the differences between expected and actual: the
+// first line that differs, with some context.
+func lineDiff(expected, actual string) string {
+	const context = 3
+
+	expectedLines, actualLines := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	first := 0
+	for first < len(expectedLines) && first < len(actualLines) && expectedLines[first] == actualLines[first] {
+		first++
+	}
+
+	excerpt := func(lines []string) string {
+		from, to := first-context, first+context+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+		if from >= to {
+			return "  <end of file>"
+		}
+		return "  " + strings.Join(lines[from:to], "\n  ")
+	}
+	return fmt.Sprintf("first difference at line %d\nexpected:\n%s\nactual:\n%s", first+1, excerpt(expectedLines), excerpt(actualLines))
+}
@@ -0,0 +1,28 @@
+package convertertest_test
+
+import (
+	"testing"
+
+	"github.com/wk8/go-conversion-gen/pkg/converter"
+	"github.com/wk8/go-conversion-gen/pkg/convertertest"
+)
+
+func TestRun(t *testing.T) {
+	fixture := convertertest.ExampleFixture(t)
+	options := converter.DefaultOptions()
+
+	result := convertertest.Run(t, fixture, options, "v1")
+
+	if options.PackageFiles != nil {
+		t.Errorf("expected Run to leave the caller's package files alone, got %v", options.PackageFiles)
+	}
+	if options.GeneratorOptions.ManualConversionsTracker != nil {
+		t.Errorf("expected Run to leave the caller's generator options alone")
+	}
+
+	result.AssertFunction("v1", "Convert_v2_Widget_To_v1_Widget", "autoConvert_v1_Widget_To_v2_Widget")
+	// there's a manual one in conversion.go
+	result.AssertNoFunction("v1", "Convert_v1_Widget_To_v2_Widget", "Convert_v1_Cache_To_v2_Cache")
+	result.AssertContains("v1", "out.Replicas = int64(in.Replicas)")
+	result.AssertFunctionGolden("v1", "autoConvert_v2_Port_To_v1_Port", "testdata/port.golden")
+}
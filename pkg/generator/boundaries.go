@@ -0,0 +1,44 @@
+package generator
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doBoundaryField writes the conversion of struct fields marked as conversion boundaries, and
+// returns true iff it did: the generator then never looks into their types, e.g. for huge
+// sub-trees shared between versions, that would otherwise get conversion functions of their own.
+// That is:
+//   - in fields with a "+<tag-name>=boundary:<function>" tag get converted by calling that
+//     function, with the same signature as conversion functions; it can be qualified, e.g.
+//     "shared.Convert", with the package resolved from the in type package's imports
+//   - fields with a "+<tag-name>=boundary" tag on either member get shallow copied, see
+//     writeShallowCopy
+//
+// A function only converts from the member it tags: converting to that member errors, unless its
+// peer member has a "+<tag-name>=boundary:<function>" tag of its own for that direction.
+func (g *Generator) doBoundaryField(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) (bool, error) {
+	if present, function := g.hasTagOption(inMember.CommentLines, "boundary"); present {
+		snippet, functionArgs, err := g.exprSnippet(function, inType.Name.Package)
+		if err != nil {
+			return true, errors.Wrapf(err, "invalid boundary tag on %s.%s", inType.Name, inMember.Name)
+		}
+		sw.Do("if err := "+snippet+"(&in."+inMember.Name+", &out."+outMember.Name+g.extraArgumentsString()+"); err != nil {\n", functionArgs)
+		sw.Do(g.returnErr()+"\n", nil)
+		sw.Do("}\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "conversion boundary, converted by %s", function)
+		return true, nil
+	}
+
+	if present, function := g.hasTagOption(outMember.CommentLines, "boundary"); present {
+		return true, errors.Errorf("boundary field %s.%s's function %s only converts from it: "+
+			"tag %s.%s with \"+%s=boundary:<function>\" to convert to it", outType.Name, outMember.Name, function,
+			inType.Name, inMember.Name, g.Options.TagName)
+	}
+
+	if g.hasTag(inMember.CommentLines, "boundary") || g.hasTag(outMember.CommentLines, "boundary") {
+		return true, g.writeShallowCopy(inType, outType, inMember, outMember, inMemberType, outMemberType, "conversion boundary", args, sw)
+	}
+	return false, nil
+}
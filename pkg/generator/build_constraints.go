@@ -0,0 +1,221 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// declarations are where the types of a package are declared.
+type declarations struct {
+	// files are the base names of the files that types are declared in, by type name.
+	files map[string]string
+	// constraints are the build constraints of the files that types are declared in, by type
+	// name, for files that have any.
+	constraints map[string]string
+}
+
+// declarationsOf returns where the types of the given package are declared, parsing its source
+// files the first time.
+func (g *Generator) declarationsOf(pkgPath string) (*declarations, error) {
+	if decls, present := g.declarations[pkgPath]; present {
+		return decls, nil
+	}
+
+	decls := &declarations{files: make(map[string]string), constraints: make(map[string]string)}
+	if pkg := g.universe[pkgPath]; pkg != nil && pkg.SourcePath != "" {
+		fileSet := token.NewFileSet()
+		packages, err := parser.ParseDir(fileSet, pkg.SourcePath, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %q to find where types are declared", pkg.SourcePath)
+		}
+
+		for _, parsed := range packages {
+			for path, file := range parsed.Files {
+				name := filepath.Base(path)
+				fileConstraint := fileBuildConstraint(name, file)
+				for _, decl := range file.Decls {
+					genDecl, ok := decl.(*ast.GenDecl)
+					if !ok || genDecl.Tok != token.TYPE {
+						continue
+					}
+					for _, spec := range genDecl.Specs {
+						typeName := spec.(*ast.TypeSpec).Name.Name
+						decls.files[typeName] = name
+						if fileConstraint != "" {
+							decls.constraints[typeName] = fileConstraint
+						}
+					}
+				}
+			}
+		}
+	}
+
+	g.declarations[pkgPath] = decls
+	return decls, nil
+}
+
+// fileBuildConstraint returns the build constraint of the given file, if any: from its
+// "//go:build" line, or else from its "// +build" lines; along with the constraints implied by its
+// name, e.g. "types_linux.go".
+func fileBuildConstraint(name string, file *ast.File) string {
+	var goBuild, plusBuild []constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+			if constraint.IsGoBuild(comment.Text) {
+				goBuild = append(goBuild, expr)
+			} else {
+				plusBuild = append(plusBuild, expr)
+			}
+		}
+	}
+
+	exprs := goBuild
+	if len(exprs) == 0 {
+		exprs = plusBuild
+	}
+	for _, tag := range fileNameTags(name) {
+		exprs = append(exprs, &constraint.TagExpr{Tag: tag})
+	}
+	return andConstraints(exprs...)
+}
+
+// fileNameTags returns the GOOS and GOARCH build tags implied by a file's name, as with
+// "types_linux.go" or "types_windows_amd64.go".
+func fileNameTags(name string) []string {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "_test"), "_")
+	if len(parts) < 2 {
+		return nil
+	}
+	parts = parts[1:]
+
+	last := parts[len(parts)-1]
+	if len(parts) >= 2 && knownOS[parts[len(parts)-2]] && knownArch[last] {
+		return []string{parts[len(parts)-2], last}
+	}
+	if knownOS[last] || knownArch[last] {
+		return []string{last}
+	}
+	return nil
+}
+
+// andConstraints returns the conjunction of exprs, as a string; or "" if there are none.
+func andConstraints(exprs ...constraint.Expr) string {
+	if len(exprs) == 0 {
+		return ""
+	}
+	conjunction := exprs[0]
+	for _, expr := range exprs[1:] {
+		if expr.String() != conjunction.String() {
+			conjunction = &constraint.AndExpr{X: conjunction, Y: expr}
+		}
+	}
+	return conjunction.String()
+}
+
+// conversionBuildConstraint returns the build constraint that the conversion functions between t
+// and other need, if any: that of the files they're declared in.
+func (g *Generator) conversionBuildConstraint(t, other *types.Type) (string, error) {
+	var exprs []constraint.Expr
+	for _, declared := range []*types.Type{t, other} {
+		decls, err := g.declarationsOf(declared.Name.Package)
+		if err != nil {
+			return "", err
+		}
+		if declaredConstraint := decls.constraints[declared.Name.Name]; declaredConstraint != "" {
+			expr, err := constraint.Parse("//go:build " + declaredConstraint)
+			if err != nil {
+				return "", err
+			}
+			exprs = append(exprs, expr)
+		}
+	}
+	return andConstraints(exprs...), nil
+}
+
+// BuildConstraints returns the distinct build constraints that the conversions this generator can
+// generate need, if any, e.g. for types declared in platform-specific files; see
+// RestrictToBuildConstraint.
+func (g *Generator) BuildConstraints(context *generator.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var constraints []string
+	for _, t := range g.typesPackage.Types {
+		peerType := g.GetPeerTypeFor(context, t)
+		if peerType == nil || g.notConvertibleReason(t, peerType) != "" {
+			continue
+		}
+		conversionConstraint, err := g.conversionBuildConstraint(t, peerType)
+		if err != nil {
+			return nil, err
+		}
+		if conversionConstraint != "" && !seen[conversionConstraint] {
+			seen[conversionConstraint] = true
+			constraints = append(constraints, conversionConstraint)
+		}
+	}
+	sort.Strings(constraints)
+	return constraints, nil
+}
+
+// RestrictToBuildConstraint restricts conversion generation to the conversions that need the given
+// build constraint, or none if empty; see BuildConstraints. This allows generating the conversions
+// of types declared in files with build constraints into files with the same constraints, with
+// several generators sharing the output package, see ShareOutputPackage.
+func (g *Generator) RestrictToBuildConstraint(buildConstraint string) {
+	g.buildConstraint = &buildConstraint
+}
+
+// notWithBuildConstraintReason returns why the conversions between t and other don't get generated
+// by this generator, given its build constraint, if they don't. Other generators can still
+// generate them, so unlike notConvertibleReason, it doesn't prevent calling them.
+func (g *Generator) notWithBuildConstraintReason(t, other *types.Type) string {
+	if g.buildConstraint == nil {
+		return ""
+	}
+	conversionConstraint, err := g.conversionBuildConstraint(t, other)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("have an unknown build constraint: %v", err)
+	case conversionConstraint == *g.buildConstraint:
+		return ""
+	case conversionConstraint == "":
+		return "need no build constraint"
+	default:
+		return fmt.Sprintf("need build constraint %q", conversionConstraint)
+	}
+}
+
+// knownOS and knownArch are the GOOS and GOARCH values that files' names imply build constraints
+// for, see go/build.
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true, "sparc": true,
+	"sparc64": true, "wasm": true,
+}
@@ -0,0 +1,111 @@
+package generator
+
+import (
+	gotypes "go/types"
+	"text/template"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// BuiltinConversionPolicy decides how conversions between different builtin types
+// (e.g. int32 to int64, or int to uint) are handled.
+type BuiltinConversionPolicy string
+
+const (
+	// BuiltinConversionCast converts between different builtin types with a plain type conversion.
+	// This is the default.
+	BuiltinConversionCast BuiltinConversionPolicy = "allow-with-cast"
+	// BuiltinConversionCheck converts between different numeric builtin types, with a runtime check
+	// returning an error when the value can't be represented in an integer destination type;
+	// conversions that can't lose information (e.g. int32 to int64) aren't checked. Conversions
+	// between non-numeric builtin types are forbidden.
+	BuiltinConversionCheck BuiltinConversionPolicy = "allow-with-check"
+	// BuiltinConversionForbid treats different builtin types as inconvertible.
+	BuiltinConversionForbid BuiltinConversionPolicy = "forbid"
+)
+
+// builtinConversionAllowed returns false iff inType and outType are different builtin types,
+// and the policy forbids converting between them.
+func (g *Generator) builtinConversionAllowed(inType, outType *types.Type) bool {
+	if inType.Kind != types.Builtin || outType.Kind != types.Builtin || inType == outType {
+		return true
+	}
+	switch g.Options.BuiltinConversionPolicy {
+	case BuiltinConversionForbid:
+		return false
+	case BuiltinConversionCheck:
+		return isNumericBuiltin(inType) && isNumericBuiltin(outType)
+	default:
+		return true
+	}
+}
+
+// writeBuiltinConversion writes code converting in, of builtin type inType, into out, of builtin
+// type outType, according to the policy; in and out are snippets, rendered with args.
+// The conversion must be allowed, see builtinConversionAllowed.
+func (g *Generator) writeBuiltinConversion(inType, outType *types.Type, in, out string, args generator.Args, sw *generator.SnippetWriter) {
+	if inType == outType {
+		sw.Do(out+" = "+in+"\n", args)
+		return
+	}
+
+	args = args.With("builtinIn", inType).With("builtinOut", outType)
+	converted := "$.builtinOut|" + rawNamer + "$(" + in + ")"
+	if g.Options.BuiltinConversionPolicy == BuiltinConversionCheck && !isLosslessBuiltinConversion(inType, outType) {
+		condition := "$.builtinIn|" + rawNamer + "$(" + converted + ") != " + in
+		if isUnsignedBuiltin(inType) != isUnsignedBuiltin(outType) {
+			condition += " || (" + in + " < 0) != (" + converted + " < 0)"
+		}
+		sw.Do("if "+condition+" {\n", args)
+		sw.Do("return $.Errorf|"+rawNamer+"$(\"cannot convert %v to $.builtinOut|"+rawNamer+"$ without loss\", "+in+")\n", args.With("Errorf", types.Ref("fmt", "Errorf")))
+		sw.Do("}\n", nil)
+	}
+	g.executeTemplate(g.template(func(t *Templates) *template.Template { return t.Builtin }), BuiltinData{
+		In:      g.renderSnippet(in, args),
+		Out:     g.renderSnippet(out, args),
+		InType:  g.rawName(inType),
+		OutType: g.rawName(outType),
+	}, sw)
+}
+
+func isNumericBuiltin(t *types.Type) bool {
+	basic := basicType(t)
+	return basic != nil && basic.Info()&gotypes.IsNumeric != 0
+}
+
+func isUnsignedBuiltin(t *types.Type) bool {
+	basic := basicType(t)
+	return basic != nil && basic.Info()&gotypes.IsUnsigned != 0
+}
+
+var (
+	// the smallest and largest sizes of platform-dependent builtin types
+	minSizes = gotypes.SizesFor("gc", "386")
+	maxSizes = gotypes.SizesFor("gc", "amd64")
+)
+
+// isLosslessBuiltinConversion returns true iff converting from numeric builtin inType to numeric
+// builtin outType never loses information on any platform; conversions to non-integer types are
+// deemed lossless, as rounding is expected.
+func isLosslessBuiltinConversion(inType, outType *types.Type) bool {
+	inBasic, outBasic := basicType(inType), basicType(outType)
+	if outBasic.Info()&gotypes.IsInteger == 0 {
+		return true
+	}
+	if inBasic.Info()&gotypes.IsInteger == 0 {
+		return false
+	}
+
+	inMaxSize, outMinSize := maxSizes.Sizeof(inBasic), minSizes.Sizeof(outBasic)
+	inUnsigned, outUnsigned := inBasic.Info()&gotypes.IsUnsigned != 0, outBasic.Info()&gotypes.IsUnsigned != 0
+	switch {
+	case inUnsigned == outUnsigned:
+		return inMaxSize <= outMinSize
+	case inUnsigned:
+		return inMaxSize < outMinSize
+	default:
+		// negative values can't be converted to unsigned types
+		return false
+	}
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// jsonRawMessage is encoding/json's RawMessage, which gengo doesn't always load the definition of.
+var jsonRawMessage = types.Name{Package: "encoding/json", Name: "RawMessage"}
+
+// doByteSliceField writes the conversion of a struct field between different byte slice types,
+// e.g. json.RawMessage, named []byte types, and plain []byte: with a type conversion, sharing the
+// underlying array, unless unsafe conversions are disabled, in which case it copies it.
+// Returns true iff it did.
+func (g *Generator) doByteSliceField(inType, outType *types.Type, inMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if inMemberType.Name == outMemberType.Name || !isByteSlice(inMemberType) || !isByteSlice(outMemberType) {
+		return false
+	}
+
+	if g.unsafeConversionsDisabled() {
+		sw.Do("if in.$.name$ != nil {\n", args)
+		sw.Do("out.$.name$ = make($.outType|"+rawNamer+"$, len(in.$.name$))\n", args)
+		sw.Do("copy(out.$.name$, in.$.name$)\n", args)
+		sw.Do("} else {\n", nil)
+		sw.Do("out.$.name$ = nil\n", args)
+		sw.Do("}\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "byte slices, copied")
+	} else {
+		sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+		g.explainFieldf(inType, outType, inMember.Name, FieldCast, "byte slices, type conversion to %v", outMemberType)
+	}
+	return true
+}
+
+// areByteSlices returns true iff inType and outType are both byte slice types, that generated
+// code can convert between with a type conversion; see doByteSliceField.
+func (g *Generator) areByteSlices(inType, outType *types.Type) bool {
+	return !g.unsafeConversionsDisabled() && isByteSlice(inType) && isByteSlice(outType)
+}
+
+// isByteSlice returns true iff t's underlying type is []byte.
+func isByteSlice(t *types.Type) bool {
+	if t.Name == jsonRawMessage {
+		return true
+	}
+	underlying := unwrapAlias(t)
+	if underlying.Kind != types.Slice {
+		return false
+	}
+	elem := unwrapAlias(underlying.Elem)
+	return elem.Kind == types.Builtin && (elem.Name.Name == "byte" || elem.Name.Name == "uint8")
+}
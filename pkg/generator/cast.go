@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// castConversionsTable is the name of the table of the conversions pruned by
+	// Options.PruneCastableConversions, in generated files.
+	castConversionsTable = "castConversions"
+	// castConversionHelper is the name of the function converting pruned conversions' types.
+	castConversionHelper = "ConvertByCast"
+)
+
+// castConversion returns true iff no conversion function gets generated from inType to outType,
+// as they have identical memory layouts: they're converted with unsafe casts instead, see
+// Options.PruneCastableConversions.
+func (g *Generator) castConversion(inType, outType *types.Type) bool {
+	if !g.Options.PruneCastableConversions || g.fieldMappings(inType, outType) != nil {
+		return false
+	}
+
+	t, peerType := inType, outType
+	if t.Name.Package != g.typesPackage.Path {
+		t, peerType = outType, inType
+	}
+	if t.Name.Package != g.typesPackage.Path || g.GetPeerTypeFor(g.context, t) != peerType || g.notConvertibleReason(t, peerType) != "" {
+		return false
+	}
+	if _, found := g.preexists(inType, outType); found {
+		return false
+	}
+	return g.useUnsafeConversion(inType, outType)
+}
+
+// generateOrCastConversion writes the conversion functions from inType to outType, unless they're
+// converted with unsafe casts; and returns how.
+func (g *Generator) generateOrCastConversion(inType, outType *types.Type, sw *generator.SnippetWriter) ConversionStrategy {
+	if !g.castConversion(inType, outType) {
+		return g.generateConversion(inType, outType, sw)
+	}
+	klog.V(5).Infof("not generating conversion from %v to %v: they share the same memory layout", inType, outType)
+	g.explainConversionf(inType, outType, "not generated, converted with an unsafe cast")
+	g.castConversions = append(g.castConversions, ConversionPair{inType, outType})
+	return CastConversion
+}
+
+// writeCastConversion writes the conversion of in to out, pointers to inType and outType, with an
+// unsafe cast; in and out are snippets rendered with args.
+func (g *Generator) writeCastConversion(inType, outType *types.Type, in, out string, args generator.Args, sw *generator.SnippetWriter) {
+	out = g.renderSnippet(out, args)
+	if strings.HasPrefix(out, "&") {
+		out = out[1:]
+	} else {
+		out = "*" + out
+	}
+
+	sw.Do("$.out$ = *(*$.outType|"+rawNamer+"$)($.Pointer|"+rawNamer+"$($.in$))\n", generator.Args{
+		"in":      g.renderSnippet(in, args),
+		"out":     out,
+		"outType": outType,
+		"Pointer": types.Ref("unsafe", "Pointer"),
+	})
+}
+
+// writeCastConversions writes the table of the conversions pruned by
+// Options.PruneCastableConversions in the output package, and the helper converting their types,
+// if any.
+func (g *Generator) writeCastConversions(sw *generator.SnippetWriter) {
+	var castConversions []ConversionPair
+	for _, other := range g.packageGenerators() {
+		castConversions = append(castConversions, other.castConversions...)
+	}
+	if len(castConversions) == 0 {
+		return
+	}
+
+	args := generator.Args{
+		"TypeOf":  types.Ref("reflect", "TypeOf"),
+		"Type":    types.Ref("reflect", "Type"),
+		"ValueOf": types.Ref("reflect", "ValueOf"),
+		"NewAt":   types.Ref("reflect", "NewAt"),
+		"Pointer": types.Ref("unsafe", "Pointer"),
+		"Errorf":  types.Ref("fmt", "Errorf"),
+	}
+
+	sw.Do("// "+castConversionsTable+" are the pairs of types that have identical memory layouts: they get\n", nil)
+	sw.Do("// converted with "+castConversionHelper+", rather than with conversion functions.\n", nil)
+	sw.Do("var "+castConversionsTable+" = map[[2]$.Type|"+rawNamer+"$]bool{\n", args)
+	for _, pair := range castConversions {
+		sw.Do("{$.TypeOf|"+rawNamer+"$((*$.inType|"+rawNamer+"$)(nil)), $.TypeOf|"+rawNamer+"$((*$.outType|"+rawNamer+"$)(nil))}: true,\n",
+			argsFromType(pair.InType, pair.OutType).With("TypeOf", args["TypeOf"]))
+	}
+	sw.Do("}\n\n", nil)
+
+	sw.Do("// "+castConversionHelper+" converts in to out, pointers to types with identical memory layouts, with\n", nil)
+	sw.Do("// an unsafe cast. It fails for types that don't get converted this way.\n", nil)
+	sw.Do("func "+castConversionHelper+"(in, out interface{}) error {\n", nil)
+	sw.Do("inValue, outValue := $.ValueOf|"+rawNamer+"$(in), $.ValueOf|"+rawNamer+"$(out)\n", args)
+	sw.Do("if !"+castConversionsTable+"[[2]$.Type|"+rawNamer+"${inValue.Type(), outValue.Type()}] {\n", args)
+	sw.Do("return $.Errorf|"+rawNamer+"$(\"no cast conversion from %T to %T\", in, out)\n", args)
+	sw.Do("}\n", nil)
+	sw.Do("$.NewAt|"+rawNamer+"$(inValue.Type().Elem(), $.Pointer|"+rawNamer+"$(outValue.Pointer())).Elem().Set(inValue.Elem())\n", args)
+	sw.Do("return nil\n", nil)
+	sw.Do("}\n\n", nil)
+}
@@ -0,0 +1,216 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/gengo/types"
+)
+
+// A CompatChangeKind is the kind of a CompatChange.
+type CompatChangeKind string
+
+const (
+	// CompatFieldAdded fields only exist in the peer type.
+	CompatFieldAdded CompatChangeKind = "added"
+	// CompatFieldRemoved fields only exist in the type.
+	CompatFieldRemoved CompatChangeKind = "removed"
+	// CompatFieldRetyped fields have different types in the type and in its peer.
+	CompatFieldRetyped CompatChangeKind = "retyped"
+	// CompatFieldRenamed fields have different names in the type and in its peer: either as
+	// declared by Options.FieldMappings, or as the only fields of the same type that got removed
+	// and added.
+	CompatFieldRenamed CompatChangeKind = "renamed"
+)
+
+// A CompatChange is a difference between a struct of the types package and its peer type; from
+// the former to the latter, e.g. fields are "added" if they only exist in the peer.
+type CompatChange struct {
+	Type, Peer *types.Type
+	Kind       CompatChangeKind
+	// Field is the name of the field in Type, empty for added fields.
+	Field string
+	// PeerField is the name of the field in Peer, empty for removed fields.
+	PeerField string
+	// Severity is how incompatible the change is:
+	//   - SeverityError for changes losing data: removed fields, and fields retyped to types
+	//     that they can't be converted to with a cast
+	//   - SeverityWarning for changes that API clients notice: renamed fields with different
+	//     serialized names, fields retyped to types they can be cast to, and added fields that
+	//     aren't optional
+	//   - SeverityInfo for the other changes
+	Severity DiagnosticSeverity
+	Message  string
+}
+
+// CompatChanges returns the differences between the structs of the types package that conversions
+// were generated for so far, and their peer types.
+func (g *Generator) CompatChanges() []CompatChange {
+	var changes []CompatChange
+	for _, planned := range g.plannedConversions {
+		if planned.Direction != ToPeer || planned.InType.Kind != types.Struct || planned.OutType.Kind != types.Struct {
+			continue
+		}
+		changes = append(changes, g.compatChanges(planned.InType, planned.OutType)...)
+	}
+	return changes
+}
+
+// compatChanges returns the differences between t and its peer.
+func (g *Generator) compatChanges(t, peer *types.Type) (changes []CompatChange) {
+	add := func(kind CompatChangeKind, field, peerField string, severity DiagnosticSeverity, format string, args ...interface{}) {
+		changes = append(changes, CompatChange{
+			Type:      t,
+			Peer:      peer,
+			Kind:      kind,
+			Field:     field,
+			PeerField: peerField,
+			Severity:  severity,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	mappings := g.fieldMappings(t, peer)
+	var removed, added []types.Member
+	for _, member := range t.Members {
+		if mapping, present := mappings.fromField(member.Name); present && mapping.isRename() {
+			if peerMember, found := findMember(peer, mapping.To); found {
+				addRenameCompatChange(add, t, peer, member, peerMember)
+				continue
+			}
+		}
+		peerMember, found := findMember(peer, member.Name)
+		if !found || mappings.isTarget(member.Name) {
+			removed = append(removed, member)
+			continue
+		}
+		if !compatibleTypes(member.Type, peerMember.Type) {
+			severity, how := SeverityError, "can't be cast"
+			if isNumericBuiltin(unwrapAlias(member.Type)) && isNumericBuiltin(unwrapAlias(peerMember.Type)) {
+				severity, how = SeverityWarning, "can be cast"
+			}
+			add(CompatFieldRetyped, member.Name, peerMember.Name, severity, "%s.%s is a %v, and %s.%s a %v, which it %s to",
+				t.Name.Name, member.Name, member.Type, peer.Name.Name, peerMember.Name, peerMember.Type, how)
+		}
+	}
+	for _, peerMember := range peer.Members {
+		if isRenamedTo(mappings, t, peerMember.Name) {
+			continue
+		}
+		if _, found := findMember(t, peerMember.Name); found && !mappings.isTarget(peerMember.Name) {
+			continue
+		}
+		added = append(added, peerMember)
+	}
+
+	// fields are assumed to be renamed if they're the only ones of their type to be removed and added
+	for _, member := range removed {
+		var candidates []types.Member
+		for _, peerMember := range added {
+			if compatibleTypes(member.Type, peerMember.Type) {
+				candidates = append(candidates, peerMember)
+			}
+		}
+		if len(candidates) != 1 || countCompatibleMembers(removed, member.Type) != 1 {
+			add(CompatFieldRemoved, member.Name, "", SeverityError, "%s.%s doesn't exist in %v", t.Name.Name, member.Name, peer)
+			continue
+		}
+		addRenameCompatChange(add, t, peer, member, candidates[0])
+		added = removeMember(added, candidates[0].Name)
+	}
+	for _, peerMember := range added {
+		severity, how := SeverityWarning, "required"
+		if isOptionalMember(peerMember) {
+			severity, how = SeverityInfo, "optional"
+		}
+		add(CompatFieldAdded, "", peerMember.Name, severity, "%s.%s doesn't exist in %v, and is %s", peer.Name.Name, peerMember.Name, t, how)
+	}
+	return
+}
+
+// addRenameCompatChange records that member got renamed to peerMember.
+func addRenameCompatChange(add func(CompatChangeKind, string, string, DiagnosticSeverity, string, ...interface{}), t, peer *types.Type, member, peerMember types.Member) {
+	if serializedName(member) == serializedName(peerMember) {
+		add(CompatFieldRenamed, member.Name, peerMember.Name, SeverityInfo, "%s.%s is %s.%s, with the same serialized name",
+			t.Name.Name, member.Name, peer.Name.Name, peerMember.Name)
+		return
+	}
+	add(CompatFieldRenamed, member.Name, peerMember.Name, SeverityWarning, "%s.%s is %s.%s, serialized as %q instead of %q",
+		t.Name.Name, member.Name, peer.Name.Name, peerMember.Name, serializedName(peerMember), serializedName(member))
+}
+
+// isRenamedTo returns true iff the given peer field is the target of a rename of one of t's fields.
+func isRenamedTo(mappings *TypeFieldMappings, t *types.Type, peerField string) bool {
+	if mappings == nil {
+		return false
+	}
+	for _, mapping := range mappings.Fields {
+		if _, found := findMember(t, mapping.From); found && mapping.To == peerField && mapping.isRename() {
+			return true
+		}
+	}
+	return false
+}
+
+// compatibleTypes returns true iff a and b are the same types, or peer types - i.e. types with the
+// same names - or made of them.
+func compatibleTypes(a, b *types.Type) bool {
+	if a == b {
+		return true
+	}
+	a, b = unwrapAlias(a), unwrapAlias(b)
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case types.Pointer, types.Slice, types.Array:
+		return a.Len == b.Len && compatibleTypes(a.Elem, b.Elem)
+	case types.Map:
+		return compatibleTypes(a.Key, b.Key) && compatibleTypes(a.Elem, b.Elem)
+	default:
+		return a.Name.Name == b.Name.Name && a.Name.Name != ""
+	}
+}
+
+func countCompatibleMembers(members []types.Member, t *types.Type) (count int) {
+	for _, member := range members {
+		if compatibleTypes(member.Type, t) {
+			count++
+		}
+	}
+	return
+}
+
+func removeMember(members []types.Member, name string) []types.Member {
+	result := make([]types.Member, 0, len(members))
+	for _, member := range members {
+		if member.Name != name {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+// serializedName returns the JSON name of member.
+func serializedName(member types.Member) string {
+	if name := strings.Split(reflect.StructTag(member.Tags).Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return member.Name
+}
+
+// isOptionalMember returns true iff member can be omitted when serialized: either a pointer, a
+// map or a slice, or tagged with omitempty.
+func isOptionalMember(member types.Member) bool {
+	switch unwrapAlias(member.Type).Kind {
+	case types.Pointer, types.Map, types.Slice:
+		return true
+	}
+	for _, option := range strings.Split(reflect.StructTag(member.Tags).Get("json"), ",")[1:] {
+		if option == "omitempty" {
+			return true
+		}
+	}
+	return false
+}
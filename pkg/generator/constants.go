@@ -2,3 +2,7 @@ package generator
 
 // DefaultTagName is the default tag name for almost all tags (types, functions, peer packages, etc...)
 const DefaultTagName = "conversion-gen"
+
+// DefaultPrivateFunctionPrefix is the default prefix of private conversion functions' names, see
+// Options.PrivateFunctionPrefix.
+const DefaultPrivateFunctionPrefix = "autoConvert_"
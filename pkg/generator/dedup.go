@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// A loopHelper is a private function converting maps, slices or pointers, factored out of
+// conversion functions; see Options.DeduplicateLoops.
+type loopHelper struct {
+	name            string
+	inType, outType *types.Type
+	body            string
+}
+
+// doDeduplicatedLoop writes the conversion of a map, slice or pointer struct field as a call to a
+// loop helper, writing the helper in Finalize the first time its body is seen.
+func (g *Generator) doDeduplicatedLoop(inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) []error {
+	buffer := &bytes.Buffer{}
+	bodyWriter := generator.NewSnippetWriter(buffer, g.context, snippetDelimiter, snippetDelimiter)
+	g.inLoopHelper = true
+	errors := g.generateFor(inMemberType, outMemberType, bodyWriter)
+	g.inLoopHelper = false
+	if err := bodyWriter.Error(); err != nil {
+		errors = append(errors, err)
+	}
+	body := buffer.String()
+
+	name, known := g.loopHelperNames[body]
+	if !known {
+		name = g.privateFunctionName(inMemberType, outMemberType)
+		g.loopHelperNames[body] = name
+		g.loopHelpers = append(g.loopHelpers, loopHelper{name: name, inType: inMemberType, outType: outMemberType, body: body})
+	}
+
+	sw.Do("if in.$.name$ != nil {\n", args)
+	sw.Do("if err := "+name+"(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
+	sw.Do(g.returnErr()+"\n", nil)
+	sw.Do("}\n", nil)
+	sw.Do("} else {\n", nil)
+	sw.Do("out.$.name$ = nil\n", args)
+	sw.Do("}\n", nil)
+	return errors
+}
+
+// writeLoopHelpers writes the loop helpers that generated code calls, if any.
+func (g *Generator) writeLoopHelpers(sw *generator.SnippetWriter) {
+	g.writeHelperFunctions(g.loopHelpers, sw)
+}
+
+// writeHelperFunctions writes the given helpers, as private functions with the signature of
+// conversion functions.
+func (g *Generator) writeHelperFunctions(helpers []loopHelper, sw *generator.SnippetWriter) {
+	for _, helper := range helpers {
+		args := argsFromType(helper.inType, helper.outType)
+		sw.Do("func "+helper.name+"(in *$.inType|"+rawNamer+"$, out *$.outType|"+rawNamer+"$", args)
+		for _, namedArgument := range g.Options.ManualConversionsTracker.additionalConversionArguments {
+			sw.Do(fmt.Sprintf(", %s", namedArgument.Name)+" $.|"+rawNamer+"$", namedArgument.Type)
+		}
+		sw.Do(") error {\n", nil)
+		sw.Do("$.$", helper.body)
+		sw.Do("return nil\n", nil)
+		sw.Do("}\n\n", nil)
+	}
+}
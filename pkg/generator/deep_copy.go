@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"bytes"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// deepCopyFunctionPrefix is the prefix of the names of deep-copy helpers, see writeDeepCopyCall.
+const deepCopyFunctionPrefix = "deepConvert_"
+
+// forcesDeepCopy returns true iff either member has a "+<tag-name>=deep-copy" tag, or a deep copy
+// is being generated, and its type is a map, slice, pointer or struct type: its value then gets
+// converted into newly allocated maps, slices and pointers at any depth, rather than cast or
+// assigned, even when that would be possible; so that in and out never share memory, e.g. for
+// fields that are mutated after conversion. Manual conversion functions still get called, and
+// fields whose conversion is set by other tags (e.g. opaque, passthrough) as well as interfaces,
+// functions and channels still get assigned.
+func (g *Generator) forcesDeepCopy(inMember, outMember *types.Member, inMemberType *types.Type) bool {
+	switch inMemberType.Kind {
+	case types.Map, types.Slice, types.Pointer, types.Struct:
+		return g.deepCopying || g.hasTag(inMember.CommentLines, "deep-copy") || g.hasTag(outMember.CommentLines, "deep-copy")
+	default:
+		return false
+	}
+}
+
+// startDeepCopy makes the code generated from now on a deep copy if deepCopy is true, see
+// forcesDeepCopy; and returns the function restoring the previous state.
+func (g *Generator) startDeepCopy(deepCopy bool) (restore func()) {
+	previous := g.deepCopying
+	g.deepCopying = previous || deepCopy
+	return func() { g.deepCopying = previous }
+}
+
+// deepCopiesElem returns true iff values of type inType, e.g. container elements, are being
+// deep-copied to outType: i.e. they could otherwise share memory with their copies, and are
+// either nested containers, see isNestedContainer, or have generated conversions.
+func (g *Generator) deepCopiesElem(inType, outType *types.Type) bool {
+	return g.deepCopying && sharesMemory(inType, map[*types.Type]bool{}) &&
+		(isNestedContainer(inType, outType) || g.convertibleOnlyWithinPackage(inType, outType))
+}
+
+// sharesMemory returns true iff copies of values of type t can share memory with them: i.e. if t
+// is, or holds at any depth, a map, slice, pointer, interface, function or channel.
+func sharesMemory(t *types.Type, visited map[*types.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind {
+	case types.Builtin:
+		return false
+	case types.Alias:
+		return sharesMemory(t.Underlying, visited)
+	case types.Array:
+		return sharesMemory(t.Elem, visited)
+	case types.Struct:
+		for _, member := range t.Members {
+			if sharesMemory(member.Type, visited) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// writeDeepCopyCall writes the conversion from in to out, pointers to inType and outType, as a
+// call to a deep-copy helper: a private function converting them without sharing memory, see
+// forcesDeepCopy. The helper gets written in Finalize, and its body generated the first time it's
+// called; errors generating it are returned then.
+func (g *Generator) writeDeepCopyCall(inType, outType *types.Type, in, out string, args generator.Args, sw *generator.SnippetWriter) (errors []error) {
+	key := [2]types.Name{inType.Name, outType.Name}
+	name, known := g.deepCopyHelperNames[key]
+	if !known {
+		name = PrivateConversionFunctionName(deepCopyFunctionPrefix, inType, outType)
+		g.deepCopyHelperNames[key] = name
+		// registered before generating the body, for recursive types
+		index := len(g.deepCopyHelpers)
+		g.deepCopyHelpers = append(g.deepCopyHelpers, loopHelper{name: name, inType: inType, outType: outType})
+
+		buffer := &bytes.Buffer{}
+		bodyWriter := generator.NewSnippetWriter(buffer, g.context, snippetDelimiter, snippetDelimiter)
+		defer g.startDeepCopy(true)()
+		defer func(inLoopHelper, inDeepCopyHelper bool) {
+			g.inLoopHelper, g.inDeepCopyHelper = inLoopHelper, inDeepCopyHelper
+		}(g.inLoopHelper, g.inDeepCopyHelper)
+		// helpers' bodies are shared, their errors get wrapped where they're called from; and the
+		// decisions made for their fields are those of the types' own conversions
+		g.inLoopHelper, g.inDeepCopyHelper = true, true
+		errors = g.generateFor(inType, outType, bodyWriter)
+		if err := bodyWriter.Error(); err != nil {
+			errors = append(errors, err)
+		}
+		g.deepCopyHelpers[index].body = buffer.String()
+	}
+
+	sw.Do("if err := "+name+"("+in+", "+out+g.extraArgumentsString()+"); err != nil {\n", args)
+	sw.Do(g.returnErr()+"\n", nil)
+	sw.Do("}\n", nil)
+	return
+}
+
+// writeDeepCopyHelpers writes the deep-copy helpers that generated code calls, if any, except
+// those already written by generators sharing the output package, see ShareOutputPackage.
+func (g *Generator) writeDeepCopyHelpers(sw *generator.SnippetWriter) {
+	var helpers []loopHelper
+	for _, helper := range g.deepCopyHelpers {
+		written := false
+		for _, other := range g.packageGenerators() {
+			if other == g {
+				break
+			}
+			if _, written = other.deepCopyHelperNames[[2]types.Name{helper.inType.Name, helper.outType.Name}]; written {
+				break
+			}
+		}
+		if !written {
+			helpers = append(helpers, helper)
+		}
+	}
+	g.writeHelperFunctions(helpers, sw)
+}
@@ -0,0 +1,350 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// DiagnosticCategory identifies the kind of problem a Diagnostic reports.
+type DiagnosticCategory string
+
+const (
+	// MissingFieldDiagnostic is reported when a field doesn't exist in the peer type.
+	MissingFieldDiagnostic DiagnosticCategory = "missing-field"
+	// InconvertibleFieldDiagnostic is reported when a field and its peer are of inconvertible types.
+	InconvertibleFieldDiagnostic DiagnosticCategory = "inconvertible-field"
+	// ExternalConversionDiagnostic is reported when a conversion to a type from another package is required.
+	ExternalConversionDiagnostic DiagnosticCategory = "external-conversion"
+	// UnsupportedTypeDiagnostic is reported when the generator doesn't know how to convert a type.
+	UnsupportedTypeDiagnostic DiagnosticCategory = "unsupported-type"
+	// DroppedConversionDiagnostic is reported when a field's conversion is dropped because of a
+	// "+<function-tag-name>=drop" tag on a manual conversion function.
+	DroppedConversionDiagnostic DiagnosticCategory = "dropped-conversion"
+	// NoPublicFunctionDiagnostic is reported when no public conversion function could be generated.
+	NoPublicFunctionDiagnostic DiagnosticCategory = "no-public-function"
+)
+
+// DiagnosticSeverity is the severity of a Diagnostic.
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+	SeverityInfo    DiagnosticSeverity = "info"
+)
+
+// A Diagnostic is a problem found while generating conversion code, tied to the declaration
+// of the offending type (or of one of its fields). Problems that a handler took care of get
+// reported too, with SeverityInfo.
+type Diagnostic struct {
+	Category DiagnosticCategory
+	Severity DiagnosticSeverity
+	Message  string
+
+	// Type is the offending type.
+	Type *types.Type
+	// Member is the name of the offending field, if any.
+	Member string
+
+	// Position is the position of Type's (or Member's) declaration; it is only known after
+	// calling DiagnosticsCollector.ResolvePositions.
+	Position token.Position
+
+	sourcePath string
+}
+
+// A DiagnosticsCollector collects the diagnostics reported by generators, so that they can be
+// written out in formats that IDEs and code-review bots understand.
+// A collector can be shared between generators.
+type DiagnosticsCollector struct {
+	diagnostics []*Diagnostic
+
+	// positions caches declarations' positions, indexed by source directory,
+	// then "<type>" or "<type>.<member>"
+	positions map[string]map[string]token.Position
+}
+
+// NewDiagnosticsCollector builds a new DiagnosticsCollector.
+func NewDiagnosticsCollector() *DiagnosticsCollector {
+	return &DiagnosticsCollector{
+		positions: make(map[string]map[string]token.Position),
+	}
+}
+
+// Diagnostics returns all the diagnostics reported so far.
+func (c *DiagnosticsCollector) Diagnostics() []*Diagnostic {
+	return c.diagnostics
+}
+
+func (c *DiagnosticsCollector) report(diagnostic *Diagnostic) {
+	c.diagnostics = append(c.diagnostics, diagnostic)
+}
+
+// ResolvePositions looks up the declarations of the types and fields the diagnostics refer to.
+func (c *DiagnosticsCollector) ResolvePositions() {
+	for _, diagnostic := range c.diagnostics {
+		if diagnostic.Position.IsValid() || diagnostic.sourcePath == "" || diagnostic.Type == nil {
+			continue
+		}
+		positions := c.positionsIn(diagnostic.sourcePath)
+		key := diagnostic.Type.Name.Name
+		if diagnostic.Member != "" {
+			if position, found := positions[key+"."+diagnostic.Member]; found {
+				diagnostic.Position = position
+				continue
+			}
+		}
+		diagnostic.Position = positions[key]
+	}
+}
+
+func (c *DiagnosticsCollector) positionsIn(sourcePath string) map[string]token.Position {
+	if positions, present := c.positions[sourcePath]; present {
+		return positions
+	}
+
+	positions := make(map[string]token.Position)
+	c.positions[sourcePath] = positions
+
+	fileSet := token.NewFileSet()
+	packages, err := parser.ParseDir(fileSet, sourcePath, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		klog.Warningf("unable to parse %q to find declarations' positions: %v", sourcePath, err)
+		return positions
+	}
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					positions[typeSpec.Name.Name] = fileSet.Position(typeSpec.Name.Pos())
+
+					structType, ok := typeSpec.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range structType.Fields.List {
+						for _, name := range field.Names {
+							positions[typeSpec.Name.Name+"."+name.Name] = fileSet.Position(name.Pos())
+						}
+						if len(field.Names) == 0 {
+							// embedded field
+							positions[typeSpec.Name.Name+"."+embeddedFieldName(field.Type)] = fileSet.Position(field.Pos())
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return positions
+}
+
+func embeddedFieldName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return embeddedFieldName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	default:
+		return ""
+	}
+}
+
+// WriteSARIF writes the diagnostics in SARIF 2.1.0 format.
+func (c *DiagnosticsCollector) WriteSARIF(w io.Writer, toolName string) error {
+	c.ResolvePositions()
+
+	rules := make(map[DiagnosticCategory]bool)
+	results := make([]map[string]interface{}, 0, len(c.diagnostics))
+	for _, diagnostic := range c.diagnostics {
+		rules[diagnostic.Category] = true
+
+		result := map[string]interface{}{
+			"ruleId":  string(diagnostic.Category),
+			"level":   sarifLevel(diagnostic.Severity),
+			"message": map[string]string{"text": diagnostic.Message},
+		}
+		if diagnostic.Position.IsValid() {
+			result["locations"] = []interface{}{
+				map[string]interface{}{
+					"physicalLocation": map[string]interface{}{
+						"artifactLocation": map[string]string{"uri": fileURI(diagnostic.Position.Filename)},
+						"region": map[string]int{
+							"startLine":   diagnostic.Position.Line,
+							"startColumn": diagnostic.Position.Column,
+						},
+					},
+				},
+			}
+		}
+		results = append(results, result)
+	}
+
+	ruleIDs := make([]string, 0, len(rules))
+	for category := range rules {
+		ruleIDs = append(ruleIDs, string(category))
+	}
+	sort.Strings(ruleIDs)
+	sarifRules := make([]map[string]string, 0, len(ruleIDs))
+	for _, ruleID := range ruleIDs {
+		sarifRules = append(sarifRules, map[string]string{"id": ruleID})
+	}
+
+	return writeJSON(w, map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":  toolName,
+						"rules": sarifRules,
+					},
+				},
+				"results": results,
+			},
+		},
+	})
+}
+
+func sarifLevel(severity DiagnosticSeverity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// WriteLSP writes the diagnostics as LSP-style PublishDiagnosticsParams, one per file.
+func (c *DiagnosticsCollector) WriteLSP(w io.Writer, source string) error {
+	c.ResolvePositions()
+
+	byURI := make(map[string][]interface{})
+	var uris []string
+	for _, diagnostic := range c.diagnostics {
+		uri := ""
+		line, column := 0, 0
+		if diagnostic.Position.IsValid() {
+			uri = fileURI(diagnostic.Position.Filename)
+			// LSP positions are 0-based
+			line, column = diagnostic.Position.Line-1, diagnostic.Position.Column-1
+		}
+		if _, present := byURI[uri]; !present {
+			uris = append(uris, uri)
+		}
+		position := map[string]int{"line": line, "character": column}
+		byURI[uri] = append(byURI[uri], map[string]interface{}{
+			"range":    map[string]interface{}{"start": position, "end": position},
+			"severity": lspSeverity(diagnostic.Severity),
+			"code":     string(diagnostic.Category),
+			"source":   source,
+			"message":  diagnostic.Message,
+		})
+	}
+
+	params := make([]interface{}, 0, len(uris))
+	for _, uri := range uris {
+		params = append(params, map[string]interface{}{
+			"uri":         uri,
+			"diagnostics": byURI[uri],
+		})
+	}
+	return writeJSON(w, params)
+}
+
+func lspSeverity(severity DiagnosticSeverity) int {
+	switch severity {
+	case SeverityError:
+		return 1
+	case SeverityWarning:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "file://" + filepath.ToSlash(path)
+}
+
+func writeJSON(w io.Writer, content interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(content); err != nil {
+		return fmt.Errorf("unable to write diagnostics: %v", err)
+	}
+	return nil
+}
+
+// reportDiagnostic reports a diagnostic to the generator's diagnostics collector, if any.
+func (g *Generator) reportDiagnostic(category DiagnosticCategory, severity DiagnosticSeverity, t *types.Type, member string, message string) {
+	if g.inDeepCopyHelper || g.Options.Diagnostics == nil {
+		return
+	}
+
+	diagnostic := &Diagnostic{
+		Category: category,
+		Severity: severity,
+		Message:  message,
+		Type:     t,
+		Member:   member,
+	}
+	if t != nil {
+		if pkg := g.universe[t.Name.Package]; pkg != nil {
+			diagnostic.sourcePath = pkg.SourcePath
+		}
+	}
+	g.Options.Diagnostics.report(diagnostic)
+}
+
+// reportHandlerResult reports what a handler did about (a field of) inType, that the generator
+// couldn't convert to outType by itself, as message describes: with a warning, and as needing
+// manual conversion, if the handler skipped; and as converted, or failed, otherwise.
+// It returns the handler's error, if it failed.
+func (g *Generator) reportHandlerResult(result HandlerResult, category DiagnosticCategory, inType, outType *types.Type, member, message string) error {
+	switch {
+	case result.Outcome == HandlerFailed:
+		severity := SeverityError
+		if skipRequested([]error{result.Err}) != nil {
+			severity = SeverityInfo
+		}
+		g.reportDiagnostic(category, severity, inType, member, fmt.Sprintf("%s: %v", message, result.Err))
+		return result.Err
+	case result.Outcome == HandlerSkipped:
+		message += ": requires manual conversion"
+		klog.Warning(message)
+		g.reportUnconverted(category, inType, outType, member, message)
+	case result.unconverted:
+		g.reportUnconverted(category, inType, outType, member, message+": requires manual conversion")
+	default:
+		g.reportDiagnostic(category, SeverityInfo, inType, member, message+": converted by a handler")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/gengo/types"
+)
+
+func TestReportHandlerResult(t *testing.T) {
+	inType := &types.Type{Name: types.Name{Package: "example.com/a/v1", Name: "Foo"}, Kind: types.Struct}
+	outType := &types.Type{Name: types.Name{Package: "example.com/a/v2", Name: "Foo"}, Kind: types.Struct}
+	message := "v1.Foo.A does not exist in peer-type v2.Foo"
+	failure := errors.New("nope")
+
+	for _, testCase := range []struct {
+		name             string
+		result           HandlerResult
+		expectedSeverity DiagnosticSeverity
+		expectedMessage  string
+		expectedTodo     bool
+		expectedErr      error
+	}{
+		{
+			name:             "skipped",
+			result:           Skipped(),
+			expectedSeverity: SeverityWarning,
+			expectedMessage:  message + ": requires manual conversion",
+			expectedTodo:     true,
+		},
+		{
+			name:             "handled",
+			result:           Handled(),
+			expectedSeverity: SeverityInfo,
+			expectedMessage:  message + ": converted by a handler",
+		},
+		{
+			name:             "reported",
+			result:           reported(),
+			expectedSeverity: SeverityWarning,
+			expectedMessage:  message + ": requires manual conversion",
+			expectedTodo:     true,
+		},
+		{
+			name:             "failed",
+			result:           Failed(failure),
+			expectedSeverity: SeverityError,
+			expectedMessage:  message + ": nope",
+			expectedErr:      failure,
+		},
+		{
+			name:             "skip conversion",
+			result:           Failed(SkipConversion("later")),
+			expectedSeverity: SeverityInfo,
+			expectedMessage:  message + ": later",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			options := DefaultOptions()
+			options.Diagnostics = NewDiagnosticsCollector()
+			options.ManualConversionsTracker = NewManualConversionsTracker()
+			g := &Generator{Options: options, outputPackage: &types.Package{Path: "example.com/a/v1"}}
+
+			err := g.reportHandlerResult(testCase.result, MissingFieldDiagnostic, inType, outType, "A", message)
+			if testCase.expectedErr != nil && err != testCase.expectedErr {
+				t.Errorf("expected error %v, got %v", testCase.expectedErr, err)
+			}
+
+			diagnostics := options.Diagnostics.Diagnostics()
+			if len(diagnostics) != 1 {
+				t.Fatalf("expected exactly one diagnostic, got %d", len(diagnostics))
+			}
+			if diagnostic := diagnostics[0]; diagnostic.Severity != testCase.expectedSeverity || diagnostic.Message != testCase.expectedMessage ||
+				diagnostic.Category != MissingFieldDiagnostic || diagnostic.Type != inType || diagnostic.Member != "A" {
+				t.Errorf("unexpected diagnostic: %+v", diagnostic)
+			}
+			if todo := len(g.todos) != 0; todo != testCase.expectedTodo {
+				t.Errorf("expected a TODO item: %v, got %v", testCase.expectedTodo, g.todos)
+			}
+		})
+	}
+}
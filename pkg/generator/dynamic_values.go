@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"fmt"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// DynamicValuesPolicy decides how fields holding dynamic values - of type interface{}, or maps or
+// slices of interface{} values, such as map[string]interface{} - are converted.
+type DynamicValuesPolicy string
+
+const (
+	// DynamicValuesUnhandled handles dynamic values as any other field. This is the default.
+	DynamicValuesUnhandled DynamicValuesPolicy = ""
+	// DynamicValuesPassThrough assigns dynamic values as is, sharing them between the input and
+	// output objects.
+	DynamicValuesPassThrough DynamicValuesPolicy = "pass-through"
+	// DynamicValuesDeepCopy deep-copies dynamic values with Options.DynamicValuesDeepCopyFunction.
+	DynamicValuesDeepCopy DynamicValuesPolicy = "deep-copy"
+	// DynamicValuesJSON deep-copies dynamic values with a JSON round-trip.
+	DynamicValuesJSON DynamicValuesPolicy = "json"
+)
+
+// doDynamicValuesField writes the conversion of a struct field holding dynamic values, according
+// to Options.DynamicValuesPolicy; returns true iff it did.
+func (g *Generator) doDynamicValuesField(inType, outType *types.Type, inMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if !g.handlesDynamicValues(inMemberType, outMemberType) {
+		return false
+	}
+
+	switch g.Options.DynamicValuesPolicy {
+	case DynamicValuesPassThrough:
+		if inMemberType.Name == outMemberType.Name {
+			sw.Do("out.$.name$ = in.$.name$\n", args)
+		} else {
+			sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+		}
+	case DynamicValuesDeepCopy:
+		args = args.With("deepCopy", g.functionExpression(g.Options.DynamicValuesDeepCopyFunction))
+		sw.Do("if in.$.name$ != nil {\n", args)
+		if underlying := unwrapAlias(inMemberType); underlying.Kind == types.Interface {
+			sw.Do("out.$.name$ = $.deepCopy$(in.$.name$)\n", args)
+		} else {
+			// deep-copy helpers typically only know about unnamed types
+			literal := "[]interface{}"
+			if underlying.Kind == types.Map {
+				args = args.With("key", underlying.Key)
+				literal = "map[$.key|" + rawNamer + "$]interface{}"
+			}
+			in, copied := "in.$.name$", "$.deepCopy$(%s).("+literal+")"
+			if inMemberType.Name.Package != "" {
+				in = literal + "(" + in + ")"
+			}
+			copied = fmt.Sprintf(copied, in)
+			if outMemberType.Name.Package != "" {
+				copied = "$.outType|" + rawNamer + "$(" + copied + ")"
+			}
+			sw.Do("out.$.name$ = "+copied+"\n", args)
+		}
+		sw.Do("} else {\n", nil)
+		sw.Do("out.$.name$ = nil\n", args)
+		sw.Do("}\n", nil)
+	case DynamicValuesJSON:
+		args = args.With("marshal", types.Ref("encoding/json", "Marshal")).
+			With("unmarshal", types.Ref("encoding/json", "Unmarshal"))
+		sw.Do("out.$.name$ = nil\n", args)
+		sw.Do("if in.$.name$ != nil {\n", args)
+		sw.Do("data, err := $.marshal|"+rawNamer+"$(in.$.name$)\n", args)
+		sw.Do("if err != nil {\n", nil)
+		sw.Do(g.returnErr()+"\n", nil)
+		sw.Do("}\n", nil)
+		sw.Do("if err := $.unmarshal|"+rawNamer+"$(data, &out.$.name$); err != nil {\n", args)
+		sw.Do(g.returnErr()+"\n", nil)
+		sw.Do("}\n", nil)
+		sw.Do("}\n", nil)
+	default:
+		return false
+	}
+
+	g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "dynamic values, converted with policy %q", g.Options.DynamicValuesPolicy)
+	return true
+}
+
+// handlesDynamicValues returns true iff fields of types inType and outType hold dynamic values
+// that Options.DynamicValuesPolicy applies to.
+func (g *Generator) handlesDynamicValues(inType, outType *types.Type) bool {
+	if g.Options.DynamicValuesPolicy == DynamicValuesUnhandled {
+		return false
+	}
+	in, out := unwrapAlias(inType), unwrapAlias(outType)
+	if in.Kind != out.Kind || !holdsDynamicValues(in) || !holdsDynamicValues(out) {
+		return false
+	}
+	return in.Kind != types.Map || unwrapAlias(in.Key) == unwrapAlias(out.Key)
+}
+
+// holdsDynamicValues returns true iff t is interface{}, or a map or slice of interface{} values.
+func holdsDynamicValues(t *types.Type) bool {
+	switch t.Kind {
+	case types.Map, types.Slice:
+		return isEmptyInterface(unwrapAlias(t.Elem))
+	default:
+		return isEmptyInterface(t)
+	}
+}
+
+func isEmptyInterface(t *types.Type) bool {
+	return t.Kind == types.Interface && len(t.Methods) == 0
+}
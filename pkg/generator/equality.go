@@ -0,0 +1,334 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// An EqualityGenerator generates a file in a conversion generator's output package, with
+// functions telling whether values of the struct types it converts are semantically equal to
+// values of their peer types, e.g.
+//
+//	func Equal_v1_Foo_v2_Foo(a *v1.Foo, b *v2.Foo) bool
+//
+// for round-trip tests, or to detect drift between versions. Fields are compared to the peer
+// fields they got converted to, as converted: builtins after converting them to the peer field's
+// type, or scaling them, containers element by element, so that nil and empty ones are equal,
+// structs with their own equality functions, and other values of identical types with
+// reflect.DeepEqual; fields that don't get converted, that get transformed otherwise, e.g. by
+// expressions, or that can't be compared, are ignored.
+// It must run after the conversion generator it compares the types of, and on the same types.
+type EqualityGenerator struct {
+	generator.DefaultGen
+
+	conversionGenerator *Generator
+	importTracker       namer.ImportTracker
+}
+
+// NewEqualityGenerator builds a new EqualityGenerator.
+func NewEqualityGenerator(outputFileName string, conversionGenerator *Generator) *EqualityGenerator {
+	return &EqualityGenerator{
+		DefaultGen: generator.DefaultGen{
+			OptionalName: outputFileName,
+		},
+		conversionGenerator: conversionGenerator,
+		importTracker:       generator.NewImportTracker(),
+	}
+}
+
+// Namers returns the name system used by EqualityGenerators.
+func (e *EqualityGenerator) Namers(*generator.Context) namer.NameSystems {
+	return namer.NameSystems{
+		rawNamer: namer.NewRawNamer(e.conversionGenerator.outputPackage.Path, e.importTracker),
+		publicImportTrackingNamer: &namerPlusImportTracking{
+			delegate: ConversionNamer(),
+			tracker:  e.importTracker,
+		},
+	}
+}
+
+// Filter filters the types this generator operates on.
+func (e *EqualityGenerator) Filter(context *generator.Context, t *types.Type) bool {
+	return e.peerFor(t) != nil
+}
+
+// peerFor returns the peer type that t gets an equality function with, if any: structs that
+// the conversion generator converted to or from their peer types.
+func (e *EqualityGenerator) peerFor(t *types.Type) *types.Type {
+	g := e.conversionGenerator
+	if t.Name.Package != g.typesPackage.Path || t.Kind != types.Struct || len(g.PublicConversionFunctions(t)) == 0 {
+		return nil
+	}
+	peerType := g.GetPeerTypeFor(g.context, t)
+	if peerType == nil || peerType.Kind != types.Struct {
+		return nil
+	}
+	return peerType
+}
+
+// Imports returns the imports to add to generated files.
+func (e *EqualityGenerator) Imports(*generator.Context) []string {
+	outputPackage := e.conversionGenerator.outputPackage.Path
+	var imports []string
+	for _, importLine := range e.importTracker.ImportLines() {
+		if importLine != outputPackage && !strings.HasSuffix(importLine, `"`+outputPackage+`"`) {
+			imports = append(imports, importLine)
+		}
+	}
+	return sortImports(imports, e.conversionGenerator.Options.LocalImportPrefix)
+}
+
+// A fieldConversion is how the conversion generator converted a struct field.
+type fieldConversion struct {
+	kind FieldConversionKind
+	// scale is the factor of the field's scale tag, if it got scaled: its value got multiplied by
+	// it, or divided by it if divided is true.
+	scale   string
+	divided bool
+}
+
+// recordFieldConversion records how inType's field got converted to outType, for equality
+// functions to compare it accordingly; the last record for a field wins, e.g. when conversions
+// are generated again.
+func (g *Generator) recordFieldConversion(inType, outType *types.Type, field string, conversion fieldConversion) {
+	key := [2]types.Name{inType.Name, outType.Name}
+	if g.fieldConversions == nil {
+		g.fieldConversions = make(map[[2]types.Name]map[string]fieldConversion)
+	}
+	if g.fieldConversions[key] == nil {
+		g.fieldConversions[key] = make(map[string]fieldConversion)
+	}
+	g.fieldConversions[key][field] = conversion
+}
+
+// equalityFunctionNameTemplate renders the name of the equality function from $.inType$ to
+// $.outType$, e.g. "Equal_v1_Foo_v2_Foo".
+const equalityFunctionNameTemplate = "Equal_$.inType|" + publicImportTrackingNamer + "$_$.outType|" + publicImportTrackingNamer + "$"
+
+// GenerateType processes the given type.
+func (e *EqualityGenerator) GenerateType(context *generator.Context, t *types.Type, writer io.Writer) error {
+	peerType := e.peerFor(t)
+	sw := generator.NewSnippetWriter(writer, context, snippetDelimiter, snippetDelimiter)
+	args := argsFromType(t, peerType)
+
+	sw.Do("// "+equalityFunctionNameTemplate+" returns true iff a and b are semantically equal, ignoring\n", args)
+	sw.Do("// the fields that can't be compared as converted between them.\n", nil)
+	sw.Do("func "+equalityFunctionNameTemplate+"(a *$.inType|"+rawNamer+"$, b *$.outType|"+rawNamer+"$) bool {\n", args)
+	sw.Do("if a == nil || b == nil {\n", nil)
+	sw.Do("return a == nil && b == nil\n", nil)
+	sw.Do("}\n", nil)
+	e.writeStructEquality(t, peerType, sw)
+	sw.Do("return true\n", nil)
+	sw.Do("}\n\n", nil)
+	return sw.Error()
+}
+
+// writeStructEquality writes the comparison of the fields of a and b, of struct types aType and
+// bType, as the conversion generator converted aType's fields, see recordFieldConversion: fields
+// that got assigned, cast, or converted element by element or by functions are compared to their
+// peer fields, possibly renamed by Options.FieldMappings, and scaled ones once scaled; others get
+// an INFO comment instead.
+func (e *EqualityGenerator) writeStructEquality(aType, bType *types.Type, sw *generator.SnippetWriter) {
+	g := e.conversionGenerator
+	conversions := g.fieldConversions[[2]types.Name{aType.Name, bType.Name}]
+	for _, aMember := range aType.Members {
+		conversion, converted := conversions[aMember.Name]
+		if !converted {
+			sw.Do("// INFO: a."+aMember.Name+" not compared, as it isn't converted\n", nil)
+			continue
+		}
+		if !comparedFieldConversions[conversion.kind] && conversion.scale == "" {
+			sw.Do(fmt.Sprintf("// INFO: a.%s not compared, as its conversion is %q\n", aMember.Name, conversion.kind), nil)
+			continue
+		}
+
+		bName := aMember.Name
+		if conversion.kind == FieldRenamed {
+			mapping, _ := g.fieldMappings(aType, bType).fromField(aMember.Name)
+			bName = mapping.To
+		}
+		bMember, found := findMember(bType, bName)
+		if !found {
+			sw.Do("// INFO: a."+aMember.Name+" not compared, as it isn't converted\n", nil)
+			continue
+		}
+		if conversion.scale != "" {
+			e.writeScaledEquality(bMember.Type, "a."+aMember.Name, "b."+bMember.Name, conversion, sw)
+			continue
+		}
+		// manual functions may transform values, only the fields that they leave to generated
+		// functions can be compared
+		if conversion.kind == FieldManualFunction && !e.hasEqualityFunction(aMember.Type, bMember.Type) {
+			sw.Do(fmt.Sprintf("// INFO: a.%s not compared, as its conversion is %q\n", aMember.Name, conversion.kind), nil)
+			continue
+		}
+		if !e.comparable(aMember.Type, bMember.Type) {
+			klog.V(5).Infof("%s.%s can't be compared to %s.%s", aType.Name, aMember.Name, bType.Name, bMember.Name)
+			sw.Do("// INFO: a."+aMember.Name+" not compared, of types that can't be compared\n", nil)
+			continue
+		}
+		e.writeEquality(aMember.Type, bMember.Type, "a."+aMember.Name, "b."+bMember.Name, 0, sw)
+	}
+}
+
+// comparedFieldConversions are the kinds of field conversions that keep values comparable, see
+// writeStructEquality.
+var comparedFieldConversions = map[FieldConversionKind]bool{
+	FieldDirect:            true,
+	FieldCast:              true,
+	FieldUnsafe:            true,
+	FieldGeneratedFunction: true,
+	FieldManualFunction:    true,
+	FieldElements:          true,
+	FieldRenamed:           true,
+}
+
+// writeScaledEquality writes code returning false unless b, of numeric type bType, is a scaled as
+// per conversion, see doScaledField.
+func (e *EqualityGenerator) writeScaledEquality(bType *types.Type, a, b string, conversion fieldConversion, sw *generator.SnippetWriter) {
+	args := generator.Args{
+		"a":       a,
+		"b":       b,
+		"outType": bType,
+		"factor":  conversion.scale,
+	}
+	scaled := "$.outType|" + rawNamer + "$($.a$) * $.factor$"
+	if conversion.divided {
+		scaled = "$.outType|" + rawNamer + "$($.a$ / $.factor$)"
+	}
+	sw.Do("if "+scaled+" != $.b$ {\n", args)
+	sw.Do("return false\n", nil)
+	sw.Do("}\n", nil)
+}
+
+// comparable returns true iff values of aType can be compared to values of bType, see
+// writeEquality.
+func (e *EqualityGenerator) comparable(aType, bType *types.Type) bool {
+	if IsFuncOrChan(aType) || IsFuncOrChan(bType) {
+		return false
+	}
+	if aType == bType {
+		return true
+	}
+	aUnderlying, bUnderlying := unwrapAlias(aType), unwrapAlias(bType)
+	if aUnderlying.Kind != bUnderlying.Kind {
+		return false
+	}
+
+	switch aUnderlying.Kind {
+	case types.Builtin:
+		return aUnderlying == bUnderlying || isNumericBuiltin(aUnderlying) && isNumericBuiltin(bUnderlying)
+	case types.Struct:
+		return e.hasEqualityFunction(aType, bType)
+	case types.Pointer, types.Slice:
+		return e.comparable(aUnderlying.Elem, bUnderlying.Elem)
+	case types.Map:
+		return e.comparableKeys(aUnderlying.Key, bUnderlying.Key) && e.comparable(aUnderlying.Elem, bUnderlying.Elem)
+	default:
+		return false
+	}
+}
+
+// comparableKeys returns true iff map keys of aKey can be converted to bKey to look them up.
+func (e *EqualityGenerator) comparableKeys(aKey, bKey *types.Type) bool {
+	if aKey == bKey {
+		return true
+	}
+	aUnderlying, bUnderlying := unwrapAlias(aKey), unwrapAlias(bKey)
+	return aUnderlying.Kind == types.Builtin && aUnderlying == bUnderlying
+}
+
+// hasEqualityFunction returns true iff an equality function gets generated from aType to bType.
+func (e *EqualityGenerator) hasEqualityFunction(aType, bType *types.Type) bool {
+	peerType := e.peerFor(aType)
+	return peerType != nil && peerType == bType
+}
+
+// writeEquality writes code returning false unless a, of type aType, is equal to b, of type
+// bType; a and b must be addressable, and comparable, see comparable. depth is that of the
+// loops the code is in, to name their variables.
+func (e *EqualityGenerator) writeEquality(aType, bType *types.Type, a, b string, depth int, sw *generator.SnippetWriter) {
+	aUnderlying, bUnderlying := unwrapAlias(aType), unwrapAlias(bType)
+	args := generator.Args{
+		"a":       a,
+		"b":       b,
+		"inType":  aType,
+		"outType": bType,
+		"i":       fmt.Sprintf("i%d", depth),
+		"k":       fmt.Sprintf("k%d", depth),
+		"av":      fmt.Sprintf("av%d", depth),
+		"bv":      fmt.Sprintf("bv%d", depth),
+		"found":   fmt.Sprintf("found%d", depth),
+	}
+
+	switch aUnderlying.Kind {
+	case types.Builtin:
+		if aType == bType {
+			sw.Do("if $.a$ != $.b$ {\n", args)
+		} else {
+			sw.Do("if $.outType|"+rawNamer+"$($.a$) != $.b$ {\n", args)
+		}
+		sw.Do("return false\n", nil)
+		sw.Do("}\n", nil)
+	case types.Struct:
+		if !e.hasEqualityFunction(aType, bType) {
+			e.writeDeepEquality(args, sw)
+			return
+		}
+		sw.Do("if !"+equalityFunctionNameTemplate+"(&$.a$, &$.b$) {\n", args)
+		sw.Do("return false\n", nil)
+		sw.Do("}\n", nil)
+	case types.Pointer:
+		if e.hasEqualityFunction(aUnderlying.Elem, bUnderlying.Elem) {
+			// equality functions handle nil pointers
+			sw.Do("if !"+equalityFunctionNameTemplate+"($.a$, $.b$) {\n", argsFromType(aUnderlying.Elem, bUnderlying.Elem).With("a", a).With("b", b))
+			sw.Do("return false\n", nil)
+			sw.Do("}\n", nil)
+			return
+		}
+		sw.Do("if ($.a$ == nil) != ($.b$ == nil) {\n", args)
+		sw.Do("return false\n", nil)
+		sw.Do("}\n", nil)
+		sw.Do("if $.a$ != nil {\n", args)
+		e.writeEquality(aUnderlying.Elem, bUnderlying.Elem, "(*"+a+")", "(*"+b+")", depth, sw)
+		sw.Do("}\n", nil)
+	case types.Slice:
+		sw.Do("if len($.a$) != len($.b$) {\n", args)
+		sw.Do("return false\n", nil)
+		sw.Do("}\n", nil)
+		sw.Do("for $.i$ := range $.a$ {\n", args)
+		e.writeEquality(aUnderlying.Elem, bUnderlying.Elem, a+"["+args["i"].(string)+"]", b+"["+args["i"].(string)+"]", depth+1, sw)
+		sw.Do("}\n", nil)
+	case types.Map:
+		key := "$.k$"
+		if aUnderlying.Key != bUnderlying.Key {
+			key = "$.bKey|" + rawNamer + "$(" + key + ")"
+		}
+		sw.Do("if len($.a$) != len($.b$) {\n", args)
+		sw.Do("return false\n", nil)
+		sw.Do("}\n", nil)
+		sw.Do("for $.k$, $.av$ := range $.a$ {\n", args)
+		sw.Do("$.bv$, $.found$ := $.b$["+key+"]\n", args.With("bKey", bUnderlying.Key))
+		sw.Do("if !$.found$ {\n", args)
+		sw.Do("return false\n", nil)
+		sw.Do("}\n", nil)
+		e.writeEquality(aUnderlying.Elem, bUnderlying.Elem, args["av"].(string), args["bv"].(string), depth+1, sw)
+		sw.Do("}\n", nil)
+	default:
+		e.writeDeepEquality(args, sw)
+	}
+}
+
+// writeDeepEquality writes code returning false unless $.a$ and $.b$, of the same type, are
+// deeply equal.
+func (e *EqualityGenerator) writeDeepEquality(args generator.Args, sw *generator.SnippetWriter) {
+	sw.Do("if !$.DeepEqual|"+rawNamer+"$($.a$, $.b$) {\n", args.With("DeepEqual", types.Ref("reflect", "DeepEqual")))
+	sw.Do("return false\n", nil)
+	sw.Do("}\n", nil)
+}
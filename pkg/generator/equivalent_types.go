@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// loadEquivalentTypes indexes Options.EquivalentTypes, together with the pairs declared in the
+// types package's doc.go file, both ways.
+func (g *Generator) loadEquivalentTypes() error {
+	pairs := g.Options.EquivalentTypes
+	for _, value := range g.extractDocFileTag(g.Options.EquivalentTypesTagName) {
+		split := strings.Split(value, "=")
+		if len(split) != 2 {
+			return errors.Errorf("invalid %s tag %q in package %s, expected <pkg-path>.<name>=<pkg-path>.<name>",
+				g.Options.EquivalentTypesTagName, value, g.typesPackage.Path)
+		}
+		pairs = append(pairs, [2]string{split[0], split[1]})
+	}
+
+	g.equivalentTypes = make(map[[2]types.Name]bool)
+	for _, pair := range pairs {
+		first, err := parseQualifiedTypeName(pair[0])
+		if err != nil {
+			return err
+		}
+		second, err := parseQualifiedTypeName(pair[1])
+		if err != nil {
+			return err
+		}
+		g.equivalentTypes[[2]types.Name{first, second}] = true
+		g.equivalentTypes[[2]types.Name{second, first}] = true
+	}
+	return nil
+}
+
+// parseQualifiedTypeName parses a type name of the form "<pkg-path>.<name>".
+func parseQualifiedTypeName(qualified string) (types.Name, error) {
+	qualified = strings.TrimSpace(qualified)
+	index := strings.LastIndex(qualified, ".")
+	if index <= 0 || index == len(qualified)-1 || strings.Contains(qualified[index:], "/") {
+		return types.Name{}, errors.Errorf("invalid type name %q, expected <pkg-path>.<name>", qualified)
+	}
+	return types.Name{Package: qualified[:index], Name: qualified[index+1:]}, nil
+}
+
+// areEquivalent returns true iff inType and outType have been declared equivalent,
+// see Options.EquivalentTypes.
+func (g *Generator) areEquivalent(inType, outType *types.Type) bool {
+	return g.equivalentTypes[[2]types.Name{inType.Name, outType.Name}]
+}
+
+// isDirectlyAssignable returns true iff values of inType can be assigned to outType, possibly
+// with a type conversion.
+func (g *Generator) isDirectlyAssignable(inType, outType *types.Type) bool {
+	return isDirectlyAssignable(inType, outType) || g.areEquivalent(inType, outType) || g.areByteSlices(inType, outType)
+}
+
+// writeDirectAssignment writes the assignment of a directly assignable struct field, converting
+// it if its types have only been declared equivalent.
+func (g *Generator) writeDirectAssignment(inType, outType *types.Type, name string, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) {
+	if g.areEquivalent(inMemberType, outMemberType) {
+		sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+		g.explainFieldf(inType, outType, name, FieldCast, "%v and %v declared equivalent, type conversion", inMemberType, outMemberType)
+		return
+	}
+	sw.Do("out.$.name$ = in.$.name$\n", args)
+	g.explainFieldf(inType, outType, name, FieldDirect, "directly assignable, direct assignment")
+}
@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/gengo/types"
+)
+
+// ErrorWrappingPolicy decides how generated code returns the errors of the conversions it calls.
+type ErrorWrappingPolicy string
+
+const (
+	// ErrorWrappingNone returns errors as is. This is the default.
+	ErrorWrappingNone ErrorWrappingPolicy = ""
+	// ErrorWrappingFmt wraps errors with the path of the field being converted, e.g.
+	//    return fmt.Errorf("Spec.Template: %w", err)
+	// Errors from outside of any field, e.g. converting the elements of slice types, are returned
+	// as is.
+	ErrorWrappingFmt ErrorWrappingPolicy = "fmt"
+	// ErrorWrappingFunction wraps errors by calling Options.ErrorWrappingFunction.
+	ErrorWrappingFunction ErrorWrappingPolicy = "function"
+)
+
+// returnErr returns the statement returning err from the current function, after a failure
+// converting the current field: wrapped as per Options.ErrorWrappingPolicy, and traced if enabled.
+// Loop helpers' bodies are shared between fields and functions, so their errors get wrapped and
+// traced where they're called from instead.
+func (g *Generator) returnErr() string {
+	if g.inLoopHelper {
+		return "return err"
+	}
+
+	field := strings.Join(g.fieldPath, ".")
+	err := "err"
+	switch g.Options.ErrorWrappingPolicy {
+	case ErrorWrappingFmt:
+		if field != "" {
+			err = fmt.Sprintf("%s(%s, err)", g.rawName(types.Ref("fmt", "Errorf")), strconv.Quote(field+": %w"))
+		}
+	case ErrorWrappingFunction:
+		err = fmt.Sprintf("%s(err, %s)", g.functionExpression(g.Options.ErrorWrappingFunction), strconv.Quote(field))
+	}
+
+	if g.tracing() {
+		err = fmt.Sprintf("%s(%s, %s, %s)", traceFailureHelper, strconv.Quote(g.currentFunction.Name.Name), strconv.Quote(field), err)
+	}
+	return "return " + err
+}
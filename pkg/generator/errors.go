@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/types"
+)
+
+// The errors below categorize failures, so that embedders can branch on them with errors.Is and
+// errors.As rather than by matching messages; they're part of this package's stable API.
+var (
+	// ErrPeerPackageNotFound is what errors from failing to load a peer package are, e.g. when
+	// building generators.
+	ErrPeerPackageNotFound = errors.New("peer package not found")
+	// ErrManualConversionSignature is what errors from functions named as conversion functions,
+	// but not signed as such, are.
+	ErrManualConversionSignature = errors.New("manual conversion function does not match expected conversion signature")
+)
+
+// An IncompleteConversionError is a conversion for which no public conversion function could be
+// generated, see Generator.IncompleteConversions.
+type IncompleteConversionError struct {
+	InType  *types.Type
+	OutType *types.Type
+	// Reasons are why the conversion couldn't be generated, typically one per field.
+	Reasons []string
+}
+
+func (e *IncompleteConversionError) Error() string {
+	return fmt.Sprintf("could not generate a public conversion function for %v -> %v, manual conversions needed: %s",
+		e.InType, e.OutType, strings.Join(e.Reasons, "; "))
+}
+
+// IncompleteConversions returns the conversions that no public function could be generated for
+// so far, as errors; see ManualConversionsNeeded.
+func (g *Generator) IncompleteConversions() []*IncompleteConversionError {
+	incomplete := make([]*IncompleteConversionError, 0, len(g.manualConversionsNeeded))
+	for _, needed := range g.manualConversionsNeeded {
+		incomplete = append(incomplete, &IncompleteConversionError{
+			InType:  needed.InType,
+			OutType: needed.OutType,
+			Reasons: needed.Reasons,
+		})
+	}
+	return incomplete
+}
+
+// categorizedError is an error that is of the given category, with its own message.
+type categorizedError struct {
+	error
+	category error
+}
+
+func (e *categorizedError) Is(target error) bool {
+	return target == e.category
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.error
+}
+
+// packageLoadError is an error from failing to load a package, see ErrPeerPackageNotFound.
+type packageLoadError struct {
+	error
+}
+
+func (e *packageLoadError) Unwrap() error {
+	return e.error
+}
+
+// multiError is a list of errors, along with a message, that is all of their categories.
+type multiError struct {
+	message string
+	errors  []error
+}
+
+func (e *multiError) Error() string {
+	messages := make([]string, 0, len(e.errors)+1)
+	messages = append(messages, e.message)
+	for _, err := range e.errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *multiError) Is(target error) bool {
+	for _, err := range e.errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *multiError) As(target interface{}) bool {
+	for _, err := range e.errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"k8s.io/gengo/types"
+)
+
+// An Explainer collects the decisions that generators make about specific types: whether a peer
+// type was found and where, whether the type was selected for conversion generation and why not,
+// which tags applied, and why each field got the code it did.
+// An explainer can be shared between generators.
+type Explainer struct {
+	// names are the names of the types to explain, either qualified ("<pkg-path>.<name>")
+	// or not.
+	names []string
+
+	// traces are the decisions made so far, indexed by qualified type name.
+	traces map[string][]string
+	// order is the order in which types were first explained.
+	order []string
+}
+
+// NewExplainer builds a new Explainer, for the types with the given names - either qualified
+// ("<pkg-path>.<name>") or not.
+func NewExplainer(names ...string) *Explainer {
+	return &Explainer{
+		names:  names,
+		traces: make(map[string][]string),
+	}
+}
+
+func (e *Explainer) explains(t *types.Type) bool {
+	for _, name := range e.names {
+		if name == t.Name.Name || name == t.Name.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Explainer) add(t *types.Type, message string) {
+	key := t.Name.String()
+	if _, present := e.traces[key]; !present {
+		e.order = append(e.order, key)
+	}
+	e.traces[key] = append(e.traces[key], message)
+}
+
+// Write writes a human-readable version of the decisions made so far; as well as a note for
+// each name that didn't match any type seen by generators.
+func (e *Explainer) Write(w io.Writer) error {
+	buffer := &bytes.Buffer{}
+	for _, key := range e.order {
+		fmt.Fprintf(buffer, "%s:\n", key)
+		for _, message := range e.traces[key] {
+			fmt.Fprintf(buffer, "  - %s\n", message)
+		}
+	}
+
+	for _, name := range e.names {
+		found := false
+		for _, key := range e.order {
+			if name == key || hasNameSuffix(key, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(buffer, "%s: not found in any input package, nor as a peer of an input type\n", name)
+		}
+	}
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// hasNameSuffix returns true iff qualifiedName is the qualified version of name.
+func hasNameSuffix(qualifiedName, name string) bool {
+	return len(qualifiedName) > len(name) && qualifiedName[len(qualifiedName)-len(name)-1:] == "."+name
+}
+
+// explainf records a decision about type t, if it needs to be explained.
+func (g *Generator) explainf(t *types.Type, format string, args ...interface{}) {
+	if g.inDeepCopyHelper || g.Options.Explainer == nil || t == nil || !g.Options.Explainer.explains(t) {
+		return
+	}
+	g.Options.Explainer.add(t, fmt.Sprintf(format, args...))
+}
+
+// explainConversionf records a decision about the conversion from inType to outType, for
+// whichever of them needs to be explained.
+func (g *Generator) explainConversionf(inType, outType *types.Type, format string, args ...interface{}) {
+	if g.inDeepCopyHelper || g.Options.Explainer == nil {
+		return
+	}
+	message := fmt.Sprintf("%v -> %v: ", inType, outType) + fmt.Sprintf(format, args...)
+	for _, t := range []*types.Type{inType, outType} {
+		if g.Options.Explainer.explains(t) {
+			g.Options.Explainer.add(t, message)
+		}
+	}
+}
+
+// explainFieldf records a decision about the conversion of inType's field to outType, for
+// whichever of them needs to be explained. Final decisions also have the kind of conversion they
+// lead to, which gets recorded for equality functions, and documented if Options.FieldDocs is set;
+// intermediate ones have none.
+func (g *Generator) explainFieldf(inType, outType *types.Type, field string, kind FieldConversionKind, format string, args ...interface{}) {
+	if g.inDeepCopyHelper {
+		return
+	}
+	if kind != "" {
+		g.recordFieldConversion(inType, outType, field, fieldConversion{kind: kind})
+	}
+	if g.Options.FieldDocs != nil && kind != "" {
+		g.Options.FieldDocs.add(inType, outType, field, kind, fmt.Sprintf(format, args...))
+	}
+	if g.Options.Explainer == nil {
+		return
+	}
+	g.explainConversionf(inType, outType, "field %s: %s", field, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// The helpers below are meant for wrapper generators, e.g. ones added with the converter's
+// ExtraGenerators, so that they don't need to duplicate this package's naming logic; they're
+// part of this package's stable API, and won't change in incompatible ways.
+
+const (
+	// SnippetDelimiter is the delimiter used in snippets of generated code.
+	SnippetDelimiter = snippetDelimiter
+	// RawNamer is the name, in the name systems returned by Generator.Namers, of the namer
+	// rendering types as they're referred to in the output package, tracking their imports.
+	RawNamer = rawNamer
+	// PublicNamer is the name, in the name systems returned by Generator.Namers, of the namer
+	// rendering types as they appear in conversion function names, tracking their imports.
+	PublicNamer = publicImportTrackingNamer
+)
+
+// FunctionNameFor returns the name of the public conversion function from in to out,
+// e.g. "Convert_v1_Foo_To_v2_Foo"; same as ConversionFunctionName.
+func FunctionNameFor(in, out *types.Type) string {
+	return ConversionFunctionName(in, out)
+}
+
+// FunctionNameTemplate returns a snippet rendering the name of the public conversion function
+// from $.inType$ to $.outType$ with the given namer - e.g. PublicNamer - see Args.
+func FunctionNameTemplate(namer string) string {
+	return conversionFunctionNameTemplate(namer)
+}
+
+// Args returns the snippet arguments for a conversion from in to out, as used by
+// FunctionNameTemplate: in as "inType", and out as "outType".
+func Args(in, out *types.Type) generator.Args {
+	return argsFromType(in, out)
+}
@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doExprField writes the conversion of a struct field to an out member that has a
+// "+<tag-name>=expr:<expression>" tag, if any; returns true iff it did.
+// The expression is assigned to the out field as is, after replacing the following placeholders:
+//   - "$name$" with the field's name
+//   - "$in$" with the in field, i.e. "in.$name$"
+//
+// References to other packages, e.g. "strings.ToLower", get imported in generated files: they're
+// resolved from the out type package's imports, or else assumed to be standard library packages.
+func (g *Generator) doExprField(inType, outType *types.Type, inMember, outMember *types.Member, sw *generator.SnippetWriter) (bool, error) {
+	present, expression := g.hasTagOption(outMember.CommentLines, "expr")
+	if !present {
+		return false, nil
+	}
+
+	expression = strings.NewReplacer("$in$", "in."+inMember.Name, "$name$", inMember.Name).Replace(expression)
+	snippet, args, err := g.exprSnippet(expression, outType.Name.Package)
+	if err != nil {
+		return true, errors.Wrapf(err, "invalid expr tag on %s.%s", outType.Name, outMember.Name)
+	}
+
+	sw.Do("out."+outMember.Name+" = "+snippet+"\n", args)
+	g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "converted with expression %s", expression)
+	return true, nil
+}
+
+// exprSnippet turns a Go expression into a snippet rendering it, where references to other
+// packages are rendered by the raw namer so that they get imported.
+func (g *Generator) exprSnippet(expression, pkgPath string) (string, generator.Args, error) {
+	if strings.Contains(expression, snippetDelimiter) {
+		return "", nil, errors.Errorf("unknown placeholder in %q", expression)
+	}
+	parsed, err := parser.ParseExpr(expression)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// ParseExpr's positions are offsets in expression, starting at 1
+	type reference struct {
+		start, end int
+		ref        *types.Type
+	}
+	var references []reference
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkgIdent, ok := selector.X.(*ast.Ident)
+		if !ok || pkgIdent.Name == "in" || pkgIdent.Name == "out" {
+			return true
+		}
+		references = append(references, reference{
+			start: int(selector.Pos()) - 1,
+			end:   int(selector.End()) - 1,
+			ref:   types.Ref(g.resolveImport(pkgPath, pkgIdent.Name), selector.Sel.Name),
+		})
+		return false
+	})
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].start > references[j].start
+	})
+
+	args := generator.Args{}
+	for i, reference := range references {
+		key := fmt.Sprintf("ref%d", i)
+		args[key] = reference.ref
+		expression = expression[:reference.start] + snippetDelimiter + "." + key + "|" + rawNamer + snippetDelimiter + expression[reference.end:]
+	}
+	return expression, args, nil
+}
+
+// resolveImport returns the path of the package that pkgPath imports as name, assuming it's a
+// standard library package if there is none. Imported packages that weren't parsed have no name,
+// and are assumed to be named after the last element of their path.
+func (g *Generator) resolveImport(pkgPath, name string) string {
+	if pkg := g.universe[pkgPath]; pkg != nil {
+		for importPath, imported := range pkg.Imports {
+			if imported.Name == name || imported.Name == "" && path.Base(importPath) == name {
+				return importPath
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// An ExternalConversionsHandlerFunc is a function that can be used as
+// Options.ExternalConversionsHandler; the ones below are ready-made ones.
+type ExternalConversionsHandlerFunc = func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult
+
+// CallConversionFunction returns a handler calling the conversion function named after the
+// conventions of this generator, e.g. Convert_a_X_To_b_Y, from the package at pkgPath - or from
+// inVar.Type's package if empty - with the generator's additional arguments; assuming it exists.
+// Conversions of unnamed types are skipped.
+func CallConversionFunction(pkgPath string) ExternalConversionsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+		functionPkgPath := pkgPath
+		if functionPkgPath == "" {
+			functionPkgPath = inVar.Type.Name.Package
+		}
+		if functionPkgPath == "" {
+			// unnamed types have neither a package nor a conventional conversion function
+			return Skipped()
+		}
+		function := handlerContext.QualifiedName(functionPkgPath, ConversionFunctionName(inVar.Type, outVar.Type))
+
+		arguments := []string{inVar.Name, outVar.Name}
+		for _, argument := range handlerContext.AdditionalArguments {
+			arguments = append(arguments, argument.Name)
+		}
+		writeErrorCheck(fmt.Sprintf("%s(%s)", function, strings.Join(arguments, ", ")), sw)
+		return Handled()
+	}
+}
+
+// DelegateToScope returns a handler delegating conversions to the additional argument with the
+// given name, e.g. a k8s.io/apimachinery/pkg/conversion.Scope, by calling its
+// Convert(in, out interface{}) error method.
+func DelegateToScope(scope string) ExternalConversionsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+		writeErrorCheck(fmt.Sprintf("%s.Convert(%s, %s)", scope, inVar.Name, outVar.Name), sw)
+		return Handled()
+	}
+}
+
+// JSONRoundTrip is a handler converting values by marshalling them to JSON, then unmarshalling
+// the result; which works for types with compatible JSON representations, at a runtime cost.
+func JSONRoundTrip(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+	marshal := handlerContext.QualifiedName("encoding/json", "Marshal")
+	unmarshal := handlerContext.QualifiedName("encoding/json", "Unmarshal")
+	sw.Do(fmt.Sprintf("if data, err := %s(%s); err != nil {\n", marshal, inVar.Name), nil)
+	sw.Do("return err\n", nil)
+	sw.Do(fmt.Sprintf("} else if err := %s(data, %s); err != nil {\n", unmarshal, outVar.Name), nil)
+	sw.Do("return err\n", nil)
+	sw.Do("}\n", nil)
+	return Handled()
+}
+
+// TodoComment is a handler writing a TODO comment where the conversion needs to be written
+// manually, and failing.
+func TodoComment(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+	sw.Do(fmt.Sprintf("// TODO: convert %s (%v) to %s (%v) manually\n", inVar.Name, inVar.Type, outVar.Name, outVar.Type), nil)
+	return Failedf("%v to %v requires manual conversion", inVar.Type, outVar.Type)
+}
+
+// IgnoreConversion is a handler leaving the variables unconverted, silently. It can also be used
+// as Options.UnsupportedTypesHandler.
+func IgnoreConversion(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+	markUsed(handlerContext, inVar, sw)
+	return Handled()
+}
+
+// CommentConversion is a handler writing a warning comment where the conversion would be, and
+// otherwise leaving the variables unconverted. It can also be used as
+// Options.UnsupportedTypesHandler.
+func CommentConversion(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+	sw.Do(fmt.Sprintf("// WARNING: %s (%v) is not converted to %s (%v)\n", inVar.Name, inVar.Type, outVar.Name, outVar.Type), nil)
+	markUsed(handlerContext, inVar, sw)
+	return Handled()
+}
+
+// markUsed makes sure that the loop variable that inVar refers to, for slice items, is used even
+// though no code converts inVar.
+func markUsed(handlerContext *HandlerContext, inVar NamedVariable, sw *generator.SnippetWriter) {
+	if depth := len(handlerContext.Nesting); depth != 0 && handlerContext.Nesting[depth-1] == types.Slice {
+		sw.Do(fmt.Sprintf("_ = %s\n", inVar.Name), nil)
+	}
+}
+
+// writeErrorCheck writes a call to a function returning an error, returning it if not nil.
+func writeErrorCheck(call string, sw *generator.SnippetWriter) {
+	sw.Do(fmt.Sprintf("if err := %s; err != nil {\n", call), nil)
+	sw.Do("return err\n", nil)
+	sw.Do("}\n", nil)
+}
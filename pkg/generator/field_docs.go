@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"k8s.io/gengo/types"
+)
+
+// A FieldConversionKind is how a struct field gets converted, see FieldDocs.
+type FieldConversionKind string
+
+const (
+	// FieldDirect fields are assigned as is.
+	FieldDirect FieldConversionKind = "direct"
+	// FieldCast fields are assigned with a type conversion.
+	FieldCast FieldConversionKind = "cast"
+	// FieldUnsafe fields are assigned with an unsafe cast, their types having the same memory layout.
+	FieldUnsafe FieldConversionKind = "unsafe"
+	// FieldGeneratedFunction fields are converted by a generated conversion function.
+	FieldGeneratedFunction FieldConversionKind = "generated function"
+	// FieldManualFunction fields are converted by a manual conversion function.
+	FieldManualFunction FieldConversionKind = "manual function"
+	// FieldElements fields are maps, slices or pointers converted element by element.
+	FieldElements FieldConversionKind = "elements"
+	// FieldTransformed fields are converted according to tags or options, e.g. with expressions,
+	// scaling, or defaults for nil pointers.
+	FieldTransformed FieldConversionKind = "transformed"
+	// FieldRenamed fields are converted to peer fields with other names, see Options.FieldMappings.
+	FieldRenamed FieldConversionKind = "renamed"
+	// FieldDropped fields are deliberately not converted, e.g. as they opted out.
+	FieldDropped FieldConversionKind = "dropped"
+	// FieldExternal fields have types whose conversions are left to Options.ExternalConversionsHandler.
+	FieldExternal FieldConversionKind = "external"
+	// FieldUnconverted fields couldn't be converted automatically, e.g. as they don't exist in the
+	// peer type: they're left to the corresponding handlers, or require manual conversion.
+	FieldUnconverted FieldConversionKind = "unconverted"
+)
+
+// FieldDocs collect how generators convert each struct field, for each pair of types; so that
+// they can be documented, e.g. for API reviews or upgrade notes. They're collected along with the
+// explanations of Explainer, and can be safely shared between generators.
+type FieldDocs struct {
+	// pairs are the documented pairs, indexed by the names of their types.
+	pairs map[[2]string]*TypePairDoc
+	// order is the order in which pairs were first documented.
+	order [][2]string
+}
+
+// A TypePairDoc documents the conversion from InType to OutType.
+type TypePairDoc struct {
+	InType, OutType *types.Type
+	// Fields are InType's fields, in order; followed by OutType's fields that are set without
+	// being converted from any of InType's, see FieldMapping.
+	Fields []FieldDoc
+}
+
+// A FieldDoc documents the conversion of a struct field.
+type FieldDoc struct {
+	Name string
+	Kind FieldConversionKind
+	// Details are the details of the conversion, as explained by Explainer.
+	Details string
+}
+
+// NewFieldDocs builds a new FieldDocs.
+func NewFieldDocs() *FieldDocs {
+	return &FieldDocs{pairs: make(map[[2]string]*TypePairDoc)}
+}
+
+// Pairs returns the pairs of types documented so far, in generation order.
+func (d *FieldDocs) Pairs() []*TypePairDoc {
+	pairs := make([]*TypePairDoc, 0, len(d.order))
+	for _, key := range d.order {
+		pairs = append(pairs, d.pairs[key])
+	}
+	return pairs
+}
+
+// add records how the given field is converted; the last record for a field wins, e.g. when
+// conversions are generated again.
+func (d *FieldDocs) add(inType, outType *types.Type, field string, kind FieldConversionKind, details string) {
+	key := [2]string{inType.Name.String(), outType.Name.String()}
+	pair, present := d.pairs[key]
+	if !present {
+		pair = &TypePairDoc{InType: inType, OutType: outType}
+		d.pairs[key] = pair
+		d.order = append(d.order, key)
+	}
+
+	for i := range pair.Fields {
+		if pair.Fields[i].Name == field {
+			pair.Fields[i] = FieldDoc{Name: field, Kind: kind, Details: details}
+			return
+		}
+	}
+	pair.Fields = append(pair.Fields, FieldDoc{Name: field, Kind: kind, Details: details})
+}
+
+// WriteMarkdown writes the documentation of the pair as a Markdown document.
+func (p *TypePairDoc) WriteMarkdown(w io.Writer) error {
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "# %s to %s\n\n", p.InType, p.OutType)
+	buffer.WriteString("| Field | Conversion | Details |\n")
+	buffer.WriteString("| --- | --- | --- |\n")
+	escaper := strings.NewReplacer("|", `\|`, "\n", " ")
+	for _, field := range p.Fields {
+		fmt.Fprintf(buffer, "| `%s` | %s | %s |\n", field.Name, field.Kind, escaper.Replace(field.Details))
+	}
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// typePairDocHTML is the template of TypePairDoc.WriteHTML.
+var typePairDocHTML = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.InType}} to {{.OutType}}</title>
+</head>
+<body>
+<h1>{{.InType}} to {{.OutType}}</h1>
+<table>
+<tr><th>Field</th><th>Conversion</th><th>Details</th></tr>
+{{- range .Fields}}
+<tr><td><code>{{.Name}}</code></td><td>{{.Kind}}</td><td>{{.Details}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the documentation of the pair as an HTML document.
+func (p *TypePairDoc) WriteHTML(w io.Writer) error {
+	buffer := &bytes.Buffer{}
+	if err := typePairDocHTML.Execute(buffer, struct {
+		InType, OutType string
+		Fields          []FieldDoc
+	}{p.InType.String(), p.OutType.String(), p.Fields}); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
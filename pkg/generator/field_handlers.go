@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// A MissingFieldsHandlerFunc is a function that can be used as Options.MissingFieldsHandler;
+// the ones below are ready-made ones.
+type MissingFieldsHandlerFunc = func(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult
+
+// An InconvertibleFieldsHandlerFunc is a function that can be used as
+// Options.InconvertibleFieldsHandler; the ones below are ready-made ones.
+type InconvertibleFieldsHandlerFunc = func(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult
+
+// FirstMissingFieldsHandler returns a handler calling the given handlers in order, until one
+// of them doesn't skip.
+func FirstMissingFieldsHandler(handlers ...MissingFieldsHandlerFunc) MissingFieldsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+		for _, handler := range handlers {
+			if result := handler(handlerContext, inVar, outVar, member, sw); result.Outcome != HandlerSkipped {
+				return result
+			}
+		}
+		return Skipped()
+	}
+}
+
+// FirstInconvertibleFieldsHandler returns a handler calling the given handlers in order, until
+// one of them doesn't skip.
+func FirstInconvertibleFieldsHandler(handlers ...InconvertibleFieldsHandlerFunc) InconvertibleFieldsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+		for _, handler := range handlers {
+			if result := handler(handlerContext, inVar, outVar, inMember, outMember, sw); result.Outcome != HandlerSkipped {
+				return result
+			}
+		}
+		return Skipped()
+	}
+}
+
+// IgnoreMissingField is a handler ignoring the missing field, silently.
+func IgnoreMissingField(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	return Handled()
+}
+
+// IgnoreInconvertibleField is a handler leaving the out field alone, silently.
+func IgnoreInconvertibleField(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	return Handled()
+}
+
+// WarnMissingField is a handler writing a warning comment where the missing field would be
+// converted, and otherwise ignoring it.
+func WarnMissingField(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	sw.Do(fmt.Sprintf("// WARNING: %s.%s does not exist in peer-type, and is not converted\n", inVar.Name, member.Name), nil)
+	return Handled()
+}
+
+// WarnInconvertibleField is a handler writing a warning comment where the inconvertible field
+// would be converted, and otherwise ignoring it.
+func WarnInconvertibleField(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	sw.Do(fmt.Sprintf("// WARNING: %s.%s has inconvertible types (%v vs %v), and is not converted\n",
+		inVar.Name, inMember.Name, inMember.Type, outMember.Type), nil)
+	return Handled()
+}
+
+// ZeroInconvertibleField is a handler assigning the zero value of its type to the out field.
+func ZeroInconvertibleField(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	sw.Do(fmt.Sprintf("%s.%s = %s\n", outVar.Name, outMember.Name, zeroLiteral(handlerContext, outMember.Type)), nil)
+	return Handled()
+}
+
+// DefaultInconvertibleField is a handler assigning the expression from the out field's
+// "+<tag-name>=default-expr:<expression>" tag to it; and skipping fields without one.
+// The expression supports the same placeholders and package references as
+// "+<tag-name>=expr:<expression>" tags.
+func DefaultInconvertibleField(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	g := handlerContext.generator
+	present, expression := g.hasTagOption(outMember.CommentLines, "default-expr")
+	if !present {
+		return Skipped()
+	}
+
+	expression = strings.NewReplacer("$in$", inVar.Name+"."+inMember.Name, "$name$", inMember.Name).Replace(expression)
+	snippet, args, err := g.exprSnippet(expression, handlerContext.OutType.Name.Package)
+	if err != nil {
+		return Failed(errors.Wrapf(err, "invalid default-expr tag on %s.%s", handlerContext.OutType.Name, outMember.Name))
+	}
+	sw.Do(outVar.Name+"."+outMember.Name+" = "+snippet+"\n", args)
+	return Handled()
+}
+
+// ReportMissingField is a handler ignoring the missing field, but reporting it as needing
+// manual conversion in diagnostics and TODO reports; without logging a warning.
+func ReportMissingField(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	return reported()
+}
+
+// ReportInconvertibleField is a handler ignoring the inconvertible field, but reporting it as
+// needing manual conversion in diagnostics and TODO reports; without logging a warning.
+func ReportInconvertibleField(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	return reported()
+}
+
+// zeroLiteral returns the zero value of t, as a Go literal referred to from the output package.
+func zeroLiteral(handlerContext *HandlerContext, t *types.Type) string {
+	switch t.Kind {
+	case types.Alias:
+		if t.Underlying.Kind == types.Struct || t.Underlying.Kind == types.Array {
+			return handlerContext.RawNamer.Name(t) + "{}"
+		}
+		return zeroLiteral(handlerContext, t.Underlying)
+	case types.Builtin:
+		return zeroValue(t)
+	case types.Struct, types.Array:
+		return handlerContext.RawNamer.Name(t) + "{}"
+	default:
+		return "nil"
+	}
+}
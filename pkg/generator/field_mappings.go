@@ -0,0 +1,332 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+	"sigs.k8s.io/yaml"
+)
+
+// FieldMappings describe declaratively how to convert the fields of given pairs of types, when
+// pairing fields up by name isn't enough; see LoadFieldMappings for their format.
+type FieldMappings struct {
+	Conversions []TypeFieldMappings `json:"conversions"`
+
+	// byTypes indexes Conversions by the names of their types, including the reversed renames.
+	byTypes map[[2]string]*TypeFieldMappings
+}
+
+// TypeFieldMappings are the field mappings for the conversion of From to To, both of the form
+// "<pkg-path>.<name>".
+type TypeFieldMappings struct {
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Fields []FieldMapping `json:"fields"`
+}
+
+// A FieldMapping tells how to convert a field, by name:
+//   - From and To: From gets converted to To, e.g. for renamed fields
+//   - From and Drop: From doesn't get converted
+//   - To and Value: To gets assigned Value, a Go expression, e.g. a constant for fields dropped
+//     from the in type
+//   - To and Expr, and optionally From: To gets assigned Expr, a Go expression that can refer to
+//     the in struct as "in", and to From as "$in$", e.g. "strings.ToLower($in$)"
+//
+// References to other packages in expressions get imported as for "expr" tags, see TagName.
+// Fields without mappings still get paired up by name; except the To fields of mappings, that only
+// get converted by their mapping.
+type FieldMapping struct {
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	Drop  bool   `json:"drop,omitempty"`
+	Value string `json:"value,omitempty"`
+	Expr  string `json:"expr,omitempty"`
+}
+
+// LoadFieldMappings loads the field mappings from the YAML (or JSON) file at path, e.g.:
+//
+//	conversions:
+//	- from: example.com/api/v1.Foo
+//	  to: example.com/api/v2.Foo
+//	  fields:
+//	  - from: SpecName
+//	    to: Name
+//	  - from: Legacy
+//	    drop: true
+//	  - to: Kind
+//	    value: '"Foo"'
+//	  - from: Title
+//	    to: Label
+//	    expr: strings.ToLower($in$)
+//
+// Mappings that only rename fields also apply to the reverse conversion, e.g. from v2.Foo.Name to
+// v1.Foo.SpecName above, unless the file has mappings for that conversion too.
+// Mappings referring to types or fields that don't exist in the packages conversions get
+// generated for make generators fail, and those conversions incomplete, respectively.
+func LoadFieldMappings(path string) (*FieldMappings, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read field mappings %q", path)
+	}
+	mappings := &FieldMappings{}
+	if err := yaml.UnmarshalStrict(raw, mappings); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse field mappings %q", path)
+	}
+	if err := mappings.index(); err != nil {
+		return nil, errors.Wrapf(err, "invalid field mappings %q", path)
+	}
+	return mappings, nil
+}
+
+// index validates the mappings, and indexes them by types.
+func (m *FieldMappings) index() error {
+	m.byTypes = make(map[[2]string]*TypeFieldMappings)
+	for i := range m.Conversions {
+		conversion := &m.Conversions[i]
+		if conversion.From == "" || conversion.To == "" {
+			return errors.Errorf("conversion #%d must have both from and to types", i+1)
+		}
+		key := [2]string{conversion.From, conversion.To}
+		if _, present := m.byTypes[key]; present {
+			return errors.Errorf("duplicate mappings for %s to %s", conversion.From, conversion.To)
+		}
+		for _, field := range conversion.Fields {
+			if err := field.validate(); err != nil {
+				return errors.Wrapf(err, "invalid mapping for %s to %s", conversion.From, conversion.To)
+			}
+		}
+		m.byTypes[key] = conversion
+	}
+
+	for i := range m.Conversions {
+		conversion := &m.Conversions[i]
+		key := [2]string{conversion.To, conversion.From}
+		if _, present := m.byTypes[key]; present {
+			continue
+		}
+		reversed := &TypeFieldMappings{From: conversion.To, To: conversion.From}
+		for _, field := range conversion.Fields {
+			if field.isRename() {
+				reversed.Fields = append(reversed.Fields, FieldMapping{From: field.To, To: field.From})
+			}
+		}
+		m.byTypes[key] = reversed
+	}
+	return nil
+}
+
+func (f *FieldMapping) validate() error {
+	switch {
+	case f.Drop && (f.From == "" || f.To != "" || f.Value != "" || f.Expr != ""):
+		return errors.Errorf("%s: dropped fields must only have a from field", f)
+	case f.Value != "" && (f.From != "" || f.Expr != ""):
+		return errors.Errorf("%s: values can't have a from field, or an expression", f)
+	case !f.Drop && f.To == "":
+		return errors.Errorf("%s: missing to field", f)
+	case !f.Drop && f.From == "" && f.Value == "" && f.Expr == "":
+		return errors.Errorf("%s: missing from field, value or expression", f)
+	}
+	return nil
+}
+
+func (f *FieldMapping) isRename() bool {
+	return f.From != "" && f.To != "" && f.Expr == ""
+}
+
+func (f FieldMapping) String() string {
+	var parts []string
+	for _, part := range [][2]string{{"from", f.From}, {"to", f.To}, {"value", f.Value}, {"expr", f.Expr}} {
+		if part[1] != "" {
+			parts = append(parts, fmt.Sprintf("%s: %q", part[0], part[1]))
+		}
+	}
+	if f.Drop {
+		parts = append(parts, "drop: true")
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// checkTypes returns an error if a mapping refers to a type that doesn't exist in one of the
+// given packages; types in other packages aren't checked, as mappings files can be shared by
+// several runs.
+func (m *FieldMappings) checkTypes(universe types.Universe, packages []string) error {
+	checked := make(map[string]bool)
+	for _, path := range packages {
+		checked[path] = true
+	}
+	for _, conversion := range m.Conversions {
+		for _, name := range []string{conversion.From, conversion.To} {
+			i := strings.LastIndex(name, ".")
+			if i < 0 {
+				return errors.Errorf("field mappings for %s to %s: %q is not of the form <pkg-path>.<name>", conversion.From, conversion.To, name)
+			}
+			path, typeName := name[:i], name[i+1:]
+			if pkg := universe[path]; checked[path] && pkg != nil && pkg.Types[typeName] == nil {
+				return errors.Errorf("field mappings for %s to %s: unknown type %s", conversion.From, conversion.To, name)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldMappings returns the field mappings for the conversion of inType to outType, if any.
+func (g *Generator) fieldMappings(inType, outType *types.Type) *TypeFieldMappings {
+	if g.Options.FieldMappings == nil {
+		return nil
+	}
+	return g.Options.FieldMappings.byTypes[[2]string{inType.Name.String(), outType.Name.String()}]
+}
+
+// fromField returns the mapping of the given in field, if any.
+func (m *TypeFieldMappings) fromField(name string) (FieldMapping, bool) {
+	if m != nil {
+		for _, field := range m.Fields {
+			if field.From == name {
+				return field, true
+			}
+		}
+	}
+	return FieldMapping{}, false
+}
+
+// isTarget returns true iff the given out field is the target of a mapping.
+func (m *TypeFieldMappings) isTarget(name string) bool {
+	if m != nil {
+		for _, field := range m.Fields {
+			if field.To == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkFieldMappings returns errors for the mappings referring to fields that don't exist.
+func checkFieldMappings(inType, outType *types.Type, mappings *TypeFieldMappings) (errs []error) {
+	if mappings == nil {
+		return nil
+	}
+	for _, field := range mappings.Fields {
+		if _, found := findMember(inType, field.From); field.From != "" && !found {
+			errs = append(errs, errors.Errorf("field mapping %s: %v has no field %s", field, inType, field.From))
+		}
+		if _, found := findMember(outType, field.To); field.To != "" && !found {
+			errs = append(errs, errors.Errorf("field mapping %s: %v has no field %s", field, outType, field.To))
+		}
+	}
+	return
+}
+
+// doMappedField writes the conversion of a struct field that has a mapping, if any; returns true
+// iff it did.
+func (g *Generator) doMappedField(inType, outType *types.Type, inMember *types.Member, mappings *TypeFieldMappings, sw *generator.SnippetWriter) (bool, []error) {
+	mapping, present := mappings.fromField(inMember.Name)
+	if !present {
+		return false, nil
+	}
+	outMember, found := findMember(outType, mapping.To)
+	if !mapping.Drop && !found {
+		// already reported by checkFieldMappings
+		return true, nil
+	}
+
+	switch {
+	case mapping.Drop:
+		sw.Do("// INFO: in."+inMember.Name+" dropped by field mapping\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldDropped, "dropped by field mapping")
+		g.reportDiagnostic(DroppedConversionDiagnostic, SeverityInfo, inType, inMember.Name,
+			fmt.Sprintf("%s.%s dropped by field mapping", inType.Name, inMember.Name))
+		return true, nil
+	case mapping.Expr != "":
+		if err := g.writeMappedExpression(inType, outType, inMember, &outMember, mapping, sw); err != nil {
+			return true, []error{err}
+		}
+		return true, nil
+	default:
+		return true, g.writeRenamedField(inType, outType, inMember, &outMember, sw)
+	}
+}
+
+// doUnsourcedFieldMappings writes the conversions of the out fields that have a mapping from a value
+// or an expression, but no in field.
+func (g *Generator) doUnsourcedFieldMappings(inType, outType *types.Type, mappings *TypeFieldMappings, sw *generator.SnippetWriter) (errs []error) {
+	if mappings == nil {
+		return nil
+	}
+	for _, mapping := range mappings.Fields {
+		if mapping.From != "" {
+			continue
+		}
+		outMember, found := findMember(outType, mapping.To)
+		if !found {
+			continue
+		}
+		if err := g.writeMappedExpression(inType, outType, nil, &outMember, mapping, sw); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// writeMappedExpression writes the assignment of a mapping's value or expression to outMember.
+func (g *Generator) writeMappedExpression(inType, outType *types.Type, inMember, outMember *types.Member, mapping FieldMapping, sw *generator.SnippetWriter) error {
+	expression := mapping.Value
+	if expression == "" {
+		expression = mapping.Expr
+		if inMember != nil {
+			expression = strings.ReplaceAll(expression, "$in$", "in."+inMember.Name)
+		}
+	}
+	snippet, args, err := g.exprSnippet(expression, outType.Name.Package)
+	if err != nil {
+		return errors.Wrapf(err, "invalid field mapping %s for %v to %v", mapping, inType, outType)
+	}
+
+	sw.Do("out."+outMember.Name+" = "+snippet+"\n", args)
+	if inMember != nil {
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "converted to %s with mapped expression %s", outMember.Name, expression)
+	} else {
+		g.explainFieldf(inType, outType, outMember.Name, FieldTransformed, "peer field set to mapped expression %s", expression)
+	}
+	return nil
+}
+
+// writeRenamedField writes the conversion of inMember to outMember, fields with different names.
+func (g *Generator) writeRenamedField(inType, outType *types.Type, inMember, outMember *types.Member, sw *generator.SnippetWriter) []error {
+	inMemberType, outMemberType := inMember.Type, outMember.Type
+	in, out := "in."+inMember.Name, "out."+outMember.Name
+	g.explainFieldf(inType, outType, inMember.Name, FieldRenamed, "mapped to %s", outMember.Name)
+
+	switch {
+	case inMemberType == outMemberType:
+		sw.Do(out+" = "+in+"\n", nil)
+	case unwrapAlias(inMemberType).Kind == types.Builtin && unwrapAlias(outMemberType).Kind == types.Builtin &&
+		g.builtinConversionAllowed(inMemberType, outMemberType):
+		g.writeBuiltinConversion(inMemberType, outMemberType, in, out, nil, sw)
+	case g.isDirectlyAssignable(inMemberType, outMemberType):
+		sw.Do(out+" = $.|"+rawNamer+"$("+in+")\n", outMemberType)
+	case g.convertibleOnlyWithinPackage(inMemberType, outMemberType):
+		g.writeConversionCall(nil, inMemberType, outMemberType, "&"+in, "&"+out, inMember.Name, nil, sw)
+	case inMemberType.Kind == outMemberType.Kind &&
+		(inMemberType.Kind == types.Map || inMemberType.Kind == types.Slice || inMemberType.Kind == types.Pointer):
+		sw.Do("if "+in+" != nil {\n", nil)
+		sw.Do("in, out := &"+in+", &"+out+"\n", nil)
+		errs := g.generateFor(inMemberType, outMemberType, sw)
+		sw.Do("} else {\n", nil)
+		sw.Do(out+" = nil\n", nil)
+		sw.Do("}\n", nil)
+		return errs
+	default:
+		result := g.handleInconvertibleField(NewNamedVariable("in", inType), NewNamedVariable("out", outType), inMember, outMember, sw)
+		if err := g.reportHandlerResult(result, InconvertibleFieldDiagnostic, inType, outType, inMember.Name,
+			fmt.Sprintf("%s.%s has inconvertible types: %s VS %s for mapped field %s.%s",
+				inType.Name, inMember.Name, inMemberType, outMemberType, outType.Name, outMember.Name)); err != nil {
+			return []error{err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/types"
+)
+
+// loadFileSelection loads which files the types package's types get selected from, if restricted;
+// see Options.Files.
+func (g *Generator) loadFileSelection() error {
+	g.selectedFiles = g.extractDocFileTag(g.Options.FilesTagName)
+	if len(g.selectedFiles) == 0 {
+		g.selectedFiles = g.Options.Files
+	}
+	if len(g.selectedFiles) == 0 {
+		return nil
+	}
+	for _, pattern := range g.selectedFiles {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid file pattern %q", pattern)
+		}
+	}
+
+	// parse errors are reported early, rather than for each type
+	_, err := g.declarationsOf(g.typesPackage.Path)
+	return err
+}
+
+// notInSelectedFilesReason returns why t isn't selected by Options.Files, if it isn't.
+func (g *Generator) notInSelectedFilesReason(t *types.Type) string {
+	if len(g.selectedFiles) == 0 {
+		return ""
+	}
+	decls, err := g.declarationsOf(t.Name.Package)
+	if err != nil {
+		return err.Error()
+	}
+	file := decls.files[t.Name.Name]
+	for _, pattern := range g.selectedFiles {
+		if matched, _ := filepath.Match(pattern, file); matched {
+			return ""
+		}
+	}
+	if file == "" {
+		return "isn't declared in any of files " + strings.Join(g.selectedFiles, ", ")
+	}
+	return "is declared in " + file + ", not in any of files " + strings.Join(g.selectedFiles, ", ")
+}
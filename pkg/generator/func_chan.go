@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// FuncChanPolicy decides how values of function and channel types, which hold no data that can be
+// converted, are handled.
+type FuncChanPolicy string
+
+const (
+	// FuncChanUnsupported handles function and channel values like any other values that the
+	// generator can't convert: by the relevant handlers if set, or else with a warning.
+	// This is the default.
+	FuncChanUnsupported FuncChanPolicy = "unsupported"
+	// FuncChanSkip leaves function and channel values unconverted, without warnings: their peers
+	// keep their zero values.
+	FuncChanSkip FuncChanPolicy = "skip"
+	// FuncChanCopy copies function and channel values as is, so that their peers refer to the
+	// same functions and channels; their types must have the same signatures, or element types.
+	FuncChanCopy FuncChanPolicy = "copy"
+	// FuncChanError fails generation upon function and channel values.
+	FuncChanError FuncChanPolicy = "error"
+)
+
+// IsFuncOrChan returns true iff t is a function or channel type, possibly behind aliases: unlike
+// other types that the generator can't convert, values of such types can't hold data, see
+// Options.FuncChanPolicy. This allows handlers to tell them apart.
+func IsFuncOrChan(t *types.Type) bool {
+	switch unwrapAlias(t).Kind {
+	case types.Func, types.Chan:
+		return true
+	default:
+		return false
+	}
+}
+
+// funcChanCopiable returns true iff inType and outType are both function types with the same
+// signature, or both channel types with the same element type, so that values can be copied
+// from one to the other.
+func funcChanCopiable(inType, outType *types.Type) bool {
+	inType, outType = unwrapAlias(inType), unwrapAlias(outType)
+	if inType == outType {
+		return IsFuncOrChan(inType)
+	}
+	if inType.Kind != outType.Kind {
+		return false
+	}
+
+	switch inType.Kind {
+	case types.Chan:
+		return inType.Elem == outType.Elem
+	case types.Func:
+		in, out := inType.Signature, outType.Signature
+		return in != nil && out != nil && in.Variadic == out.Variadic &&
+			sameTypes(in.Parameters, out.Parameters) && sameTypes(in.Results, out.Results)
+	default:
+		return false
+	}
+}
+
+func sameTypes(x, y []*types.Type) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// doFuncChanField writes the conversion of struct fields of function or channel types according
+// to Options.FuncChanPolicy, and returns true iff it did; along with an error if the policy
+// forbids them.
+func (g *Generator) doFuncChanField(inType, outType *types.Type, inMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) (bool, error) {
+	if !IsFuncOrChan(inMemberType) && !IsFuncOrChan(outMemberType) {
+		return false, nil
+	}
+
+	switch g.Options.FuncChanPolicy {
+	case FuncChanSkip:
+		sw.Do("// INFO: in."+inMember.Name+" skipped, as a function or channel\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldDropped, "%s skipped by policy %q", inMemberType.Kind, g.Options.FuncChanPolicy)
+		g.reportDiagnostic(DroppedConversionDiagnostic, SeverityInfo, inType, inMember.Name,
+			fmt.Sprintf("%s.%s skipped, as a function or channel", inType.Name, inMember.Name))
+		return true, nil
+	case FuncChanCopy:
+		if !funcChanCopiable(inMemberType, outMemberType) {
+			return false, nil
+		}
+		if inMemberType == outMemberType {
+			sw.Do("out.$.name$ = in.$.name$\n", args)
+			g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "%s copied by policy %q", inMemberType.Kind, g.Options.FuncChanPolicy)
+		} else {
+			sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+			g.explainFieldf(inType, outType, inMember.Name, FieldCast, "%s copied by policy %q, type conversion", inMemberType.Kind, g.Options.FuncChanPolicy)
+		}
+		return true, nil
+	case FuncChanError:
+		g.explainFieldf(inType, outType, inMember.Name, FieldUnconverted, "%s forbidden by policy %q", inMemberType.Kind, g.Options.FuncChanPolicy)
+		return true, errors.Errorf("%s.%s is a function or channel, forbidden by policy %q", inType.Name, inMember.Name, g.Options.FuncChanPolicy)
+	default:
+		return false, nil
+	}
+}
+
+// doFuncChan writes the conversion of *in to *out, of function or channel types, according to
+// Options.FuncChanPolicy, and returns true iff it did; along with an error if the policy
+// forbids them.
+func (g *Generator) doFuncChan(inType, outType *types.Type, sw *generator.SnippetWriter) (bool, error) {
+	if !IsFuncOrChan(inType) && !IsFuncOrChan(outType) {
+		return false, nil
+	}
+
+	switch g.Options.FuncChanPolicy {
+	case FuncChanSkip:
+		return true, nil
+	case FuncChanCopy:
+		if !funcChanCopiable(inType, outType) {
+			return false, nil
+		}
+		if inType == outType {
+			sw.Do("*out = *in\n", nil)
+		} else {
+			sw.Do("*out = $.|"+rawNamer+"$(*in)\n", outType)
+		}
+		return true, nil
+	case FuncChanError:
+		return true, errors.Errorf("%s is a function or channel, forbidden by policy %q", inType.Name, g.Options.FuncChanPolicy)
+	default:
+		return false, nil
+	}
+}
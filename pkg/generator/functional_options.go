@@ -0,0 +1,81 @@
+package generator
+
+// An Option modifies Options, e.g. for programmatic configuration that only sets what it needs
+// on top of DefaultOptions; see Options for what each one does.
+type Option func(*Options)
+
+// Apply applies the given options, in order, and returns o.
+func (o *Options) Apply(options ...Option) *Options {
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// NewOptions returns DefaultOptions, with the given options applied.
+func NewOptions(options ...Option) *Options {
+	return DefaultOptions().Apply(options...)
+}
+
+// WithTagName sets Options.TagName.
+func WithTagName(tagName string) Option {
+	return func(o *Options) {
+		o.TagName = tagName
+	}
+}
+
+// WithFunctionTagName sets Options.FunctionTagName.
+func WithFunctionTagName(tagName string) Option {
+	return func(o *Options) {
+		o.FunctionTagName = tagName
+	}
+}
+
+// WithPeerPackagesTagName sets Options.PeerPackagesTagName.
+func WithPeerPackagesTagName(tagName string) Option {
+	return func(o *Options) {
+		o.PeerPackagesTagName = tagName
+	}
+}
+
+// WithReadOnlyPeerPackages adds to Options.ReadOnlyPeerPackages.
+func WithReadOnlyPeerPackages(peerPackages ...string) Option {
+	return func(o *Options) {
+		o.ReadOnlyPeerPackages = append(o.ReadOnlyPeerPackages, peerPackages...)
+	}
+}
+
+// WithoutUnsafeConversions sets Options.NoUnsafeConversions.
+func WithoutUnsafeConversions() Option {
+	return func(o *Options) {
+		o.NoUnsafeConversions = true
+	}
+}
+
+// WithTargetPlatforms adds to Options.TargetPlatforms.
+func WithTargetPlatforms(platforms ...string) Option {
+	return func(o *Options) {
+		o.TargetPlatforms = append(o.TargetPlatforms, platforms...)
+	}
+}
+
+// WithManualConversionsTracker sets Options.ManualConversionsTracker.
+func WithManualConversionsTracker(tracker *ManualConversionsTracker) Option {
+	return func(o *Options) {
+		o.ManualConversionsTracker = tracker
+	}
+}
+
+// WithLocalImportPrefix sets Options.LocalImportPrefix.
+func WithLocalImportPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.LocalImportPrefix = prefix
+	}
+}
+
+// WithPublicFunctionOnError sets Options.PublicFunctionOnError.
+func WithPublicFunctionOnError() Option {
+	return func(o *Options) {
+		o.PublicFunctionOnError = true
+	}
+}
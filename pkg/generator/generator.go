@@ -17,9 +17,15 @@ limitations under the License.
 package generator
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/gengo/generator"
@@ -46,11 +52,88 @@ type Generator struct {
 	outputPackage *types.Package
 	// peerPackages are the packages that contain the peers of typesPackage's types .
 	peerPackages []string
+	// readOnlyPeerPackages are the peer packages that conversions must not write into.
+	readOnlyPeerPackages map[string]bool
+	// noUnsafeConversions is true if typesPackage's doc.go file has a "+<tag-name>=no-unsafe" tag,
+	// see unsafeConversionsDisabled.
+	noUnsafeConversions bool
 	// unsafeConversionArbitrator allows comparing types' memory layouts to decide whether
 	// to use unsafe conversions.
 	unsafeConversionArbitrator *unsafeConversionArbitrator
-	// peerTypes caches the peer types found so far.
-	peerTypes map[string]*types.Type
+	// peerTypes caches the peer types found so far, indexed by the names of the types they are
+	// peers of.
+	peerTypes map[types.Name]*types.Type
+	// smallStructs caches whether structs are small enough for public conversion functions to take
+	// them by value, see Options.ValueInputMaxSize.
+	smallStructs map[*types.Type]bool
+	// universe is used to locate types' packages when reporting diagnostics.
+	universe types.Universe
+	// manualConversionsNeeded are the conversions that no public function could be generated for.
+	manualConversionsNeeded []ManualConversionNeeded
+	// todos are the conversions that were left for manual conversion, see TodoItems.
+	todos []TodoItem
+	// publicFunctions are the public conversion functions available in the output package,
+	// indexed by the type from the types package they involve; see PublicConversionFunctions.
+	publicFunctions map[*types.Type][]*types.Type
+	// publicConversions are the conversions that publicFunctions are for, in generation order.
+	publicConversions []ConversionPair
+	// castConversions are the conversions that get no functions, as they're unsafe casts; see
+	// Options.PruneCastableConversions.
+	castConversions []ConversionPair
+	// sharingGenerators are the generators generating into the same output package as this one,
+	// this one included, if any; see ShareOutputPackage.
+	sharingGenerators []*Generator
+	// currentFunction is the private conversion function being generated, from currentInType to
+	// currentOutType.
+	currentFunction               *types.Type
+	currentInType, currentOutType *types.Type
+	// fieldPath and nesting are where code is being generated in the current function, see
+	// HandlerContext.
+	fieldPath []string
+	nesting   []types.Kind
+	// usedGenericHelpers are the generic helpers that generated code calls, see GenericHelpers.
+	usedGenericHelpers map[string]bool
+	// loopHelpers are the loop helpers that generated code calls, see DeduplicateLoops; and
+	// loopHelperNames their names, indexed by body.
+	loopHelpers     []loopHelper
+	loopHelperNames map[string]string
+	// inLoopHelper is true while generating the body of a loop or deep-copy helper.
+	inLoopHelper bool
+	// deepCopying is true while generating code that mustn't share memory between in and out, see
+	// forcesDeepCopy; and inDeepCopyHelper while generating the body of a deep-copy helper.
+	deepCopying, inDeepCopyHelper bool
+	// deepCopyHelpers are the deep-copy helpers that generated code calls, see writeDeepCopyCall;
+	// and deepCopyHelperNames their names, indexed by the types they convert between.
+	deepCopyHelpers     []loopHelper
+	deepCopyHelperNames map[[2]types.Name]string
+	// traced is true once generated code calls the trace helpers, see TraceBuildTag.
+	traced bool
+	// equivalentTypes are the pairs of types declared equivalent, both ways; see EquivalentTypes.
+	equivalentTypes map[[2]types.Name]bool
+	// selectedTypes are the types of the types package that conversions can be generated for, if
+	// restricted by ImplementersOf.
+	selectedTypes map[*types.Type]bool
+	// selectedFiles are the patterns of the files that types get selected from, if restricted by
+	// Files.
+	selectedFiles []string
+	// declarations are where the types of the packages parsed so far are declared, by package
+	// path; see declarationsOf.
+	declarations map[string]*declarations
+	// buildConstraint is the build constraint of the conversions that get generated, if
+	// restricted; see RestrictToBuildConstraint.
+	buildConstraint *string
+	// fieldConversions are how struct fields got converted so far, indexed by the names of the
+	// types of their conversions and then by field name; see recordFieldConversion.
+	fieldConversions map[[2]types.Name]map[string]fieldConversion
+	// plannedConversions are the conversions generated so far, see PlannedConversions.
+	plannedConversions []PlannedConversion
+	// context is the context of the type being generated.
+	context *generator.Context
+	// tempNames names generated code's temporary variables, see temp.
+	tempNames *tempNames
+	// templateErr is the first error executing templates since GenerateType or Finalize last
+	// returned, see executeTemplate.
+	templateErr error
 }
 
 // NewConversionGenerator builds a new Generator.
@@ -58,6 +141,9 @@ func NewConversionGenerator(context *generator.Context, outputFileName, typesPac
 	if options == nil {
 		options = DefaultOptions()
 	}
+	if err := options.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid options")
+	}
 	if options.ManualConversionsTracker == nil {
 		options.ManualConversionsTracker = NewManualConversionsTracker()
 	}
@@ -70,6 +156,19 @@ func NewConversionGenerator(context *generator.Context, outputFileName, typesPac
 	if err != nil {
 		return nil, err
 	}
+	if err := checkPeerTags(typesPkg, options.TagName); err != nil {
+		return nil, err
+	}
+
+	if options.PrivateFunctionPrefix == "" {
+		options.PrivateFunctionPrefix = DefaultPrivateFunctionPrefix
+	}
+	options.ManualConversionsTracker.addPrivateFunctionPrefix(options.PrivateFunctionPrefix)
+
+	unsafeConversionArbitrator, err := newUnsafeConversionArbitrator(options.ManualConversionsTracker, options.TargetPlatforms)
+	if err != nil {
+		return nil, err
+	}
 
 	g := &Generator{
 		DefaultGen: generator.DefaultGen{
@@ -82,21 +181,110 @@ func NewConversionGenerator(context *generator.Context, outputFileName, typesPac
 		typesPackage:  typesPkg,
 		outputPackage: oututPkg,
 
-		unsafeConversionArbitrator: newUnsafeConversionArbitrator(options.ManualConversionsTracker),
-		peerTypes:                  make(map[string]*types.Type),
+		unsafeConversionArbitrator: unsafeConversionArbitrator,
+		peerTypes:                  make(map[types.Name]*types.Type),
+		smallStructs:               make(map[*types.Type]bool),
+		universe:                   context.Universe,
+		publicFunctions:            make(map[*types.Type][]*types.Type),
+		usedGenericHelpers:         make(map[string]bool),
+		loopHelperNames:            make(map[string]string),
+		deepCopyHelperNames:        make(map[[2]types.Name]string),
+		declarations:               make(map[string]*declarations),
 	}
+	unsafeConversionArbitrator.transformsValue = g.transformsValue
 
-	// get peer packages from the package's doc.go file, if any
-	g.peerPackages = append(g.extractDocFileTag(options.PeerPackagesTagName), peerPackages...)
+	reservedNames := append([]string{}, options.ReservedNames...)
+	for _, namedArgument := range options.ManualConversionsTracker.additionalConversionArguments {
+		reservedNames = append(reservedNames, namedArgument.Name)
+	}
+	g.tempNames = newTempNames(reservedNames...)
+
+	// get peer packages and package-level options from the package's doc.go file, if any
+	docPeerPackages, packageOptions := g.docFileTags()
+	g.noUnsafeConversions = packageOptions[noUnsafeTag]
+	g.peerPackages = append(docPeerPackages, peerPackages...)
 
+	g.readOnlyPeerPackages = make(map[string]bool)
+	for _, pkg := range append(g.extractDocFileTag(options.ReadOnlyPeerPackagesTagName), options.ReadOnlyPeerPackages...) {
+		g.readOnlyPeerPackages[pkg] = true
+	}
+
+	if err := g.loadEquivalentTypes(); err != nil {
+		return nil, err
+	}
+	if err := g.loadImplementersSelection(context); err != nil {
+		return nil, err
+	}
+	if err := g.loadFileSelection(); err != nil {
+		return nil, err
+	}
+
+	// per-type peer packages also need to be loaded, see GetPeerTypeFor
+	allPeerPackages := append(append([]string{}, g.peerPackages...), g.typePeerPackages()...)
+	isPeerPackage := make(map[string]bool)
+	for _, peerPackage := range allPeerPackages {
+		isPeerPackage[peerPackage] = true
+	}
+	if options.FieldMappings != nil {
+		if err := options.FieldMappings.checkTypes(context.Universe, append(allPeerPackages, typesPackage)); err != nil {
+			return nil, err
+		}
+	}
 	if err := findManualConversionFunctions(context, options.ManualConversionsTracker,
-		append(g.peerPackages, outputPackage, typesPackage)); err != nil {
+		append(allPeerPackages, outputPackage, typesPackage), isPeerPackage); err != nil {
 		return nil, err
 	}
 
 	return g, nil
 }
 
+// PeerPackages returns the packages this generator looks for peer types in.
+func (g *Generator) PeerPackages() []string {
+	return g.peerPackages
+}
+
+// HeaderOverride returns the boilerplate header that the types package's doc.go file asks to use
+// instead of the converter-wide one, if any (see Options.HeaderTagName and Options.HeaderFileTagName).
+// Occurrences of "YEAR" are replaced with the current 4-digit year.
+func (g *Generator) HeaderOverride() ([]byte, bool, error) {
+	var header []byte
+	if lines := g.extractDocFileTag(g.Options.HeaderTagName); len(lines) != 0 {
+		header = []byte(strings.Join(lines, "\n"))
+	} else if files := g.extractDocFileTag(g.Options.HeaderFileTagName); len(files) != 0 {
+		if len(files) != 1 {
+			return nil, false, fmt.Errorf("package %q has several %q tags", g.typesPackage.Path, g.Options.HeaderFileTagName)
+		}
+		path := files[0]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(g.typesPackage.SourcePath, path)
+		}
+		var err error
+		if header, err = ioutil.ReadFile(path); err != nil {
+			return nil, false, errors.Wrapf(err, "unable to read header file for package %q", g.typesPackage.Path)
+		}
+	} else {
+		return nil, false, nil
+	}
+
+	header = bytes.Replace(header, []byte("YEAR"), []byte(strconv.Itoa(time.Now().UTC().Year())), -1)
+	header = append(bytes.TrimRight(header, "\n"), '\n', '\n')
+	return header, true, nil
+}
+
+// ReExportPackage returns the package that the types package's doc.go file asks to re-export
+// the public conversion functions from, if any (see Options.ReExportPackageTagName).
+func (g *Generator) ReExportPackage() (string, error) {
+	packages := g.extractDocFileTag(g.Options.ReExportPackageTagName)
+	switch len(packages) {
+	case 0:
+		return "", nil
+	case 1:
+		return packages[0], nil
+	default:
+		return "", fmt.Errorf("package %q has several %q tags", g.typesPackage.Path, g.Options.ReExportPackageTagName)
+	}
+}
+
 // TODO wkpo need to be quite that verbose?
 func getPackage(context *generator.Context, pkgPath string) (*types.Package, error) {
 	pkg := context.Universe[pkgPath]
@@ -107,14 +295,18 @@ func getPackage(context *generator.Context, pkgPath string) (*types.Package, err
 	return pkg, errors.Wrapf(err, "unable to load package %q", pkgPath)
 }
 
-func findManualConversionFunctions(context *generator.Context, tracker *ManualConversionsTracker, packagePaths []string) error {
+// findManualConversionFunctions looks for manual conversion functions in the given packages;
+// failing to load peer packages is an ErrPeerPackageNotFound.
+func findManualConversionFunctions(context *generator.Context, tracker *ManualConversionsTracker, packagePaths []string, peerPackages map[string]bool) error {
 	for _, packagePath := range packagePaths {
-		if errors := tracker.findManualConversionFunctions(context, packagePath); len(errors) != 0 {
-			errMsg := "Errors when looking for manual conversion functions in " + packagePath + ":"
-			for _, err := range errors {
-				errMsg += "\n" + err.Error()
+		if errs := tracker.findManualConversionFunctions(context, packagePath); len(errs) != 0 {
+			for i, err := range errs {
+				var loadErr *packageLoadError
+				if peerPackages[packagePath] && errors.As(err, &loadErr) {
+					errs[i] = &categorizedError{err, ErrPeerPackageNotFound}
+				}
 			}
-			return fmt.Errorf(errMsg)
+			return &multiError{message: "Errors when looking for manual conversion functions in " + packagePath + ":", errors: errs}
 		}
 	}
 	return nil
@@ -150,12 +342,64 @@ func (n *namerPlusImportTracking) Name(t *types.Type) string {
 
 // Filter filters the types this generator operates on.
 func (g *Generator) Filter(context *generator.Context, t *types.Type) bool {
+	// other generators may share the output package, see ShareOutputPackage
+	if t.Name.Package != g.typesPackage.Path {
+		return false
+	}
+
+	if tags := g.extractTag(t.CommentLines); len(tags) != 0 {
+		g.explainf(t, "tags: %v", tags)
+	}
+
 	peerType := g.GetPeerTypeFor(context, t)
-	return peerType != nil && g.convertibleOnlyWithinPackage(t, peerType)
+	if peerType == nil {
+		g.explainf(t, "skipped: no peer type found")
+		return false
+	}
+	if reason := g.notConvertibleReason(t, peerType); reason != "" {
+		g.explainf(t, "skipped: %s", reason)
+		return false
+	}
+	if g.generatedForLocalPeer(context, t, peerType) {
+		g.explainf(t, "skipped: conversions with peer type %v are generated for the latter", peerType)
+		return false
+	}
+	if g.generatedBySharingGenerator(context, t, peerType) {
+		g.explainf(t, "skipped: conversions with peer type %v are generated along with the latter", peerType)
+		return false
+	}
+	if reason := g.notWithBuildConstraintReason(t, peerType); reason != "" {
+		g.explainf(t, "skipped: conversions with peer type %v %s", peerType, reason)
+		return false
+	}
+	g.explainf(t, "selected for conversion generation, with peer type %v", peerType)
+	return true
 }
 
-// Imports returns the imports to add to generated files.
-func (g *Generator) Imports(*generator.Context) (imports []string) {
+// generatedForLocalPeer returns true iff peerType, t's peer type, belongs to the same package as
+// t, and has t as its own peer type: conversions between them then get generated once, for
+// whichever has the name that sorts first.
+func (g *Generator) generatedForLocalPeer(context *generator.Context, t, peerType *types.Type) bool {
+	return peerType.Name.Package == t.Name.Package && peerType.Name.Name < t.Name.Name &&
+		g.GetPeerTypeFor(context, peerType) == t
+}
+
+// HasEligibleTypes returns true iff at least one of the types package's types passes Filter,
+// i.e. has a peer type it can be converted to and from.
+func (g *Generator) HasEligibleTypes(context *generator.Context) bool {
+	for _, t := range g.typesPackage.Types {
+		if peerType := g.GetPeerTypeFor(context, t); peerType != nil && g.notConvertibleReason(t, peerType) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Imports returns the imports to add to generated files, deduplicated and sorted; see
+// Options.LocalImportPrefix.
+func (g *Generator) Imports(*generator.Context) []string {
+	var imports []string
+
 	// from the import tracker
 	for _, importLine := range g.ImportTracker.ImportLines() {
 		if g.isOtherPackage(importLine) {
@@ -168,7 +412,7 @@ func (g *Generator) Imports(*generator.Context) (imports []string) {
 		imports = append(imports, importLine)
 	}
 
-	return
+	return sortImports(imports, g.Options.LocalImportPrefix)
 }
 
 func (g *Generator) isOtherPackage(pkg string) bool {
@@ -185,63 +429,191 @@ func (g *Generator) isOtherPackage(pkg string) bool {
 func (g *Generator) GenerateType(context *generator.Context, t *types.Type, writer io.Writer) error {
 	klog.V(5).Infof("generating for type %v", t)
 	peerType := g.GetPeerTypeFor(context, t)
+	g.context = context
 	sw := generator.NewSnippetWriter(writer, context, snippetDelimiter, snippetDelimiter)
-	g.generateConversion(t, peerType, sw)
-	g.generateConversion(peerType, t, sw)
-	return sw.Error()
+	todosSince := len(g.todos)
+	if g.readOnlyPeerPackages[peerType.Name.Package] {
+		klog.V(5).Infof("not generating conversion from %v to %v: %s is read-only", t, peerType, peerType.Name.Package)
+		g.explainConversionf(t, peerType, "not generated, %s is read-only", peerType.Name.Package)
+		g.planConversion(t, peerType, ToPeer, SkippedConversion, todosSince)
+	} else {
+		g.planConversion(t, peerType, ToPeer, g.generateOrCastConversion(t, peerType, sw), todosSince)
+	}
+	todosSince = len(g.todos)
+	g.planConversion(peerType, t, FromPeer, g.generateOrCastConversion(peerType, t, sw), todosSince)
+	g.writeViewFunction(t, peerType, sw)
+	g.writeViewFunction(peerType, t, sw)
+	return g.templateError(sw)
+
+}
 
+// Finalize writes what generated files need after all conversion functions: loop, deep-copy,
+// generic and trace helpers, the table of cast conversions, and the init function registering conversion functions, if enabled.
+func (g *Generator) Finalize(context *generator.Context, writer io.Writer) error {
+	sw := generator.NewSnippetWriter(writer, context, snippetDelimiter, snippetDelimiter)
+	g.writeLoopHelpers(sw)
+	g.writeDeepCopyHelpers(sw)
+	if g.writesPackageHelpers() {
+		g.writeGenericHelpers(sw)
+		g.writeTraceHelpers(sw)
+		g.writeCastConversions(sw)
+	}
+	g.writeRegistry(sw)
+	return g.templateError(sw)
 }
 
-func (g *Generator) generateConversion(inType, outType *types.Type, sw *generator.SnippetWriter) {
-	// function signature
-	sw.Do("func auto", nil)
-	g.writeConversionFunctionSignature(inType, outType, sw, true)
-	sw.Do(" {\n", nil)
+// generateConversion writes the conversion functions from inType to outType, and returns how.
+func (g *Generator) generateConversion(inType, outType *types.Type, sw *generator.SnippetWriter) ConversionStrategy {
+	function, found := g.preexists(inType, outType)
+	noPublicFunction := g.noPublicFun(inType) || g.noPublicFun(outType)
+	// see Options.NoPrivateFunctions; functions taking their input by value call private ones
+	// taking pointers, so that their bodies are the same
+	inlined := g.Options.NoPrivateFunctions && !found && !noPublicFunction && !g.valueInput(inType, outType)
+
+	// the body only gets written once complete, as handlers can ask to skip it
+	body := &bytes.Buffer{}
+	bodyWriter := generator.NewSnippetWriter(body, g.context, snippetDelimiter, snippetDelimiter)
+
+	g.currentFunction = g.privateFunction(inType, outType)
+	if inlined {
+		g.currentFunction = g.publicFunction(inType, outType)
+	}
+	g.currentInType, g.currentOutType = inType, outType
+	g.fieldPath, g.nesting = nil, nil
+	g.recordFunction(g.currentFunction, GeneratedFunction)
+	g.writeTraceEntry(bodyWriter)
+
+	errors := g.generateFor(inType, outType, bodyWriter)
+	bodyWriter.Do("return nil\n", nil)
+	if err := bodyWriter.Error(); err != nil {
+		errors = append(errors, err)
+	}
 
-	// body
-	errors := g.generateFor(inType, outType, sw)
+	if skip := skipRequested(errors); skip != nil && !found {
+		g.explainConversionf(inType, outType, "not generated, as requested by a handler: %v", skip)
+		klog.Warningf("Not generating conversion functions for %v -> %v: %v", inType, outType, skip)
+		g.forgetFunction(g.currentFunction)
+		return SkippedConversion
+	}
 
-	// close function body
-	sw.Do("return nil\n", nil)
-	sw.Do("}\n\n", nil)
+	publicOnError := len(errors) != 0 && g.publicFunctionOnError(inType, outType)
+	if inlined && len(errors) != 0 && !publicOnError {
+		// no public function after all: the manual one needs a private function to call
+		g.renameFunction(g.currentFunction, g.privateFunction(inType, outType))
+		g.currentFunction = g.privateFunction(inType, outType)
+		inlined = false
+	}
+	if !inlined {
+		sw.Do("func ", nil)
+		g.writePrivateFunctionSignature(inType, outType, sw, true)
+		sw.Do(" {\n", nil)
+		// written as is rather than through a template, to spare a copy of the body; write errors
+		// are tracked by gengo's error tracker
+		sw.Out().Write(body.Bytes())
+		sw.Do("}\n\n", nil)
+	}
 
-	if _, found := g.preexists(inType, outType); found {
+	if found {
 		// there is a public manual Conversion method: use it.
-		return
+		g.explainConversionf(inType, outType, "no public function generated, using manual function %v", function)
+		if function.Name.Package == g.outputPackage.Path {
+			g.addPublicFunction(inType, outType)
+		}
+		g.recordFunction(function, ManualFunction)
+		return ManualConversion
 	}
 
-	if g.noPublicFun(inType) || g.noPublicFun(outType) {
+	if noPublicFunction {
 		// no public conversion function
-		return
+		g.explainConversionf(inType, outType, "no public function generated, as requested by a no-public tag")
+		return PrivateOnlyConversion
 	}
 
-	if len(errors) == 0 {
+	if len(errors) == 0 || publicOnError {
 		// Emit a public conversion function.
-		sw.Do("// "+conversionFunctionNameTemplate(publicImportTrackingNamer)+" is an autogenerated conversion function.\nfunc ", argsFromType(inType, outType))
+		sw.Do("// "+conversionFunctionNameTemplate(publicImportTrackingNamer)+" is an autogenerated conversion function.\n", argsFromType(inType, outType))
+		if publicOnError {
+			g.explainConversionf(inType, outType, "public function generated despite errors: %v", errors)
+			klog.Warningf("Generating public conversion function for %v -> %v despite errors; it needs manual work:", inType, outType)
+			sw.Do("//\n// TODO: this conversion is incomplete, manual conversions are needed:\n", nil)
+			for _, err := range errors {
+				klog.Warningf("      - %v", err)
+				sw.Do("//   - $.$\n", err.Error())
+			}
+		}
+		if message, deprecated := g.deprecationMessage(inType, outType); deprecated {
+			sw.Do("//\n// Deprecated: $.$\n", message)
+		}
+		sw.Do("func ", nil)
 		g.writeConversionFunctionSignature(inType, outType, sw, true)
-		sw.Do(" {\nreturn auto", nil)
-		g.writeConversionFunctionSignature(inType, outType, sw, false)
-		sw.Do("\n}\n\n", nil)
-		return
+		sw.Do(" {\n", nil)
+		switch {
+		case inlined:
+			sw.Out().Write(body.Bytes())
+		case g.Options.MetricsFunction != "":
+			g.writeObservedConversionCall(inType, outType, sw)
+		default:
+			sw.Do("return ", nil)
+			g.writePrivateFunctionSignature(inType, outType, sw, false)
+			sw.Do("\n", nil)
+		}
+		sw.Do("}\n\n", nil)
+		g.addPublicFunction(inType, outType)
+		g.explainConversionf(inType, outType, "public function generated")
+		if !inlined {
+			g.recordFunction(g.publicFunction(inType, outType), GeneratedFunction)
+			g.recordCall(g.publicFunction(inType, outType), g.currentFunction, GeneratedFunction, true)
+		}
+		return GeneratedConversion
 	}
 
 	// there were errors generating the private conversion function
+	g.explainConversionf(inType, outType, "no public function generated, because of errors: %v", errors)
 	klog.Errorf("Warning: could not find nor generate a final Conversion function for %v -> %v", inType, outType)
 	klog.Errorf("  you need to add manual conversions:")
-	for _, err := range errors {
+	messages := make([]string, len(errors))
+	for i, err := range errors {
 		klog.Errorf("      - %v", err)
+		messages[i] = err.Error()
 	}
+	g.reportDiagnostic(NoPublicFunctionDiagnostic, SeverityError, g.localType(inType, outType), "",
+		fmt.Sprintf("could not generate a public conversion function for %v -> %v, manual conversions needed: %s",
+			inType, outType, strings.Join(messages, "; ")))
+	g.manualConversionsNeeded = append(g.manualConversionsNeeded, ManualConversionNeeded{
+		InType:  inType,
+		OutType: outType,
+		Reasons: messages,
+	})
+	return IncompleteConversion
 }
 
 // writeConversionFunctionSignature writes the signature of the conversion function from inType to outType
 // into the given snippet writer.
 // includeArgsTypes controls whether the arguments' types' will be included.
 func (g *Generator) writeConversionFunctionSignature(inType, outType *types.Type, sw *generator.SnippetWriter, includeArgsTypes bool) {
+	g.writeFunctionSignature(conversionFunctionPrefix, inType, outType, sw, includeArgsTypes)
+}
+
+// writePrivateFunctionSignature is the same as writeConversionFunctionSignature, for the private
+// conversion function from inType to outType.
+func (g *Generator) writePrivateFunctionSignature(inType, outType *types.Type, sw *generator.SnippetWriter, includeArgsTypes bool) {
+	g.writeFunctionSignature(g.Options.PrivateFunctionPrefix, inType, outType, sw, includeArgsTypes)
+}
+
+func (g *Generator) writeFunctionSignature(prefix string, inType, outType *types.Type, sw *generator.SnippetWriter, includeArgsTypes bool) {
 	args := argsFromType(inType, outType)
-	sw.Do(conversionFunctionNameTemplate(publicImportTrackingNamer), args)
-	sw.Do("(in", nil)
-	if includeArgsTypes {
-		sw.Do(" *$.inType|"+rawNamer+"$", args)
+	sw.Do(functionNameTemplate(prefix, publicImportTrackingNamer), args)
+	valueInput := g.valueInput(inType, outType)
+	switch {
+	case includeArgsTypes && valueInput && prefix == conversionFunctionPrefix:
+		sw.Do("(in $.inType|"+rawNamer+"$", args)
+	case includeArgsTypes:
+		sw.Do("(in *$.inType|"+rawNamer+"$", args)
+	case valueInput:
+		// private functions get called from public ones
+		sw.Do("(&in", nil)
+	default:
+		sw.Do("(in", nil)
 	}
 	sw.Do(", out", nil)
 	if includeArgsTypes {
@@ -287,108 +659,160 @@ func (g *Generator) generateFor(inType, outType *types.Type, sw *generator.Snipp
 }
 
 func (g *Generator) doBuiltin(inType, outType *types.Type, sw *generator.SnippetWriter) []error {
-	if inType == outType {
-		sw.Do("*out = *in\n", nil)
-	} else {
-		sw.Do("*out = $.|"+rawNamer+"$(*in)\n", outType)
+	if !g.builtinConversionAllowed(inType, outType) {
+		result := g.handleUnsupportedType(NewNamedVariable("in", inType), NewNamedVariable("out", outType), sw)
+		if err := g.reportHandlerResult(result, UnsupportedTypeDiagnostic, inType, outType, "",
+			fmt.Sprintf("can't convert %s to %s: forbidden by builtin conversion policy %q", inType.Name, outType.Name, g.Options.BuiltinConversionPolicy)); err != nil {
+			return []error{err}
+		}
+		return nil
 	}
+	g.writeBuiltinConversion(inType, outType, "*in", "*out", nil, sw)
 	return nil
 }
 
 func (g *Generator) doMap(inType, outType *types.Type, sw *generator.SnippetWriter) (errors []error) {
-	sw.Do("*out = make($.|"+rawNamer+"$, len(*in))\n", outType)
-	if isDirectlyAssignable(inType.Key, outType.Key) {
-		sw.Do("for key, val := range *in {\n", nil)
-		if isDirectlyAssignable(inType.Elem, outType.Elem) {
-			if inType.Key == outType.Key {
-				sw.Do("(*out)[key] = ", nil)
-			} else {
-				sw.Do("(*out)[$.|"+rawNamer+"$(key)] = ", outType.Key)
-			}
-			if inType.Elem == outType.Elem {
-				sw.Do("val\n", nil)
-			} else {
-				sw.Do("$.|"+rawNamer+"$(val)\n", outType.Elem)
-			}
+	defer g.nest(inType.Kind)()
+	g.writeAllocation(inType, outType, sw)
+
+	keyFunction, convertibleKeys := g.mapKeyConversion(inType.Key, outType.Key)
+	structKeys := !convertibleKeys && g.structKeysConvertible(inType.Key, outType.Key)
+	if !convertibleKeys && !structKeys {
+		sw.Do("for range *in {\n", nil)
+		sw.Do("// FIXME: Converting unassignable keys unsupported $.|"+rawNamer+"$\n", inType.Key)
+		sw.Do("}\n", nil)
+		return
+	}
+
+	key, val, newKey, newVal := g.temp("key"), g.temp("val"), g.temp("newKey"), g.temp("newVal")
+	sw.Do("for "+key+", "+val+" := range *in {\n", nil)
+	outKey := key
+	if structKeys {
+		g.writeStructKeyConversion(inType.Key, outType.Key, sw)
+		outKey = newKey
+	} else if keyFunction != nil || !g.isDirectlyAssignable(inType.Key, outType.Key) {
+		sw.Do("var "+newKey+" $.|"+rawNamer+"$\n", outType.Key)
+		g.writeConversionCall(keyFunction, inType.Key, outType.Key, "&"+key, "&"+newKey, "", nil, sw)
+		outKey = newKey
+	} else if inType.Key != outType.Key {
+		outKey = "$.outKey|" + rawNamer + "$(" + key + ")"
+	}
+	keyArgs := generator.Args{"outKey": outType.Key}
+
+	if g.isDirectlyAssignable(inType.Elem, outType.Elem) && g.builtinConversionAllowed(inType.Elem, outType.Elem) && !g.deepCopiesElem(inType.Elem, outType.Elem) {
+		outVal := "(*out)[" + outKey + "]"
+		if inType.Elem.Kind == types.Builtin {
+			g.writeBuiltinConversion(inType.Elem, outType.Elem, val, outVal, keyArgs, sw)
+		} else if inType.Elem == outType.Elem {
+			sw.Do(outVal+" = "+val+"\n", keyArgs)
 		} else {
-			sw.Do("newVal := new($.|"+rawNamer+"$)\n", outType.Elem)
+			sw.Do(outVal+" = $.outElem|"+rawNamer+"$("+val+")\n", keyArgs.With("outElem", outType.Elem))
+		}
+	} else {
+		sw.Do(newVal+" := new($.|"+rawNamer+"$)\n", outType.Elem)
 
-			manualOrInternal := false
+		manualOrInternal := false
 
-			if function, ok := g.preexists(inType.Elem, outType.Elem); ok {
-				manualOrInternal = true
-				sw.Do("if err := $.|"+rawNamer+"$(&val, newVal"+g.extraArgumentsString()+"); err != nil {\n", function)
-			} else if g.convertibleOnlyWithinPackage(inType.Elem, outType.Elem) {
-				manualOrInternal = true
-				sw.Do("if err := "+conversionFunctionNameTemplate(publicImportTrackingNamer)+"(&val, newVal"+g.extraArgumentsString()+"); err != nil {\n",
-					argsFromType(inType.Elem, outType.Elem))
-			}
+		if function, ok := g.preexists(inType.Elem, outType.Elem); ok {
+			manualOrInternal = true
+			g.writeConversionCall(function, inType.Elem, outType.Elem, "&"+val, newVal, "", nil, sw)
+		} else if g.deepCopiesElem(inType.Elem, outType.Elem) && !isNestedContainer(inType.Elem, outType.Elem) {
+			manualOrInternal = true
+			errors = append(errors, g.writeDeepCopyCall(inType.Elem, outType.Elem, "&"+val, newVal, nil, sw)...)
+		} else if g.convertibleOnlyWithinPackage(inType.Elem, outType.Elem) {
+			manualOrInternal = true
+			g.writeConversionCall(nil, inType.Elem, outType.Elem, "&"+val, newVal, "", nil, sw)
+		} else if isNestedContainer(inType.Elem, outType.Elem) {
+			manualOrInternal = true
+			errors = append(errors, g.writeNestedConversion(inType.Elem, outType.Elem, val, "&"+val, newVal, sw)...)
+		}
 
-			if manualOrInternal {
-				sw.Do("return err\n}\n", nil)
-			} else if g.Options.ExternalConversionsHandler == nil {
-				klog.Warningf("%s's values of type %s require manual conversion to external type %s",
-					inType.Name, inType.Elem, outType.Name)
-			} else if _, err := g.Options.ExternalConversionsHandler(NewNamedVariable("&val", inType.Elem), NewNamedVariable("newVal", outType.Elem), sw); err != nil {
-				errors = append(errors, err)
-			}
+		if !manualOrInternal {
+			g.recordExternalCall(inType.Elem, outType.Elem)
+		}
 
-			if inType.Key == outType.Key {
-				sw.Do("(*out)[key] = *newVal\n", nil)
-			} else {
-				sw.Do("(*out)[$.|"+rawNamer+"$(key)] = *newVal\n", outType.Key)
+		if manualOrInternal {
+			// already converted
+		} else {
+			result := g.handleExternalConversion(NewNamedVariable("&"+val, inType.Elem), NewNamedVariable(newVal, outType.Elem), sw)
+			if err := g.reportHandlerResult(result, ExternalConversionDiagnostic, inType.Elem, outType.Elem, "",
+				fmt.Sprintf("%s's values of type %s convert to external type %s", inType.Name, inType.Elem, outType.Elem)); err != nil {
+				errors = append(errors, err)
 			}
 		}
-	} else {
-		// TODO: Implement it when necessary.
-		sw.Do("for range *in {\n", nil)
-		sw.Do("// FIXME: Converting unassignable keys unsupported $.|"+rawNamer+"$\n", inType.Key)
+
+		sw.Do("(*out)["+outKey+"] = *"+newVal+"\n", keyArgs)
 	}
 	sw.Do("}\n", nil)
 
 	return
 }
 
+// mapKeyConversion returns whether map keys of type inKey can be converted to outKey: either
+// with a conversion function, or because they're directly assignable; in which case it also
+// returns the manual conversion function to use, if any - or nil to use the generated one, or
+// direct assignment. As for struct fields, manual functions take precedence over direct
+// assignment, unless they're copy-only.
+func (g *Generator) mapKeyConversion(inKey, outKey *types.Type) (function *types.Type, convertible bool) {
+	assignable := g.isDirectlyAssignable(inKey, outKey)
+	if function, ok := g.preexists(inKey, outKey); ok && (!assignable || !g.functionHasTag(function, "copy-only")) {
+		return function, true
+	}
+	if assignable {
+		return nil, true
+	}
+	return nil, g.convertibleOnlyWithinPackage(inKey, outKey)
+}
+
 func (g *Generator) doSlice(inType, outType *types.Type, sw *generator.SnippetWriter) (errors []error) {
-	sw.Do("*out = make($.|"+rawNamer+"$, len(*in))\n", outType)
+	defer g.nest(inType.Kind)()
+	g.writeAllocation(inType, outType, sw)
 	if inType.Elem == outType.Elem && inType.Elem.Kind == types.Builtin {
 		sw.Do("copy(*out, *in)\n", nil)
 	} else {
-		sw.Do("for i := range *in {\n", nil)
-		if isDirectlyAssignable(inType.Elem, outType.Elem) {
-			if inType.Elem == outType.Elem {
-				sw.Do("(*out)[i] = (*in)[i]\n", nil)
+		i := g.temp("i")
+		inElem, outElem := "(*in)["+i+"]", "(*out)["+i+"]"
+		sw.Do("for "+i+" := range *in {\n", nil)
+		if handled, errs := g.doPointerElems(inType, outType, sw); handled {
+			errors = append(errors, errs...)
+			g.explainConversionf(inType, outType, "elements converted between pointers and values, nil elements policy %q", g.Options.NilElementsPolicy)
+		} else if g.isDirectlyAssignable(inType.Elem, outType.Elem) && g.builtinConversionAllowed(inType.Elem, outType.Elem) && !g.deepCopiesElem(inType.Elem, outType.Elem) {
+			if inType.Elem.Kind == types.Builtin {
+				g.writeBuiltinConversion(inType.Elem, outType.Elem, inElem, outElem, nil, sw)
+			} else if inType.Elem == outType.Elem {
+				sw.Do(outElem+" = "+inElem+"\n", nil)
 			} else {
-				sw.Do("(*out)[i] = $.|"+rawNamer+"$((*in)[i])\n", outType.Elem)
+				sw.Do(outElem+" = $.|"+rawNamer+"$("+inElem+")\n", outType.Elem)
 			}
 		} else {
 			manualOrInternal := false
 
 			if function, ok := g.preexists(inType.Elem, outType.Elem); ok {
 				manualOrInternal = true
-				sw.Do("if err := $.|"+rawNamer+"$(&(*in)[i], &(*out)[i]"+g.extraArgumentsString()+"); err != nil {\n", function)
+				g.writeConversionCall(function, inType.Elem, outType.Elem, "&"+inElem, "&"+outElem, "", nil, sw)
+			} else if g.deepCopiesElem(inType.Elem, outType.Elem) && !isNestedContainer(inType.Elem, outType.Elem) {
+				manualOrInternal = true
+				errors = append(errors, g.writeDeepCopyCall(inType.Elem, outType.Elem, "&"+inElem, "&"+outElem, nil, sw)...)
 			} else if g.convertibleOnlyWithinPackage(inType.Elem, outType.Elem) {
 				manualOrInternal = true
-				sw.Do("if err := "+conversionFunctionNameTemplate(publicImportTrackingNamer)+"(&(*in)[i], &(*out)[i]"+g.extraArgumentsString()+"); err != nil {\n",
-					argsFromType(inType.Elem, outType.Elem))
+				g.writeConversionCall(nil, inType.Elem, outType.Elem, "&"+inElem, "&"+outElem, "", nil, sw)
+			} else if isNestedContainer(inType.Elem, outType.Elem) {
+				manualOrInternal = true
+				errors = append(errors, g.writeNestedConversion(inType.Elem, outType.Elem, inElem, "&"+inElem, "&"+outElem, sw)...)
 			}
 
-			if manualOrInternal {
-				sw.Do("return err\n}\n", nil)
-			} else {
-				conversionHandled := false
-				var err error
+			if !manualOrInternal {
+				g.recordExternalCall(inType.Elem, outType.Elem)
 
-				if g.Options.ExternalConversionsHandler == nil {
-					klog.Warningf("%s's items of type %s require manual conversion to external type %s",
-						inType.Name, inType.Name, outType.Name)
-				} else if conversionHandled, err = g.Options.ExternalConversionsHandler(NewNamedVariable("&(*in)[i]", inType.Elem), NewNamedVariable("&(*out)[i]", outType.Elem), sw); err != nil {
+				result := g.handleExternalConversion(NewNamedVariable("&"+inElem, inType.Elem), NewNamedVariable("&"+outElem, outType.Elem), sw)
+				if err := g.reportHandlerResult(result, ExternalConversionDiagnostic, inType.Elem, outType.Elem, "",
+					fmt.Sprintf("%s's items of type %s convert to external type %s", inType.Name, inType.Elem, outType.Elem)); err != nil {
 					errors = append(errors, err)
 				}
 
-				if !conversionHandled {
+				if result.Outcome != HandlerHandled {
 					// so that the compiler doesn't barf
-					sw.Do("_ = i\n", nil)
+					sw.Do("_ = "+i+"\n", nil)
 				}
 			}
 		}
@@ -398,18 +822,42 @@ func (g *Generator) doSlice(inType, outType *types.Type, sw *generator.SnippetWr
 }
 
 func (g *Generator) doStruct(inType, outType *types.Type, sw *generator.SnippetWriter) (errors []error) {
+	depth := len(g.fieldPath)
+	defer func() { g.fieldPath = g.fieldPath[:depth] }()
+
+	mappings := g.fieldMappings(inType, outType)
+	errors = append(errors, checkFieldMappings(inType, outType, mappings)...)
+
 	for _, inMember := range inType.Members {
+		g.fieldPath = append(g.fieldPath[:depth], inMember.Name)
 		if g.optedOut(inMember) {
 			// This field is excluded from conversion.
 			sw.Do("// INFO: in."+inMember.Name+" opted out of conversion generation\n", nil)
+			g.explainFieldf(inType, outType, inMember.Name, FieldDropped, "opted out of conversion generation")
+			g.reportDiagnostic(DroppedConversionDiagnostic, SeverityInfo, inType, inMember.Name,
+				fmt.Sprintf("%s.%s opted out of conversion generation", inType.Name, inMember.Name))
+			continue
+		}
+		if handled, errs := g.doMappedField(inType, outType, &inMember, mappings, sw); handled {
+			errors = append(errors, errs...)
 			continue
 		}
 		outMember, found := findMember(outType, inMember.Name)
+		if found && mappings.isTarget(outMember.Name) {
+			// The peer field is converted from another field, or expression.
+			found = false
+		}
+		if !found && g.isMapKeyField(inType, inMember.Name) {
+			// This field is the key of a map in the peer, see doMapKeyedSlice.
+			g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "does not exist in peer type, but is used as a map key")
+			continue
+		}
 		if !found {
 			// This field doesn't exist in the peer.
-			if g.Options.MissingFieldsHandler == nil {
-				klog.Warningf("%s.%s requires manual conversion: does not exist in peer-type %s", inType.Name, inMember.Name, outType.Name)
-			} else if err := g.Options.MissingFieldsHandler(NewNamedVariable("in", inType), NewNamedVariable("out", outType), &inMember, sw); err != nil {
+			g.explainFieldf(inType, outType, inMember.Name, FieldUnconverted, "does not exist in peer type, handler set: %v", g.Options.MissingFieldsHandler != nil)
+			result := g.handleMissingField(NewNamedVariable("in", inType), NewNamedVariable("out", outType), &inMember, sw)
+			if err := g.reportHandlerResult(result, MissingFieldDiagnostic, inType, outType, inMember.Name,
+				fmt.Sprintf("%s.%s does not exist in peer-type %s", inType.Name, inMember.Name, outType.Name)); err != nil {
 				errors = append(errors, err)
 			}
 			continue
@@ -430,19 +878,35 @@ func (g *Generator) doStruct(inType, outType *types.Type, sw *generator.SnippetW
 		}
 
 		args := argsFromType(inMemberType, outMemberType).With("name", inMember.Name)
+		if handled, err := g.doOpaqueMember(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw); handled {
+			if err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		if handled, err := g.doBoundaryField(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw); handled {
+			if err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		deepCopy := g.forcesDeepCopy(&inMember, &outMember, inMemberType)
 
 		// try a direct memory copy for any type that has exactly equivalent values
-		if g.useUnsafeConversion(inMemberType, outMemberType) {
+		if !deepCopy && g.useUnsafeConversion(inMemberType, outMemberType) && !g.handlesDynamicValues(inMemberType, outMemberType) {
 			args = args.With("Pointer", types.Ref("unsafe", "Pointer"))
 			switch inMemberType.Kind {
 			case types.Pointer:
 				sw.Do("out.$.name$ = ($.outType|"+rawNamer+"$)($.Pointer|"+rawNamer+"$(in.$.name$))\n", args)
+				g.explainFieldf(inType, outType, inMember.Name, FieldUnsafe, "%v and %v have the same memory layout, using an unsafe cast", inMemberType, outMemberType)
 				continue
 			case types.Map:
 				sw.Do("out.$.name$ = *(*$.outType|"+rawNamer+"$)($.Pointer|"+rawNamer+"$(&in.$.name$))\n", args)
+				g.explainFieldf(inType, outType, inMember.Name, FieldUnsafe, "%v and %v have the same memory layout, using an unsafe cast", inMemberType, outMemberType)
 				continue
 			case types.Slice:
 				sw.Do("out.$.name$ = *(*$.outType|"+rawNamer+"$)($.Pointer|"+rawNamer+"$(&in.$.name$))\n", args)
+				g.explainFieldf(inType, outType, inMember.Name, FieldUnsafe, "%v and %v have the same memory layout, using an unsafe cast", inMemberType, outMemberType)
 				continue
 			}
 		}
@@ -450,24 +914,79 @@ func (g *Generator) doStruct(inType, outType *types.Type, sw *generator.SnippetW
 		// check based on the top level name, not the underlying names
 		if function, ok := g.preexists(inMember.Type, outMember.Type); ok {
 			if g.functionHasTag(function, "drop") {
+				g.explainFieldf(inType, outType, inMember.Name, FieldDropped, "conversion dropped by manual function %v", function)
+				g.reportDiagnostic(DroppedConversionDiagnostic, SeverityInfo, inType, inMember.Name,
+					fmt.Sprintf("conversion of %s.%s dropped by %s", inType.Name, inMember.Name, function.Name))
 				continue
 			}
 			if !g.functionHasTag(function, "copy-only") || !isFastConversion(inMemberType, outMemberType) {
-				args["function"] = function
-				sw.Do("if err := $.function|"+rawNamer+"$(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
-				sw.Do("return err\n", nil)
-				sw.Do("}\n", nil)
+				g.writeConversionCall(function, inMemberType, outMemberType, "&in.$.name$", "&out.$.name$", inMember.Name, args, sw)
+				g.explainFieldf(inType, outType, inMember.Name, FieldManualFunction, "converted by manual function %v", function)
 				continue
 			}
 			klog.V(5).Infof("Skipped function %s because it is copy-only and we can use direct assignment", function.Name)
+			g.explainFieldf(inType, outType, inMember.Name, "", "skipped copy-only manual function %v in favor of direct assignment", function)
+		}
+
+		if g.doOpaqueField(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if g.doDynamicValuesField(inType, outType, &inMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if g.doInterfaceMember(inType, outType, &inMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if !deepCopy && g.doByteSliceField(inType, outType, &inMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if !deepCopy && g.doMajorVersionEquivalentField(inType, outType, &inMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if handled, err := g.doExprField(inType, outType, &inMember, &outMember, sw); handled {
+			if err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		if handled, err := g.doScaledField(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw); handled {
+			if err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		if handled, errs := g.doNilPointerField(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw); handled {
+			errors = append(errors, errs...)
+			continue
+		}
+		if g.doOptionalScalar(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if g.doMapKeyedSlice(inType, outType, &inMember, &outMember, inMemberType, outMemberType, args, sw) {
+			continue
+		}
+		if handled, err := g.doFuncChanField(inType, outType, &inMember, inMemberType, outMemberType, args, sw); handled {
+			if err != nil {
+				errors = append(errors, err)
+			}
+			continue
 		}
 
 		// If we can't auto-convert, punt before we emit any code.
-		if inMemberType.Kind != outMemberType.Kind {
-			if g.Options.InconvertibleFieldsHandler == nil {
-				klog.Warningf("%s.%s requires manual conversion: inconvertible types: %s VS %s for %s.%s",
-					inType.Name, inMember.Name, inMemberType, outMemberType, outType.Name, outMember.Name)
-			} else if err := g.Options.InconvertibleFieldsHandler(NewNamedVariable("in", inType), NewNamedVariable("out", outType), &inMember, &outMember, sw); err != nil {
+		if inMemberType.Kind != outMemberType.Kind || !g.builtinConversionAllowed(inMemberType, outMemberType) {
+			message := fmt.Sprintf("%s.%s has inconvertible types: %s VS %s for %s.%s",
+				inType.Name, inMember.Name, inMemberType, outMemberType, outType.Name, outMember.Name)
+			if inMemberType.Kind != outMemberType.Kind {
+				g.explainFieldf(inType, outType, inMember.Name, FieldUnconverted, "inconvertible kinds %s and %s, handler set: %v",
+					inMemberType.Kind, outMemberType.Kind, g.Options.InconvertibleFieldsHandler != nil)
+			} else {
+				g.explainFieldf(inType, outType, inMember.Name, FieldUnconverted, "builtin conversion from %v to %v forbidden by builtin conversion policy %q, handler set: %v",
+					inMemberType, outMemberType, g.Options.BuiltinConversionPolicy, g.Options.InconvertibleFieldsHandler != nil)
+				message = fmt.Sprintf("%s.%s can't be converted from %s to %s for %s.%s: forbidden by builtin conversion policy %q",
+					inType.Name, inMember.Name, inMemberType, outMemberType, outType.Name, outMember.Name, g.Options.BuiltinConversionPolicy)
+			}
+			result := g.handleInconvertibleField(NewNamedVariable("in", inType), NewNamedVariable("out", outType), &inMember, &outMember, sw)
+			if err := g.reportHandlerResult(result, InconvertibleFieldDiagnostic, inType, outType, inMember.Name, message); err != nil {
 				errors = append(errors, err)
 			}
 			continue
@@ -477,34 +996,63 @@ func (g *Generator) doStruct(inType, outType *types.Type, sw *generator.SnippetW
 		case types.Builtin:
 			if inMemberType == outMemberType {
 				sw.Do("out.$.name$ = in.$.name$\n", args)
+				g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "same builtin type, direct assignment")
 			} else {
-				sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+				g.writeBuiltinConversion(inMemberType, outMemberType, "in.$.name$", "out.$.name$", args, sw)
+				g.explainFieldf(inType, outType, inMember.Name, FieldCast, "different builtin types, type conversion to %v with policy %q", outMemberType, g.Options.BuiltinConversionPolicy)
 			}
 		case types.Map, types.Slice, types.Pointer:
-			if isDirectlyAssignable(inMemberType, outMemberType) {
-				sw.Do("out.$.name$ = in.$.name$\n", args)
+			if !deepCopy && g.isDirectlyAssignable(inMemberType, outMemberType) {
+				g.writeDirectAssignment(inType, outType, inMember.Name, inMemberType, outMemberType, args, sw)
 				continue
 			}
+			if deepCopy {
+				g.explainFieldf(inType, outType, inMember.Name, "", "deep copy forced by tag")
+			}
+			restore := g.startDeepCopy(deepCopy)
 
+			if !deepCopy && g.doGenericHelperField(inType, outType, &inMember, inMemberType, outMemberType, args, sw) {
+				restore()
+				continue
+			}
+
+			if g.Options.DeduplicateLoops {
+				g.explainFieldf(inType, outType, inMember.Name, FieldElements, "%s converted element by element, in a loop helper", inMemberType.Kind)
+				errors = append(errors, g.doDeduplicatedLoop(inMemberType, outMemberType, args, sw)...)
+				restore()
+				continue
+			}
+
+			g.explainFieldf(inType, outType, inMember.Name, FieldElements, "%s converted element by element", inMemberType.Kind)
 			sw.Do("if in.$.name$ != nil {\n", args)
 			sw.Do("in, out := &in.$.name$, &out.$.name$\n", args)
-			g.generateFor(inMemberType, outMemberType, sw)
+			errors = append(errors, g.generateFor(inMemberType, outMemberType, sw)...)
 			sw.Do("} else {\n", nil)
 			sw.Do("out.$.name$ = nil\n", args)
 			sw.Do("}\n", nil)
+			restore()
 		case types.Struct:
-			if isDirectlyAssignable(inMemberType, outMemberType) {
-				sw.Do("out.$.name$ = in.$.name$\n", args)
+			if deepCopy && sharesMemory(inMemberType, map[*types.Type]bool{}) && g.convertibleOnlyWithinPackage(inMemberType, outMemberType) {
+				restore := g.startDeepCopy(true)
+				errors = append(errors, g.writeDeepCopyCall(inMemberType, outMemberType, "&in.$.name$", "&out.$.name$", args, sw)...)
+				restore()
+				g.explainFieldf(inType, outType, inMember.Name, FieldGeneratedFunction, "deep copy forced by tag, converted by %s",
+					PrivateConversionFunctionName(deepCopyFunctionPrefix, inMemberType, outMemberType))
+				continue
+			}
+			if g.isDirectlyAssignable(inMemberType, outMemberType) {
+				g.writeDirectAssignment(inType, outType, inMember.Name, inMemberType, outMemberType, args, sw)
 				continue
 			}
 			if g.convertibleOnlyWithinPackage(inMemberType, outMemberType) {
-				sw.Do("if err := "+conversionFunctionNameTemplate(publicImportTrackingNamer)+"(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
-				sw.Do("return err\n}\n", nil)
+				g.writeConversionCall(nil, inMemberType, outMemberType, "&in.$.name$", "&out.$.name$", inMember.Name, args, sw)
+				g.explainFieldf(inType, outType, inMember.Name, FieldGeneratedFunction, "converted by %s", ConversionFunctionName(inMemberType, outMemberType))
 			} else {
 				errors = g.callExternalConversionsHandlerForStructField(inType, outType, inMemberType, outMemberType, &inMember, &outMember, sw, errors)
 			}
 		case types.Alias:
-			if isDirectlyAssignable(inMemberType, outMemberType) {
+			if g.isDirectlyAssignable(inMemberType, outMemberType) {
+				g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "directly assignable alias, direct assignment")
 				if inMemberType == outMemberType {
 					sw.Do("out.$.name$ = in.$.name$\n", args)
 				} else {
@@ -512,42 +1060,47 @@ func (g *Generator) doStruct(inType, outType *types.Type, sw *generator.SnippetW
 				}
 			} else {
 				if g.convertibleOnlyWithinPackage(inMemberType, outMemberType) {
-					sw.Do("if err := "+conversionFunctionNameTemplate(publicImportTrackingNamer)+"(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
-					sw.Do("return err\n}\n", nil)
+					g.writeConversionCall(nil, inMemberType, outMemberType, "&in.$.name$", "&out.$.name$", inMember.Name, args, sw)
+					g.explainFieldf(inType, outType, inMember.Name, FieldGeneratedFunction, "converted by %s", ConversionFunctionName(inMemberType, outMemberType))
 				} else {
 					errors = g.callExternalConversionsHandlerForStructField(inType, outType, inMemberType, outMemberType, &inMember, &outMember, sw, errors)
 				}
 			}
 		default:
 			if g.convertibleOnlyWithinPackage(inMemberType, outMemberType) {
-				sw.Do("if err := "+conversionFunctionNameTemplate(publicImportTrackingNamer)+"(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
-				sw.Do("return err\n}\n", nil)
+				g.writeConversionCall(nil, inMemberType, outMemberType, "&in.$.name$", "&out.$.name$", inMember.Name, args, sw)
+				g.explainFieldf(inType, outType, inMember.Name, FieldGeneratedFunction, "converted by %s", ConversionFunctionName(inMemberType, outMemberType))
 			} else {
 				errors = g.callExternalConversionsHandlerForStructField(inType, outType, inMemberType, outMemberType, &inMember, &outMember, sw, errors)
 			}
 		}
 	}
+	g.fieldPath = g.fieldPath[:depth]
+	errors = append(errors, g.doUnsourcedFieldMappings(inType, outType, mappings, sw)...)
 	return
 }
 
 func (g *Generator) callExternalConversionsHandlerForStructField(inType, outType, inMemberType, outMemberType *types.Type, inMember, outMember *types.Member, sw *generator.SnippetWriter, errors []error) []error {
-	if g.Options.ExternalConversionsHandler == nil {
-		klog.Warningf("%s.%s requires manual conversion to external type %s.%s",
-			inType.Name, inMember.Name, outType.Name, outMember.Name)
-	} else {
-		inVar := NewNamedVariable(fmt.Sprintf("&in.%s", inMember.Name), inMemberType)
-		outVar := NewNamedVariable(fmt.Sprintf("&out.%s", outMember.Name), outMemberType)
-		if _, err := g.Options.ExternalConversionsHandler(inVar, outVar, sw); err != nil {
-			errors = append(errors, err)
-		}
+	g.recordExternalCall(inMemberType, outMemberType)
+	g.explainFieldf(inType, outType, inMember.Name, FieldExternal, "requires external conversion from %v to %v (%s), handler set: %v",
+		inMemberType, outMemberType, g.notConvertibleReason(inMemberType, outMemberType), g.Options.ExternalConversionsHandler != nil)
+	inVar := NewNamedVariable(fmt.Sprintf("&in.%s", inMember.Name), inMemberType)
+	outVar := NewNamedVariable(fmt.Sprintf("&out.%s", outMember.Name), outMemberType)
+	result := g.handleExternalConversion(inVar, outVar, sw)
+	if err := g.reportHandlerResult(result, ExternalConversionDiagnostic, inType, outType, inMember.Name,
+		fmt.Sprintf("%s.%s converts to external type %s.%s", inType.Name, inMember.Name, outType.Name, outMember.Name)); err != nil {
+		errors = append(errors, err)
 	}
 	return errors
 }
 
 func (g *Generator) doPointer(inType, outType *types.Type, sw *generator.SnippetWriter) (errors []error) {
-	sw.Do("*out = new($.Elem|"+rawNamer+"$)\n", outType)
-	if isDirectlyAssignable(inType.Elem, outType.Elem) {
-		if inType.Elem == outType.Elem {
+	defer g.nest(inType.Kind)()
+	g.writeAllocation(inType, outType, sw)
+	if g.isDirectlyAssignable(inType.Elem, outType.Elem) && g.builtinConversionAllowed(inType.Elem, outType.Elem) && !g.deepCopiesElem(inType.Elem, outType.Elem) {
+		if inType.Elem.Kind == types.Builtin {
+			g.writeBuiltinConversion(inType.Elem, outType.Elem, "**in", "**out", nil, sw)
+		} else if inType.Elem == outType.Elem {
 			sw.Do("**out = **in\n", nil)
 		} else {
 			sw.Do("**out = $.|"+rawNamer+"$(**in)\n", outType.Elem)
@@ -557,19 +1110,30 @@ func (g *Generator) doPointer(inType, outType *types.Type, sw *generator.Snippet
 
 		if function, ok := g.preexists(inType.Elem, outType.Elem); ok {
 			manualOrInternal = true
-			sw.Do("if err := $.|"+rawNamer+"$(*in, *out"+g.extraArgumentsString()+"); err != nil {\n", function)
+			g.writeConversionCall(function, inType.Elem, outType.Elem, "*in", "*out", "", nil, sw)
+		} else if g.deepCopiesElem(inType.Elem, outType.Elem) && !isNestedContainer(inType.Elem, outType.Elem) {
+			manualOrInternal = true
+			errors = append(errors, g.writeDeepCopyCall(inType.Elem, outType.Elem, "*in", "*out", nil, sw)...)
 		} else if g.convertibleOnlyWithinPackage(inType.Elem, outType.Elem) {
 			manualOrInternal = true
-			sw.Do("if err := "+conversionFunctionNameTemplate(publicImportTrackingNamer)+"(*in, *out"+g.extraArgumentsString()+"); err != nil {\n", argsFromType(inType.Elem, outType.Elem))
+			g.writeConversionCall(nil, inType.Elem, outType.Elem, "*in", "*out", "", nil, sw)
+		} else if isNestedContainer(inType.Elem, outType.Elem) {
+			manualOrInternal = true
+			errors = append(errors, g.writeNestedConversion(inType.Elem, outType.Elem, "**in", "*in", "*out", sw)...)
+		}
+
+		if !manualOrInternal {
+			g.recordExternalCall(inType.Elem, outType.Elem)
 		}
 
 		if manualOrInternal {
-			sw.Do("return err\n}\n", nil)
-		} else if g.Options.ExternalConversionsHandler == nil {
-			klog.Warningf("%s's values of type %s require manual conversion to external type %s",
-				inType.Name, inType.Elem, outType.Name)
-		} else if _, err := g.Options.ExternalConversionsHandler(NewNamedVariable("*in", inType), NewNamedVariable("*out", outType), sw); err != nil {
-			errors = append(errors, err)
+			// already converted
+		} else {
+			result := g.handleExternalConversion(NewNamedVariable("*in", inType.Elem), NewNamedVariable("*out", outType.Elem), sw)
+			if err := g.reportHandlerResult(result, ExternalConversionDiagnostic, inType.Elem, outType.Elem, "",
+				fmt.Sprintf("%s's values of type %s convert to external type %s", inType.Name, inType.Elem, outType.Elem)); err != nil {
+				errors = append(errors, err)
+			}
 		}
 	}
 	return
@@ -581,53 +1145,139 @@ func (g *Generator) doAlias(inType, outType *types.Type, sw *generator.SnippetWr
 }
 
 func (g *Generator) doUnknown(inType, outType *types.Type, sw *generator.SnippetWriter) []error {
-	if g.Options.UnsupportedTypesHandler == nil {
-		klog.Warningf("Don't know how to convert %s to %s", inType.Name, outType.Name)
-	} else if err := g.Options.UnsupportedTypesHandler(NewNamedVariable("in", inType), NewNamedVariable("out", outType), sw); err != nil {
+	if handled, err := g.doFuncChan(inType, outType, sw); handled {
+		if err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+	result := g.handleUnsupportedType(NewNamedVariable("in", inType), NewNamedVariable("out", outType), sw)
+	if err := g.reportHandlerResult(result, UnsupportedTypeDiagnostic, inType, outType, "",
+		fmt.Sprintf("don't know how to convert %s to %s", inType.Name, outType.Name)); err != nil {
 		return []error{err}
 	}
 	return nil
 }
 
 func (g *Generator) extraArgumentsString() string {
-	result := ""
-	for _, namedArgument := range g.Options.ManualConversionsTracker.additionalConversionArguments {
-		result += ", " + namedArgument.Name
+	return g.Options.ManualConversionsTracker.additionalArgumentsString
+}
+
+// InvalidatePeerCache forgets the peer types found so far, as well as the types that no peer type
+// was found for: e.g. for wrapper generators to call after adding peer packages to the universe.
+func (g *Generator) InvalidatePeerCache() {
+	g.peerTypes = make(map[types.Name]*types.Type)
+}
+
+// peerTagOptions are the options of the "+<tag-name>" tags that tell where types' peers are, see
+// Options.TagName.
+var peerTagOptions = []string{"peerName", "peerPackage"}
+
+// checkPeerTags returns an error if tagName is empty while some of pkg's types have tags telling
+// where their peers are, as these would be silently ignored otherwise.
+func checkPeerTags(pkg *types.Package, tagName string) error {
+	if tagName != "" {
+		return nil
 	}
-	return result
+	names := make([]string, 0, len(pkg.Types))
+	for name := range pkg.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := pkg.Types[name]
+		for key, values := range types.ExtractCommentTags("+", t.CommentLines) {
+			for _, value := range values {
+				for _, option := range peerTagOptions {
+					if strings.HasPrefix(value, option+":") {
+						return errors.Errorf("type %s has a \"+%s=%s\" tag, but no tag name is set: set the tag name (e.g. to %q) for %s tags to be used",
+							t.Name, key, value, key, option)
+					}
+				}
+			}
+		}
+	}
+	return nil
 }
 
-// GetPeerTypeFor returns the peer type for type t.
+// GetPeerTypeFor returns the peer type for type t; results are cached, see InvalidatePeerCache and
+// Options.NoPeerTypeMissCache.
 func (g *Generator) GetPeerTypeFor(context *generator.Context, t *types.Type) *types.Type {
-	if peerType, found := g.peerTypes[t.Name.Name]; found {
+	if peerType, found := g.peerTypes[t.Name]; found {
 		return peerType
 	}
 
 	peerName := t.Name.Name
 	if present, name := g.hasTagOption(t.CommentLines, "peerName"); present && len(name) != 0 {
 		klog.V(5).Infof("Using custom peer name %q for input type %s", name, t.Name)
+		g.explainf(t, "using custom peer name %q", name)
 		peerName = name
 	}
 
+	peerPackages := g.peerPackages
+	if present, pkg := g.hasTagOption(t.CommentLines, "peerPackage"); present && len(pkg) != 0 {
+		klog.V(5).Infof("Using custom peer package %q for input type %s", pkg, t.Name)
+		peerPackages = []string{pkg}
+	}
+	if len(peerPackages) == 0 && peerName != t.Name.Name {
+		// custom peer names then refer to types of the types package itself
+		klog.V(5).Infof("Looking for peer type %q of input type %s in its own package", peerName, t.Name)
+		peerPackages = []string{g.typesPackage.Path}
+	}
+
 	var peerType *types.Type
-	for _, peerPkgPath := range g.peerPackages {
+	g.explainf(t, "looking for peer type %q in packages %v", peerName, peerPackages)
+	for _, peerPkgPath := range peerPackages {
 		peerPkg := context.Universe[peerPkgPath]
-		if peerPkg != nil && peerPkg.Has(peerName) {
+		if peerPkg != nil && peerPkg.Has(peerName) && peerPkg.Types[peerName] != t {
 			peerType = peerPkg.Types[peerName]
+			g.explainf(t, "found peer type in %s", peerPkgPath)
 			break
 		}
+		if peerPkg == nil {
+			g.explainf(t, "peer package %s is not loaded", peerPkgPath)
+		} else {
+			g.explainf(t, "no type %q in peer package %s", peerName, peerPkgPath)
+		}
 	}
 
-	g.peerTypes[t.Name.Name] = peerType
-
 	if peerType != nil {
 		klog.V(5).Infof("Found peer type %s for input type %s", peerType, t)
+		g.peerTypes[t.Name] = peerType
+	} else if !g.Options.NoPeerTypeMissCache {
+		g.peerTypes[t.Name] = nil
 	}
 
 	return peerType
 }
 
+// typePeerPackages returns the peer packages that the types package's types ask to look for
+// their own peers in.
+func (g *Generator) typePeerPackages() (packages []string) {
+	for _, t := range g.typesPackage.Types {
+		if present, pkg := g.hasTagOption(t.CommentLines, "peerPackage"); present && len(pkg) != 0 {
+			packages = append(packages, pkg)
+		}
+	}
+	return
+}
+
+// localType returns whichever of inType and outType belongs to the types package, if any.
+func (g *Generator) localType(inType, outType *types.Type) *types.Type {
+	if outType.Name.Package == g.typesPackage.Path {
+		return outType
+	}
+	return inType
+}
+
 func (g *Generator) convertibleOnlyWithinPackage(inType, outType *types.Type) bool {
+	return g.notConvertibleReason(inType, outType) == ""
+}
+
+// notConvertibleReason returns why no conversion functions can be generated between inType and
+// outType in this package, or an empty string if they can.
+func (g *Generator) notConvertibleReason(inType, outType *types.Type) string {
 	var t, other *types.Type
 	if inType.Name.Package == g.typesPackage.Path {
 		t, other = inType, outType
@@ -636,16 +1286,35 @@ func (g *Generator) convertibleOnlyWithinPackage(inType, outType *types.Type) bo
 	}
 
 	if t.Name.Package != g.typesPackage.Path {
-		return false
+		return fmt.Sprintf("neither %v nor %v belong to package %s", inType, outType, g.typesPackage.Path)
 	}
 
 	if g.optedOut(t) {
 		klog.V(5).Infof("type %v requests no conversion generation, skipping", t)
-		return false
+		return fmt.Sprintf("%v opted out of conversion generation", t)
 	}
 
-	return t.Kind == types.Struct && // TODO: Consider generating functions for other kinds too
-		!namer.IsPrivateGoName(other.Name.Name) // filter out private types
+	if reason := g.notSelectedReason(t); reason != "" {
+		return fmt.Sprintf("%v %s", t, reason)
+	}
+	if reason := g.notInSelectedFilesReason(t); reason != "" {
+		return fmt.Sprintf("%v %s", t, reason)
+	}
+
+	// TODO: Consider generating functions for other kinds too
+	if t.Kind != types.Struct {
+		return fmt.Sprintf("%v is a %s, only structs are supported", t, t.Kind)
+	}
+	if namer.IsPrivateGoName(other.Name.Name) {
+		// filter out private types, unless generated code can refer to them
+		if !g.Options.PrivatePeerTypes {
+			return fmt.Sprintf("%v is private", other)
+		}
+		if other.Name.Package != g.outputPackage.Path {
+			return fmt.Sprintf("%v is private, and doesn't belong to output package %s", other, g.outputPackage.Path)
+		}
+	}
+	return ""
 }
 
 // optedOut returns true iff type (or member) t has a comment tag of the form "<tag-name>=false"
@@ -664,10 +1333,40 @@ func (g *Generator) optedOut(t interface{}) bool {
 	return g.hasTag(commentLines, "false")
 }
 
+// transformsValue returns true iff the member has tags changing its value during conversions, or
+// forcing it to be deep-copied, see forcesDeepCopy.
+func (g *Generator) transformsValue(member *types.Member) bool {
+	scaled, _ := g.hasTagOption(member.CommentLines, "scale")
+	expr, _ := g.hasTagOption(member.CommentLines, "expr")
+	return scaled || expr || g.hasTag(member.CommentLines, "deep-copy")
+}
+
 func (g *Generator) noPublicFun(t *types.Type) bool {
 	return g.hasTag(t.CommentLines, "no-public")
 }
 
+// publicFunctionOnError returns true iff the public conversion function between inType and outType
+// should be generated even if some of their fields couldn't be converted; see
+// Options.PublicFunctionOnError.
+func (g *Generator) publicFunctionOnError(inType, outType *types.Type) bool {
+	return g.Options.PublicFunctionOnError || g.hasTag(inType.CommentLines, "public-on-error") || g.hasTag(outType.CommentLines, "public-on-error")
+}
+
+// deprecationMessage returns the message to use in the "Deprecated:" paragraph of the public
+// conversion function between inType and outType, if either of them has a
+// "+<tag-name>=deprecated" or "+<tag-name>=deprecated:<message>" tag.
+func (g *Generator) deprecationMessage(inType, outType *types.Type) (string, bool) {
+	for _, t := range []*types.Type{inType, outType} {
+		if present, message := g.hasTagOption(t.CommentLines, "deprecated"); present && message != "" {
+			return message, true
+		}
+		if g.hasTag(t.CommentLines, "deprecated") {
+			return fmt.Sprintf("conversions involving %v are deprecated.", t), true
+		}
+	}
+	return "", false
+}
+
 func (g *Generator) hasTag(comments []string, value string) bool {
 	vals := g.extractTag(comments)
 	for _, val := range vals {
@@ -682,7 +1381,7 @@ func (g *Generator) hasTag(comments []string, value string) bool {
 func (g *Generator) hasTagOption(comments []string, optionName string) (bool, string) {
 	vals := g.extractTag(comments)
 	for _, val := range vals {
-		split := strings.Split(val, ":")
+		split := strings.SplitN(val, ":", 2)
 		if len(split) == 2 && split[0] == optionName {
 			return true, split[1]
 		}
@@ -714,8 +1413,55 @@ func (g *Generator) preexists(inType, outType *types.Type) (*types.Type, bool) {
 	return g.Options.ManualConversionsTracker.preexists(inType, outType)
 }
 
+// noUnsafeTag is the value of the "+<tag-name>" tag that disables unsafe conversions for the
+// package whose doc.go file has it.
+const noUnsafeTag = "no-unsafe"
+
+// packageOptionTags are the values of the "+<tag-name>" tag in doc.go files that set package-level
+// options; all the other values name peer packages, as both share the same tag by default, see
+// Options.PeerPackagesTagName. New package-level options must be added here.
+var packageOptionTags = map[string]bool{
+	noUnsafeTag: true,
+}
+
+// docFileTags returns the peer packages named in the types package's doc.go file, and the
+// package-level options that it sets, see packageOptionTags.
+func (g *Generator) docFileTags() (peerPackages []string, options map[string]bool) {
+	options = make(map[string]bool)
+	for _, value := range g.extractDocFileTag(g.Options.TagName) {
+		if packageOptionTags[value] {
+			options[value] = true
+		}
+	}
+	for _, value := range g.extractDocFileTag(g.Options.PeerPackagesTagName) {
+		if !packageOptionTags[value] {
+			peerPackages = append(peerPackages, value)
+		}
+	}
+	return
+}
+
+// unsafeConversionsDisabled returns true iff unsafe conversions are disabled, either by
+// Options.NoUnsafeConversions, or for the types package by a "+<tag-name>=no-unsafe" tag in its
+// doc.go file.
+func (g *Generator) unsafeConversionsDisabled() bool {
+	return g.Options.NoUnsafeConversions || g.noUnsafeConversions
+}
+
 func (g *Generator) useUnsafeConversion(t1, t2 *types.Type) bool {
-	return !g.Options.NoUnsafeConversions && g.unsafeConversionArbitrator.canUseUnsafeConversion(t1, t2)
+	return !g.unsafeConversionsDisabled() && g.unsafeConversionArbitrator.canUseUnsafeConversion(t1, t2)
+}
+
+// WillUseUnsafe returns true iff struct fields of types in and out get converted with an unsafe
+// cast, i.e. iff they are pointers, maps or slices with the same memory layout, and unsafe
+// conversions are allowed. Decisions are cached, and shared with generation.
+func (g *Generator) WillUseUnsafe(in, out *types.Type) bool {
+	switch unwrapAlias(in).Kind {
+	case types.Pointer, types.Map, types.Slice:
+		return g.useUnsafeConversion(in, out)
+	default:
+		return false
+	}
 }
 
 func (g *Generator) ManualConversions() map[ConversionPair]*types.Type {
@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"testing"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+func TestGetPeerTypeForSameNamedTypes(t *testing.T) {
+	universe := types.Universe{}
+	newType := func(pkgPath, name string, commentLines ...string) *types.Type {
+		t := universe.Type(types.Name{Package: pkgPath, Name: name})
+		t.Kind = types.Struct
+		t.CommentLines = commentLines
+		return t
+	}
+
+	fooA := newType("example.com/a/v1", "Foo", "+conversion-gen=peerPackage:example.com/a/v2")
+	fooB := newType("example.com/b/v1", "Foo", "+conversion-gen=peerPackage:example.com/b/v2")
+	peerA := newType("example.com/a/v2", "Foo")
+	peerB := newType("example.com/b/v2", "Foo")
+
+	g := &Generator{
+		Options:   DefaultOptions(),
+		peerTypes: make(map[types.Name]*types.Type),
+	}
+	context := &generator.Context{Universe: universe}
+
+	// twice, the second time from the cache
+	for i := 0; i < 2; i++ {
+		if peer := g.GetPeerTypeFor(context, fooA); peer != peerA {
+			t.Errorf("expected %v's peer to be %v, got %v", fooA, peerA, peer)
+		}
+		if peer := g.GetPeerTypeFor(context, fooB); peer != peerB {
+			t.Errorf("expected %v's peer to be %v, got %v", fooB, peerB, peer)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+const (
+	convertSliceHelper = "convertSlice"
+	convertMapHelper   = "convertMap"
+)
+
+// genericHelpers are the definitions of the generic helpers written to generated files when
+// Options.GenericHelpers is set, indexed by name.
+// Options.GenericHelpersPackage can provide them instead, exported.
+var genericHelpers = map[string]string{
+	convertSliceHelper: `func convertSlice[In, Out any](in []In, out *[]Out, convert func(*In, *Out) error) error {
+	if in == nil {
+		*out = nil
+		return nil
+	}
+	*out = make([]Out, len(in))
+	for i := range in {
+		if err := convert(&in[i], &(*out)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+`,
+	convertMapHelper: `func convertMap[K comparable, In, Out any](in map[K]In, out *map[K]Out, convert func(*In, *Out) error) error {
+	if in == nil {
+		*out = nil
+		return nil
+	}
+	*out = make(map[K]Out, len(in))
+	for key, val := range in {
+		var converted Out
+		if err := convert(&val, &converted); err != nil {
+			return err
+		}
+		(*out)[key] = converted
+	}
+	return nil
+}
+`,
+}
+
+// doGenericHelperField writes the conversion of a slice or map struct field by calling a generic
+// helper, if Options.GenericHelpers is set and its elements are converted by conversion
+// functions; returns true iff it did.
+func (g *Generator) doGenericHelperField(inType, outType *types.Type, inMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if !g.Options.GenericHelpers {
+		return false
+	}
+
+	var helper string
+	switch {
+	case inMemberType.Kind == types.Slice && outMemberType.Kind == types.Slice:
+		helper = convertSliceHelper
+	case inMemberType.Kind == types.Map && outMemberType.Kind == types.Map && inMemberType.Key == outMemberType.Key:
+		helper = convertMapHelper
+	default:
+		return false
+	}
+
+	inElem, outElem := inMemberType.Elem, outMemberType.Elem
+	function, manual := g.preexists(inElem, outElem)
+	if !manual {
+		if unwrapAlias(inElem).Kind != types.Struct || !g.convertibleOnlyWithinPackage(inElem, outElem) {
+			return false
+		}
+		function = g.publicFunction(inElem, outElem)
+	}
+
+	helperRef := types.Ref(g.outputPackage.Path, helper)
+	if g.Options.GenericHelpersPackage != "" {
+		helperRef = types.Ref(g.Options.GenericHelpersPackage, exportedName(helper))
+	} else {
+		g.usedGenericHelpers[helper] = true
+	}
+	args = args.With("helper", helperRef).With("function", function).With("inElem", inElem).With("outElem", outElem).
+		With("inContainer", unnamedContainer(inMemberType)).With("outContainer", unnamedContainer(outMemberType))
+
+	// named slice and map types need converting to their underlying types, as the helpers' type
+	// parameters can't be inferred from pointers to them
+	inArg, outArg := "in.$.name$", "&out.$.name$"
+	if inMemberType.Name.Package != "" {
+		inArg = "$.inContainer|" + rawNamer + "$(in.$.name$)"
+	}
+	if outMemberType.Name.Package != "" {
+		outArg = "(*$.outContainer|" + rawNamer + "$)(&out.$.name$)"
+	}
+	sw.Do("if err := $.helper|"+rawNamer+"$("+inArg+", "+outArg+", ", args)
+	valueInput := (manual && takesValueInput(function)) || (!manual && g.smallStruct(inElem))
+	if extraArgs := g.extraArgumentsString(); extraArgs == "" && !valueInput {
+		sw.Do("$.function|"+rawNamer+"$", args)
+	} else {
+		in := "in"
+		if valueInput {
+			in = "*in"
+		}
+		sw.Do("func(in *$.inElem|"+rawNamer+"$, out *$.outElem|"+rawNamer+"$) error {\n", args)
+		sw.Do("return $.function|"+rawNamer+"$("+in+", out"+extraArgs+")\n", args)
+		sw.Do("}", nil)
+	}
+	sw.Do("); err != nil {\n", nil)
+	sw.Do(g.returnErr()+"\n", nil)
+	sw.Do("}\n", nil)
+
+	if manual {
+		g.recordManualCall(function)
+	} else {
+		g.recordInternalCall(inElem, outElem)
+	}
+	g.explainFieldf(inType, outType, inMember.Name, FieldElements, "%s converted by generic helper %s, with %v", inMemberType.Kind, helper, function)
+	return true
+}
+
+// writeGenericHelpers writes the generic helpers used by the generated code of the output
+// package, if any.
+func (g *Generator) writeGenericHelpers(sw *generator.SnippetWriter) {
+	for _, helper := range []string{convertSliceHelper, convertMapHelper} {
+		for _, other := range g.packageGenerators() {
+			if other.usedGenericHelpers[helper] {
+				sw.Do("$.$\n", genericHelpers[helper])
+				break
+			}
+		}
+	}
+}
+
+// unnamedContainer returns the unnamed slice or map type underlying t.
+func unnamedContainer(t *types.Type) *types.Type {
+	return &types.Type{Kind: t.Kind, Key: t.Key, Elem: t.Elem}
+}
+
+func exportedName(name string) string {
+	return string(name[0]-'a'+'A') + name[1:]
+}
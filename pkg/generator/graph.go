@@ -0,0 +1,290 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/gengo/types"
+)
+
+// ConversionFunctionKind is the kind of a node in a ConversionGraph.
+type ConversionFunctionKind string
+
+const (
+	// GeneratedFunction is a conversion function generated by a generator.
+	GeneratedFunction ConversionFunctionKind = "generated"
+	// ManualFunction is a manually written conversion function.
+	ManualFunction ConversionFunctionKind = "manual"
+	// ExternalConversion stands for conversion code that was delegated to the
+	// ExternalConversionsHandler, or left for manual conversion if there is none.
+	ExternalConversion ConversionFunctionKind = "external"
+	// UnknownFunction is a function that generated code calls, but that is neither generated nor
+	// known as a manual conversion function - typically because it still needs to be written.
+	UnknownFunction ConversionFunctionKind = "unknown"
+)
+
+// A ConversionGraph collects the conversion functions that generators generate or use, and which
+// of them call which; so that it can be visualized e.g. with graphviz.
+// A graph can be shared between generators.
+type ConversionGraph struct {
+	// nodes are the kinds of the functions known so far, indexed by their IDs.
+	nodes map[string]ConversionFunctionKind
+	// labels are the functions' labels, indexed by their IDs.
+	labels map[string]string
+	// edges are the functions each function calls, indexed by their IDs; values are true iff
+	// the call is made on the caller's own input, see ConversionCall.SameObject.
+	edges map[string]map[string]bool
+}
+
+// NewConversionGraph builds a new ConversionGraph.
+func NewConversionGraph() *ConversionGraph {
+	return &ConversionGraph{
+		nodes:  make(map[string]ConversionFunctionKind),
+		labels: make(map[string]string),
+		edges:  make(map[string]map[string]bool),
+	}
+}
+
+// addNode adds a node, or updates its kind if it is still unknown.
+func (c *ConversionGraph) addNode(id, label string, kind ConversionFunctionKind) {
+	if previous, present := c.nodes[id]; present && (previous != UnknownFunction || kind == UnknownFunction) {
+		return
+	}
+	c.nodes[id] = kind
+	c.labels[id] = label
+}
+
+func (c *ConversionGraph) addEdge(from, to string, sameObject bool) {
+	if c.edges[from] == nil {
+		c.edges[from] = make(map[string]bool)
+	}
+	c.edges[from][to] = c.edges[from][to] || sameObject
+}
+
+// removeNode removes a node, along with the calls it makes.
+func (c *ConversionGraph) removeNode(id string) {
+	delete(c.nodes, id)
+	delete(c.labels, id)
+	delete(c.edges, id)
+}
+
+// renameNode gives node from the ID to, and the given label; along with the calls it makes and
+// receives.
+func (c *ConversionGraph) renameNode(from, to, label string) {
+	kind, present := c.nodes[from]
+	if !present {
+		return
+	}
+	edges := c.edges[from]
+	c.removeNode(from)
+	c.addNode(to, label, kind)
+	for callee, sameObject := range edges {
+		c.addEdge(to, callee, sameObject)
+	}
+	for _, callees := range c.edges {
+		if sameObject, calls := callees[from]; calls {
+			delete(callees, from)
+			callees[to] = callees[to] || sameObject
+		}
+	}
+}
+
+// Kind returns the kind of the function with the given ID, as returned by FunctionID.
+func (c *ConversionGraph) Kind(id string) (ConversionFunctionKind, bool) {
+	kind, present := c.nodes[id]
+	return kind, present
+}
+
+// Calls returns the IDs of the functions that the function with the given ID calls, sorted.
+func (c *ConversionGraph) Calls(id string) []string {
+	return sortedKeys(c.edges[id])
+}
+
+// Cycles returns the cycles of calls made on the same object, that would recurse infinitely at
+// runtime; each cycle is a list of function IDs, starting and ending with the same function.
+// Cycles going through fields (e.g. for recursive types) are fine, and not reported.
+func (c *ConversionGraph) Cycles() (cycles [][]string) {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	states := make(map[string]int, len(c.nodes))
+	var stack []string
+
+	var visit func(id string)
+	visit = func(id string) {
+		states[id] = inProgress
+		stack = append(stack, id)
+		for _, callee := range c.Calls(id) {
+			if !c.edges[id][callee] {
+				continue
+			}
+			switch states[callee] {
+			case unvisited:
+				visit(callee)
+			case inProgress:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == callee {
+						cycle := append(append([]string{}, stack[i:]...), callee)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[id] = done
+	}
+
+	ids := make(map[string]bool, len(c.nodes))
+	for id := range c.nodes {
+		ids[id] = true
+	}
+	for _, id := range sortedKeys(ids) {
+		if states[id] == unvisited {
+			visit(id)
+		}
+	}
+	return
+}
+
+// FunctionID returns the ID of the given function in conversion graphs.
+func FunctionID(function *types.Type) string {
+	return function.Name.String()
+}
+
+// WriteDOT writes the graph in graphviz's DOT format.
+func (c *ConversionGraph) WriteDOT(w io.Writer) error {
+	ids := make(map[string]bool, len(c.nodes))
+	for id := range c.nodes {
+		ids[id] = true
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("digraph conversions {\n")
+	buffer.WriteString("  rankdir=LR;\n")
+	buffer.WriteString("  node [shape=box];\n")
+	for _, id := range sortedKeys(ids) {
+		fmt.Fprintf(buffer, "  %q [label=%q%s];\n", id, c.labels[id], dotNodeStyle(c.nodes[id]))
+	}
+	for _, from := range sortedKeys(ids) {
+		for _, to := range c.Calls(from) {
+			fmt.Fprintf(buffer, "  %q -> %q;\n", from, to)
+		}
+	}
+	buffer.WriteString("}\n")
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+func dotNodeStyle(kind ConversionFunctionKind) string {
+	switch kind {
+	case ManualFunction:
+		return ", style=filled, fillcolor=lightblue"
+	case ExternalConversion:
+		return ", shape=ellipse, style=dashed, color=red"
+	case UnknownFunction:
+		return ", style=dashed"
+	default:
+		return ""
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// privateFunction returns a reference to the generated private conversion function
+// from inType to outType.
+func (g *Generator) privateFunction(inType, outType *types.Type) *types.Type {
+	return types.Ref(g.outputPackage.Path, g.privateFunctionName(inType, outType))
+}
+
+// privateFunctionName returns the name of the private conversion function from inType to outType,
+// see Options.PrivateFunctionPrefix.
+func (g *Generator) privateFunctionName(inType, outType *types.Type) string {
+	return PrivateConversionFunctionName(g.Options.PrivateFunctionPrefix, inType, outType)
+}
+
+// publicFunction returns a reference to the public conversion function from inType to outType,
+// in the output package.
+func (g *Generator) publicFunction(inType, outType *types.Type) *types.Type {
+	return types.Ref(g.outputPackage.Path, ConversionFunctionName(inType, outType))
+}
+
+// recordFunction records a conversion function in the generator's conversion graph, if any.
+// For manual functions, that includes the calls they make to other conversion functions.
+func (g *Generator) recordFunction(function *types.Type, kind ConversionFunctionKind) {
+	if g.Options.Graph == nil {
+		return
+	}
+	id := FunctionID(function)
+	if previous, known := g.Options.Graph.Kind(id); known && previous == kind {
+		return
+	}
+	g.Options.Graph.addNode(id, function.Name.Name, kind)
+
+	if kind == ManualFunction {
+		for _, call := range g.Options.ManualConversionsTracker.manualCalls(function) {
+			calleeKind := UnknownFunction
+			if g.Options.ManualConversionsTracker.isManualFunction(call.Callee) {
+				calleeKind = ManualFunction
+			}
+			g.recordCall(function, call.Callee, calleeKind, call.SameObject)
+		}
+	}
+}
+
+// forgetFunction removes a function recorded by recordFunction, e.g. because it ended up not
+// being generated.
+func (g *Generator) forgetFunction(function *types.Type) {
+	if g.Options.Graph != nil {
+		g.Options.Graph.removeNode(FunctionID(function))
+	}
+}
+
+// renameFunction renames a function recorded by recordFunction, e.g. because it ended up being
+// generated under another name.
+func (g *Generator) renameFunction(function, renamed *types.Type) {
+	if g.Options.Graph != nil {
+		g.Options.Graph.renameNode(FunctionID(function), FunctionID(renamed), renamed.Name.Name)
+	}
+}
+
+// recordCall records that function calls the callee, of the given kind, in the generator's
+// conversion graph, if any.
+func (g *Generator) recordCall(function, callee *types.Type, kind ConversionFunctionKind, sameObject bool) {
+	if g.Options.Graph == nil {
+		return
+	}
+	g.recordFunction(callee, kind)
+	g.Options.Graph.addEdge(FunctionID(function), FunctionID(callee), sameObject)
+}
+
+// recordInternalCall records that the private conversion function currently being generated
+// calls the public conversion function from inType to outType, in the output package.
+func (g *Generator) recordInternalCall(inType, outType *types.Type) {
+	g.recordCall(g.currentFunction, g.publicFunction(inType, outType), UnknownFunction, false)
+}
+
+// recordManualCall records that the private conversion function currently being generated
+// calls the given manual conversion function.
+func (g *Generator) recordManualCall(function *types.Type) {
+	g.recordCall(g.currentFunction, function, ManualFunction, false)
+}
+
+// recordExternalCall records that the private conversion function currently being generated
+// needs an external conversion from inType to outType.
+func (g *Generator) recordExternalCall(inType, outType *types.Type) {
+	external := types.Ref("", fmt.Sprintf("external: %v -> %v", inType, outType))
+	g.recordCall(g.currentFunction, external, ExternalConversion, false)
+}
@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// A HandlerContext tells handlers (see e.g. Options.MissingFieldsHandler) where the code they
+// write ends up.
+type HandlerContext struct {
+	// InType and OutType are the types that the conversion function being generated converts.
+	InType, OutType *types.Type
+	// FieldPath are the names of the fields of InType, from the outermost, that the handled
+	// variables are or are in; for field handlers, that includes the handled field itself.
+	// It is empty when InType isn't a struct.
+	FieldPath []string
+	// Nesting are the kinds of the maps, slices and pointers, from the outermost, that the handled
+	// variables are elements of; e.g. [Map, Slice] for elements of slices that are values of a map.
+	// It is empty when the handled variables are fields, or InType and OutType themselves.
+	Nesting []types.Kind
+	// AdditionalArguments are the additional arguments of conversion functions, see
+	// NewManualConversionsTracker.
+	AdditionalArguments []NamedVariable
+
+	// Context is the generator's context, with the universe of known types.
+	Context *generator.Context
+	// RawNamer names types as they're referred to in the output package, e.g. "v1.Foo",
+	// registering the imports they need; it's the namer that snippets get with RawNamer.
+	RawNamer namer.Namer
+	// PublicNamer names types as they appear in conversion function names, e.g. "v1_Foo",
+	// registering the imports they need; it's the namer that snippets get with PublicNamer.
+	PublicNamer namer.Namer
+	// ImportTracker tracks the imports of the file being generated.
+	ImportTracker namer.ImportTracker
+
+	// generator is the generator calling the handler, for the built-in handlers.
+	generator *Generator
+}
+
+// QualifiedName returns how to refer to the given identifier of the given package - e.g. a
+// function - from the output package, and makes sure that the package gets imported.
+func (c *HandlerContext) QualifiedName(pkgPath, name string) string {
+	return c.RawNamer.Name(types.Ref(pkgPath, name))
+}
+
+// handlerContext returns the context to pass to handlers at this point of the generation.
+func (g *Generator) handlerContext() *HandlerContext {
+	namers := g.Namers(g.context)
+	return &HandlerContext{
+		InType:              g.currentInType,
+		OutType:             g.currentOutType,
+		FieldPath:           append([]string{}, g.fieldPath...),
+		Nesting:             append([]types.Kind{}, g.nesting...),
+		AdditionalArguments: g.Options.ManualConversionsTracker.additionalConversionArguments,
+		Context:             g.context,
+		RawNamer:            namers[rawNamer],
+		PublicNamer:         namers[publicImportTrackingNamer],
+		ImportTracker:       g.ImportTracker,
+		generator:           g,
+	}
+}
+
+// nest records that code is being generated for the elements of a container of the given kind,
+// until the returned function gets called.
+func (g *Generator) nest(kind types.Kind) (unnest func()) {
+	depth := len(g.nesting)
+	g.nesting = append(g.nesting, kind)
+	return func() { g.nesting = g.nesting[:depth] }
+}
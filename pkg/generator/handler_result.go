@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"fmt"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// A HandlerOutcome is what a handler did, see HandlerResult.
+type HandlerOutcome string
+
+const (
+	// HandlerHandled means that the handler wrote the code converting the handled variables.
+	HandlerHandled HandlerOutcome = "handled"
+	// HandlerSkipped means that the handler left the handled variables alone: the generator then
+	// warns about them, as if there were no handler.
+	HandlerSkipped HandlerOutcome = "skipped"
+	// HandlerFailed means that the handled variables need to be converted manually; which prevents
+	// generating the public conversion function, see Options.PublicFunctionOnError.
+	HandlerFailed HandlerOutcome = "failed"
+)
+
+// A HandlerResult is what handlers (see e.g. Options.MissingFieldsHandler) return.
+type HandlerResult struct {
+	Outcome HandlerOutcome
+	// Err is why the handler failed, with HandlerFailed; it can be a SkipConversionError.
+	Err error
+
+	// unconverted means that the handled variables still need converting manually, with
+	// HandlerHandled: they get reported as such, without a warning; see ReportMissingField.
+	unconverted bool
+}
+
+// Handled returns a HandlerHandled result.
+func Handled() HandlerResult {
+	return HandlerResult{Outcome: HandlerHandled}
+}
+
+// reported returns a HandlerHandled result, still reporting the handled variables as needing
+// manual conversion.
+func reported() HandlerResult {
+	return HandlerResult{Outcome: HandlerHandled, unconverted: true}
+}
+
+// Skipped returns a HandlerSkipped result.
+func Skipped() HandlerResult {
+	return HandlerResult{Outcome: HandlerSkipped}
+}
+
+// Failed returns a HandlerFailed result, failing with err.
+func Failed(err error) HandlerResult {
+	return HandlerResult{Outcome: HandlerFailed, Err: err}
+}
+
+// Failedf returns a HandlerFailed result, failing with the given message.
+func Failedf(format string, args ...interface{}) HandlerResult {
+	return Failed(fmt.Errorf(format, args...))
+}
+
+// The adapters below turn handlers written for the signatures that handlers used to have into
+// current ones: errors make them fail, and ExternalConversionsHandler's boolean being false makes
+// it skip.
+
+// AdaptMissingFieldsHandler adapts a missing fields handler with the former signature.
+func AdaptMissingFieldsHandler(handler func(inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) error) func(*HandlerContext, NamedVariable, NamedVariable, *types.Member, *generator.SnippetWriter) HandlerResult {
+	return func(_ *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+		return resultFromError(handler(inVar, outVar, member, sw))
+	}
+}
+
+// AdaptInconvertibleFieldsHandler adapts an inconvertible fields handler with the former signature.
+func AdaptInconvertibleFieldsHandler(handler func(inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) error) func(*HandlerContext, NamedVariable, NamedVariable, *types.Member, *types.Member, *generator.SnippetWriter) HandlerResult {
+	return func(_ *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+		return resultFromError(handler(inVar, outVar, inMember, outMember, sw))
+	}
+}
+
+// AdaptUnsupportedTypesHandler adapts an unsupported types handler with the former signature.
+func AdaptUnsupportedTypesHandler(handler func(inVar, outVar NamedVariable, sw *generator.SnippetWriter) error) func(*HandlerContext, NamedVariable, NamedVariable, *generator.SnippetWriter) HandlerResult {
+	return func(_ *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+		return resultFromError(handler(inVar, outVar, sw))
+	}
+}
+
+// AdaptExternalConversionsHandler adapts an external conversions handler with the former signature.
+func AdaptExternalConversionsHandler(handler func(inVar, outVar NamedVariable, sw *generator.SnippetWriter) (bool, error)) func(*HandlerContext, NamedVariable, NamedVariable, *generator.SnippetWriter) HandlerResult {
+	return func(_ *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+		handled, err := handler(inVar, outVar, sw)
+		if err != nil {
+			return Failed(err)
+		}
+		if !handled {
+			return Skipped()
+		}
+		return Handled()
+	}
+}
+
+func resultFromError(err error) HandlerResult {
+	if err != nil {
+		return Failed(err)
+	}
+	return Handled()
+}
+
+// The methods below call Options.HandlerPlugin, if set, then the corresponding handlers, if set,
+// if it skipped; and skip otherwise.
+
+func (g *Generator) handleMissingField(inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	if g.Options.HandlerPlugin != nil {
+		if result := g.Options.HandlerPlugin.MissingFieldsHandler()(g.handlerContext(), inVar, outVar, member, sw); result.Outcome != HandlerSkipped {
+			return result
+		}
+	}
+	if g.Options.MissingFieldsHandler == nil {
+		return Skipped()
+	}
+	return g.Options.MissingFieldsHandler(g.handlerContext(), inVar, outVar, member, sw)
+}
+
+func (g *Generator) handleInconvertibleField(inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+	if g.Options.HandlerPlugin != nil {
+		if result := g.Options.HandlerPlugin.InconvertibleFieldsHandler()(g.handlerContext(), inVar, outVar, inMember, outMember, sw); result.Outcome != HandlerSkipped {
+			return result
+		}
+	}
+	if g.Options.InconvertibleFieldsHandler == nil {
+		return Skipped()
+	}
+	return g.Options.InconvertibleFieldsHandler(g.handlerContext(), inVar, outVar, inMember, outMember, sw)
+}
+
+func (g *Generator) handleUnsupportedType(inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+	if g.Options.HandlerPlugin != nil {
+		if result := g.Options.HandlerPlugin.UnsupportedTypesHandler()(g.handlerContext(), inVar, outVar, sw); result.Outcome != HandlerSkipped {
+			return result
+		}
+	}
+	if g.Options.UnsupportedTypesHandler == nil {
+		return Skipped()
+	}
+	return g.Options.UnsupportedTypesHandler(g.handlerContext(), inVar, outVar, sw)
+}
+
+func (g *Generator) handleExternalConversion(inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+	if g.Options.HandlerPlugin != nil {
+		if result := g.Options.HandlerPlugin.ExternalConversionsHandler()(g.handlerContext(), inVar, outVar, sw); result.Outcome != HandlerSkipped {
+			return result
+		}
+	}
+	if g.Options.ExternalConversionsHandler == nil {
+		return Skipped()
+	}
+	return g.Options.ExternalConversionsHandler(g.handlerContext(), inVar, outVar, sw)
+}
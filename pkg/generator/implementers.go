@@ -0,0 +1,329 @@
+package generator
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// loadImplementersSelection computes which of the types package's types conversions get generated
+// for when Options.ImplementersOf is set: those implementing the interface, and the types of the
+// package that their fields need converting.
+func (g *Generator) loadImplementersSelection(context *generator.Context) error {
+	if g.Options.ImplementersOf == "" {
+		return nil
+	}
+
+	ref := registryFunctionRef(g.Options.ImplementersOf)
+	if ref.Name.Package == "" {
+		return errors.Errorf("invalid interface %q, must be of the form <pkg-path>.<name>", g.Options.ImplementersOf)
+	}
+	pkg, err := getPackage(context, ref.Name.Package)
+	if err != nil {
+		return err
+	}
+	iface := pkg.Types[ref.Name.Name]
+	if iface == nil {
+		// the universe only holds the types that loaded packages refer to, e.g. not runtime.Object
+		// if only generated files do
+		if pkg, err = context.AddDirectory(ref.Name.Package); err != nil {
+			return errors.Wrapf(err, "unable to load package %q", ref.Name.Package)
+		}
+		iface = pkg.Types[ref.Name.Name]
+	}
+	if iface == nil || unwrapAlias(iface).Kind != types.Interface {
+		return errors.Errorf("%q is not an interface", g.Options.ImplementersOf)
+	}
+	iface = unwrapAlias(iface)
+
+	g.selectedTypes = make(map[*types.Type]bool)
+	generated := newGeneratedMethods(context.Universe)
+	for _, t := range g.typesPackage.Types {
+		if t.Kind == types.Struct && implements(t, iface, generated) {
+			g.selectReferencedTypes(t)
+		}
+	}
+	if len(g.selectedTypes) == 0 {
+		klog.Warningf("No type of %s implements %s, no conversions get generated for it", g.typesPackage.Path, g.Options.ImplementersOf)
+	}
+	return nil
+}
+
+// selectReferencedTypes adds t to the selected types if it belongs to the types package, along with
+// the types of the package that it refers to.
+func (g *Generator) selectReferencedTypes(t *types.Type) {
+	if t == nil {
+		return
+	}
+	if t.Name.Package == g.typesPackage.Path && t.Name.Name != "" {
+		if g.selectedTypes[t] {
+			return
+		}
+		g.selectedTypes[t] = true
+	}
+
+	switch t.Kind {
+	case types.Struct:
+		for _, member := range t.Members {
+			g.selectReferencedTypes(member.Type)
+		}
+	case types.Alias:
+		g.selectReferencedTypes(t.Underlying)
+	case types.Map:
+		g.selectReferencedTypes(t.Key)
+		g.selectReferencedTypes(t.Elem)
+	case types.Pointer, types.Slice, types.Array:
+		g.selectReferencedTypes(t.Elem)
+	}
+}
+
+// notSelectedReason returns why t isn't selected by Options.ImplementersOf, if it isn't.
+func (g *Generator) notSelectedReason(t *types.Type) string {
+	if g.selectedTypes == nil || g.selectedTypes[t] {
+		return ""
+	}
+	return "neither implements " + g.Options.ImplementersOf + " nor is needed by a type that does"
+}
+
+// implements returns true iff t, or a pointer to it, has all of iface's methods; promoted methods
+// of embedded fields included, as well as methods declared in generated files, see
+// generatedMethods.
+func implements(t, iface *types.Type, generated *generatedMethods) bool {
+	methods := methodSet(t, generated, map[*types.Type]bool{})
+	for name, method := range iface.Methods {
+		if !sameSignature(methods[name], method) {
+			return false
+		}
+	}
+	return true
+}
+
+// methodSet returns the methods of t and of a pointer to it, by name, including promoted ones.
+func methodSet(t *types.Type, generated *generatedMethods, visited map[*types.Type]bool) map[string]*types.Type {
+	methods := make(map[string]*types.Type)
+	if t == nil || visited[t] {
+		return methods
+	}
+	visited[t] = true
+
+	for _, member := range unwrapAlias(t).Members {
+		if !member.Embedded {
+			continue
+		}
+		embedded := member.Type
+		if embedded.Kind == types.Pointer {
+			embedded = embedded.Elem
+		}
+		for name, method := range methodSet(embedded, generated, visited) {
+			methods[name] = method
+		}
+	}
+	// declared methods shadow promoted ones
+	for name, method := range generated.of(t) {
+		methods[name] = method
+	}
+	for name, method := range t.Methods {
+		methods[name] = method
+	}
+	return methods
+}
+
+// generatedMethods are the methods declared in packages' generated files, e.g. DeepCopyObject in
+// zz_generated.deepcopy.go: gengo doesn't load these, as they have a "!ignore_autogenerated" build
+// constraint. They're parsed from source, so only have the names of their parameters' and
+// results' types, which is all sameSignature looks at.
+type generatedMethods struct {
+	universe types.Universe
+	// byPackage are the methods found so far, indexed by package path, type name then method name.
+	byPackage map[string]map[string]map[string]*types.Type
+}
+
+func newGeneratedMethods(universe types.Universe) *generatedMethods {
+	return &generatedMethods{
+		universe:  universe,
+		byPackage: make(map[string]map[string]map[string]*types.Type),
+	}
+}
+
+// of returns the methods of t declared in generated files, by name.
+func (m *generatedMethods) of(t *types.Type) map[string]*types.Type {
+	if t.Name.Package == "" {
+		return nil
+	}
+	methods, loaded := m.byPackage[t.Name.Package]
+	if !loaded {
+		if pkg := m.universe[t.Name.Package]; pkg != nil {
+			methods = parseGeneratedMethods(t.Name.Package, pkg.SourcePath)
+		}
+		m.byPackage[t.Name.Package] = methods
+	}
+	return methods[t.Name.Name]
+}
+
+// parseGeneratedMethods parses the generated files of the package at sourcePath, i.e. those that
+// only get built without the ignore_autogenerated build tag, and returns the methods they declare,
+// indexed by receiver type name then method name.
+func parseGeneratedMethods(pkgPath, sourcePath string) map[string]map[string]*types.Type {
+	methods := make(map[string]map[string]*types.Type)
+	if sourcePath == "" {
+		return methods
+	}
+
+	withTag := build.Default
+	withTag.BuildTags = append(append([]string(nil), withTag.BuildTags...), "ignore_autogenerated")
+	fileSet := token.NewFileSet()
+	packages, err := parser.ParseDir(fileSet, sourcePath, func(info os.FileInfo) bool {
+		if strings.HasSuffix(info.Name(), "_test.go") {
+			return false
+		}
+		withoutTagMatch, err := build.Default.MatchFile(sourcePath, info.Name())
+		if err != nil || !withoutTagMatch {
+			return false
+		}
+		withTagMatch, err := withTag.MatchFile(sourcePath, info.Name())
+		return err == nil && !withTagMatch
+	}, 0)
+	if err != nil {
+		klog.Warningf("unable to parse %q to find methods declared in generated files: %v", sourcePath, err)
+		return methods
+	}
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			imports := fileImports(file)
+			for _, decl := range file.Decls {
+				funcDecl, ok := decl.(*ast.FuncDecl)
+				if !ok || funcDecl.Recv == nil || len(funcDecl.Recv.List) != 1 {
+					continue
+				}
+				receiver := funcDecl.Recv.List[0].Type
+				if star, ok := receiver.(*ast.StarExpr); ok {
+					receiver = star.X
+				}
+				ident, ok := receiver.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if methods[ident.Name] == nil {
+					methods[ident.Name] = make(map[string]*types.Type)
+				}
+				methods[ident.Name][funcDecl.Name.Name] = &types.Type{
+					Name:      types.Name{Package: pkgPath, Name: funcDecl.Name.Name},
+					Kind:      types.Func,
+					Signature: parsedSignature(funcDecl.Type, pkgPath, imports),
+				}
+			}
+		}
+	}
+	return methods
+}
+
+// parsedSignature returns the signature of the given function type, with parameter and result
+// types that only have names, named as gengo does.
+func parsedSignature(funcType *ast.FuncType, pkgPath string, imports map[string]string) *types.Signature {
+	signature := &types.Signature{}
+	fieldTypes := func(fields *ast.FieldList) (fieldTypes []*types.Type) {
+		if fields == nil {
+			return nil
+		}
+		for _, field := range fields.List {
+			expr := field.Type
+			if ellipsis, ok := expr.(*ast.Ellipsis); ok {
+				signature.Variadic = true
+				expr = &ast.ArrayType{Elt: ellipsis.Elt}
+			}
+			t := &types.Type{Name: typeStringName(typeExprString(expr, pkgPath, imports))}
+			for i := 0; i < len(field.Names) || i == 0; i++ {
+				fieldTypes = append(fieldTypes, t)
+			}
+		}
+		return fieldTypes
+	}
+	signature.Parameters = fieldTypes(funcType.Params)
+	signature.Results = fieldTypes(funcType.Results)
+	return signature
+}
+
+// typeExprString returns the string that go/types would give the type of the given expression;
+// or an empty string for type expressions it doesn't know, that then don't match any type.
+func typeExprString(expr ast.Expr, pkgPath string, imports map[string]string) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if _, isType := gotypes.Universe.Lookup(expr.Name).(*gotypes.TypeName); isType {
+			return expr.Name
+		}
+		return pkgPath + "." + expr.Name
+	case *ast.SelectorExpr:
+		if x, ok := expr.X.(*ast.Ident); ok && imports[x.Name] != "" {
+			return imports[x.Name] + "." + expr.Sel.Name
+		}
+	case *ast.StarExpr:
+		if elem := typeExprString(expr.X, pkgPath, imports); elem != "" {
+			return "*" + elem
+		}
+	case *ast.ArrayType:
+		elem := typeExprString(expr.Elt, pkgPath, imports)
+		if elem == "" {
+			return ""
+		}
+		if expr.Len == nil {
+			return "[]" + elem
+		}
+		if length, ok := expr.Len.(*ast.BasicLit); ok {
+			return "[" + length.Value + "]" + elem
+		}
+	case *ast.MapType:
+		key, elem := typeExprString(expr.Key, pkgPath, imports), typeExprString(expr.Value, pkgPath, imports)
+		if key != "" && elem != "" {
+			return "map[" + key + "]" + elem
+		}
+	case *ast.InterfaceType:
+		if expr.Methods == nil || len(expr.Methods.List) == 0 {
+			return "interface{}"
+		}
+	}
+	return ""
+}
+
+// typeStringName returns the name that gengo gives types with the given go/types string.
+func typeStringName(typeString string) types.Name {
+	if typeString == "" || strings.ContainsAny(typeString[:1], "*[") || strings.HasPrefix(typeString, "map[") ||
+		strings.HasPrefix(typeString, "interface{") {
+		return types.Name{Name: typeString}
+	}
+	if i := strings.LastIndex(typeString, "."); i != -1 {
+		return types.Name{Package: typeString[:i], Name: typeString[i+1:]}
+	}
+	return types.Name{Name: typeString}
+}
+
+// sameSignature returns true iff methods a and b have the same parameter and result types.
+func sameSignature(a, b *types.Type) bool {
+	if a == nil || b == nil || a.Signature == nil || b.Signature == nil {
+		return a != nil && b != nil && a.Signature == b.Signature
+	}
+	if len(a.Signature.Parameters) != len(b.Signature.Parameters) || len(a.Signature.Results) != len(b.Signature.Results) ||
+		a.Signature.Variadic != b.Signature.Variadic {
+		return false
+	}
+	for i, parameter := range a.Signature.Parameters {
+		if parameter.Name != b.Signature.Parameters[i].Name {
+			return false
+		}
+	}
+	for i, result := range a.Signature.Results {
+		if result.Name != b.Signature.Results[i].Name {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// importGroup is the group an import belongs to in generated files, in order.
+type importGroup int
+
+const (
+	stdlibImports importGroup = iota
+	thirdPartyImports
+	localImports
+)
+
+type importLine struct {
+	line  string
+	path  string
+	group importGroup
+}
+
+// sortImports deduplicates import lines, of the form "<path>", "\"<path>\"" or "<name> \"<path>\"",
+// by path, keeping the first line for each path; and sorts them by group (standard library,
+// third-party, then packages matching localPrefixes, a comma-separated list of import path
+// prefixes), then by path.
+func sortImports(lines []string, localPrefixes string) []string {
+	seen := make(map[string]bool)
+	imports := make([]importLine, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		path := importPath(line)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		imports = append(imports, importLine{line: line, path: path, group: groupOf(path, localPrefixes)})
+	}
+
+	sort.SliceStable(imports, func(i, j int) bool {
+		if imports[i].group != imports[j].group {
+			return imports[i].group < imports[j].group
+		}
+		return imports[i].path < imports[j].path
+	})
+
+	sorted := make([]string, len(imports))
+	for i, imp := range imports {
+		sorted[i] = imp.line
+	}
+	return sorted
+}
+
+// importPath extracts the path from an import line.
+func importPath(line string) string {
+	index := strings.Index(line, `"`)
+	if index < 0 {
+		return line
+	}
+	if path, err := strconv.Unquote(line[index:]); err == nil {
+		return path
+	}
+	return strings.Trim(line[index:], `"`)
+}
+
+func groupOf(path, localPrefixes string) importGroup {
+	for _, prefix := range strings.Split(localPrefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" && strings.HasPrefix(path, prefix) {
+			return localImports
+		}
+	}
+	if !strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+		return stdlibImports
+	}
+	return thirdPartyImports
+}
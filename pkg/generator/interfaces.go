@@ -0,0 +1,239 @@
+package generator
+
+import (
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doOpaqueField writes the conversion of struct fields holding opaque values, e.g. interfaces or
+// raw-extension-like wrappers; returns true iff it did. That is:
+//   - fields with a "+<tag-name>=passthrough" tag, or whose type has it, get assigned as is, or
+//     with a type conversion if their types differ
+//   - interface fields get converted by Options.InterfaceConversionFunction, if set
+func (g *Generator) doOpaqueField(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if g.isPassthrough(inMember, outMember, inMemberType, outMemberType) {
+		if inMemberType == outMemberType || (inMemberType.Kind == types.Interface && outMemberType.Kind == types.Interface) {
+			sw.Do("out.$.name$ = in.$.name$\n", args)
+		} else {
+			sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+		}
+		g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "passed through")
+		return true
+	}
+
+	if inMemberType.Kind == types.Interface && outMemberType.Kind == types.Interface && g.Options.InterfaceConversionFunction != "" {
+		args = args.With("interfaceConversion", g.functionExpression(g.Options.InterfaceConversionFunction))
+		sw.Do("if err := $.interfaceConversion$(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
+		sw.Do(g.returnErr()+"\n", nil)
+		sw.Do("}\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "interface converted by %s", g.Options.InterfaceConversionFunction)
+		return true
+	}
+
+	return false
+}
+
+// doInterfaceMember writes the conversion of struct fields of interface types, embedded or not,
+// and returns true iff it did: values of in's type that implement out's type, e.g. with the same
+// interface on both sides, or an io.ReadCloser converted to an io.Reader, get assigned as is;
+// others get converted by Options.InterfaceAdapterFunction, if set. Empty interfaces are left
+// to Options.DynamicValuesPolicy.
+func (g *Generator) doInterfaceMember(inType, outType *types.Type, inMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if inMemberType.Kind != types.Interface || outMemberType.Kind != types.Interface ||
+		len(inMemberType.Methods) == 0 || len(outMemberType.Methods) == 0 {
+		return false
+	}
+
+	if implementsInterface(inMemberType, outMemberType) {
+		sw.Do("out.$.name$ = in.$.name$\n", args)
+		g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "%v implements %v, direct assignment", inMemberType, outMemberType)
+		return true
+	}
+	if g.Options.InterfaceAdapterFunction != "" {
+		args = args.With("interfaceAdapter", g.functionExpression(g.Options.InterfaceAdapterFunction))
+		sw.Do("if err := $.interfaceAdapter$(&in.$.name$, &out.$.name$"+g.extraArgumentsString()+"); err != nil {\n", args)
+		sw.Do(g.returnErr()+"\n", nil)
+		sw.Do("}\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "%v doesn't implement %v, adapted by %s", inMemberType, outMemberType, g.Options.InterfaceAdapterFunction)
+		return true
+	}
+	return false
+}
+
+// implementsInterface returns true iff values of interface type t implement interface type
+// iface, i.e. t has all of iface's methods with identical signatures. Unexported methods are only
+// deemed identical if both types are.
+func implementsInterface(t, iface *types.Type) bool {
+	if t == iface {
+		return true
+	}
+	for name, method := range iface.Methods {
+		if !token.IsExported(name) {
+			return false
+		}
+		if implementation, found := t.Methods[name]; !found || !funcChanCopiable(implementation, method) {
+			return false
+		}
+	}
+	return true
+}
+
+// doOpaqueMember writes the conversion of struct fields with a "+<tag-name>=opaque" tag on either
+// member, and returns true iff it did: these get shallow copied regardless of their types, e.g.
+// for caches intentionally shared between versions; see writeShallowCopy.
+func (g *Generator) doOpaqueMember(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) (bool, error) {
+	if !g.hasTag(inMember.CommentLines, "opaque") && !g.hasTag(outMember.CommentLines, "opaque") {
+		return false, nil
+	}
+	return true, g.writeShallowCopy(inType, outType, inMember, outMember, inMemberType, outMemberType, "opaque", args, sw)
+}
+
+// writeShallowCopy writes the shallow copy of a struct field, regardless of its type: assigned as
+// is if assignable, cast with unsafe if its types have the same memory layout, or else converted
+// if Go allows converting between them. Errors if none of these work, or if the field's value
+// holds a lock, e.g. a sync.Mutex, that copying would copy: such fields must hold them by pointer.
+// reason says why, for explanations.
+func (g *Generator) writeShallowCopy(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, reason string, args generator.Args, sw *generator.SnippetWriter) error {
+	// inMemberType and outMemberType are unwrapped from aliases, keeping their names: the members'
+	// own types tell what Go allows
+	in, out := inMember.Type, outMember.Type
+	for _, t := range []*types.Type{in, out} {
+		if lock := heldLock(t, make(map[*types.Type]bool)); lock == t {
+			return errors.Errorf("%s field %s.%s can't be shallow copied, as %v is a lock: hold it by pointer instead", reason, inType.Name, inMember.Name, t)
+		} else if lock != nil {
+			return errors.Errorf("%s field %s.%s can't be shallow copied, as %v holds a %v by value: hold it by pointer instead", reason, inType.Name, inMember.Name, t, lock)
+		}
+	}
+
+	switch {
+	case isAssignable(in, out):
+		sw.Do("out.$.name$ = in.$.name$\n", args)
+		g.explainFieldf(inType, outType, inMember.Name, FieldDirect, "%s, assigned as is", reason)
+	case g.useUnsafeConversion(in, out):
+		g.writeCastConversion(inMemberType, outMemberType, "&in.$.name$", "&out.$.name$", args, sw)
+		g.explainFieldf(inType, outType, inMember.Name, FieldUnsafe, "%s, %v and %v have the same memory layout, using an unsafe cast", reason, inMemberType, outMemberType)
+	case isConvertible(in, out):
+		sw.Do("out.$.name$ = $.outType|"+rawNamer+"$(in.$.name$)\n", args)
+		g.explainFieldf(inType, outType, inMember.Name, FieldCast, "%s, type conversion", reason)
+	default:
+		return errors.Errorf("%s field %s.%s can't be shallow copied, as %v can't be converted to %v", reason, inType.Name, inMember.Name, inMemberType, outMemberType)
+	}
+	return nil
+}
+
+// isAssignable returns true iff Go allows assigning values of type in to type out, i.e. they're
+// identical, or their underlying types are and either isn't named.
+func isAssignable(in, out *types.Type) bool {
+	if in == out || isNamed(in) && in.Name == out.Name {
+		return true
+	}
+	return (!isNamed(in) || !isNamed(out)) && identicalUnderlyingTypes(in, out)
+}
+
+// isNamed returns true iff t is a named type, e.g. a builtin or a declared type, as opposed to a
+// type literal, e.g. a slice of a named type.
+func isNamed(t *types.Type) bool {
+	return t.Name.Package != "" || t.Kind == types.Builtin
+}
+
+// isConvertible returns true iff Go allows converting values of type in to type out, i.e. both
+// are numbers, or strings, or their underlying types are identical, struct tags aside.
+func isConvertible(in, out *types.Type) bool {
+	in, out = unwrapAlias(in), unwrapAlias(out)
+	if in == out {
+		return true
+	}
+	if in.Kind == types.Builtin && out.Kind == types.Builtin {
+		inBasic, outBasic := basicType(in), basicType(out)
+		if inBasic == nil || outBasic == nil {
+			return false
+		}
+		if inBasic.Info()&gotypes.IsNumeric != 0 && outBasic.Info()&gotypes.IsNumeric != 0 {
+			return true
+		}
+		return inBasic.Info()&gotypes.IsString != 0 && outBasic.Info()&gotypes.IsString != 0
+	}
+	if in.Kind == types.Pointer && out.Kind == types.Pointer && !isNamed(in) && !isNamed(out) {
+		return identicalUnderlyingTypes(in.Elem, out.Elem)
+	}
+	return identicalUnderlyingTypes(in, out)
+}
+
+// identicalUnderlyingTypes returns true iff x and y's underlying types are identical, struct
+// tags aside.
+func identicalUnderlyingTypes(x, y *types.Type) bool {
+	x, y = unwrapAlias(x), unwrapAlias(y)
+	if x == y {
+		return true
+	}
+	if x.Kind != y.Kind {
+		return false
+	}
+
+	switch x.Kind {
+	case types.Slice, types.Pointer:
+		return x.Elem == y.Elem
+	case types.Array:
+		return x.Len == y.Len && x.Elem == y.Elem
+	case types.Map:
+		return x.Key == y.Key && x.Elem == y.Elem
+	case types.Struct:
+		if len(x.Members) != len(y.Members) {
+			return false
+		}
+		for i, xMember := range x.Members {
+			yMember := y.Members[i]
+			if xMember.Name != yMember.Name || xMember.Embedded != yMember.Embedded || xMember.Type != yMember.Type ||
+				(!token.IsExported(xMember.Name) && !isSamePackage(x, y)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return funcChanCopiable(x, y)
+	}
+}
+
+// heldLock returns the type of the lock that values of type t hold, if any, i.e. of a value
+// with Lock and Unlock methods, e.g. a sync.Mutex, that copying t would copy.
+func heldLock(t *types.Type, alreadyVisitedTypes map[*types.Type]bool) *types.Type {
+	if alreadyVisitedTypes[t] {
+		return nil
+	}
+	alreadyVisitedTypes[t] = true
+
+	if _, found := t.Methods["Lock"]; found {
+		if _, found := t.Methods["Unlock"]; found {
+			return t
+		}
+	}
+	switch t.Kind {
+	case types.Alias:
+		return heldLock(t.Underlying, alreadyVisitedTypes)
+	case types.Array:
+		return heldLock(t.Elem, alreadyVisitedTypes)
+	case types.Struct:
+		for _, member := range t.Members {
+			if lock := heldLock(member.Type, alreadyVisitedTypes); lock != nil {
+				return lock
+			}
+		}
+	}
+	return nil
+}
+
+// isPassthrough returns true iff either member, or either member's type, has a
+// "+<tag-name>=passthrough" tag.
+func (g *Generator) isPassthrough(inMember, outMember *types.Member, inMemberType, outMemberType *types.Type) bool {
+	for _, commentLines := range [][]string{inMember.CommentLines, outMember.CommentLines, inMemberType.CommentLines, outMemberType.CommentLines} {
+		if g.hasTag(commentLines, "passthrough") {
+			return true
+		}
+	}
+	return false
+}
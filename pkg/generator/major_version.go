@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doMajorVersionEquivalentField writes the conversion of a struct field between the same struct
+// type in two major versions of a module, as an unsafe cast; see Options.MajorVersionEquivalence.
+// Returns true iff it did. Pointers, slices and maps of such types never get here: they get cast
+// as any other field whose type has the same memory layout as its peer's.
+func (g *Generator) doMajorVersionEquivalentField(inType, outType *types.Type, inMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if inMemberType.Kind != types.Struct || !g.areMajorVersionEquivalent(inMemberType, outMemberType) {
+		return false
+	}
+
+	args = args.With("Pointer", types.Ref("unsafe", "Pointer"))
+	sw.Do("out.$.name$ = *(*$.outType|"+rawNamer+"$)($.Pointer|"+rawNamer+"$(&in.$.name$))\n", args)
+	g.explainFieldf(inType, outType, inMember.Name, FieldUnsafe, "%v and %v are the same type in different major versions, using an unsafe cast", inMemberType, outMemberType)
+	return true
+}
+
+// areMajorVersionEquivalent returns true iff inType and outType have the same name and memory
+// layout, in packages from different major versions of the same module, and
+// Options.MajorVersionEquivalence is set.
+func (g *Generator) areMajorVersionEquivalent(inType, outType *types.Type) bool {
+	return g.Options.MajorVersionEquivalence &&
+		inType.Name.Name == outType.Name.Name &&
+		isMajorVersionOf(inType.Name.Package, outType.Name.Package) &&
+		g.useUnsafeConversion(inType, outType)
+}
+
+// isMajorVersionOf returns true iff the given import paths only differ by a major version suffix,
+// e.g. "example.com/api" and "example.com/api/v2", or "example.com/api/v2/types" and
+// "example.com/api/v3/types".
+func isMajorVersionOf(path1, path2 string) bool {
+	elements1, elements2 := strings.Split(path1, "/"), strings.Split(path2, "/")
+	if len(elements1) > len(elements2) {
+		elements1, elements2 = elements2, elements1
+	}
+
+	switch len(elements2) - len(elements1) {
+	case 0:
+		differing := -1
+		for i := range elements1 {
+			if elements1[i] != elements2[i] {
+				if differing != -1 {
+					return false
+				}
+				differing = i
+			}
+		}
+		return differing != -1 && isMajorVersionSuffix(elements1[differing]) && isMajorVersionSuffix(elements2[differing])
+	case 1:
+		for i, element := range elements2 {
+			if isMajorVersionSuffix(element) {
+				without := append(append([]string{}, elements2[:i]...), elements2[i+1:]...)
+				if strings.Join(without, "/") == strings.Join(elements1, "/") {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// isMajorVersionSuffix returns true iff element is a Go module major version suffix, e.g. "v2".
+func isMajorVersionSuffix(element string) bool {
+	if len(element) < 2 || element[0] != 'v' {
+		return false
+	}
+	version, err := strconv.Atoi(element[1:])
+	return err == nil && version >= 2 && strconv.Itoa(version) == element[1:]
+}
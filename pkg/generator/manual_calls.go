@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// A ConversionCall is a call from a conversion function to another conversion function.
+type ConversionCall struct {
+	Callee *types.Type
+	// SameObject is true if the callee is called on the caller's own input, rather than e.g. on
+	// one of its fields; cycles of such calls never terminate.
+	SameObject bool
+}
+
+// manualCalls returns the calls that the given manual conversion function makes to other
+// conversion functions, as found by parsing its source.
+func (t *ManualConversionsTracker) manualCalls(function *types.Type) []ConversionCall {
+	pkgPath := function.Name.Package
+	calls, parsed := t.manualFunctionCalls[pkgPath]
+	if !parsed {
+		calls = parseConversionCalls(pkgPath, t.sourcePaths[pkgPath], t.isConversionFunctionName)
+		t.manualFunctionCalls[pkgPath] = calls
+		if hash := t.packageHashes[pkgPath]; hash != "" {
+			t.cache.storeCalls(pkgPath, hash, calls)
+		}
+	}
+	return calls[function.Name.Name]
+}
+
+// parseConversionCalls parses the package at sourcePath, and returns the calls that each of its
+// conversion functions makes to other conversion functions, indexed by function name; conversion
+// functions being those whose names isConversionFunctionName accepts.
+func parseConversionCalls(pkgPath, sourcePath string, isConversionFunctionName func(string) bool) map[string][]ConversionCall {
+	calls := make(map[string][]ConversionCall)
+	if sourcePath == "" {
+		return calls
+	}
+
+	fileSet := token.NewFileSet()
+	packages, err := parser.ParseDir(fileSet, sourcePath, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		klog.Warningf("unable to parse %q to find calls between conversion functions: %v", sourcePath, err)
+		return calls
+	}
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			imports := fileImports(file)
+			for _, decl := range file.Decls {
+				funcDecl, ok := decl.(*ast.FuncDecl)
+				if !ok || funcDecl.Body == nil || funcDecl.Recv != nil || !isConversionFunctionName(funcDecl.Name.Name) {
+					continue
+				}
+				calls[funcDecl.Name.Name] = conversionCallsIn(funcDecl, pkgPath, imports, isConversionFunctionName)
+			}
+		}
+	}
+	return calls
+}
+
+// isConversionFunctionName returns true iff name is that of a public conversion function, or of a
+// private one, see addPrivateFunctionPrefix.
+func (t *ManualConversionsTracker) isConversionFunctionName(name string) bool {
+	if strings.HasPrefix(name, conversionFunctionPrefix) {
+		return true
+	}
+	for prefix := range t.privateFunctionPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileImports maps the names that the given file imports packages as, to their import paths.
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	return imports
+}
+
+func conversionCallsIn(funcDecl *ast.FuncDecl, pkgPath string, imports map[string]string, isConversionFunctionName func(string) bool) (calls []ConversionCall) {
+	inName := ""
+	if params := funcDecl.Type.Params.List; len(params) != 0 && len(params[0].Names) != 0 {
+		inName = params[0].Names[0].Name
+	}
+
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		var callee *types.Type
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			callee = types.Ref(pkgPath, fun.Name)
+		case *ast.SelectorExpr:
+			if pkgIdent, ok := fun.X.(*ast.Ident); ok && imports[pkgIdent.Name] != "" {
+				callee = types.Ref(imports[pkgIdent.Name], fun.Sel.Name)
+			}
+		}
+		if callee == nil || !isConversionFunctionName(callee.Name.Name) {
+			return true
+		}
+
+		calls = append(calls, ConversionCall{
+			Callee:     callee,
+			SameObject: inName != "" && rootIdentifier(call.Args[0]) == inName,
+		})
+		return true
+	})
+	return
+}
+
+// rootIdentifier returns the name of the variable that expr refers to as a whole, if any: e.g.
+// "in" for "in" or "(*T)(unsafe.Pointer(in))", but not for "&in.Field".
+func rootIdentifier(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.ParenExpr:
+		return rootIdentifier(e.X)
+	case *ast.CallExpr:
+		// type conversions
+		if len(e.Args) == 1 {
+			return rootIdentifier(e.Args[0])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// manualConversionsCacheVersion is bumped whenever the cache's format, or what gets cached,
+// changes; caches with other versions are discarded.
+const manualConversionsCacheVersion = 1
+
+// A ManualConversionsCache persists what ManualConversionsTracker finds when scanning packages
+// for manual conversion functions - which functions are conversion functions, and which other
+// conversion functions they call - across runs and converters; see
+// ManualConversionsTracker.UseCache.
+// Entries are keyed by package path, and invalidated whenever any of the package's source files,
+// or the tracker's additional conversion arguments, change.
+type ManualConversionsCache struct {
+	path    string
+	entries map[string]*manualConversionsCacheEntry
+	dirty   bool
+}
+
+type manualConversionsCacheFile struct {
+	Version  int                                     `json:"version"`
+	Packages map[string]*manualConversionsCacheEntry `json:"packages"`
+}
+
+type manualConversionsCacheEntry struct {
+	// Hash is the hash of the package's source files, and of the additional conversion arguments.
+	Hash string `json:"hash"`
+	// Functions are the names of the package's manual conversion functions.
+	Functions []string `json:"functions,omitempty"`
+	// Calls are the calls that the package's conversion functions make, indexed by function name;
+	// nil until they're needed, see ManualConversionsTracker.manualCalls.
+	Calls map[string][]cachedConversionCall `json:"calls,omitempty"`
+}
+
+type cachedConversionCall struct {
+	Package    string `json:"package"`
+	Name       string `json:"name"`
+	SameObject bool   `json:"sameObject,omitempty"`
+}
+
+// LoadManualConversionsCache loads the cache at path; the file doesn't need to exist. Caches
+// that can't be parsed, or written by other versions of this generator, are discarded.
+func LoadManualConversionsCache(path string) (*ManualConversionsCache, error) {
+	cache := &ManualConversionsCache{
+		path:    path,
+		entries: make(map[string]*manualConversionsCacheEntry),
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "unable to read manual conversions cache %q", path)
+	}
+
+	var file manualConversionsCacheFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		klog.Warningf("Discarding invalid manual conversions cache %q: %v", path, err)
+		return cache, nil
+	}
+	if file.Version != manualConversionsCacheVersion {
+		klog.V(2).Infof("Discarding manual conversions cache %q with version %d", path, file.Version)
+		return cache, nil
+	}
+	if file.Packages != nil {
+		cache.entries = file.Packages
+	}
+	return cache, nil
+}
+
+// Save writes the cache back to where it was loaded from, if it changed.
+func (c *ManualConversionsCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(&manualConversionsCacheFile{
+		Version:  manualConversionsCacheVersion,
+		Packages: c.entries,
+	}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize manual conversions cache")
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return errors.Wrapf(err, "unable to create directory for manual conversions cache %q", c.path)
+	}
+	if err := ioutil.WriteFile(c.path, append(raw, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "unable to write manual conversions cache %q", c.path)
+	}
+	c.dirty = false
+	return nil
+}
+
+// lookup returns the entry for the given package, if it's still valid for the given hash.
+func (c *ManualConversionsCache) lookup(pkgPath, hash string) (*manualConversionsCacheEntry, bool) {
+	entry, present := c.entries[pkgPath]
+	if !present || entry == nil || entry.Hash != hash {
+		return nil, false
+	}
+	return entry, true
+}
+
+// storeFunctions records the manual conversion functions of the given package, discarding
+// whatever was cached for it.
+func (c *ManualConversionsCache) storeFunctions(pkgPath, hash string, functions []string) {
+	sort.Strings(functions)
+	c.entries[pkgPath] = &manualConversionsCacheEntry{
+		Hash:      hash,
+		Functions: functions,
+	}
+	c.dirty = true
+}
+
+// storeCalls records the calls made by the conversion functions of the given package, if it
+// has a valid entry.
+func (c *ManualConversionsCache) storeCalls(pkgPath, hash string, calls map[string][]ConversionCall) {
+	entry, present := c.lookup(pkgPath, hash)
+	if !present {
+		return
+	}
+
+	entry.Calls = make(map[string][]cachedConversionCall, len(calls))
+	for function, functionCalls := range calls {
+		cached := make([]cachedConversionCall, 0, len(functionCalls))
+		for _, call := range functionCalls {
+			cached = append(cached, cachedConversionCall{
+				Package:    call.Callee.Name.Package,
+				Name:       call.Callee.Name.Name,
+				SameObject: call.SameObject,
+			})
+		}
+		entry.Calls[function] = cached
+	}
+	c.dirty = true
+}
+
+// conversionCalls returns the calls cached in entry, if any.
+func (entry *manualConversionsCacheEntry) conversionCalls() (map[string][]ConversionCall, bool) {
+	if entry.Calls == nil {
+		return nil, false
+	}
+
+	calls := make(map[string][]ConversionCall, len(entry.Calls))
+	for function, cached := range entry.Calls {
+		functionCalls := make([]ConversionCall, 0, len(cached))
+		for _, call := range cached {
+			functionCalls = append(functionCalls, ConversionCall{
+				Callee:     types.Ref(call.Package, call.Name),
+				SameObject: call.SameObject,
+			})
+		}
+		calls[function] = functionCalls
+	}
+	return calls, true
+}
+
+// packageHash hashes the Go source files at sourcePath, tests excluded, along with the given
+// additional conversion arguments; it returns an empty string if any of the files can't be read.
+func packageHash(sourcePath string, additionalConversionArguments []NamedVariable) string {
+	if sourcePath == "" {
+		return ""
+	}
+	paths, err := filepath.Glob(filepath.Join(sourcePath, "*.go"))
+	if err != nil {
+		return ""
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, argument := range additionalConversionArguments {
+		hash.Write([]byte(argument.Type.Name.String() + "\n"))
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		hash.Write([]byte(filepath.Base(path) + "\n"))
+		hash.Write(content)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
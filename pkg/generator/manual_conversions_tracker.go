@@ -32,6 +32,9 @@ import (
 type ManualConversionsTracker struct {
 	// see the explanation on NewManualConversionsTracker.
 	additionalConversionArguments []NamedVariable
+	// additionalArgumentsString is the list of the additional arguments' names, each preceded by a
+	// comma; computed once, as it's needed for most calls between conversion functions.
+	additionalArgumentsString string
 
 	// processedPackages keeps track of which packages have already been processed, as there
 	// is no need to ever process the same package twice.
@@ -40,6 +43,20 @@ type ManualConversionsTracker struct {
 	// conversionFunctions keeps track of the manual function definitions known to this tracker.
 	conversionFunctions map[ConversionPair]*types.Type
 
+	// sourcePaths are the source directories of processed packages.
+	sourcePaths map[string]string
+	// manualFunctionCalls caches the calls between conversion functions in processed packages,
+	// see manualCalls.
+	manualFunctionCalls map[string]map[string][]ConversionCall
+	// privateFunctionPrefixes are the prefixes of the private conversion functions that generators
+	// using this tracker generate, see Options.PrivateFunctionPrefix.
+	privateFunctionPrefixes map[string]bool
+
+	// cache, if set, persists scan results across runs; see UseCache.
+	cache *ManualConversionsCache
+	// packageHashes are the cache hashes of processed packages, when using a cache.
+	packageHashes map[string]string
+
 	// see conversionFunctionName
 	buffer          *bytes.Buffer
 	conversionNamer *namer.NameStrategy
@@ -54,15 +71,38 @@ type ManualConversionsTracker struct {
 //    Convert_a_X_To_b_Y(in *a.X, out *b.Y, s conversion.Scope) error
 // Manually defined conversion functions will also be expected to have similar signatures.
 func NewManualConversionsTracker(additionalConversionArguments ...NamedVariable) *ManualConversionsTracker {
+	var additionalArgumentsString strings.Builder
+	for _, namedArgument := range additionalConversionArguments {
+		additionalArgumentsString.WriteString(", " + namedArgument.Name)
+	}
+
 	return &ManualConversionsTracker{
 		additionalConversionArguments: additionalConversionArguments,
+		additionalArgumentsString:     additionalArgumentsString.String(),
 		processedPackages:             make(map[string][]error),
 		conversionFunctions:           make(map[ConversionPair]*types.Type),
+		sourcePaths:                   make(map[string]string),
+		manualFunctionCalls:           make(map[string]map[string][]ConversionCall),
+		privateFunctionPrefixes:       map[string]bool{DefaultPrivateFunctionPrefix: true},
+		packageHashes:                 make(map[string]string),
 		buffer:                        &bytes.Buffer{},
 		conversionNamer:               ConversionNamer(),
 	}
 }
 
+// addPrivateFunctionPrefix records that private conversion functions' names can start with
+// prefix, so that calls to them from manual conversion functions are found.
+func (t *ManualConversionsTracker) addPrivateFunctionPrefix(prefix string) {
+	t.privateFunctionPrefixes[prefix] = true
+}
+
+// UseCache makes the tracker look up, and record, the results of scanning packages in the given
+// cache. Packages still get loaded, but their functions aren't scanned, nor their sources parsed
+// to find calls between conversion functions, as long as they haven't changed.
+func (t *ManualConversionsTracker) UseCache(cache *ManualConversionsCache) {
+	t.cache = cache
+}
+
 var errorName = types.Ref("", "error").Name
 
 // findManualConversionFunctions looks for conversion functions in the given package.
@@ -74,13 +114,30 @@ func (t *ManualConversionsTracker) findManualConversionFunctions(context *genera
 
 	pkg, err := context.AddDirectory(packagePath)
 	if err != nil {
-		return []error{fmt.Errorf("unable to add directory %q to context: %v", packagePath, err)}
+		return []error{&packageLoadError{fmt.Errorf("unable to add directory %q to context: %v", packagePath, err)}}
 	}
 	if pkg == nil {
 		klog.Warningf("Skipping nil package passed to getManualConversionFunctions")
 		return
 	}
 	klog.V(5).Infof("Scanning for conversion functions in %v", pkg.Path)
+	t.sourcePaths[pkg.Path] = pkg.SourcePath
+
+	var hash string
+	if t.cache != nil {
+		if hash = packageHash(pkg.SourcePath, t.additionalConversionArguments); hash != "" {
+			t.packageHashes[pkg.Path] = hash
+			if entry, hit := t.cache.lookup(pkg.Path, hash); hit {
+				if errors, loaded := t.loadCachedFunctions(pkg, entry); loaded {
+					t.processedPackages[packagePath] = errors
+					return errors
+				}
+			}
+		}
+	}
+
+	var found []string
+	spliced := splicedFunctions(pkg.SourcePath)
 
 	for _, function := range pkg.Functions {
 		if function.Underlying == nil || function.Underlying.Kind != types.Func {
@@ -94,31 +151,77 @@ func (t *ManualConversionsTracker) findManualConversionFunctions(context *genera
 
 		klog.V(8).Infof("Considering function %s", function.Name)
 
+		if spliced[function.Name.Name] {
+			klog.V(8).Infof("%s was generated between splice markers", function.Name)
+			continue
+		}
+
 		isConversionFunc, inType, outType := t.isConversionFunction(function)
 		if !isConversionFunc {
 			if strings.HasPrefix(function.Name.Name, conversionFunctionPrefix) {
-				errors = append(errors, fmt.Errorf("function %s %s does not match expected conversion signature",
-					function.Name.Package, function.Name.Name))
+				errors = append(errors, &categorizedError{fmt.Errorf("function %s %s does not match expected conversion signature",
+					function.Name.Package, function.Name.Name), ErrManualConversionSignature})
 			}
 			continue
 		}
 
 		// it is a conversion function
-		key := ConversionPair{inType.Elem, outType.Elem}
-		if previousConversionFunc, present := t.conversionFunctions[key]; present {
-			errors = append(errors, fmt.Errorf("duplicate static conversion defined: %s -> %s from:\n%s.%s\n%s.%s",
-				inType, outType, previousConversionFunc.Name.Package, previousConversionFunc.Name.Name, function.Name.Package, function.Name.Name))
+		if err := t.addConversionFunction(function, inType, outType); err != nil {
+			errors = append(errors, err)
 			continue
 		}
-		t.conversionFunctions[key] = function
+		found = append(found, function.Name.Name)
 	}
 
+	if hash != "" && len(errors) == 0 {
+		t.cache.storeFunctions(pkg.Path, hash, found)
+	}
 	t.processedPackages[packagePath] = errors
 	return
 }
 
+// addConversionFunction records a manual conversion function from inType to outType, which are
+// the types of its parameters: pointer types, except for inType if it takes its input by value.
+func (t *ManualConversionsTracker) addConversionFunction(function, inType, outType *types.Type) error {
+	key := ConversionPair{inputElem(inType), outType.Elem}
+	if previousConversionFunc, present := t.conversionFunctions[key]; present {
+		return fmt.Errorf("duplicate static conversion defined: %s -> %s from:\n%s.%s\n%s.%s",
+			inType, outType, previousConversionFunc.Name.Package, previousConversionFunc.Name.Name, function.Name.Package, function.Name.Name)
+	}
+	t.conversionFunctions[key] = function
+	return nil
+}
+
+// loadCachedFunctions records the manual conversion functions that the cache knows the package
+// has, and the calls they make if cached; returns false if the cache doesn't match the package.
+func (t *ManualConversionsTracker) loadCachedFunctions(pkg *types.Package, entry *manualConversionsCacheEntry) (errors []error, loaded bool) {
+	functions := make([]*types.Type, 0, len(entry.Functions))
+	for _, name := range entry.Functions {
+		function := pkg.Functions[name]
+		if function == nil || function.Underlying == nil || function.Underlying.Signature == nil ||
+			len(function.Underlying.Signature.Parameters) < 2 {
+			return nil, false
+		}
+		functions = append(functions, function)
+	}
+	klog.V(5).Infof("Using cached conversion functions for %v", pkg.Path)
+
+	for _, function := range functions {
+		parameters := function.Underlying.Signature.Parameters
+		if err := t.addConversionFunction(function, parameters[0], parameters[1]); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if calls, cached := entry.conversionCalls(); cached {
+		t.manualFunctionCalls[pkg.Path] = calls
+	}
+	return errors, true
+}
+
 // isConversionFunction returns true iff the given function is a conversion function; that is of the form
 // func Convert_a_X_To_b_Y(in *a.X, out *b.Y, additionalConversionArguments...) error
+// or, for structs taken by value, see Options.ValueInputMaxSize,
+// func Convert_a_X_To_b_Y(in a.X, out *b.Y, additionalConversionArguments...) error
 // If it is a signature functions, also returns the inType and outType.
 func (t *ManualConversionsTracker) isConversionFunction(function *types.Type) (bool, *types.Type, *types.Type) {
 	signature := function.Underlying.Signature
@@ -138,7 +241,7 @@ func (t *ManualConversionsTracker) isConversionFunction(function *types.Type) (b
 	}
 	inType := signature.Parameters[0]
 	outType := signature.Parameters[1]
-	if inType.Kind != types.Pointer || outType.Kind != types.Pointer {
+	if (inType.Kind != types.Pointer && inType.Kind != types.Struct) || outType.Kind != types.Pointer {
 		klog.V(8).Infof("%s does not have pointers parameters for in/out", function.Name)
 		return false, nil, nil
 	}
@@ -151,18 +254,37 @@ func (t *ManualConversionsTracker) isConversionFunction(function *types.Type) (b
 	}
 
 	// check it satisfies the naming convention
-	if function.Name.Name != t.conversionFunctionName(inType.Elem, outType.Elem) {
+	if function.Name.Name != t.conversionFunctionName(inputElem(inType), outType.Elem) {
 		return false, nil, nil
 	}
 
 	return true, inType, outType
 }
 
+// inputElem returns the type that a conversion function's input parameter of type inType is for:
+// the type it points to, unless it takes its input by value.
+func inputElem(inType *types.Type) *types.Type {
+	if inType.Kind == types.Pointer {
+		return inType.Elem
+	}
+	return inType
+}
+
 func (t *ManualConversionsTracker) preexists(inType, outType *types.Type) (*types.Type, bool) {
 	function, ok := t.conversionFunctions[ConversionPair{inType, outType}]
 	return function, ok
 }
 
+// isManualFunction returns true iff function is a manual conversion function known to this tracker.
+func (t *ManualConversionsTracker) isManualFunction(function *types.Type) bool {
+	for _, known := range t.conversionFunctions {
+		if known.Name == function.Name {
+			return true
+		}
+	}
+	return false
+}
+
 // conversionFunctionName returns the name of the conversion function for in to out.
 func (t *ManualConversionsTracker) conversionFunctionName(in, out *types.Type) string {
 	return conversionFunctionName(in, out, t.conversionNamer, t.buffer)
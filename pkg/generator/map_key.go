@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doMapKeyedSlice writes the conversion of a struct field between a map keyed by strings and a slice
+// of structs carrying the key as one of their fields, if either field has a "+<tag-name>=mapKey:<field>"
+// tag naming that key field; returns true iff it did.
+// Slices are sorted by key, so that conversions are deterministic.
+func (g *Generator) doMapKeyedSlice(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	keyField := g.mapKeyField(inMember, outMember)
+	if keyField == "" {
+		return false
+	}
+
+	switch {
+	case isStringKeyedMap(inMemberType) && outMemberType.Kind == types.Slice:
+		outElem := unwrapAlias(outMemberType.Elem)
+		keyMember, found := findMember(outElem, keyField)
+		if !found || !g.canConvertMapKeyedElems(inMemberType.Elem, outMemberType.Elem, inMemberType.Key, keyMember.Type) {
+			return false
+		}
+		args = args.With("outElem", outMemberType.Elem).With("key", keyField).With("Strings", types.Ref("sort", "Strings")).
+			With("keys", g.temp("keys")).With("i", g.temp("i")).With("k", g.temp("key")).With("val", g.temp("val"))
+
+		sw.Do("if in.$.name$ != nil {\n", args)
+		sw.Do("$.keys$ := make([]string, 0, len(in.$.name$))\n", args)
+		sw.Do("for $.k$ := range in.$.name$ {\n", args)
+		sw.Do("$.keys$ = append($.keys$, $.k$)\n", args)
+		sw.Do("}\n", nil)
+		sw.Do("$.Strings|"+rawNamer+"$($.keys$)\n", args)
+		sw.Do("out.$.name$ = make([]$.outElem|"+rawNamer+"$, len($.keys$))\n", args)
+		sw.Do("for $.i$, $.k$ := range $.keys$ {\n", args)
+		sw.Do("$.val$ := in.$.name$[$.k$]\n", args)
+		g.writeMapKeyedElemConversion(inMemberType.Elem, outMemberType.Elem, "&$.val$", "&out.$.name$[$.i$]", args, sw)
+		g.writeBuiltinConversion(inMemberType.Key, unwrapAlias(keyMember.Type), "$.k$", "out.$.name$[$.i$].$.key$", args, sw)
+		sw.Do("}\n", nil)
+		sw.Do("} else {\n", nil)
+		sw.Do("out.$.name$ = nil\n", args)
+		sw.Do("}\n", nil)
+
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "map converted to a slice sorted by key, with keys in field %s", keyField)
+		return true
+
+	case inMemberType.Kind == types.Slice && isStringKeyedMap(outMemberType):
+		inElem := unwrapAlias(inMemberType.Elem)
+		keyMember, found := findMember(inElem, keyField)
+		if !found || !g.canConvertMapKeyedElems(inMemberType.Elem, outMemberType.Elem, keyMember.Type, outMemberType.Key) {
+			return false
+		}
+		args = args.With("outElem", outMemberType.Elem).With("key", keyField).
+			With("i", g.temp("i")).With("k", g.temp("key")).With("val", g.temp("val"))
+
+		sw.Do("if in.$.name$ != nil {\n", args)
+		sw.Do("out.$.name$ = make($.outType|"+rawNamer+"$, len(in.$.name$))\n", args)
+		sw.Do("for $.i$ := range in.$.name$ {\n", args)
+		sw.Do("var $.val$ $.outElem|"+rawNamer+"$\n", args)
+		g.writeMapKeyedElemConversion(inMemberType.Elem, outMemberType.Elem, "&in.$.name$[$.i$]", "&$.val$", args, sw)
+		sw.Do("var $.k$ string\n", args)
+		g.writeBuiltinConversion(unwrapAlias(keyMember.Type), outMemberType.Key, "in.$.name$[$.i$].$.key$", "$.k$", args, sw)
+		sw.Do("out.$.name$[$.k$] = $.val$\n", args)
+		sw.Do("}\n", nil)
+		sw.Do("} else {\n", nil)
+		sw.Do("out.$.name$ = nil\n", args)
+		sw.Do("}\n", nil)
+
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "slice converted to a map, keyed by field %s", keyField)
+		return true
+
+	default:
+		return false
+	}
+}
+
+// mapKeyField returns the key field named by a "+<tag-name>=mapKey:<field>" tag on either member,
+// if any.
+func (g *Generator) mapKeyField(members ...*types.Member) string {
+	for _, member := range members {
+		if present, field := g.hasTagOption(member.CommentLines, "mapKey"); present && field != "" {
+			return field
+		}
+	}
+	return ""
+}
+
+func isStringKeyedMap(t *types.Type) bool {
+	return t.Kind == types.Map && t.Key == types.String
+}
+
+// canConvertMapKeyedElems returns true iff inElem can be converted to outElem by a manual or
+// generated conversion function, and inKey to outKey, both of them being builtin types.
+func (g *Generator) canConvertMapKeyedElems(inElem, outElem, inKey, outKey *types.Type) bool {
+	inKey, outKey = unwrapAlias(inKey), unwrapAlias(outKey)
+	if inKey.Kind != types.Builtin || outKey.Kind != types.Builtin || !g.builtinConversionAllowed(inKey, outKey) {
+		return false
+	}
+	if _, ok := g.preexists(inElem, outElem); ok {
+		return true
+	}
+	return unwrapAlias(inElem).Kind == types.Struct && g.convertibleOnlyWithinPackage(inElem, outElem)
+}
+
+// writeMapKeyedElemConversion writes the conversion from in to out, pointers to inElem and outElem;
+// see canConvertMapKeyedElems.
+func (g *Generator) writeMapKeyedElemConversion(inElem, outElem *types.Type, in, out string, args generator.Args, sw *generator.SnippetWriter) {
+	function, _ := g.preexists(inElem, outElem)
+	g.writeConversionCall(function, inElem, outElem, in, out, "", args, sw)
+}
+
+// isMapKeyField returns true iff t's field is the key field of a slice of t converted to a map,
+// see doMapKeyedSlice; such fields are expected not to exist in the map's elements.
+// Since the tag can be either on the slice or on the map, this looks for tagged fields of either
+// kind, and then for slices of t with the same name.
+func (g *Generator) isMapKeyField(t *types.Type, field string) bool {
+	var candidates []*types.Type
+	for _, pkgPath := range append([]string{g.typesPackage.Path}, g.peerPackages...) {
+		if pkg := g.universe[pkgPath]; pkg != nil {
+			for _, candidate := range pkg.Types {
+				if candidate.Kind == types.Struct {
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+
+	taggedNames := make(map[string]bool)
+	for _, candidate := range candidates {
+		for i := range candidate.Members {
+			if g.mapKeyField(&candidate.Members[i]) == field {
+				taggedNames[candidate.Name.Name+"."+candidate.Members[i].Name] = true
+			}
+		}
+	}
+	if len(taggedNames) == 0 {
+		return false
+	}
+
+	for _, candidate := range candidates {
+		for _, member := range candidate.Members {
+			memberType := unwrapAlias(member.Type)
+			if memberType.Kind == types.Slice && unwrapAlias(memberType.Elem) == t && taggedNames[candidate.Name.Name+"."+member.Name] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package generator
+
+import (
+	"strconv"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// writeObservedConversionCall writes the body of the public conversion function from inType to
+// outType: a call to the private one, reported to Options.MetricsFunction.
+func (g *Generator) writeObservedConversionCall(inType, outType *types.Type, sw *generator.SnippetWriter) {
+	args := generator.Args{
+		"now":     types.Ref("time", "Now"),
+		"since":   types.Ref("time", "Since"),
+		"observe": g.functionExpression(g.Options.MetricsFunction),
+		"from":    strconv.Quote(inType.Name.String()),
+		"to":      strconv.Quote(outType.Name.String()),
+		"start":   g.temp("start"),
+		"err":     g.temp("err"),
+	}
+	sw.Do("$.start$ := $.now|"+rawNamer+"$()\n", args)
+	sw.Do("$.err$ := ", args)
+	g.writePrivateFunctionSignature(inType, outType, sw, false)
+	sw.Do("\n$.observe$($.from$, $.to$, $.err$, $.since|"+rawNamer+"$($.start$))\n", args)
+	sw.Do("return $.err$\n", args)
+}
@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// isNestedContainer returns true iff inType and outType are unnamed maps, slices or pointers of
+// the same kind, that conversions of the containers holding them can convert inline - e.g. the
+// []*T values of a map[string][]*T, or the map[K]V items of a []map[K]V.
+func isNestedContainer(inType, outType *types.Type) bool {
+	if inType.Name.Package != "" || outType.Name.Package != "" || inType.Kind != outType.Kind {
+		return false
+	}
+	switch inType.Kind {
+	case types.Map, types.Slice, types.Pointer:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeNestedConversion writes the inline conversion of a nested container, see
+// isNestedContainer: inValue is the input value, e.g. "(*in)[i]", and inRef and outRef pointers
+// to the input and output values, e.g. "&(*in)[i]" and "&(*out)[i]". Nil inputs leave outputs
+// untouched.
+func (g *Generator) writeNestedConversion(inType, outType *types.Type, inValue, inRef, outRef string, sw *generator.SnippetWriter) []error {
+	sw.Do("if "+inValue+" != nil {\n", nil)
+	sw.Do("in, out := "+inRef+", "+outRef+"\n", nil)
+	errors := g.generateFor(inType, outType, sw)
+	sw.Do("}\n", nil)
+	return errors
+}
@@ -0,0 +1,155 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// The policies that "+<tag-name>=nil:<policy>" tags can select, see doNilPointerField.
+const (
+	nilPointerTag = "nil"
+
+	// nilPointerZero converts nil pointers to zero values.
+	nilPointerZero = "zero"
+	// nilPointerDefault converts nil pointers to the expression given by a
+	// "+<tag-name>=default-expr:<expression>" tag on either field.
+	nilPointerDefault = "default"
+	// nilPointerError makes conversions return an error on nil pointers.
+	nilPointerError = "error"
+
+	// omitZeroTag, on either field, makes zero values convert to nil pointers rather than to
+	// pointers to zero values, and nil pointers to zero values unless a nil policy says otherwise;
+	// so that unset fields stay unset across versions.
+	omitZeroTag = "omit-zero"
+)
+
+// doNilPointerField writes the conversion of a struct field between a pointer *T and a value U,
+// either way, if either field has a "+<tag-name>=nil:<policy>" or a "+<tag-name>=omit-zero" tag,
+// and T can be converted to U; the policy decides what nil pointers convert to, while values
+// convert to new pointers - unless they're zero values, with omit-zero.
+// Returns true iff it did, along with the errors generating it, e.g. if the tag is invalid.
+func (g *Generator) doNilPointerField(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) (bool, []error) {
+	var policy string
+	for _, member := range []*types.Member{outMember, inMember} {
+		if present, value := g.hasTagOption(member.CommentLines, nilPointerTag); present {
+			policy = value
+			break
+		}
+	}
+	omitZero := g.hasTag(outMember.CommentLines, omitZeroTag) || g.hasTag(inMember.CommentLines, omitZeroTag)
+	if policy == "" {
+		if !omitZero {
+			return false, nil
+		}
+		policy = nilPointerZero
+	}
+
+	in, out := "in."+inMember.Name, "out."+outMember.Name
+	switch {
+	case inMemberType.Kind == types.Pointer && outMemberType.Kind != types.Pointer:
+		if !g.canConvertElems(inMemberType.Elem, outMemberType) {
+			return false, nil
+		}
+
+		var onNil string
+		var onNilArgs generator.Args
+		switch policy {
+		case nilPointerZero:
+			onNil = out + " = " + zeroLiteral(g.handlerContext(), outMemberType)
+		case nilPointerDefault:
+			present, expression := g.hasTagOption(outMember.CommentLines, "default-expr")
+			if !present {
+				present, expression = g.hasTagOption(inMember.CommentLines, "default-expr")
+			}
+			if !present {
+				return true, []error{errors.Errorf("%s.%s: nil policy %q requires a default-expr tag", inType.Name, inMember.Name, nilPointerDefault)}
+			}
+			snippet, snippetArgs, err := g.exprSnippet(expression, outType.Name.Package)
+			if err != nil {
+				return true, []error{errors.Wrapf(err, "invalid default-expr tag on %s.%s", inType.Name, inMember.Name)}
+			}
+			onNil, onNilArgs = out+" = "+snippet, snippetArgs
+		case nilPointerError:
+			onNil = "return $.Errorf|" + rawNamer + "$(" + strconv.Quote(strings.Join(g.fieldPath, ".")+" is nil") + ")"
+			onNilArgs = generator.Args{"Errorf": types.Ref("fmt", "Errorf")}
+		default:
+			return true, []error{errors.Errorf("%s.%s: unknown nil policy %q", inType.Name, inMember.Name, policy)}
+		}
+
+		sw.Do("if in.$.name$ != nil {\n", args)
+		errs := g.writeElemConversion(inMemberType.Elem, outMemberType, in, "&"+out, sw)
+		sw.Do("} else {\n", nil)
+		sw.Do(onNil+"\n", onNilArgs)
+		sw.Do("}\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "%v converted to %v, nil policy %q", inMemberType, outMemberType, policy)
+		return true, errs
+
+	case inMemberType.Kind != types.Pointer && outMemberType.Kind == types.Pointer:
+		if !g.canConvertElems(inMemberType, outMemberType.Elem) {
+			return false, nil
+		}
+
+		if omitZero {
+			condition, conditionArgs := g.nonZeroCondition(in, inMemberType)
+			sw.Do("if "+condition+" {\n", conditionArgs)
+		}
+		sw.Do(out+" = new($.|"+rawNamer+"$)\n", outMemberType.Elem)
+		errs := g.writeElemConversion(inMemberType, outMemberType.Elem, "&"+in, out, sw)
+		if omitZero {
+			sw.Do("} else {\n", nil)
+			sw.Do(out+" = nil\n", nil)
+			sw.Do("}\n", nil)
+		}
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "%v converted to %v, allocating, omitting zero values: %v", inMemberType, outMemberType, omitZero)
+		return true, errs
+
+	default:
+		return false, nil
+	}
+}
+
+// nonZeroCondition returns the condition that expr, of type t, isn't t's zero value, as a snippet
+// rendered with the returned args. Values that can't be compared are checked with reflection.
+func (g *Generator) nonZeroCondition(expr string, t *types.Type) (string, generator.Args) {
+	underlying := unwrapAlias(t)
+	switch {
+	case underlying.Kind == types.Builtin:
+		return expr + " != " + zeroValue(underlying), nil
+	case isComparable(underlying, map[*types.Type]bool{}):
+		return expr + " != ($.type|" + rawNamer + "${})", generator.Args{"type": t}
+	default:
+		return "!$.ValueOf|" + rawNamer + "$(" + expr + ").IsZero()", generator.Args{"ValueOf": types.Ref("reflect", "ValueOf")}
+	}
+}
+
+// isComparable returns true iff values of t can be compared with ==; visiting tracks the types
+// being checked, to stop on recursive types.
+func isComparable(t *types.Type, visiting map[*types.Type]bool) bool {
+	if visiting[t] {
+		return true
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind {
+	case types.Builtin, types.Pointer, types.Interface, types.Chan:
+		return true
+	case types.Alias:
+		return isComparable(t.Underlying, visiting)
+	case types.Array:
+		return isComparable(t.Elem, visiting)
+	case types.Struct:
+		for _, member := range t.Members {
+			if !isComparable(member.Type, visiting) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	gotypes "go/types"
+	"strconv"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doOptionalScalar writes the conversion of a struct field between an optional builtin, i.e. a
+// pointer to a builtin type, and a plain builtin, if inMemberType and outMemberType are such a pair
+// and OptionalScalars is set; returns true iff it did.
+func (g *Generator) doOptionalScalar(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) bool {
+	if !g.Options.OptionalScalars {
+		return false
+	}
+
+	switch {
+	case isOptionalBuiltin(inMemberType) && outMemberType.Kind == types.Builtin:
+		if !g.builtinConversionAllowed(inMemberType.Elem, outMemberType) {
+			return false
+		}
+		defaultValue := zeroValue(outMemberType)
+		for _, member := range []*types.Member{inMember, outMember} {
+			if present, value := g.hasTagOption(member.CommentLines, "default"); present {
+				defaultValue = defaultLiteral(outMemberType, value)
+				break
+			}
+		}
+		args = args.With("default", defaultValue)
+
+		sw.Do("if in.$.name$ != nil {\n", args)
+		g.writeBuiltinConversion(inMemberType.Elem, outMemberType, "*in.$.name$", "out.$.name$", args, sw)
+		sw.Do("} else {\n", nil)
+		sw.Do("out.$.name$ = $.default$\n", args)
+		sw.Do("}\n", nil)
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "optional %v converted to %v, defaulting to %s", inMemberType, outMemberType, defaultValue)
+		return true
+
+	case inMemberType.Kind == types.Builtin && isOptionalBuiltin(outMemberType):
+		if !g.builtinConversionAllowed(inMemberType, outMemberType.Elem) {
+			return false
+		}
+		args = args.With("outElem", outMemberType.Elem)
+
+		if g.Options.OptionalScalarsKeepZeros {
+			sw.Do("out.$.name$ = new($.outElem|"+rawNamer+"$)\n", args)
+			g.writeBuiltinConversion(inMemberType, outMemberType.Elem, "in.$.name$", "*out.$.name$", args, sw)
+		} else {
+			sw.Do("if in.$.name$ != "+zeroValue(inMemberType)+" {\n", args)
+			sw.Do("out.$.name$ = new($.outElem|"+rawNamer+"$)\n", args)
+			g.writeBuiltinConversion(inMemberType, outMemberType.Elem, "in.$.name$", "*out.$.name$", args, sw)
+			sw.Do("} else {\n", nil)
+			sw.Do("out.$.name$ = nil\n", args)
+			sw.Do("}\n", nil)
+		}
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "%v converted to optional %v, keeping zero values: %v", inMemberType, outMemberType, g.Options.OptionalScalarsKeepZeros)
+		return true
+
+	default:
+		return false
+	}
+}
+
+// isOptionalBuiltin returns true iff t is a pointer to a builtin type.
+func isOptionalBuiltin(t *types.Type) bool {
+	return t.Kind == types.Pointer && t.Elem.Kind == types.Builtin
+}
+
+// defaultLiteral returns the Go literal for the default value given by a tag for builtin type t;
+// string values get quoted, unless they already are.
+func defaultLiteral(t *types.Type, value string) string {
+	if basic := basicType(t); basic != nil && basic.Info()&gotypes.IsString != 0 {
+		if _, err := strconv.Unquote(value); err != nil {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
+
+// zeroValue returns the zero value of builtin type t, as a Go literal.
+func zeroValue(t *types.Type) string {
+	basic := basicType(t)
+	switch {
+	case basic == nil:
+		return "nil"
+	case basic.Info()&gotypes.IsString != 0:
+		return `""`
+	case basic.Info()&gotypes.IsBoolean != 0:
+		return "false"
+	default:
+		return "0"
+	}
+}
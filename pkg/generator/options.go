@@ -1,6 +1,9 @@
 package generator
 
 import (
+	"go/token"
+
+	"github.com/pkg/errors"
 	"k8s.io/gengo/generator"
 	"k8s.io/gengo/types"
 )
@@ -15,15 +18,164 @@ type Options struct {
 
 	// if NoUnsafeConversions is set to true, it disables the use of unsafe conversions
 	// between types that share the same memory layouts.
+	// Packages can also disable them for their own conversions with a "+<tag-name>=no-unsafe" tag
+	// in their doc.go file.
 	NoUnsafeConversions bool
 
+	// MajorVersionEquivalence, if true, makes conversions between types with the same name and
+	// memory layout, in packages from different major versions of the same module (e.g.
+	// example.com/api.Foo and example.com/api/v2.Foo), use unsafe casts, as for types from the same
+	// package; rather than needing conversion functions. Has no effect if NoUnsafeConversions is set.
+	// Only fields of such struct types are concerned: pointers, slices and maps of them (e.g.
+	// map[string][]*Foo) get cast whenever their memory layouts match, whether this is set or not.
+	MajorVersionEquivalence bool
+
+	// PruneCastableConversions, if true, generates no conversion functions between types that
+	// share the same memory layouts as a whole: generated code converts them with unsafe casts
+	// instead, and generated files list them in a table, along with a ConvertByCast helper that
+	// converts them; which keeps generated code minimal when converting between many identical
+	// types. Has no effect if NoUnsafeConversions is set.
+	PruneCastableConversions bool
+
+	// ViewFunctions, if true, also generates functions returning pointers to values as pointers to
+	// their peer types, without copying them, for types that share the same memory layout, e.g.
+	//    View_v1_Foo(in *v1.Foo) *v2.Foo
+	// for read-only hot paths where even copying is too expensive: their results alias their
+	// inputs. Has no effect if NoUnsafeConversions is set.
+	ViewFunctions bool
+
+	// TargetPlatforms, of the form "<GOOS>/<GOARCH>", are the platforms the generated code is meant
+	// to be built for; both need to be known to go/build. When set, unsafe conversions are also
+	// used between different builtin types (e.g. int and int64) iff they have the same memory
+	// layout on all of these platforms.
+	// When left empty, builtin types need to be identical for unsafe conversions to be used.
+	TargetPlatforms []string
+
+	// ValueInputMaxSize, if positive, makes the public conversion functions generated from structs
+	// of at most that many bytes take their input by value rather than by pointer, e.g.
+	//    Convert_v1_Point_To_v2_Point(in v1.Point, out *v2.Point) error
+	// so that callers don't need to take the addresses of temporaries. Sizes are those on the
+	// target platforms if any, see TargetPlatforms, or else on amd64. Such functions call private
+	// ones taking pointers, even with NoPrivateFunctions. Manual conversion functions can take
+	// their input by value regardless, and get called accordingly.
+	ValueInputMaxSize int64
+
+	// BuiltinConversionPolicy decides how conversions between different builtin types (e.g. int32 to
+	// int64, or int to uint) are handled: either BuiltinConversionCast (the default),
+	// BuiltinConversionCheck, or BuiltinConversionForbid.
+	// Forbidden conversions are handled as inconvertible fields.
+	BuiltinConversionPolicy BuiltinConversionPolicy
+
+	// FuncChanPolicy decides how values of function and channel types are handled: either
+	// FuncChanUnsupported (the default), FuncChanSkip, FuncChanCopy, or FuncChanError.
+	// Handlers can tell them apart from other unsupported values with IsFuncOrChan.
+	FuncChanPolicy FuncChanPolicy
+
+	// OptionalScalars, if true, treats pointers to builtin types (e.g. *int32 or *string) as optional
+	// values, as with proto3 optional fields: they then convert to and from plain builtin types,
+	// instead of being deemed inconvertible. Nil pointers convert to zero values, or to the value
+	// given by a "+<tag-name>=default:<value>" tag on either field; zero values convert to nil
+	// pointers, unless OptionalScalarsKeepZeros is set.
+	OptionalScalars bool
+
+	// OptionalScalarsKeepZeros, if true, makes zero values convert to pointers to zero values rather
+	// than to nil pointers, see OptionalScalars.
+	OptionalScalarsKeepZeros bool
+
+	// NilElementsPolicy decides how nil elements are handled when converting slices of pointers to
+	// slices of values (e.g. []*T to []U): either NilElementsToZero (the default), or
+	// NilElementsToError.
+	NilElementsPolicy NilElementsPolicy
+
+	// DynamicValuesPolicy decides how fields holding dynamic values, i.e. of type interface{}, or maps
+	// or slices of interface{} values (e.g. map[string]interface{} config bags), are converted:
+	// either DynamicValuesUnhandled (the default), DynamicValuesPassThrough, DynamicValuesDeepCopy, or
+	// DynamicValuesJSON.
+	DynamicValuesPolicy DynamicValuesPolicy
+
+	// DynamicValuesDeepCopyFunction is the function deep-copying dynamic values with
+	// DynamicValuesDeepCopy, e.g. "k8s.io/apimachinery/pkg/runtime.DeepCopyJSONValue"; it must be of
+	// the form "<pkg-path>.<expression>", or just "<expression>" if local to the output package, and
+	// have the signature
+	//    func(interface{}) interface{}
+	// returning a value of the same type as its argument.
+	DynamicValuesDeepCopyFunction string
+
+	// ErrorWrappingPolicy decides how generated code returns the errors of the conversions it
+	// calls, for fields of structs and elements of maps, slices and pointers alike: either
+	// ErrorWrappingNone (the default), ErrorWrappingFmt, or ErrorWrappingFunction.
+	ErrorWrappingPolicy ErrorWrappingPolicy
+
+	// ErrorWrappingFunction is the function wrapping errors with ErrorWrappingFunction, e.g.
+	// "example.com/mypkg.WrapConversionError"; it must be of the form "<pkg-path>.<expression>", or
+	// just "<expression>" if local to the output package, and have the signature
+	//    func(err error, field string) error
+	// where field is the path of the field being converted, e.g. "Spec.Template", possibly empty.
+	ErrorWrappingFunction string
+
+	// FieldMappings, if set, describe how to convert the fields of given pairs of types, e.g. renamed
+	// fields, instead of pairing them up by name; see LoadFieldMappings.
+	FieldMappings *FieldMappings
+
+	// ImplementersOf, if set, restricts conversion generation to the types implementing that
+	// interface, e.g. "k8s.io/apimachinery/pkg/runtime.Object", along with the types of the same
+	// package that their fields need converting; instead of all the types that have peers. Methods
+	// count whether they have value or pointer receivers, are promoted from embedded fields, and
+	// include those declared in generated files, e.g. DeepCopyObject in zz_generated.deepcopy.go.
+	// Selecting no type logs a warning.
+	ImplementersOf string
+
+	// Files, if set, restricts conversion generation to the types declared in the files of the
+	// types package whose base names match these patterns, as with filepath.Match, e.g. "types.go"
+	// or "types*.go"; leaving out e.g. types from other generated files of the package. Fields of
+	// types from other files then need manual conversions.
+	Files []string
+
+	// FilesTagName is the marker that the generator will look for in the doc.go file of input
+	// packages for the files to restrict conversion generation to, instead of Files:
+	// "+<tag-name>=<pattern>" in an input package's doc.go file; can be repeated.
+	FilesTagName string
+
 	// TagName is the marker that the generator will look for in types' comments:
 	// "+<tag-name>=false" in a type's comment will instruct conversion-gen to skip that type.
 	// "+<tag-name>=no-public" in a type's comment will instruct conversion-gen to not generate any public conversion
-	// "+<tag-name>=peerName:PeerTypeName" in a type's comment will tell conversion-gen to look for peer types with the given name,
-	//                                     instead of assuming peer types will have the same name
 	//   function involving that type (either to or from it). It will still generate private conversion functions,
 	//   that can then be wrapped publicly with additional logic.
+	// "+<tag-name>=peerName:PeerTypeName" in a type's comment will tell conversion-gen to look for peer types with the given name,
+	//                                     instead of assuming peer types will have the same name; if the input package
+	//                                     has no peer packages, it names a type of the input package itself, e.g. for
+	//                                     conversions between FooSpec and FooSpecV2 in the same package
+	// "+<tag-name>=peerPackage:<pkg-path>" in a type's comment will tell conversion-gen to look for that type's peer
+	//                                      in the given package only, instead of in the input package's peer packages
+	// "+<tag-name>=deprecated:Some message" in a type's comment will mark the public conversion functions
+	//   involving that type as deprecated, with the given message - or a generic one when using just
+	//   "+<tag-name>=deprecated" - so that linters flag their call sites.
+	// "+<tag-name>=default:<value>" in a field's comment gives the value to use when converting a nil
+	//   optional builtin to that field, or from that field, as a Go literal - string values don't need
+	//   quoting; see OptionalScalars.
+	// "+<tag-name>=mapKey:<field>" in a field's comment converts it between a map keyed by strings and
+	//   a slice of structs carrying the key in the given field, sorted by key.
+	// "+<tag-name>=scale:<factor>" in a numeric field's comment means that its value multiplied by factor
+	//   is its peer field's value, e.g. for seconds and milliseconds; factor must fit in the type of the
+	//   field it multiplies or divides. See doScaledField.
+	// "+<tag-name>=passthrough" in a field's or in a type's comment makes conversions assign that field, or
+	//   fields of that type, as is - e.g. for interfaces or raw-extension-like wrappers; see doOpaqueField.
+	// "+<tag-name>=opaque" in a field's comment makes conversions shallow copy that field, regardless of its
+	//   type, e.g. for caches shared between versions; sync primitives must be held by pointer. See
+	//   doOpaqueMember.
+	// "+<tag-name>=boundary" in a field's comment makes conversions shallow copy that field without looking
+	//   into its type, and "+<tag-name>=boundary:<function>" convert it from that field with that function
+	//   instead, its peer field needing its own for the other direction, e.g. for huge sub-trees shared
+	//   between versions; see doBoundaryField.
+	// "+<tag-name>=expr:<expression>" in a field's comment gives the Go expression to assign to that field
+	//   when converting to it, e.g. "expr:strings.ToLower($in$)"; see doExprField.
+	// "+<tag-name>=nil:<policy>" in a field's comment converts it between a pointer and a value, nil
+	//   pointers converting to zero values with "nil:zero", to the expression of a
+	//   "+<tag-name>=default-expr:<expression>" tag with "nil:default", or to errors with "nil:error".
+	// "+<tag-name>=omit-zero" in a field's comment converts it between a pointer and a value, zero
+	//   values converting to nil pointers and nil pointers to zero values, unless a nil tag says otherwise.
+	// "+<tag-name>=public-on-error" in a type's comment generates public conversion functions involving
+	//   that type even if some of its fields couldn't be converted; see PublicFunctionOnError.
 	// TODO wkpo rename to TypeTagName ?
 	TagName string
 
@@ -41,6 +193,36 @@ type Options struct {
 	// the converter to look for that package's peer types in the specified peer packages.
 	PeerPackagesTagName string
 
+	// ReadOnlyPeerPackages are peer packages that conversions must not write into, e.g. vendored
+	// third-party packages: only conversions from their types to the input package's types are
+	// generated.
+	ReadOnlyPeerPackages []string
+
+	// ReadOnlyPeerPackagesTagName is the marker that the generator will look for in the doc.go file
+	// of input packages for additional read-only peer packages, see ReadOnlyPeerPackages:
+	// "+<tag-name>=<peer-pkg>" in an input package's doc.go file; can be repeated.
+	ReadOnlyPeerPackagesTagName string
+
+	// NoPeerTypeMissCache, if true, keeps the generator from caching that a type has no peer type:
+	// it then looks for it again each time it's needed, e.g. for wrapper generators that add peer
+	// packages to the universe mid-run. See also Generator.InvalidatePeerCache.
+	NoPeerTypeMissCache bool
+
+	// PrivatePeerTypes, if true, generates conversions involving private peer types, as long as they
+	// belong to the output package, e.g. internal hub types of the input package; see the
+	// "+<tag-name>=peerName:PeerTypeName" tag. Private peer types are otherwise skipped.
+	PrivatePeerTypes bool
+
+	// EquivalentTypes are pairs of types from different packages, of the form "<pkg-path>.<name>",
+	// known to be identical: conversions between them are plain type conversions, e.g. for types
+	// copied from one package to another.
+	EquivalentTypes [][2]string
+
+	// EquivalentTypesTagName is the marker that the generator will look for in the doc.go file
+	// of input packages for additional equivalent types, see EquivalentTypes:
+	// "+<tag-name>=<pkg-path>.<name>=<pkg-path>.<name>" in an input package's doc.go file; can be repeated.
+	EquivalentTypesTagName string
+
 	// ExtraImportsTagName is the marker that the generator will look for in the doc.go file
 	// of input packages for extra imports to include in the generated conversion files.
 	// Note that this should only be used in some very specific cases where `ImportTracker`s
@@ -48,72 +230,308 @@ type Options struct {
 	// go package versions.
 	ExtraImportsTagName string
 
+	// LocalImportPrefix is a comma-separated list of import path prefixes, e.g. the current module's
+	// path: generated files import matching packages in their own group, after third-party packages.
+	LocalImportPrefix string
+
+	// HeaderFileTagName is the marker that the generator will look for in the doc.go file
+	// of input packages for a boilerplate header file to use for that package only, instead
+	// of the converter-wide header:
+	// "+<tag-name>=<path>" in an input package's doc.go file; relative paths are resolved from the
+	// package's directory.
+	HeaderFileTagName string
+
+	// HeaderTagName is the marker that the generator will look for in the doc.go file
+	// of input packages for an inline boilerplate header to use for that package only, instead
+	// of the converter-wide header; each occurrence is one line of the header, e.g.
+	// "+<tag-name>=// Copyright YEAR ACME Corp."
+	// Takes precedence over HeaderFileTagName.
+	HeaderTagName string
+
+	// ReExportPackageTagName is the marker that the generator will look for in the doc.go file
+	// of input packages for a package to re-export that package's public conversion functions from:
+	// "+<tag-name>=<facade-pkg>" in an input package's doc.go file will instruct the converter to
+	// also generate a file in the facade package, with variables aliasing the public conversion
+	// functions; see ReExportGenerator.
+	ReExportPackageTagName string
+
+	// RegistryFunction, if set, makes generated files also contain an init function registering
+	// their public conversion functions by calling it, e.g. "example.com/mypkg.Registry.Add"; it must
+	// be of the form "<pkg-path>.<expression>", or just "<expression>" if local to the output package.
+	// It gets called for each public conversion function from *A to *B as
+	//    RegistryFunction((*A)(nil), (*B)(nil), func(in, out interface{}, <additional arguments>) error)
+	// where the function asserts in and out to *A and *B, and calls the conversion function.
+	RegistryFunction string
+
+	// MetricsFunction, if set, makes public conversion functions report each conversion by calling
+	// it, e.g. "example.com/conversionmetrics.Observe" to feed Prometheus metrics; it must be of the
+	// form "<pkg-path>.<expression>", or just "<expression>" if local to the output package. It gets
+	// called after each conversion from *A to *B as
+	//    MetricsFunction("<A's package path>.A", "<B's package path>.B", err error, d time.Duration)
+	// with the error returned by the conversion, if any, and how long it took.
+	MetricsFunction string
+
+	// PrivateFunctionPrefix is what private conversion functions' names start with, instead of
+	// public ones' "Convert_": e.g. "convert_" names the private function wrapped by
+	// Convert_v1_Foo_To_v2_Foo convert_v1_Foo_To_v2_Foo. Defaults to DefaultPrivateFunctionPrefix.
+	PrivateFunctionPrefix string
+
+	// NoPrivateFunctions, if true, writes the code of conversions directly in public conversion
+	// functions, instead of in private functions that they wrap. Private functions still get
+	// generated when there's no public function to write the code in, e.g. for manual conversion
+	// functions to call. Incompatible with MetricsFunction.
+	NoPrivateFunctions bool
+
+	// GenericHelpers, if true, makes generated code convert slices and maps of types that have
+	// conversion functions by calling generic helpers, e.g.
+	//    func convertSlice[In, Out any](in []In, out *[]Out, convert func(*In, *Out) error) error
+	// rather than with loops; which shrinks generated files, but requires Go 1.18 or later.
+	// Helpers are written to generated files, unless GenericHelpersPackage is set.
+	GenericHelpers bool
+
+	// GenericHelpersPackage, if set, is the package providing the generic helpers, exported (e.g.
+	// ConvertSlice), instead of writing them to generated files; see GenericHelpers.
+	GenericHelpersPackage string
+
+	// DeduplicateLoops, if true, factors the code converting map, slice and pointer fields element
+	// by element into private helper functions, that all the fields with identical conversion code
+	// in a package call; which reduces the size of generated files.
+	DeduplicateLoops bool
+
+	// TraceBuildTag, if set, makes generated private conversion functions log with klog, at
+	// verbosity TraceVerbosity, when they get called, and when converting one of their fields fails;
+	// but only in binaries built with that build tag, see TraceFile: it otherwise costs a boolean
+	// check per call.
+	TraceBuildTag string
+
+	// TraceVerbosity is the klog verbosity that entry traces get logged at, see TraceBuildTag;
+	// failures are always logged as errors.
+	TraceVerbosity int
+
+	// Templates, if set, override some of the templates used to write generated code, e.g. to
+	// change how errors are handled; see Templates.
+	Templates *Templates
+
+	// ReservedNames are identifiers that generated code must not name temporary variables after,
+	// e.g. loop variables, on top of the additional conversion arguments' names; e.g. package-level
+	// identifiers that templates or expression tags refer to. Temporary variables then get
+	// numbered names instead, e.g. "i1".
+	ReservedNames []string
+
+	// InterfaceConversionFunction, if set, converts fields of interface types, e.g. with a registered
+	// serializer; it must be of the form "<pkg-path>.<expression>", or just "<expression>" if local to
+	// the output package, and gets called for each interface field as
+	//    InterfaceConversionFunction(&in.Field, &out.Field, <additional arguments>) error
+	// Without it, interface fields whose input type implements their output type get assigned as
+	// is, e.g. when both peers embed io.Reader; see InterfaceAdapterFunction for the other ones.
+	InterfaceConversionFunction string
+
+	// InterfaceAdapterFunction, if set, converts fields of interface types whose input type doesn't
+	// implement their output type, e.g. by wrapping values into adapters delegating to their
+	// methods; it must be of the form "<pkg-path>.<expression>", or just "<expression>" if local to
+	// the output package, and gets called for each such field as
+	//    InterfaceAdapterFunction(&in.Field, &out.Field, <additional arguments>) error
+	// Without it, such fields are handled as unsupported, unless tagged as passthrough. Has no
+	// effect if InterfaceConversionFunction is set.
+	InterfaceAdapterFunction string
+
+	// PublicFunctionOnError, if true, generates public conversion functions even when handlers
+	// returned errors for some fields, with these errors listed in their doc comments: builds then
+	// keep working, while the manual work left stays visible. Types can also opt in individually,
+	// see TagName.
+	PublicFunctionOnError bool
+
 	// MissingFieldsHandler allows setting a callback to decide what happens when converting
 	// from inVar.Type to outVar.Type, and when inVar.Type's member doesn't exist in outType.
+	// Like all handlers, it gets a HandlerContext telling where in the conversion function being
+	// generated it gets called from, e.g. for fields of nested maps or slices; and giving the
+	// namers to refer to types and to other packages' identifiers with.
 	// The callback can freely write into the snippet writer, at the spot in the auto-generated
 	// conversion function where the conversion code for that field should be.
-	// If the handler returns an error, the auto-generated private conversion function
+	// If the handler fails (see HandlerResult), the auto-generated private conversion function
 	// (i.e. autoConvert_a_X_To_b_Y) will still be generated, but not the public wrapper for it
-	// (i.e. Convert_a_X_To_b_Y).
+	// (i.e. Convert_a_X_To_b_Y); unless it fails with a SkipConversionError.
 	// The handler can also choose to panic to stop the generation altogether, e.g. by calling
 	// klog.Fatalf.
-	// If this is not set, missing fields are silently ignored.
+	// If this is not set, or if the handler skips, the generator warns about the conversion.
 	// Note that the snippet writer's context is that of the generator (in particular, it can use
 	// any namers defined by the generator).
-	MissingFieldsHandler func(inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) error
+	MissingFieldsHandler func(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult
 
 	// InconvertibleFieldsHandler allows setting a callback to decide what happens when converting
 	// from inVar.Type to outVar.Type, and when inVar.Type's inMember and outVar.Type's outMember are of
 	// inconvertible types.
 	// Same as for other handlers, the callback can freely write into the snippet writer, at the spot in
 	// the auto-generated conversion function where the conversion code for that field should be.
-	// If the handler returns an error, the auto-generated private conversion function
+	// If the handler fails (see HandlerResult), the auto-generated private conversion function
 	// (i.e. autoConvert_a_X_To_b_Y) will still be generated, but not the public wrapper for it
-	// (i.e. Convert_a_X_To_b_Y).
+	// (i.e. Convert_a_X_To_b_Y); unless it fails with a SkipConversionError.
 	// The handler can also choose to panic to stop the generation altogether, e.g. by calling
 	// klog.Fatalf.
-	// If this is not set, missing fields are silently ignored.
+	// If this is not set, or if the handler skips, the generator warns about the conversion.
 	// Note that the snippet writer's context is that of the generator (in particular, it can use
 	// any namers defined by the generator).
-	InconvertibleFieldsHandler func(inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) error
+	InconvertibleFieldsHandler func(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult
 
 	// UnsupportedTypesHandler allows setting a callback to decide what happens when converting
 	// from inVar.Type to outVar.Type, and this generator has no idea how to handle that conversion.
 	// Same as for other handlers, the callback can freely write into the snippet writer, at the spot in
 	// the auto-generated conversion function where the conversion code for that type should be.
-	// If the handler returns an error, the auto-generated private conversion function
+	// If the handler fails (see HandlerResult), the auto-generated private conversion function
 	// (i.e. autoConvert_a_X_To_b_Y) will still be generated, but not the public wrapper for it
-	// (i.e. Convert_a_X_To_b_Y).
+	// (i.e. Convert_a_X_To_b_Y); unless it fails with a SkipConversionError.
 	// The handler can also choose to panic to stop the generation altogether, e.g. by calling
 	// klog.Fatalf.
-	// If this is not set, missing fields are silently ignored.
+	// If this is not set, or if the handler skips, the generator warns about the conversion.
 	// Note that the snippet writer's context is that of the generator (in particular, it can use
 	// any namers defined by the generator).
-	UnsupportedTypesHandler func(inVar, outVar NamedVariable, sw *generator.SnippetWriter) error
+	UnsupportedTypesHandler func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult
 
 	// ExternalConversionsHandler allows setting a callback to decide what happens when converting
 	// from inVar.Type to outVar.Type, but outVar.Type is in a different package than inVar.Type - and so
 	// this generator can't know where to find a conversion function for that.
+	// inVar.Name and outVar.Name are expressions of pointers to the values to convert.
+	// See e.g. CallConversionFunction for ready-made handlers.
 	// Same as for other handlers, the callback can freely write into the snippet writer, at the spot in
 	// the auto-generated conversion function where the conversion code for that type should be.
-	// If the handler returns an error, the auto-generated private conversion function
+	// If the handler fails (see HandlerResult), the auto-generated private conversion function
 	// (i.e. autoConvert_a_X_To_b_Y) will still be generated, but not the public wrapper for it
-	// (i.e. Convert_a_X_To_b_Y).
+	// (i.e. Convert_a_X_To_b_Y); unless it fails with a SkipConversionError.
 	// The handler can also choose to panic to stop the generation altogether, e.g. by calling
 	// klog.Fatalf.
-	// If this is not set, missing fields are silently ignored.
-	// The boolean returned by the handler should indicate whether it has written code to handle
-	// the conversion.
+	// If this is not set, or if the handler skips, the generator warns about the conversion.
 	// Note that the snippet writer's context is that of the generator (in particular, it can use
 	// any namers defined by the generator).
-	ExternalConversionsHandler func(inVar, outVar NamedVariable, sw *generator.SnippetWriter) (bool, error)
+	ExternalConversionsHandler func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult
+
+	// HandlerPlugin, if set, is an external executable that gets to handle what all the handlers
+	// above would, before them: they only get called if it skips. See HandlerPlugin.
+	HandlerPlugin *HandlerPlugin
+
+	// Diagnostics, if set, collects the problems found while generating conversion code
+	// (missing fields, inconvertible types, dropped conversions, etc...), along with the
+	// positions of the offending declarations; they can then be written out e.g. as SARIF.
+	// Collectors can be safely shared between generators.
+	Diagnostics *DiagnosticsCollector
+
+	// Graph, if set, collects the generated and manual conversion functions, and which of them call
+	// which, e.g. to visualize conversion coverage.
+	// Graphs can be safely shared between generators.
+	Graph *ConversionGraph
+
+	// FieldDocs, if set, collects how each struct field gets converted, to document conversions.
+	// FieldDocs can be safely shared between generators.
+	FieldDocs *FieldDocs
+
+	// Explainer, if set, collects the decisions made about specific types, to help debugging why
+	// they did or didn't get the conversion code they did.
+	// Explainers can be safely shared between generators.
+	Explainer *Explainer
 }
 
 func DefaultOptions() *Options {
 	return &Options{
-		TagName:             DefaultTagName,
-		FunctionTagName:     DefaultTagName,
-		PeerPackagesTagName: DefaultTagName,
-		ExtraImportsTagName: DefaultTagName + "-extra-imports",
+		BuiltinConversionPolicy:     BuiltinConversionCast,
+		FuncChanPolicy:              FuncChanUnsupported,
+		NilElementsPolicy:           NilElementsToZero,
+		PrivateFunctionPrefix:       DefaultPrivateFunctionPrefix,
+		TagName:                     DefaultTagName,
+		FunctionTagName:             DefaultTagName,
+		PeerPackagesTagName:         DefaultTagName,
+		ExtraImportsTagName:         DefaultTagName + "-extra-imports",
+		HeaderFileTagName:           DefaultTagName + "-header-file",
+		HeaderTagName:               DefaultTagName + "-header",
+		ReExportPackageTagName:      DefaultTagName + "-reexport",
+		ReadOnlyPeerPackagesTagName: DefaultTagName + "-read-only-peer",
+		EquivalentTypesTagName:      DefaultTagName + "-assignable",
+		FilesTagName:                DefaultTagName + "-files",
+	}
+}
+
+// Validate returns an error if the options are inconsistent, e.g. options that only matter with
+// unsafe conversions while these are disabled; NewConversionGenerator calls it. Empty options
+// with defaults, e.g. policies or PrivateFunctionPrefix, are valid.
+func (o *Options) Validate() error {
+	switch o.BuiltinConversionPolicy {
+	case "", BuiltinConversionCast, BuiltinConversionCheck, BuiltinConversionForbid:
+	default:
+		return errors.Errorf("unknown builtin conversion policy %q", o.BuiltinConversionPolicy)
+	}
+	switch o.FuncChanPolicy {
+	case "", FuncChanUnsupported, FuncChanSkip, FuncChanCopy, FuncChanError:
+	default:
+		return errors.Errorf("unknown function and channel policy %q", o.FuncChanPolicy)
+	}
+	switch o.NilElementsPolicy {
+	case "", NilElementsToZero, NilElementsToError:
+	default:
+		return errors.Errorf("unknown nil elements policy %q", o.NilElementsPolicy)
+	}
+	switch o.DynamicValuesPolicy {
+	case DynamicValuesUnhandled, DynamicValuesPassThrough, DynamicValuesDeepCopy, DynamicValuesJSON:
+	default:
+		return errors.Errorf("unknown dynamic values policy %q", o.DynamicValuesPolicy)
+	}
+	switch o.ErrorWrappingPolicy {
+	case ErrorWrappingNone, ErrorWrappingFmt, ErrorWrappingFunction:
+	default:
+		return errors.Errorf("unknown error wrapping policy %q", o.ErrorWrappingPolicy)
+	}
+
+	if o.DynamicValuesPolicy == DynamicValuesDeepCopy && o.DynamicValuesDeepCopyFunction == "" {
+		return errors.Errorf("dynamic values policy %q requires a deep-copy function", DynamicValuesDeepCopy)
+	}
+	if o.DynamicValuesPolicy != DynamicValuesDeepCopy && o.DynamicValuesDeepCopyFunction != "" {
+		return errors.Errorf("deep-copy function %q is only used with dynamic values policy %q, not %q",
+			o.DynamicValuesDeepCopyFunction, DynamicValuesDeepCopy, o.DynamicValuesPolicy)
+	}
+	if o.ErrorWrappingPolicy == ErrorWrappingFunction && o.ErrorWrappingFunction == "" {
+		return errors.Errorf("error wrapping policy %q requires a wrapping function", ErrorWrappingFunction)
+	}
+	if o.ErrorWrappingPolicy != ErrorWrappingFunction && o.ErrorWrappingFunction != "" {
+		return errors.Errorf("wrapping function %q is only used with error wrapping policy %q, not %q",
+			o.ErrorWrappingFunction, ErrorWrappingFunction, o.ErrorWrappingPolicy)
+	}
+
+	if o.PrivateFunctionPrefix != "" && (!token.IsIdentifier(o.PrivateFunctionPrefix) || o.PrivateFunctionPrefix == conversionFunctionPrefix) {
+		return errors.Errorf("invalid private function prefix %q", o.PrivateFunctionPrefix)
+	}
+	if o.ManualConversionsTracker != nil {
+		for _, namedArgument := range o.ManualConversionsTracker.additionalConversionArguments {
+			if namedArgument.Name == "in" || namedArgument.Name == "out" {
+				return errors.Errorf("additional conversion argument %q has the name of a conversion function's own argument", namedArgument.Name)
+			}
+		}
+	}
+	if o.InterfaceConversionFunction != "" && o.InterfaceAdapterFunction != "" {
+		return errors.Errorf("interface adapter function %q has no effect with interface conversion function %q", o.InterfaceAdapterFunction, o.InterfaceConversionFunction)
+	}
+	if o.NoPrivateFunctions && o.MetricsFunction != "" {
+		return errors.New("public conversion functions need private functions to wrap to report metrics")
+	}
+
+	if o.NoUnsafeConversions {
+		for _, option := range []struct {
+			name string
+			set  bool
+		}{
+			{"major version equivalence", o.MajorVersionEquivalence},
+			{"castable conversion pruning", o.PruneCastableConversions},
+			{"view functions", o.ViewFunctions},
+		} {
+			if option.set {
+				return errors.Errorf("%s needs unsafe conversions, which are disabled", option.name)
+			}
+		}
+	}
+	if o.OptionalScalarsKeepZeros && !o.OptionalScalars {
+		return errors.New("keeping zeros of optional scalars needs optional scalars to be enabled")
+	}
+	if o.GenericHelpersPackage != "" && !o.GenericHelpers {
+		return errors.Errorf("generic helpers package %q needs generic helpers to be enabled", o.GenericHelpersPackage)
+	}
+	if o.PrivatePeerTypes && o.TagName == "" {
+		return errors.New("private peer types are named by peerName tags, which need a tag name")
 	}
+	return nil
 }
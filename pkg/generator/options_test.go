@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/gengo/types"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		modify   func(o *Options)
+		expected string
+	}{
+		{
+			name:   "default options",
+			modify: func(*Options) {},
+		},
+		{
+			name: "major version equivalence without unsafe conversions",
+			modify: func(o *Options) {
+				o.NoUnsafeConversions = true
+				o.MajorVersionEquivalence = true
+			},
+			expected: "major version equivalence needs unsafe conversions, which are disabled",
+		},
+		{
+			name: "castable conversion pruning without unsafe conversions",
+			modify: func(o *Options) {
+				o.NoUnsafeConversions = true
+				o.PruneCastableConversions = true
+			},
+			expected: "castable conversion pruning needs unsafe conversions, which are disabled",
+		},
+		{
+			name: "view functions without unsafe conversions",
+			modify: func(o *Options) {
+				o.NoUnsafeConversions = true
+				o.ViewFunctions = true
+			},
+			expected: "view functions needs unsafe conversions, which are disabled",
+		},
+		{
+			name: "private peer types without tag name",
+			modify: func(o *Options) {
+				o.TagName = ""
+				o.PrivatePeerTypes = true
+			},
+			expected: "private peer types are named by peerName tags, which need a tag name",
+		},
+		{
+			name: "deep-copy policy without function",
+			modify: func(o *Options) {
+				o.DynamicValuesPolicy = DynamicValuesDeepCopy
+			},
+			expected: `dynamic values policy "deep-copy" requires a deep-copy function`,
+		},
+		{
+			name: "error wrapping function without its policy",
+			modify: func(o *Options) {
+				o.ErrorWrappingFunction = "Wrap"
+			},
+			expected: `wrapping function "Wrap" is only used with error wrapping policy`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			options := DefaultOptions()
+			tc.modify(options)
+
+			err := options.Validate()
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckPeerTags(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		tagName      string
+		commentLines []string
+		expected     string
+	}{
+		{
+			name:         "peerName tag with a tag name",
+			tagName:      DefaultTagName,
+			commentLines: []string{"+conversion-gen=peerName:Bar"},
+		},
+		{
+			name:         "no peer tag without a tag name",
+			commentLines: []string{"+conversion-gen=false"},
+		},
+		{
+			name:         "peerName tag without a tag name",
+			commentLines: []string{"+conversion-gen=peerName:Bar"},
+			expected:     `type example.com/a/v1.Foo has a "+conversion-gen=peerName:Bar" tag, but no tag name is set: set the tag name (e.g. to "conversion-gen") for peerName tags to be used`,
+		},
+		{
+			name:         "peerPackage tag without a tag name",
+			commentLines: []string{"+conversion-gen=peerPackage:example.com/a/v2"},
+			expected:     `type example.com/a/v1.Foo has a "+conversion-gen=peerPackage:example.com/a/v2" tag, but no tag name is set: set the tag name (e.g. to "conversion-gen") for peerPackage tags to be used`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			universe := types.Universe{}
+			foo := universe.Type(types.Name{Package: "example.com/a/v1", Name: "Foo"})
+			foo.Kind = types.Struct
+			foo.CommentLines = tc.commentLines
+
+			err := checkPeerTags(universe.Package("example.com/a/v1"), tc.tagName)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			} else if err == nil || err.Error() != tc.expected {
+				t.Errorf("expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
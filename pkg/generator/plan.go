@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"k8s.io/gengo/types"
+)
+
+// A ConversionDirection tells whether a conversion is from a type of the types package to its
+// peer, or the other way around.
+type ConversionDirection string
+
+const (
+	// ToPeer conversions are from a type of the types package to its peer type.
+	ToPeer ConversionDirection = "to-peer"
+	// FromPeer conversions are from a peer type to its type in the types package.
+	FromPeer ConversionDirection = "from-peer"
+)
+
+// A ConversionStrategy is how a conversion gets generated.
+type ConversionStrategy string
+
+const (
+	// GeneratedConversion means that both the private and the public conversion functions
+	// are generated.
+	GeneratedConversion ConversionStrategy = "generated"
+	// ManualConversion means that the private conversion function is generated, but that the public
+	// one is a manual function.
+	ManualConversion ConversionStrategy = "manual"
+	// PrivateOnlyConversion means that only the private conversion function is generated, as
+	// requested by a no-public tag.
+	PrivateOnlyConversion ConversionStrategy = "private-only"
+	// IncompleteConversion means that only the private conversion function is generated,
+	// as some conversions need to be written manually; see TodoItems.
+	IncompleteConversion ConversionStrategy = "incomplete"
+	// SkippedConversion means that nothing is generated, as the output type is in a read-only peer
+	// package, or as a handler asked to skip the conversion; see SkipConversionError.
+	SkippedConversion ConversionStrategy = "skipped"
+	// CastConversion means that nothing is generated, as both types share the same memory layout,
+	// and get converted with unsafe casts; see Options.PruneCastableConversions.
+	CastConversion ConversionStrategy = "cast"
+)
+
+// A PlannedConversion is a conversion that this generator has planned, see PlannedConversions.
+type PlannedConversion struct {
+	InType    *types.Type
+	OutType   *types.Type
+	Direction ConversionDirection
+	Strategy  ConversionStrategy
+	// Function is the name of the public conversion function from InType to OutType, be it
+	// generated or manual, if any.
+	Function string
+	// Warnings are the problems found when generating the conversion, if any.
+	Warnings []string
+}
+
+// PlannedConversions returns all the conversions that this generator has generated so far, in
+// generation order.
+func (g *Generator) PlannedConversions() []PlannedConversion {
+	return append([]PlannedConversion{}, g.plannedConversions...)
+}
+
+// planConversion records a conversion, with the todos reported since the given index as warnings.
+func (g *Generator) planConversion(inType, outType *types.Type, direction ConversionDirection, strategy ConversionStrategy, todosSince int) {
+	planned := PlannedConversion{
+		InType:    inType,
+		OutType:   outType,
+		Direction: direction,
+		Strategy:  strategy,
+	}
+
+	switch strategy {
+	case GeneratedConversion:
+		planned.Function = ConversionFunctionName(inType, outType)
+	case ManualConversion:
+		if function, found := g.preexists(inType, outType); found {
+			planned.Function = function.Name.Name
+		}
+	}
+
+	seen := make(map[string]bool)
+	addWarning := func(warning string) {
+		if !seen[warning] {
+			seen[warning] = true
+			planned.Warnings = append(planned.Warnings, warning)
+		}
+	}
+	for _, todo := range g.todos[todosSince:] {
+		addWarning(todo.Reason)
+	}
+	if strategy == IncompleteConversion && len(g.manualConversionsNeeded) != 0 {
+		for _, reason := range g.manualConversionsNeeded[len(g.manualConversionsNeeded)-1].Reasons {
+			addWarning(reason)
+		}
+	}
+
+	g.plannedConversions = append(g.plannedConversions, planned)
+}
@@ -0,0 +1,254 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+)
+
+// The kinds of handlers that HandlerPlugins get called as, see HandlerPluginRequest.Handler.
+const (
+	PluginMissingField       = "missingField"
+	PluginInconvertibleField = "inconvertibleField"
+	PluginUnsupportedType    = "unsupportedType"
+	PluginExternalConversion = "externalConversion"
+)
+
+// A HandlerPlugin is an external executable acting as a handler, see Options.HandlerPlugin.
+// It gets run once for each call, with a JSON-serialized HandlerPluginRequest on its standard
+// input; and must write a JSON-serialized HandlerPluginResponse on its standard output.
+type HandlerPlugin struct {
+	// Command is the path of the executable, looked up in PATH if it doesn't contain any slash.
+	Command string
+	// Args are the arguments to run it with.
+	Args []string
+}
+
+// NewHandlerPlugin returns the plugin run by the given command line, split on whitespace.
+func NewHandlerPlugin(commandLine string) (*HandlerPlugin, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, errors.New("empty handler plugin command")
+	}
+	return &HandlerPlugin{Command: fields[0], Args: fields[1:]}, nil
+}
+
+// A HandlerPluginRequest is what HandlerPlugins get on their standard input.
+type HandlerPluginRequest struct {
+	// Handler is the kind of handler the plugin is called as, e.g. PluginMissingField.
+	Handler string `json:"handler"`
+	// InType and OutType are the types that the conversion function being generated converts,
+	// see HandlerContext.
+	InType  PluginType `json:"inType"`
+	OutType PluginType `json:"outType"`
+	// FieldPath and Nesting are where the handled variables are, see HandlerContext.
+	FieldPath []string `json:"fieldPath,omitempty"`
+	Nesting   []string `json:"nesting,omitempty"`
+	// In and Out are the handled variables.
+	In  PluginVariable `json:"in"`
+	Out PluginVariable `json:"out"`
+	// InMember and OutMember are the handled fields, for field handlers; OutMember is only set for
+	// PluginInconvertibleField.
+	InMember  *PluginMember `json:"inMember,omitempty"`
+	OutMember *PluginMember `json:"outMember,omitempty"`
+	// AdditionalArguments are the additional arguments of conversion functions, see
+	// NewManualConversionsTracker.
+	AdditionalArguments []PluginVariable `json:"additionalArguments,omitempty"`
+}
+
+// A PluginType describes a type to HandlerPlugins.
+type PluginType struct {
+	// Name is the type's fully qualified name, e.g. "example.com/pkg.Foo" or "[]string".
+	Name string `json:"name"`
+	// Package is the path of the type's package, empty for builtin and unnamed types.
+	Package string `json:"package,omitempty"`
+	// Kind is the type's kind, e.g. "Struct" or "Pointer"; that of the underlying type for aliases.
+	Kind string `json:"kind"`
+}
+
+// A PluginVariable describes a variable to HandlerPlugins.
+type PluginVariable struct {
+	// Name is the expression that generated code refers to the variable with.
+	Name string     `json:"name"`
+	Type PluginType `json:"type"`
+}
+
+// A PluginMember describes a struct field to HandlerPlugins.
+type PluginMember struct {
+	Name         string     `json:"name"`
+	Type         PluginType `json:"type"`
+	Embedded     bool       `json:"embedded,omitempty"`
+	Tags         string     `json:"tags,omitempty"`
+	CommentLines []string   `json:"commentLines,omitempty"`
+}
+
+// A HandlerPluginResponse is what HandlerPlugins write on their standard output.
+type HandlerPluginResponse struct {
+	// Outcome is what the plugin decided.
+	Outcome HandlerOutcome `json:"outcome"`
+	// Code is the conversion code to write, with HandlerHandled; or written before failing, with
+	// HandlerFailed. It can refer to other packages' identifiers as $<pkg-path>.<name>$, e.g.
+	// $k8s.io/utils/pointer.Int32$, which get qualified and imported as needed.
+	Code string `json:"code,omitempty"`
+	// Error is why the plugin failed, with HandlerFailed.
+	Error string `json:"error,omitempty"`
+}
+
+// pluginReferenceRegex matches the references to other packages' identifiers in plugins' code.
+var pluginReferenceRegex = regexp.MustCompile(`\$([^$\s]+)\.([A-Za-z_][A-Za-z0-9_]*)\$`)
+
+// MissingFieldsHandler returns a missing fields handler calling the plugin.
+func (p *HandlerPlugin) MissingFieldsHandler() MissingFieldsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, member *types.Member, sw *generator.SnippetWriter) HandlerResult {
+		request := newHandlerPluginRequest(PluginMissingField, handlerContext, inVar, outVar)
+		request.InMember = newPluginMember(member)
+		return p.handle(handlerContext, request, sw)
+	}
+}
+
+// InconvertibleFieldsHandler returns an inconvertible fields handler calling the plugin.
+func (p *HandlerPlugin) InconvertibleFieldsHandler() InconvertibleFieldsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, inMember, outMember *types.Member, sw *generator.SnippetWriter) HandlerResult {
+		request := newHandlerPluginRequest(PluginInconvertibleField, handlerContext, inVar, outVar)
+		request.InMember = newPluginMember(inMember)
+		request.OutMember = newPluginMember(outMember)
+		return p.handle(handlerContext, request, sw)
+	}
+}
+
+// UnsupportedTypesHandler returns an unsupported types handler calling the plugin.
+func (p *HandlerPlugin) UnsupportedTypesHandler() ExternalConversionsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+		return p.handle(handlerContext, newHandlerPluginRequest(PluginUnsupportedType, handlerContext, inVar, outVar), sw)
+	}
+}
+
+// ExternalConversionsHandler returns an external conversions handler calling the plugin.
+func (p *HandlerPlugin) ExternalConversionsHandler() ExternalConversionsHandlerFunc {
+	return func(handlerContext *HandlerContext, inVar, outVar NamedVariable, sw *generator.SnippetWriter) HandlerResult {
+		return p.handle(handlerContext, newHandlerPluginRequest(PluginExternalConversion, handlerContext, inVar, outVar), sw)
+	}
+}
+
+// handle runs the plugin with request, and writes the code it returns, if any.
+func (p *HandlerPlugin) handle(handlerContext *HandlerContext, request *HandlerPluginRequest, sw *generator.SnippetWriter) HandlerResult {
+	response, err := p.run(request)
+	if err != nil {
+		return Failed(err)
+	}
+
+	switch response.Outcome {
+	case HandlerHandled, HandlerFailed:
+	case HandlerSkipped:
+		return Skipped()
+	default:
+		return Failedf("handler plugin %q returned unknown outcome %q", p.Command, response.Outcome)
+	}
+
+	if code := pluginCode(handlerContext, response.Code); code != "" {
+		sw.Do("$.$", code)
+	}
+	if response.Outcome == HandlerFailed {
+		if response.Error == "" {
+			return Failedf("handler plugin %q failed on %v to %v", p.Command, request.In.Type.Name, request.Out.Type.Name)
+		}
+		return Failedf("%s", response.Error)
+	}
+	return Handled()
+}
+
+// run runs the plugin with request, and returns its response.
+func (p *HandlerPlugin) run(request *HandlerPluginRequest) (*HandlerPluginResponse, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to serialize handler plugin request")
+	}
+	klog.V(5).Infof("Calling handler plugin %q with %s", p.Command, input)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.Command(p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "handler plugin %q failed: %s", p.Command, strings.TrimSpace(stderr.String()))
+	}
+
+	response := &HandlerPluginResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the response of handler plugin %q", p.Command)
+	}
+	return response, nil
+}
+
+// pluginCode returns code with its references to other packages' identifiers qualified, and
+// ending with a newline if not empty.
+func pluginCode(handlerContext *HandlerContext, code string) string {
+	if code == "" {
+		return ""
+	}
+	code = pluginReferenceRegex.ReplaceAllStringFunc(code, func(reference string) string {
+		match := pluginReferenceRegex.FindStringSubmatch(reference)
+		return handlerContext.QualifiedName(match[1], match[2])
+	})
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+	return code
+}
+
+func newHandlerPluginRequest(handler string, handlerContext *HandlerContext, inVar, outVar NamedVariable) *HandlerPluginRequest {
+	request := &HandlerPluginRequest{
+		Handler:   handler,
+		InType:    newPluginType(handlerContext.InType),
+		OutType:   newPluginType(handlerContext.OutType),
+		FieldPath: handlerContext.FieldPath,
+		In:        newPluginVariable(inVar),
+		Out:       newPluginVariable(outVar),
+	}
+	for _, kind := range handlerContext.Nesting {
+		request.Nesting = append(request.Nesting, string(kind))
+	}
+	for _, argument := range handlerContext.AdditionalArguments {
+		request.AdditionalArguments = append(request.AdditionalArguments, newPluginVariable(argument))
+	}
+	return request
+}
+
+func newPluginType(t *types.Type) PluginType {
+	if t == nil {
+		return PluginType{}
+	}
+	return PluginType{
+		Name:    t.String(),
+		Package: t.Name.Package,
+		Kind:    string(unwrapAlias(t).Kind),
+	}
+}
+
+func newPluginVariable(variable NamedVariable) PluginVariable {
+	return PluginVariable{
+		Name: variable.Name,
+		Type: newPluginType(variable.Type),
+	}
+}
+
+func newPluginMember(member *types.Member) *PluginMember {
+	if member == nil {
+		return nil
+	}
+	return &PluginMember{
+		Name:         member.Name,
+		Type:         newPluginType(member.Type),
+		Embedded:     member.Embedded,
+		Tags:         member.Tags,
+		CommentLines: member.CommentLines,
+	}
+}
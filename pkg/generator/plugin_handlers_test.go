@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+func TestHandlerPlugin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("no shell: %v", err)
+	}
+
+	inType := &types.Type{Name: types.Name{Package: "example.com/a/v1", Name: "Foo"}, Kind: types.Struct}
+	outType := &types.Type{Name: types.Name{Package: "example.com/a/v2", Name: "Foo"}, Kind: types.Struct}
+	member := &types.Member{Name: "A", Type: types.String}
+
+	for _, testCase := range []struct {
+		name string
+		// script is what the plugin runs, once it saved its request
+		script          string
+		expectedOutcome HandlerOutcome
+		expectedErr     string
+		expectedCode    string
+	}{
+		{
+			name:            "handled",
+			script:          `echo '{"outcome": "handled", "code": "out.B = $example.com/fns.Convert$(in.A)"}'`,
+			expectedOutcome: HandlerHandled,
+			expectedCode:    "out.B = fns.Convert(in.A)\n",
+		},
+		{
+			name:            "skipped",
+			script:          `echo '{"outcome": "skipped"}'`,
+			expectedOutcome: HandlerSkipped,
+		},
+		{
+			name:            "failed",
+			script:          `echo '{"outcome": "failed", "code": "// TODO: in.A", "error": "nope"}'`,
+			expectedOutcome: HandlerFailed,
+			expectedErr:     "nope",
+			expectedCode:    "// TODO: in.A\n",
+		},
+		{
+			name:            "failed without error",
+			script:          `echo '{"outcome": "failed"}'`,
+			expectedOutcome: HandlerFailed,
+			expectedErr:     `failed on example.com/a/v1.Foo to example.com/a/v2.Foo`,
+		},
+		{
+			name:            "unknown outcome",
+			script:          `echo '{"outcome": "maybe", "code": "out.B = in.A"}'`,
+			expectedOutcome: HandlerFailed,
+			expectedErr:     `returned unknown outcome "maybe"`,
+		},
+		{
+			name:            "crash",
+			script:          "echo boom >&2; exit 3",
+			expectedOutcome: HandlerFailed,
+			expectedErr:     "failed: boom: exit status 3",
+		},
+		{
+			name:            "bad output",
+			script:          "echo not JSON",
+			expectedOutcome: HandlerFailed,
+			expectedErr:     "unable to parse the response",
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			requestPath := filepath.Join(dir, "request.json")
+			scriptPath := filepath.Join(dir, "plugin.sh")
+			script := "#!/bin/sh\ncat > " + requestPath + "\n" + testCase.script + "\n"
+			if err := ioutil.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+			plugin, err := NewHandlerPlugin("sh " + scriptPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buffer := &bytes.Buffer{}
+			sw := generator.NewSnippetWriter(buffer, &generator.Context{}, snippetDelimiter, snippetDelimiter)
+			handlerContext := &HandlerContext{
+				InType:    inType,
+				OutType:   outType,
+				FieldPath: []string{"A"},
+				RawNamer:  namer.NewRawNamer("example.com/a/v1", generator.NewImportTracker()),
+			}
+			result := plugin.MissingFieldsHandler()(handlerContext, NewNamedVariable("in", inType), NewNamedVariable("out", outType), member, sw)
+			if err := sw.Error(); err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Outcome != testCase.expectedOutcome {
+				t.Errorf("expected outcome %q, got %q (%v)", testCase.expectedOutcome, result.Outcome, result.Err)
+			}
+			if testCase.expectedErr == "" && result.Err != nil || testCase.expectedErr != "" && (result.Err == nil || !strings.Contains(result.Err.Error(), testCase.expectedErr)) {
+				t.Errorf("expected error %q, got %v", testCase.expectedErr, result.Err)
+			}
+			if code := buffer.String(); code != testCase.expectedCode {
+				t.Errorf("expected code %q, got %q", testCase.expectedCode, code)
+			}
+
+			raw, err := ioutil.ReadFile(requestPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := &HandlerPluginRequest{}
+			if err := json.Unmarshal(raw, request); err != nil {
+				t.Fatal(err)
+			}
+			if request.Handler != PluginMissingField || request.InType.Name != "example.com/a/v1.Foo" || request.InMember == nil || request.InMember.Name != "A" || request.In.Name != "in" {
+				t.Errorf("unexpected request: %s", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// NilElementsPolicy decides how nil elements are handled when converting slices of pointers to
+// slices of values.
+type NilElementsPolicy string
+
+const (
+	// NilElementsToZero converts nil elements to zero values. This is the default.
+	NilElementsToZero NilElementsPolicy = "zero"
+	// NilElementsToError makes conversions return an error on nil elements.
+	NilElementsToError NilElementsPolicy = "error"
+)
+
+// doPointerElems writes the loop body converting (*in)[i] to (*out)[i] when converting between a
+// slice of pointers and a slice of values, e.g. []*T and []U, if T can be converted to U;
+// returns true iff it did, and the errors generating it.
+func (g *Generator) doPointerElems(inType, outType *types.Type, sw *generator.SnippetWriter) (bool, []error) {
+	inElem, outElem, i := inType.Elem, outType.Elem, g.temp("i")
+	switch {
+	case inElem.Kind == types.Pointer && outElem.Kind != types.Pointer:
+		if !g.canConvertElems(inElem.Elem, outElem) {
+			return false, nil
+		}
+		sw.Do("if (*in)["+i+"] != nil {\n", nil)
+		errors := g.writeElemConversion(inElem.Elem, outElem, "(*in)["+i+"]", "&(*out)["+i+"]", sw)
+		if g.Options.NilElementsPolicy == NilElementsToError {
+			sw.Do("} else {\n", nil)
+			sw.Do("return $.Errorf|"+rawNamer+"$(\"nil element at index %d\", "+i+")\n", generator.Args{"Errorf": types.Ref("fmt", "Errorf")})
+		}
+		sw.Do("}\n", nil)
+		return true, errors
+
+	case inElem.Kind != types.Pointer && outElem.Kind == types.Pointer:
+		if !g.canConvertElems(inElem, outElem.Elem) {
+			return false, nil
+		}
+		sw.Do("(*out)["+i+"] = new($.|"+rawNamer+"$)\n", outElem.Elem)
+		return true, g.writeElemConversion(inElem, outElem.Elem, "&(*in)["+i+"]", "(*out)["+i+"]", sw)
+
+	default:
+		return false, nil
+	}
+}
+
+// canConvertElems returns true iff writeElemConversion can convert inType to outType.
+func (g *Generator) canConvertElems(inType, outType *types.Type) bool {
+	if inType.Kind == types.Builtin && outType.Kind == types.Builtin {
+		return g.builtinConversionAllowed(inType, outType)
+	}
+	if inType == outType {
+		return true
+	}
+	if _, ok := g.preexists(inType, outType); ok {
+		return true
+	}
+	return unwrapAlias(inType).Kind == types.Struct && g.convertibleOnlyWithinPackage(inType, outType)
+}
+
+// writeElemConversion writes the conversion from in to out, pointers to inType and outType;
+// see canConvertElems. Returns the errors generating it.
+func (g *Generator) writeElemConversion(inType, outType *types.Type, in, out string, sw *generator.SnippetWriter) []error {
+	if inType.Kind == types.Builtin && outType.Kind == types.Builtin {
+		g.writeBuiltinConversion(inType, outType, dereference(in), dereference(out), nil, sw)
+		return nil
+	}
+
+	function, ok := g.preexists(inType, outType)
+	if !ok && g.deepCopiesElem(inType, outType) {
+		return g.writeDeepCopyCall(inType, outType, in, out, nil, sw)
+	}
+	if inType == outType {
+		sw.Do(dereference(out)+" = "+dereference(in)+"\n", nil)
+		return nil
+	}
+	g.writeConversionCall(function, inType, outType, in, out, "", nil, sw)
+	return nil
+}
+
+// dereference returns the expression for the value that pointer expression expr points to.
+func dereference(expr string) string {
+	if strings.HasPrefix(expr, "&") {
+		return expr[1:]
+	}
+	return "*" + expr
+}
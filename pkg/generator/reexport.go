@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"io"
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// addPublicFunction records that the output package has a public conversion function from
+// inType to outType.
+func (g *Generator) addPublicFunction(inType, outType *types.Type) {
+	local := g.localType(inType, outType)
+	g.publicFunctions[local] = append(g.publicFunctions[local], g.publicFunction(inType, outType))
+	g.publicConversions = append(g.publicConversions, ConversionPair{inType, outType})
+}
+
+// PublicConversionFunctions returns the public conversion functions, either generated or manual,
+// that the output package has to or from type t, from the types package.
+// Only meaningful once t has been processed by GenerateType.
+func (g *Generator) PublicConversionFunctions(t *types.Type) []*types.Type {
+	return g.publicFunctions[t]
+}
+
+// A ReExportGenerator generates a file in a facade package, that re-exports the public
+// conversion functions of a conversion generator's output package, e.g.
+//
+//	var Convert_v1_Foo_To_v2_Foo = impl.Convert_v1_Foo_To_v2_Foo
+//
+// This is useful when the canonical conversion package must stay internal.
+// It must run after the conversion generator it re-exports from, and on the same types.
+type ReExportGenerator struct {
+	generator.DefaultGen
+
+	conversionGenerator *Generator
+	facadePackage       string
+	importTracker       namer.ImportTracker
+}
+
+// NewReExportGenerator builds a new ReExportGenerator.
+func NewReExportGenerator(outputFileName, facadePackage string, conversionGenerator *Generator) *ReExportGenerator {
+	return &ReExportGenerator{
+		DefaultGen: generator.DefaultGen{
+			OptionalName: outputFileName,
+		},
+		conversionGenerator: conversionGenerator,
+		facadePackage:       facadePackage,
+		importTracker:       generator.NewImportTracker(),
+	}
+}
+
+// Namers returns the name system used by ReExportGenerators.
+func (r *ReExportGenerator) Namers(*generator.Context) namer.NameSystems {
+	return namer.NameSystems{
+		rawNamer: namer.NewRawNamer(r.facadePackage, r.importTracker),
+	}
+}
+
+// Filter filters the types this generator operates on.
+func (r *ReExportGenerator) Filter(_ *generator.Context, t *types.Type) bool {
+	return len(r.conversionGenerator.PublicConversionFunctions(t)) != 0
+}
+
+// Imports returns the imports to add to generated files.
+func (r *ReExportGenerator) Imports(*generator.Context) []string {
+	var imports []string
+	for _, importLine := range r.importTracker.ImportLines() {
+		if importLine != r.facadePackage && !strings.HasSuffix(importLine, `"`+r.facadePackage+`"`) {
+			imports = append(imports, importLine)
+		}
+	}
+	return sortImports(imports, r.conversionGenerator.Options.LocalImportPrefix)
+}
+
+// GenerateType processes the given type.
+func (r *ReExportGenerator) GenerateType(context *generator.Context, t *types.Type, writer io.Writer) error {
+	sw := generator.NewSnippetWriter(writer, context, snippetDelimiter, snippetDelimiter)
+	for _, function := range r.conversionGenerator.PublicConversionFunctions(t) {
+		args := generator.Args{
+			"name":     function.Name.Name,
+			"function": function,
+		}
+		sw.Do("// $.name$ re-exports $.function|"+rawNamer+"$.\n", args)
+		sw.Do("var $.name$ = $.function|"+rawNamer+"$\n\n", args)
+	}
+	return sw.Error()
+}
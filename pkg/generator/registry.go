@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// writeRegistry writes the init function registering the public conversion functions, and the
+// cast conversions, if Options.RegistryFunction is set.
+func (g *Generator) writeRegistry(sw *generator.SnippetWriter) {
+	if g.Options.RegistryFunction == "" || len(g.publicConversions)+len(g.castConversions) == 0 {
+		return
+	}
+
+	registry := g.functionExpression(g.Options.RegistryFunction)
+
+	sw.Do("func init() {\n", nil)
+	for _, pair := range g.publicConversions {
+		args := argsFromType(pair.InType, pair.OutType).With("registry", registry)
+		sw.Do("$.registry$((*$.inType|"+rawNamer+"$)(nil), (*$.outType|"+rawNamer+"$)(nil), func(in, out interface{}", args)
+		for _, namedArgument := range g.Options.ManualConversionsTracker.additionalConversionArguments {
+			sw.Do(fmt.Sprintf(", %s", namedArgument.Name)+" $.|"+rawNamer+"$", namedArgument.Type)
+		}
+		sw.Do(") error {\n", nil)
+		in := "in.(*$.inType|" + rawNamer + "$)"
+		if g.valueInput(pair.InType, pair.OutType) {
+			in = dereference(in)
+		}
+		sw.Do("return "+conversionFunctionNameTemplate(publicImportTrackingNamer)+
+			"("+in+", out.(*$.outType|"+rawNamer+"$)"+g.extraArgumentsString()+")\n", args)
+		sw.Do("})\n", nil)
+	}
+	for _, pair := range g.castConversions {
+		args := argsFromType(pair.InType, pair.OutType).With("registry", registry)
+		sw.Do("$.registry$((*$.inType|"+rawNamer+"$)(nil), (*$.outType|"+rawNamer+"$)(nil), func(in, out interface{}", args)
+		for _, namedArgument := range g.Options.ManualConversionsTracker.additionalConversionArguments {
+			sw.Do(fmt.Sprintf(", %s", namedArgument.Name)+" $.|"+rawNamer+"$", namedArgument.Type)
+		}
+		sw.Do(") error {\n", nil)
+		sw.Do("return "+castConversionHelper+"(in, out)\n", nil)
+		sw.Do("})\n", nil)
+	}
+	sw.Do("}\n\n", nil)
+}
+
+// functionExpression returns how generated code refers to a function option, e.g.
+// Options.RegistryFunction: functions of the form "<pkg-path>.<expression>" are rendered with the
+// right package name, and their import tracked; others are local to the output package, and
+// written as is.
+func (g *Generator) functionExpression(function string) string {
+	if ref := registryFunctionRef(function); ref.Name.Package != "" {
+		return g.rawName(ref)
+	}
+	return function
+}
+
+// registryFunctionRef turns a registry function of the form "<pkg-path>.<expression>" into a
+// type reference, so that namers render it with the right package name, and track its import;
+// references to anything else have no package.
+func registryFunctionRef(registryFunction string) *types.Type {
+	lastSlash := strings.LastIndex(registryFunction, "/")
+	if dot := strings.Index(registryFunction[lastSlash+1:], "."); lastSlash != -1 && dot != -1 {
+		split := lastSlash + 1 + dot
+		return types.Ref(registryFunction[:split], registryFunction[split+1:])
+	}
+	return types.Ref("", registryFunction)
+}
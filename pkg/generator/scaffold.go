@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// A ManualConversionNeeded is a conversion for which no public conversion function could be
+// generated, and that needs to be written manually.
+type ManualConversionNeeded struct {
+	InType  *types.Type
+	OutType *types.Type
+	// Reasons are why the conversion couldn't be generated, typically one per field.
+	Reasons []string
+}
+
+// ManualConversionsNeeded returns the conversions that no public function could be generated for
+// so far.
+func (g *Generator) ManualConversionsNeeded() []ManualConversionNeeded {
+	return g.manualConversionsNeeded
+}
+
+// ScaffoldManualConversions renders a Go file, starting with the given header, that contains
+// correctly named and signed stub functions for each of the manual conversions needed.
+// The stubs call the generated private conversion functions, then return a TODO error.
+// Returns nil if there are no manual conversions needed.
+func (g *Generator) ScaffoldManualConversions(header []byte) ([]byte, error) {
+	if len(g.manualConversionsNeeded) == 0 {
+		return nil, nil
+	}
+
+	importTracker := generator.NewImportTracker()
+	context := &generator.Context{
+		Namers: namer.NameSystems{
+			rawNamer: namer.NewRawNamer(g.outputPackage.Path, importTracker),
+			publicImportTrackingNamer: &namerPlusImportTracking{
+				delegate: ConversionNamer(),
+				tracker:  importTracker,
+			},
+		},
+	}
+
+	body := &bytes.Buffer{}
+	sw := generator.NewSnippetWriter(body, context, snippetDelimiter, snippetDelimiter)
+	for _, needed := range g.manualConversionsNeeded {
+		args := argsFromType(needed.InType, needed.OutType).With("Errorf", types.Ref("fmt", "Errorf"))
+
+		sw.Do("// "+conversionFunctionNameTemplate(publicImportTrackingNamer)+" is a manual conversion function.\nfunc ", args)
+		g.writeConversionFunctionSignature(needed.InType, needed.OutType, sw, true)
+		sw.Do(" {\nif err := ", nil)
+		g.writePrivateFunctionSignature(needed.InType, needed.OutType, sw, false)
+		sw.Do("; err != nil {\nreturn err\n}\n", nil)
+		for _, reason := range needed.Reasons {
+			sw.Do("// TODO: "+strings.Replace(reason, "\n", " ", -1)+"\n", nil)
+		}
+		sw.Do("return $.Errorf|"+rawNamer+"$(\"TODO: manual conversion from $.inType|"+rawNamer+"$ to $.outType|"+rawNamer+"$ not implemented\")\n}\n\n", args)
+	}
+	if err := sw.Error(); err != nil {
+		return nil, errors.Wrapf(err, "unable to render manual conversion stubs for %q", g.outputPackage.Path)
+	}
+
+	file := bytes.NewBuffer(append([]byte{}, header...))
+	fmt.Fprintf(file, "package %s\n\n", g.outputPackage.Name)
+	if importLines := importTracker.ImportLines(); len(importLines) != 0 {
+		file.WriteString("import (\n")
+		for _, importLine := range importLines {
+			if g.isOtherPackage(importLine) {
+				file.WriteString(importLine + "\n")
+			}
+		}
+		file.WriteString(")\n\n")
+	}
+	file.Write(body.Bytes())
+
+	formatted, err := format.Source(file.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to format manual conversion stubs for %q", g.outputPackage.Path)
+	}
+	return formatted, nil
+}
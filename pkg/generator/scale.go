@@ -0,0 +1,111 @@
+package generator
+
+import (
+	gotypes "go/types"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// doScaledField writes the conversion of a numeric struct field whose unit differs from its peer's,
+// if either field has a "+<tag-name>=scale:<factor>" tag; returns true iff it did.
+// The tag means that the tagged field's value multiplied by factor is the peer field's value, e.g.
+// "scale:1000" on a field in seconds whose peer is in milliseconds: conversions from the tagged
+// field multiply, checking for overflows of integer types; conversions to it divide, truncating,
+// checking that the result fits narrower integer types.
+func (g *Generator) doScaledField(inType, outType *types.Type, inMember, outMember *types.Member, inMemberType, outMemberType *types.Type, args generator.Args, sw *generator.SnippetWriter) (bool, error) {
+	inPresent, inFactor := g.hasTagOption(inMember.CommentLines, "scale")
+	outPresent, outFactor := g.hasTagOption(outMember.CommentLines, "scale")
+	if !inPresent && !outPresent {
+		return false, nil
+	}
+
+	// inMemberType and outMemberType are named after named builtin types, not after their builtins
+	inBuiltin, outBuiltin := unwrapAlias(inMember.Type), unwrapAlias(outMember.Type)
+	if !isNumericBuiltin(inBuiltin) || !isNumericBuiltin(outBuiltin) || !g.builtinConversionAllowed(inBuiltin, outBuiltin) {
+		return true, errors.Errorf("%s.%s has a scale tag, but %v can't be converted to %v", inType.Name, inMember.Name, inMemberType, outMemberType)
+	}
+	// factor is a constant of the type of the field whose value gets scaled, its peer being tagged
+	factor, taggedType, taggedMember, scaledType, scaledMember, scaledBuiltin := inFactor, inType, inMember, outType, outMember, outBuiltin
+	if !inPresent {
+		factor, taggedType, taggedMember, scaledType, scaledMember, scaledBuiltin = outFactor, outType, outMember, inType, inMember, inBuiltin
+	}
+	parsed, err := strconv.ParseUint(factor, 10, 64)
+	if err != nil || parsed == 0 {
+		return true, errors.Errorf("%s.%s has an invalid scale tag %q, it must be a positive integer", taggedType.Name, taggedMember.Name, factor)
+	}
+	if !factorFits(basicType(scaledBuiltin), parsed) {
+		return true, errors.Errorf("%s.%s has an invalid scale tag %q, %s overflows the type of %s.%s", taggedType.Name, taggedMember.Name, factor, factor, scaledType.Name, scaledMember.Name)
+	}
+	args = args.With("factor", factor)
+
+	args = args.With("scaled", g.temp("scaled"))
+	if !inPresent {
+		if g.needsScaledRangeCheck(inBuiltin, outBuiltin) {
+			sw.Do("{\n", nil)
+			sw.Do("$.scaled$ := in.$.name$ / $.factor$\n", args)
+			g.writeScaledRangeCheck(inBuiltin, outBuiltin, "$.scaled$", "cannot scale %v down by $.factor$ without overflow", args, sw)
+			g.writeBuiltinConversion(inMemberType, outMemberType, "$.scaled$", "out.$.name$", args, sw)
+			sw.Do("}\n", nil)
+		} else {
+			g.writeBuiltinConversion(inMemberType, outMemberType, "(in.$.name$ / $.factor$)", "out.$.name$", args, sw)
+		}
+		g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "divided by %s", factor)
+		g.recordFieldConversion(inType, outType, inMember.Name, fieldConversion{kind: FieldTransformed, scale: factor, divided: true})
+		return true, nil
+	}
+
+	sw.Do("{\n", nil)
+	sw.Do("var $.scaled$ $.outType|"+rawNamer+"$\n", args)
+	if g.needsScaledRangeCheck(inBuiltin, outBuiltin) {
+		g.writeScaledRangeCheck(inBuiltin, outBuiltin, "in.$.name$", "cannot scale %v by $.factor$ without overflow", args, sw)
+	}
+	g.writeBuiltinConversion(inMemberType, outMemberType, "in.$.name$", "$.scaled$", args, sw)
+	if basicType(outBuiltin).Info()&gotypes.IsInteger != 0 {
+		sw.Do("if $.scaled$*$.factor$/$.factor$ != $.scaled$ {\n", args)
+		sw.Do("return $.Errorf|"+rawNamer+"$(\"cannot scale %v by $.factor$ without overflow\", in.$.name$)\n", args.With("Errorf", types.Ref("fmt", "Errorf")))
+		sw.Do("}\n", nil)
+	}
+	sw.Do("out.$.name$ = $.scaled$ * $.factor$\n", args)
+	sw.Do("}\n", nil)
+	g.explainFieldf(inType, outType, inMember.Name, FieldTransformed, "multiplied by %s", factor)
+	g.recordFieldConversion(inType, outType, inMember.Name, fieldConversion{kind: FieldTransformed, scale: factor})
+	return true, nil
+}
+
+// needsScaledRangeCheck returns true iff converting scaled fields from builtin type inBuiltin to
+// outBuiltin needs checking that values fit, see writeScaledRangeCheck: i.e. for narrowing integer
+// conversions, unless the builtin conversion policy already checks them.
+func (g *Generator) needsScaledRangeCheck(inBuiltin, outBuiltin *types.Type) bool {
+	integers := basicType(inBuiltin).Info()&basicType(outBuiltin).Info()&gotypes.IsInteger != 0
+	return integers && !isLosslessBuiltinConversion(inBuiltin, outBuiltin) && g.Options.BuiltinConversionPolicy != BuiltinConversionCheck
+}
+
+// writeScaledRangeCheck writes code returning an error with message, formatted with the in field,
+// unless value, a snippet of type $.inType$, fits in type $.outType$; whose builtin types are
+// inBuiltin and outBuiltin.
+func (g *Generator) writeScaledRangeCheck(inBuiltin, outBuiltin *types.Type, value, message string, args generator.Args, sw *generator.SnippetWriter) {
+	converted := "$.outType|" + rawNamer + "$(" + value + ")"
+	condition := "$.inType|" + rawNamer + "$(" + converted + ") != " + value
+	if isUnsignedBuiltin(inBuiltin) != isUnsignedBuiltin(outBuiltin) {
+		condition += " || (" + value + " < 0) != (" + converted + " < 0)"
+	}
+	sw.Do("if "+condition+" {\n", args)
+	sw.Do("return $.Errorf|"+rawNamer+"$(\""+message+"\", in.$.name$)\n", args.With("Errorf", types.Ref("fmt", "Errorf")))
+	sw.Do("}\n", nil)
+}
+
+// factorFits returns true iff factor can be a constant of numeric builtin type basic, on all
+// platforms.
+func factorFits(basic *gotypes.Basic, factor uint64) bool {
+	if basic.Info()&gotypes.IsInteger == 0 {
+		return true
+	}
+	bits := minSizes.Sizeof(basic) * 8
+	if basic.Info()&gotypes.IsUnsigned == 0 {
+		bits--
+	}
+	return bits >= 64 || factor < 1<<uint(bits)
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	gotypes "go/types"
+	"testing"
+)
+
+func TestFactorFits(t *testing.T) {
+	for _, testCase := range []struct {
+		kind     gotypes.BasicKind
+		factor   uint64
+		expected bool
+	}{
+		{gotypes.Int8, 127, true},
+		{gotypes.Int8, 128, false},
+		{gotypes.Uint8, 255, true},
+		{gotypes.Uint8, 1000, false},
+		{gotypes.Int32, 1000000000, true},
+		{gotypes.Int, 1 << 31, false},
+		{gotypes.Int64, 1 << 62, true},
+		{gotypes.Uint64, 1<<64 - 1, true},
+		{gotypes.Float32, 1<<64 - 1, true},
+	} {
+		if actual := factorFits(gotypes.Typ[testCase.kind], testCase.factor); actual != testCase.expected {
+			t.Errorf("expected factorFits(%v, %d) to be %v", gotypes.Typ[testCase.kind], testCase.factor, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// ShareOutputPackage declares that the given generators, for different types packages, generate
+// conversions into the same output package, and run in this order: helpers that generated code
+// needs once per package, such as generic and trace helpers, then only get written by the last
+// one, for all of them; and those writing the same file share their import tracker.
+func ShareOutputPackage(generators ...*Generator) error {
+	for _, g := range generators {
+		if g.outputPackage.Path != generators[0].outputPackage.Path {
+			return errors.Errorf("generators for %s and %s have different output packages", generators[0].typesPackage.Path, g.typesPackage.Path)
+		}
+		if g.sharingGenerators != nil {
+			return errors.Errorf("the generator for %s already shares its output package", g.typesPackage.Path)
+		}
+	}
+	if len(generators) < 2 {
+		return nil
+	}
+
+	importTrackers := make(map[string]namer.ImportTracker)
+	for _, g := range generators {
+		g.sharingGenerators = generators
+		// so that packages get the same names in all the code of a file, without getting imported
+		// in files that don't use them
+		if importTracker, present := importTrackers[g.Filename()]; present {
+			g.ImportTracker = importTracker
+		} else {
+			importTrackers[g.Filename()] = g.ImportTracker
+		}
+	}
+	return nil
+}
+
+// writesPackageHelpers returns true iff g writes the helpers needed once per output package, see
+// ShareOutputPackage.
+func (g *Generator) writesPackageHelpers() bool {
+	return g.sharingGenerators == nil || g.sharingGenerators[len(g.sharingGenerators)-1] == g
+}
+
+// packageGenerators returns the generators that generate into g's output package, g included.
+func (g *Generator) packageGenerators() []*Generator {
+	if g.sharingGenerators == nil {
+		return []*Generator{g}
+	}
+	return g.sharingGenerators
+}
+
+// generatedBySharingGenerator returns true iff conversions between t and peerType, its peer type,
+// get generated by another generator sharing the output package that runs before g: as peerType
+// belongs to its types package, and has t as its own peer type.
+func (g *Generator) generatedBySharingGenerator(context *generator.Context, t, peerType *types.Type) bool {
+	for _, other := range g.packageGenerators() {
+		if other == g {
+			return false
+		}
+		if other.typesPackage.Path == peerType.Name.Package && other.GetPeerTypeFor(context, peerType) == t &&
+			other.notConvertibleReason(peerType, t) == "" && other.notWithBuildConstraintReason(peerType, t) == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// A SkipConversionError is an error that handlers can fail with, possibly wrapped, to skip the
+// conversion being generated altogether: neither its private nor its public conversion functions
+// get generated, rather than shipping an incomplete private function.
+// The private function still gets generated if there is a manual public function, since that
+// function is likely to call it.
+type SkipConversionError struct {
+	// Reason is why the conversion should be skipped.
+	Reason string
+}
+
+// SkipConversion returns a SkipConversionError with the given reason.
+func SkipConversion(format string, args ...interface{}) error {
+	return &SkipConversionError{Reason: fmt.Sprintf(format, args...)}
+}
+
+func (e *SkipConversionError) Error() string {
+	return e.Reason
+}
+
+// skipRequested returns the first of errs asking to skip the conversion, if any.
+func skipRequested(errs []error) *SkipConversionError {
+	for _, err := range errs {
+		var skip *SkipConversionError
+		if errors.As(err, &skip) {
+			return skip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// SpliceBeginMarker and SpliceEndMarker are the comment lines delimiting where generated code
+	// gets spliced into existing, otherwise hand-written, files.
+	SpliceBeginMarker = "// +conversion-gen:begin"
+	SpliceEndMarker   = "// +conversion-gen:end"
+)
+
+// FindSpliceRegion returns the offsets in src of the region delimited by SpliceBeginMarker and
+// SpliceEndMarker, markers excluded; found is false if src has neither marker, and an error is
+// returned if it only has one of them, or more than one of either.
+func FindSpliceRegion(src []byte) (start, end int, found bool, err error) {
+	start, end = -1, -1
+	for offset := 0; offset < len(src); {
+		lineEnd := bytes.IndexByte(src[offset:], '\n')
+		next := offset + lineEnd + 1
+		if lineEnd == -1 {
+			next = len(src)
+		}
+
+		switch strings.TrimSpace(string(src[offset:next])) {
+		case SpliceBeginMarker:
+			if start != -1 {
+				return 0, 0, false, errors.Errorf("more than one %q marker", SpliceBeginMarker)
+			}
+			start = next
+		case SpliceEndMarker:
+			if end != -1 {
+				return 0, 0, false, errors.Errorf("more than one %q marker", SpliceEndMarker)
+			}
+			end = offset
+		}
+		offset = next
+	}
+
+	switch {
+	case start == -1 && end == -1:
+		return 0, 0, false, nil
+	case start == -1:
+		return 0, 0, false, errors.Errorf("%q marker without a %q marker", SpliceEndMarker, SpliceBeginMarker)
+	case end == -1:
+		return 0, 0, false, errors.Errorf("%q marker without a %q marker", SpliceBeginMarker, SpliceEndMarker)
+	case end < start:
+		return 0, 0, false, errors.Errorf("%q marker before the %q marker", SpliceEndMarker, SpliceBeginMarker)
+	}
+	return start, end, true, nil
+}
+
+// splicedFunctions returns the names of the functions declared between splice markers in the
+// package at sourcePath; those were generated, and must not be mistaken for manual ones.
+func splicedFunctions(sourcePath string) map[string]bool {
+	functions := make(map[string]bool)
+	if sourcePath == "" {
+		return functions
+	}
+
+	paths, err := filepath.Glob(filepath.Join(sourcePath, "*.go"))
+	if err != nil {
+		klog.Warningf("unable to list Go files in %q: %v", sourcePath, err)
+		return functions
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				klog.Warningf("unable to read %q to find spliced functions: %v", path, err)
+			}
+			continue
+		}
+		start, end, found, err := FindSpliceRegion(src)
+		if err != nil {
+			klog.Warningf("invalid splice markers in %q: %v", path, err)
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		fileSet := token.NewFileSet()
+		file, err := parser.ParseFile(fileSet, path, src, 0)
+		if err != nil {
+			klog.Warningf("unable to parse %q to find spliced functions: %v", path, err)
+			continue
+		}
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				if offset := fileSet.Position(funcDecl.Pos()).Offset; start <= offset && offset < end {
+					functions[funcDecl.Name.Name] = true
+				}
+			}
+		}
+	}
+	return functions
+}
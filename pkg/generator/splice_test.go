@@ -0,0 +1,74 @@
+package generator
+
+import "testing"
+
+func TestFindSpliceRegion(t *testing.T) {
+	for _, testCase := range []struct {
+		name          string
+		src           string
+		expectedFound bool
+		expectedError string
+		expected      string
+	}{
+		{
+			name: "no markers",
+			src:  "package v1\n",
+		},
+		{
+			name:          "both markers",
+			src:           "package v1\n\n// +conversion-gen:begin\nfunc f() {}\n  // +conversion-gen:end\n",
+			expectedFound: true,
+			expected:      "func f() {}\n",
+		},
+		{
+			name:          "empty region",
+			src:           "package v1\n// +conversion-gen:begin\n// +conversion-gen:end",
+			expectedFound: true,
+		},
+		{
+			name:          "begin marker only",
+			src:           "package v1\n// +conversion-gen:begin\n",
+			expectedError: `"// +conversion-gen:begin" marker without a "// +conversion-gen:end" marker`,
+		},
+		{
+			name:          "end marker only",
+			src:           "package v1\n// +conversion-gen:end\n",
+			expectedError: `"// +conversion-gen:end" marker without a "// +conversion-gen:begin" marker`,
+		},
+		{
+			name:          "markers in the wrong order",
+			src:           "package v1\n// +conversion-gen:end\n// +conversion-gen:begin\n",
+			expectedError: `"// +conversion-gen:end" marker before the "// +conversion-gen:begin" marker`,
+		},
+		{
+			name:          "duplicate begin marker",
+			src:           "// +conversion-gen:begin\n// +conversion-gen:begin\n// +conversion-gen:end\n",
+			expectedError: `more than one "// +conversion-gen:begin" marker`,
+		},
+		{
+			name:          "duplicate end marker",
+			src:           "// +conversion-gen:begin\n// +conversion-gen:end\n// +conversion-gen:end\n",
+			expectedError: `more than one "// +conversion-gen:end" marker`,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			start, end, found, err := FindSpliceRegion([]byte(testCase.src))
+
+			if testCase.expectedError != "" {
+				if err == nil || err.Error() != testCase.expectedError {
+					t.Fatalf("expected error %q, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != testCase.expectedFound {
+				t.Fatalf("expected found to be %v, got %v", testCase.expectedFound, found)
+			}
+			if found && testCase.src[start:end] != testCase.expected {
+				t.Errorf("expected region %q, got %q", testCase.expected, testCase.src[start:end])
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// structKeysConvertible returns true iff map keys of struct type inKey can be converted to outKey
+// without conversion functions, as needed for keys from other packages: with an unsafe cast if
+// they have the same memory layout, or else field by field, see keyFieldsConvertible.
+func (g *Generator) structKeysConvertible(inKey, outKey *types.Type) bool {
+	if unwrapAlias(inKey).Kind != types.Struct || unwrapAlias(outKey).Kind != types.Struct {
+		return false
+	}
+	return g.useUnsafeConversion(inKey, outKey) || g.keyFieldsConvertible(unwrapAlias(inKey), unwrapAlias(outKey))
+}
+
+// keyFieldsConvertible returns true iff structs inKey and outKey have fields with the same names,
+// that all convert cleanly: builtins that the policy allows converting, directly assignable
+// fields, or structs whose own fields do. Keys missing fields would collide.
+func (g *Generator) keyFieldsConvertible(inKey, outKey *types.Type) bool {
+	if len(inKey.Members) != len(outKey.Members) {
+		return false
+	}
+	for _, inMember := range inKey.Members {
+		outMember, found := findMember(outKey, inMember.Name)
+		if !found {
+			return false
+		}
+		switch {
+		case inMember.Type.Kind == types.Builtin && outMember.Type.Kind == types.Builtin:
+			if !g.builtinConversionAllowed(inMember.Type, outMember.Type) {
+				return false
+			}
+		case g.isDirectlyAssignable(inMember.Type, outMember.Type):
+		case unwrapAlias(inMember.Type).Kind == types.Struct && unwrapAlias(outMember.Type).Kind == types.Struct:
+			if !g.keyFieldsConvertible(unwrapAlias(inMember.Type), unwrapAlias(outMember.Type)) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// writeStructKeyConversion writes the conversion of the key temporary variable, of struct type
+// inKey, into a new newKey temporary variable of struct type outKey; see structKeysConvertible.
+func (g *Generator) writeStructKeyConversion(inKey, outKey *types.Type, sw *generator.SnippetWriter) {
+	key, newKey := g.temp("key"), g.temp("newKey")
+	sw.Do("var "+newKey+" $.|"+rawNamer+"$\n", outKey)
+	if g.useUnsafeConversion(inKey, outKey) {
+		g.writeCastConversion(inKey, outKey, "&"+key, "&"+newKey, nil, sw)
+		return
+	}
+	g.writeKeyFieldsConversion(unwrapAlias(inKey), unwrapAlias(outKey), key, newKey, sw)
+}
+
+// writeKeyFieldsConversion writes the conversion of in, of struct type inKey, into out, of struct
+// type outKey, field by field; see keyFieldsConvertible.
+func (g *Generator) writeKeyFieldsConversion(inKey, outKey *types.Type, in, out string, sw *generator.SnippetWriter) {
+	for _, inMember := range inKey.Members {
+		outMember, _ := findMember(outKey, inMember.Name)
+		inField, outField := in+"."+inMember.Name, out+"."+outMember.Name
+		switch {
+		case inMember.Type.Kind == types.Builtin && outMember.Type.Kind == types.Builtin:
+			g.writeBuiltinConversion(inMember.Type, outMember.Type, inField, outField, nil, sw)
+		case inMember.Type == outMember.Type:
+			sw.Do(outField+" = "+inField+"\n", nil)
+		case g.isDirectlyAssignable(inMember.Type, outMember.Type):
+			sw.Do(outField+" = $.|"+rawNamer+"$("+inField+")\n", outMember.Type)
+		default:
+			g.writeKeyFieldsConversion(unwrapAlias(inMember.Type), unwrapAlias(outMember.Type), inField, outField, sw)
+		}
+	}
+}
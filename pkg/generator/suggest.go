@@ -0,0 +1,476 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/types"
+)
+
+// A TagSuggestionKind is the kind of a TagSuggestion.
+type TagSuggestionKind string
+
+const (
+	// SuggestedFieldMapping suggestions are field mappings to add to Options.FieldMappings.
+	SuggestedFieldMapping TagSuggestionKind = "field-mapping"
+	// SuggestedTag suggestions are comment tags to add, to fields or to doc.go files.
+	SuggestedTag TagSuggestionKind = "tag"
+	// SuggestedRemoval suggestions are manual conversion functions that can be removed once the
+	// other suggestions for them are applied, as the generated functions would be equivalent.
+	SuggestedRemoval TagSuggestionKind = "removal"
+)
+
+// A TagSuggestion is a change that would let the generator take over (part of) a manual
+// conversion function, see TagSuggestions.
+type TagSuggestion struct {
+	// Function is the manual conversion function that the suggestion is about.
+	Function *types.Type
+	// Position is where the code that the suggestion is about is, of the form "<file>:<line>".
+	Position string
+	Kind     TagSuggestionKind
+	// Target is what Tag is for, for SuggestedTag suggestions: either a field, of the form
+	// "<pkg-path>.<type>.<field>", or a package, whose doc.go file Tag is for.
+	Target string
+	// Tag is the comment line to add to Target, for SuggestedTag suggestions, e.g.
+	// "+conversion-gen=scale:1000".
+	Tag string
+	// Mapping is the mapping to add to Options.FieldMappings, with a single field, for
+	// SuggestedFieldMapping suggestions.
+	Mapping *TypeFieldMappings
+	Message string
+}
+
+// TagSuggestions analyzes the manual conversion functions of the output package used by the
+// conversions generated so far, and returns the tags and field mappings that would let the
+// generator generate them instead; e.g. to migrate legacy hand-written conversions.
+// Only simple statements are recognized: calls to the private conversion function, assignments to
+// out fields of in fields, of constants or of expressions of in fields, conversions of numeric
+// fields with different units, and type conversions between equivalent types.
+func (g *Generator) TagSuggestions() ([]TagSuggestion, error) {
+	var manual []PlannedConversion
+	for _, planned := range g.plannedConversions {
+		if planned.Strategy != ManualConversion {
+			continue
+		}
+		if function, found := g.preexists(planned.InType, planned.OutType); found && function.Name.Package == g.outputPackage.Path {
+			manual = append(manual, planned)
+		}
+	}
+	if len(manual) == 0 {
+		return nil, nil
+	}
+
+	fileSet := token.NewFileSet()
+	declarations, err := parseFunctionDeclarations(fileSet, g.outputPackage.SourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []TagSuggestion
+	// both conversions between two types can lead to the same tag suggestions, e.g. for scale tags
+	suggestedTags := make(map[[2]string]bool)
+	for _, planned := range manual {
+		function, _ := g.preexists(planned.InType, planned.OutType)
+		declaration, found := declarations[function.Name.Name]
+		if !found {
+			continue
+		}
+		analyzer := &suggestionAnalyzer{
+			g:        g,
+			function: function,
+			inType:   planned.InType,
+			outType:  planned.OutType,
+			file:     declaration.file,
+			fileSet:  fileSet,
+		}
+		for _, suggestion := range analyzer.analyze(declaration.funcDecl) {
+			if suggestion.Kind == SuggestedTag {
+				key := [2]string{suggestion.Target, suggestion.Tag}
+				if suggestedTags[key] {
+					continue
+				}
+				suggestedTags[key] = true
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// a functionDeclaration is a top-level function declaration, along with the file it's in.
+type functionDeclaration struct {
+	funcDecl *ast.FuncDecl
+	file     *ast.File
+}
+
+// parseFunctionDeclarations returns the top-level functions declared in the package at
+// sourcePath, indexed by name.
+func parseFunctionDeclarations(fileSet *token.FileSet, sourcePath string) (map[string]functionDeclaration, error) {
+	packages, err := parser.ParseDir(fileSet, sourcePath, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %q", sourcePath)
+	}
+
+	declarations := make(map[string]functionDeclaration)
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Body != nil {
+					declarations[funcDecl.Name.Name] = functionDeclaration{funcDecl: funcDecl, file: file}
+				}
+			}
+		}
+	}
+	return declarations, nil
+}
+
+// a suggestionAnalyzer finds the suggestions for a manual conversion function.
+type suggestionAnalyzer struct {
+	g                 *Generator
+	function          *types.Type
+	inType, outType   *types.Type
+	file              *ast.File
+	fileSet           *token.FileSet
+	inName, outName   string
+	suggestions       []TagSuggestion
+	referencedFields  map[string]bool
+	callsPrivate      bool
+	equivalentTypes   bool
+	unrecognizedFound bool
+}
+
+func (a *suggestionAnalyzer) analyze(funcDecl *ast.FuncDecl) []TagSuggestion {
+	params := funcDecl.Type.Params.List
+	if len(params) == 0 || len(params[0].Names) == 0 {
+		return nil
+	}
+	names := params[0].Names
+	if len(names) == 1 {
+		if len(params) < 2 || len(params[1].Names) == 0 {
+			return nil
+		}
+		names = append(names, params[1].Names[0])
+	}
+	a.inName, a.outName = names[0].Name, names[1].Name
+
+	a.referencedFields = make(map[string]bool)
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok && isIdent(selector.X, a.inName) {
+			a.referencedFields[selector.Sel.Name] = true
+		}
+		return true
+	})
+
+	for _, stmt := range funcDecl.Body.List {
+		switch {
+		case a.isPrivateFunctionCall(stmt):
+			a.callsPrivate = true
+		case isReturnNil(stmt):
+		case a.analyzeEquivalentTypes(stmt):
+		case a.analyzeFieldAssignment(stmt):
+		default:
+			a.unrecognizedFound = true
+		}
+	}
+
+	if a.callsPrivate {
+		a.suggestDroppedFields(funcDecl)
+	}
+	switch {
+	case !a.callsPrivate && !a.equivalentTypes || a.unrecognizedFound:
+	case len(a.suggestions) == 0:
+		a.add(funcDecl, SuggestedRemoval, "", "", nil, "%s only calls %s, the generated function would be equivalent",
+			a.function.Name.Name, a.g.privateFunctionName(a.inType, a.outType))
+	default:
+		a.add(funcDecl, SuggestedRemoval, "", "", nil, "%s can be removed once the other suggestions for it are applied, the generated function would be equivalent",
+			a.function.Name.Name)
+	}
+	return a.suggestions
+}
+
+func (a *suggestionAnalyzer) add(node ast.Node, kind TagSuggestionKind, target, tag string, mapping *FieldMapping, format string, args ...interface{}) {
+	position := a.fileSet.Position(node.Pos())
+	suggestion := TagSuggestion{
+		Function: a.function,
+		Position: fmt.Sprintf("%s:%d", position.Filename, position.Line),
+		Kind:     kind,
+		Target:   target,
+		Tag:      tag,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if mapping != nil {
+		suggestion.Mapping = &TypeFieldMappings{
+			From:   a.inType.Name.String(),
+			To:     a.outType.Name.String(),
+			Fields: []FieldMapping{*mapping},
+		}
+	}
+	a.suggestions = append(a.suggestions, suggestion)
+}
+
+// isPrivateFunctionCall returns true iff stmt returns the result of the private conversion
+// function that the manual function wraps, or returns its error if any.
+func (a *suggestionAnalyzer) isPrivateFunctionCall(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt:
+		return len(stmt.Results) == 1 && a.isPrivateCallExpr(stmt.Results[0])
+	case *ast.IfStmt:
+		init, ok := stmt.Init.(*ast.AssignStmt)
+		return ok && len(init.Rhs) == 1 && a.isPrivateCallExpr(init.Rhs[0]) && stmt.Else == nil && len(stmt.Body.List) == 1
+	}
+	return false
+}
+
+func (a *suggestionAnalyzer) isPrivateCallExpr(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	return ok && isIdent(call.Fun, a.g.privateFunctionName(a.inType, a.outType)) &&
+		len(call.Args) >= 2 && isIdent(call.Args[0], a.inName) && isIdent(call.Args[1], a.outName)
+}
+
+// analyzeEquivalentTypes suggests declaring the in and out types equivalent, if stmt assigns one
+// to the other with a type conversion; returns true iff it did.
+func (a *suggestionAnalyzer) analyzeEquivalentTypes(stmt ast.Stmt) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.ASSIGN || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return false
+	}
+	if star, ok := assign.Lhs[0].(*ast.StarExpr); !ok || !isIdent(star.X, a.outName) {
+		return false
+	}
+
+	rhs := assign.Rhs[0]
+	// either *(*T)(unsafe.Pointer(in)), or T(*in)
+	if star, ok := rhs.(*ast.StarExpr); ok {
+		rhs = star.X
+	}
+	call, ok := rhs.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+	arg := call.Args[0]
+	if star, ok := arg.(*ast.StarExpr); ok {
+		arg = star.X
+	} else if pointer, ok := arg.(*ast.CallExpr); ok && len(pointer.Args) == 1 && a.render(pointer.Fun) == "unsafe.Pointer" {
+		arg = pointer.Args[0]
+	}
+	if !isIdent(arg, a.inName) {
+		return false
+	}
+
+	a.equivalentTypes = true
+	a.add(stmt, SuggestedTag, a.g.typesPackage.Path,
+		fmt.Sprintf("+%s=%s=%s", a.g.Options.EquivalentTypesTagName, a.inType.Name, a.outType.Name), nil,
+		"%v and %v are converted with a type conversion: declare them equivalent", a.inType, a.outType)
+	return true
+}
+
+// analyzeFieldAssignment suggests how to generate the conversion of an out field, if stmt
+// assigns it a value that the generator can be told to assign; returns true iff it did, or if
+// the generator assigns the same value already.
+func (a *suggestionAnalyzer) analyzeFieldAssignment(stmt ast.Stmt) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.ASSIGN || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return false
+	}
+	lhs, ok := assign.Lhs[0].(*ast.SelectorExpr)
+	if !ok || !isIdent(lhs.X, a.outName) {
+		return false
+	}
+	outField := lhs.Sel.Name
+	outMember, found := findMember(a.outType, outField)
+	if !found {
+		return false
+	}
+	rhs := assign.Rhs[0]
+
+	inFields, ok := a.inFieldsIn(rhs)
+	if !ok {
+		return false
+	}
+	source := a.render(rhs)
+
+	// mappings' targets don't get paired up with in fields by name: in fields with the same name
+	// must then be the mappings' sources, so as not to be deemed inconvertible
+	_, sameName := findMember(a.inType, outField)
+
+	if len(inFields) == 0 {
+		mapping := &FieldMapping{To: outField, Value: source}
+		if sameName {
+			mapping = &FieldMapping{From: outField, To: outField, Expr: source}
+		}
+		a.add(stmt, SuggestedFieldMapping, "", "", mapping, "%v.%s is assigned a value", a.outType, outField)
+		return true
+	}
+	if inField := a.isInField(rhs); inField != "" {
+		if inField != outField {
+			a.add(stmt, SuggestedFieldMapping, "", "", &FieldMapping{From: inField, To: outField},
+				"%v.%s is renamed %v.%s", a.inType, inField, a.outType, outField)
+		}
+		return true
+	}
+
+	if inField, factor, multiplied := a.scaledField(rhs); inField == outField && factor != "" {
+		inMember, _ := findMember(a.inType, inField)
+		target, member := a.outType, outMember
+		if multiplied {
+			target, member = a.inType, inMember
+		}
+		if isNumericBuiltin(unwrapAlias(inMember.Type)) && isNumericBuiltin(unwrapAlias(outMember.Type)) {
+			a.add(stmt, SuggestedTag, target.Name.String()+"."+member.Name, fmt.Sprintf("+%s=scale:%s", a.g.Options.TagName, factor), nil,
+				"%v.%s is %v.%s with a different unit", a.outType, outField, a.inType, inField)
+			return true
+		}
+	}
+
+	if len(inFields) == 1 && inFields[0] == outField && sameName {
+		expression := strings.Replace(source, a.inName+"."+outField, "$in$", -1)
+		a.add(stmt, SuggestedTag, a.outType.Name.String()+"."+outField, fmt.Sprintf("+%s=expr:%s", a.g.Options.TagName, expression), nil,
+			"%v.%s is computed from %v.%s", a.outType, outField, a.inType, outField)
+		return true
+	}
+	mapping := &FieldMapping{To: outField, Expr: source}
+	if sameName {
+		mapping.From = outField
+	}
+	if a.inName != "in" {
+		// mapping expressions refer to the in struct as "in"
+		mapping.Expr = a.renamedIn(rhs)
+	}
+	a.add(stmt, SuggestedFieldMapping, "", "", mapping,
+		"%v.%s is computed from %v.%s", a.outType, outField, a.inType, strings.Join(inFields, ", "))
+	return true
+}
+
+// suggestDroppedFields suggests dropping the in type's fields that have no peer field, and that
+// the manual function doesn't convert.
+func (a *suggestionAnalyzer) suggestDroppedFields(funcDecl *ast.FuncDecl) {
+	mappings := a.g.fieldMappings(a.inType, a.outType)
+	for _, member := range a.inType.Members {
+		if _, found := findMember(a.outType, member.Name); found || a.referencedFields[member.Name] || a.g.optedOut(member) {
+			continue
+		}
+		if _, mapped := mappings.fromField(member.Name); mapped {
+			continue
+		}
+		a.add(funcDecl, SuggestedFieldMapping, "", "", &FieldMapping{From: member.Name, Drop: true},
+			"%v.%s has no peer field, and isn't converted", a.inType, member.Name)
+	}
+}
+
+// isInField returns the name of the in field that expr is, if any.
+func (a *suggestionAnalyzer) isInField(expr ast.Expr) string {
+	if selector, ok := expr.(*ast.SelectorExpr); ok && isIdent(selector.X, a.inName) {
+		if _, found := findMember(a.inType, selector.Sel.Name); found {
+			return selector.Sel.Name
+		}
+	}
+	return ""
+}
+
+// scaledField returns the in field that expr multiplies or divides by an integer factor, if any,
+// along with the factor; possibly with type conversions to numeric types.
+func (a *suggestionAnalyzer) scaledField(expr ast.Expr) (inField, factor string, multiplied bool) {
+	binary, ok := unwrapNumericConversion(expr).(*ast.BinaryExpr)
+	if !ok || (binary.Op != token.MUL && binary.Op != token.QUO) {
+		return "", "", false
+	}
+	field, literal := unwrapNumericConversion(binary.X), unwrapNumericConversion(binary.Y)
+	if binary.Op == token.MUL && a.isInField(field) == "" {
+		field, literal = literal, field
+	}
+	if lit, ok := literal.(*ast.BasicLit); ok && lit.Kind == token.INT && a.isInField(field) != "" {
+		return a.isInField(field), lit.Value, binary.Op == token.MUL
+	}
+	return "", "", false
+}
+
+// inFieldsIn returns the in fields that expr refers to; and false if it refers to anything else
+// than the in struct, imported packages and predeclared identifiers, e.g. local variables.
+func (a *suggestionAnalyzer) inFieldsIn(expr ast.Expr) ([]string, bool) {
+	imports := fileImports(a.file)
+	var fields []string
+	seen := make(map[string]bool)
+	valid := true
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			if ident, ok := node.X.(*ast.Ident); ok {
+				switch {
+				case ident.Name == a.inName:
+					if !seen[node.Sel.Name] {
+						seen[node.Sel.Name] = true
+						fields = append(fields, node.Sel.Name)
+					}
+				case imports[ident.Name] == "":
+					valid = false
+				}
+				return false
+			}
+		case *ast.Ident:
+			if node.Name != a.inName && gotypes.Universe.Lookup(node.Name) == nil {
+				valid = false
+			}
+		case *ast.FuncLit, *ast.CompositeLit:
+			valid = false
+		}
+		return valid
+	})
+	return fields, valid
+}
+
+// renamedIn renders expr, with the in struct named "in".
+func (a *suggestionAnalyzer) renamedIn(expr ast.Expr) string {
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && ident.Name == a.inName {
+			ident.Name = "in"
+		}
+		return true
+	})
+	return a.render(expr)
+}
+
+func (a *suggestionAnalyzer) render(node ast.Node) string {
+	buffer := &bytes.Buffer{}
+	if err := printer.Fprint(buffer, a.fileSet, node); err != nil {
+		return ""
+	}
+	return buffer.String()
+}
+
+// unwrapNumericConversion returns the operand of expr if it's a conversion to a numeric type, e.g.
+// int64(in.Foo); or expr itself otherwise.
+func unwrapNumericConversion(expr ast.Expr) ast.Expr {
+	if paren, ok := expr.(*ast.ParenExpr); ok {
+		expr = paren.X
+	}
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		if ident, ok := call.Fun.(*ast.Ident); ok {
+			if typeName, ok := gotypes.Universe.Lookup(ident.Name).(*gotypes.TypeName); ok {
+				if basic, ok := typeName.Type().(*gotypes.Basic); ok && basic.Info()&gotypes.IsNumeric != 0 {
+					return call.Args[0]
+				}
+			}
+		}
+	}
+	return expr
+}
+
+func isReturnNil(stmt ast.Stmt) bool {
+	ret, ok := stmt.(*ast.ReturnStmt)
+	return ok && len(ret.Results) == 1 && isIdent(ret.Results[0], "nil")
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"strconv"
+)
+
+// tempNames names the temporary variables of generated code, e.g. loop variables, so that they
+// don't collide with the additional conversion arguments, nor with Options.ReservedNames: a
+// temporary variable named after one of these would shadow it in its scope, e.g. get passed to
+// conversion functions instead of the additional argument, if it compiles at all.
+// Names are deterministic, and the same for all the functions that a generator writes: base
+// names, followed by the smallest number that makes them free if taken, e.g. "i1" with an
+// additional argument named "i".
+type tempNames struct {
+	reserved map[string]bool
+	names    map[string]string
+}
+
+func newTempNames(reserved ...string) *tempNames {
+	t := &tempNames{
+		reserved: make(map[string]bool),
+		names:    make(map[string]string),
+	}
+	for _, name := range reserved {
+		t.reserved[name] = true
+	}
+	return t
+}
+
+// name returns the name of the temporary variable with the given base name.
+func (t *tempNames) name(base string) string {
+	if name, found := t.names[base]; found {
+		return name
+	}
+	name := base
+	for i := 1; t.reserved[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	// names taken by temporary variables aren't free for others
+	t.reserved[name] = true
+	t.names[base] = name
+	return name
+}
+
+// temp returns the name of the temporary variable with the given base name, see tempNames.
+func (g *Generator) temp(base string) string {
+	return g.tempNames.name(base)
+}
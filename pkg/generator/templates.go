@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// Templates are the text/templates used to write some of the generated code; they can be
+// overridden to adjust its style, e.g. to wrap errors, log, or add comments.
+// Nil templates fall back to the default ones, see DefaultTemplates.
+// Note that templates can't add imports to generated files: packages that they use, e.g. fmt,
+// need to be imported with ExtraImportsTagName tags. Errors executing them get returned by
+// Generator.GenerateType and Generator.Finalize.
+type Templates struct {
+	// ConversionCall calls a conversion function and handles its error, for struct fields as well
+	// as for the elements of maps, slices and pointers; it's executed with a ConversionCallData.
+	ConversionCall *template.Template
+	// Builtin converts a value between two different builtin types; it's executed with a
+	// BuiltinData.
+	Builtin *template.Template
+	// Map, Slice and Pointer allocate the output of map, slice and pointer conversions; they're
+	// executed with an AllocationData.
+	Map     *template.Template
+	Slice   *template.Template
+	Pointer *template.Template
+}
+
+// ConversionCallData is the data that Templates.ConversionCall is executed with.
+type ConversionCallData struct {
+	// Function is the name of the conversion function to call.
+	Function string
+	// In and Out are the arguments to call it with, pointers to InType and OutType respectively;
+	// except for In if the function takes its input by value, see Options.ValueInputMaxSize.
+	In, Out         string
+	InType, OutType string
+	// ExtraArgs are the additional conversion arguments to pass on, each preceded by a comma;
+	// see NewManualConversionsTracker.
+	ExtraArgs string
+	// Field is the name of the struct field being converted, if any.
+	Field string
+	// ReturnErr is the statement returning err from the calling function, e.g. "return err";
+	// see Options.ErrorWrappingPolicy and Options.TraceBuildTag.
+	ReturnErr string
+}
+
+// BuiltinData is the data that Templates.Builtin is executed with.
+type BuiltinData struct {
+	// In is the value to convert, of type InType, to assign to Out, of type OutType.
+	In, Out         string
+	InType, OutType string
+}
+
+// AllocationData is the data that Templates.Map, Templates.Slice and Templates.Pointer are
+// executed with.
+type AllocationData struct {
+	// Out is the variable to allocate, of type OutType; ElemType is the type of its elements.
+	Out, OutType, ElemType string
+	// In is the value being converted.
+	In string
+}
+
+// DefaultTemplates returns the templates used by default.
+func DefaultTemplates() *Templates {
+	return &Templates{
+		ConversionCall: template.Must(template.New("ConversionCall").Parse(
+			"if err := {{.Function}}({{.In}}, {{.Out}}{{.ExtraArgs}}); err != nil {\n{{.ReturnErr}}\n}\n")),
+		Builtin: template.Must(template.New("Builtin").Parse("{{.Out}} = {{.OutType}}({{.In}})\n")),
+		Map:     template.Must(template.New("Map").Parse("{{.Out}} = make({{.OutType}}, len({{.In}}))\n")),
+		Slice:   template.Must(template.New("Slice").Parse("{{.Out}} = make({{.OutType}}, len({{.In}}))\n")),
+		Pointer: template.Must(template.New("Pointer").Parse("{{.Out}} = new({{.ElemType}})\n")),
+	}
+}
+
+var defaultTemplates = DefaultTemplates()
+
+// TemplatesFrom returns the templates defined in set by name, e.g. with
+// {{define "ConversionCall"}}...{{end}}; the ones it doesn't define are left nil.
+func TemplatesFrom(set *template.Template) *Templates {
+	return &Templates{
+		ConversionCall: set.Lookup("ConversionCall"),
+		Builtin:        set.Lookup("Builtin"),
+		Map:            set.Lookup("Map"),
+		Slice:          set.Lookup("Slice"),
+		Pointer:        set.Lookup("Pointer"),
+	}
+}
+
+// template returns the template that get selects from the options' templates, or the default one.
+func (g *Generator) template(get func(*Templates) *template.Template) *template.Template {
+	if g.Options.Templates != nil {
+		if t := get(g.Options.Templates); t != nil {
+			return t
+		}
+	}
+	return get(defaultTemplates)
+}
+
+// executeTemplate writes the result of executing t with data; failures get returned by
+// GenerateType or Finalize, see templateError.
+func (g *Generator) executeTemplate(t *template.Template, data interface{}, sw *generator.SnippetWriter) {
+	buffer := &bytes.Buffer{}
+	if err := t.Execute(buffer, data); err != nil {
+		g.failTemplate(errors.Wrapf(err, "unable to execute template %q", t.Name()))
+		return
+	}
+	sw.Do("$.$", buffer.String())
+}
+
+// renderSnippet renders the given snippet with args, the same way a snippet writer would.
+func (g *Generator) renderSnippet(snippet string, args interface{}) string {
+	funcs := template.FuncMap{
+		rawNamer: g.rawName,
+		publicImportTrackingNamer: func(t *types.Type) string {
+			g.ImportTracker.AddType(t)
+			return ConversionNamer().Name(t)
+		},
+	}
+	t, err := template.New("").Delims(snippetDelimiter, snippetDelimiter).Funcs(funcs).Parse(snippet)
+	if err != nil {
+		g.failTemplate(errors.Wrapf(err, "unable to parse snippet %q", snippet))
+		return ""
+	}
+	buffer := &bytes.Buffer{}
+	if err := t.Execute(buffer, args); err != nil {
+		g.failTemplate(errors.Wrapf(err, "unable to render snippet %q", snippet))
+		return ""
+	}
+	return buffer.String()
+}
+
+// failTemplate records err as the error executing templates, unless there already is one.
+func (g *Generator) failTemplate(err error) {
+	if g.templateErr == nil {
+		g.templateErr = err
+	}
+}
+
+// templateError returns the error writing to sw, if any; or else the first error executing
+// templates since the last call, if any.
+func (g *Generator) templateError(sw *generator.SnippetWriter) error {
+	if err := sw.Error(); err != nil {
+		return err
+	}
+	err := g.templateErr
+	g.templateErr = nil
+	return err
+}
+
+// rawName returns the name of t in the output package, tracking its import.
+func (g *Generator) rawName(t *types.Type) string {
+	return namer.NewRawNamer(g.outputPackage.Path, g.ImportTracker).Name(t)
+}
+
+// writeConversionCall writes a call converting in to out, pointers to inType and outType: to
+// function if it's a manual conversion function, or else to the generated one. in and out are
+// snippets rendered with args; field is the name of the struct field being converted, if any.
+// Conversions that get no functions are written as unsafe casts, see Options.PruneCastableConversions.
+func (g *Generator) writeConversionCall(function, inType, outType *types.Type, in, out, field string, args generator.Args, sw *generator.SnippetWriter) {
+	if function == nil && g.castConversion(inType, outType) {
+		g.writeCastConversion(inType, outType, in, out, args, sw)
+		return
+	}
+
+	var name string
+	in = g.renderSnippet(in, args)
+	if function != nil {
+		name = g.rawName(function)
+		g.recordManualCall(function)
+		if takesValueInput(function) {
+			in = dereference(in)
+		}
+	} else {
+		name = g.renderSnippet(conversionFunctionNameTemplate(publicImportTrackingNamer), argsFromType(inType, outType))
+		g.recordInternalCall(inType, outType)
+		if g.smallStruct(inType) {
+			in = dereference(in)
+		}
+	}
+
+	g.executeTemplate(g.template(func(t *Templates) *template.Template { return t.ConversionCall }), ConversionCallData{
+		Function:  name,
+		In:        in,
+		Out:       g.renderSnippet(out, args),
+		InType:    g.rawName(inType),
+		OutType:   g.rawName(outType),
+		ExtraArgs: g.extraArgumentsString(),
+		Field:     field,
+		ReturnErr: g.returnErr(),
+	}, sw)
+}
+
+// writeAllocation writes the allocation of *out, for the conversion of *in from inType to outType,
+// be they maps, slices or pointers.
+func (g *Generator) writeAllocation(inType, outType *types.Type, sw *generator.SnippetWriter) {
+	var t *template.Template
+	switch outType.Kind {
+	case types.Map:
+		t = g.template(func(t *Templates) *template.Template { return t.Map })
+	case types.Slice:
+		t = g.template(func(t *Templates) *template.Template { return t.Slice })
+	default:
+		t = g.template(func(t *Templates) *template.Template { return t.Pointer })
+	}
+	g.executeTemplate(t, AllocationData{
+		Out:      "*out",
+		OutType:  g.rawName(outType),
+		ElemType: g.rawName(outType.Elem),
+		In:       "*in",
+	}, sw)
+}
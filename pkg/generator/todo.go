@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// A TodoItem is a conversion that was left for manual conversion.
+type TodoItem struct {
+	Category DiagnosticCategory
+	InType   *types.Type
+	OutType  *types.Type
+	// Field is the name of inType's field that couldn't be converted, if relevant.
+	Field string
+	// Reason is why the conversion couldn't be generated.
+	Reason string
+	// Signature is the signature of the manual conversion function from InType to OutType
+	// that would take care of it, as it should be written in the output package.
+	Signature string
+}
+
+// TodoItems returns all the conversions that this generator has left for manual conversion
+// so far: both the fields that it couldn't convert, and the conversions that it couldn't generate
+// a public function for.
+func (g *Generator) TodoItems() []TodoItem {
+	items := append([]TodoItem{}, g.todos...)
+	for _, needed := range g.manualConversionsNeeded {
+		for _, reason := range needed.Reasons {
+			items = append(items, TodoItem{
+				Category:  NoPublicFunctionDiagnostic,
+				InType:    needed.InType,
+				OutType:   needed.OutType,
+				Reason:    reason,
+				Signature: g.conversionFunctionSignature(needed.InType, needed.OutType),
+			})
+		}
+	}
+	return items
+}
+
+// reportUnconverted records that (a field of) inType couldn't be converted to outType, and
+// reports it as a diagnostic.
+func (g *Generator) reportUnconverted(category DiagnosticCategory, inType, outType *types.Type, member string, message string) {
+	g.reportDiagnostic(category, SeverityWarning, inType, member, message)
+	g.todos = append(g.todos, TodoItem{
+		Category:  category,
+		InType:    inType,
+		OutType:   outType,
+		Field:     member,
+		Reason:    message,
+		Signature: g.conversionFunctionSignature(inType, outType),
+	})
+}
+
+// conversionFunctionSignature returns the full signature of the conversion function from inType
+// to outType, with types named relative to the output package.
+func (g *Generator) conversionFunctionSignature(inType, outType *types.Type) string {
+	rawNamer := namer.NewRawNamer(g.outputPackage.Path, nil)
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "func %s(in *%s, out *%s", ConversionFunctionName(inType, outType),
+		rawNamer.Name(inType), rawNamer.Name(outType))
+	for _, namedArgument := range g.Options.ManualConversionsTracker.additionalConversionArguments {
+		fmt.Fprintf(buffer, ", %s %s", namedArgument.Name, rawNamer.Name(namedArgument.Type))
+	}
+	buffer.WriteString(") error")
+	return buffer.String()
+}
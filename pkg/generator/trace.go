@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+const (
+	klogPackage = "k8s.io/klog/v2"
+
+	traceEnabledVar    = "conversionTracing"
+	traceEntryHelper   = "traceConversion"
+	traceFailureHelper = "traceConversionFailure"
+)
+
+// traceHelpers are the definitions of the trace helpers written to generated files when
+// Options.TraceBuildTag is set; they get rendered with the names of the klog functions to call,
+// and the verbosity to log entries at.
+const traceHelpers = `// ` + traceEnabledVar + ` is only set in binaries built with the $.tag$ build tag.
+var ` + traceEnabledVar + ` bool
+
+func ` + traceEntryHelper + `(function string) {
+	if ` + traceEnabledVar + ` {
+		$.V|` + rawNamer + `$($.verbosity$).InfoS("Converting", "function", function)
+	}
+}
+
+func ` + traceFailureHelper + `(function, field string, err error) error {
+	if ` + traceEnabledVar + ` {
+		$.ErrorS|` + rawNamer + `$(err, "Conversion failed", "function", function, "field", field)
+	}
+	return err
+}
+
+`
+
+// tracing returns true iff generated functions should be traced, see Options.TraceBuildTag.
+func (g *Generator) tracing() bool {
+	return g.Options.TraceBuildTag != ""
+}
+
+// writeTraceEntry writes the trace of the current function getting called, if enabled.
+func (g *Generator) writeTraceEntry(sw *generator.SnippetWriter) {
+	if !g.tracing() {
+		return
+	}
+	g.traced = true
+	sw.Do(traceEntryHelper+"($.$)\n", strconv.Quote(g.currentFunction.Name.Name))
+}
+
+// writeTraceHelpers writes the trace helpers that the generated code of the output package calls,
+// if any.
+func (g *Generator) writeTraceHelpers(sw *generator.SnippetWriter) {
+	traced := false
+	for _, other := range g.packageGenerators() {
+		traced = traced || other.traced
+	}
+	if !traced {
+		return
+	}
+	sw.Do(traceHelpers, generator.Args{
+		"tag":       strconv.Quote(g.Options.TraceBuildTag),
+		"verbosity": g.Options.TraceVerbosity,
+		"V":         types.Ref(klogPackage, "V"),
+		"ErrorS":    types.Ref(klogPackage, "ErrorS"),
+	})
+}
+
+// TraceFile returns the content of the file that enables tracing in generated functions when
+// built with Options.TraceBuildTag, to put next to the generated file; header must include that
+// build tag as a constraint. Returns nil if tracing is disabled, or no function of the output
+// package got traced.
+func (g *Generator) TraceFile(header []byte) []byte {
+	traced := false
+	for _, other := range g.packageGenerators() {
+		traced = traced || other.traced
+	}
+	if !traced {
+		return nil
+	}
+
+	file := bytes.NewBuffer(append([]byte{}, header...))
+	fmt.Fprintf(file, "package %s\n\nfunc init() {\n\t%s = true\n}\n", g.outputPackage.Name, traceEnabledVar)
+	return file.Bytes()
+}
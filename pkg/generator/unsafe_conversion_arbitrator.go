@@ -17,6 +17,10 @@ limitations under the License.
 package generator
 
 import (
+	"fmt"
+	gotypes "go/types"
+	"strings"
+
 	"k8s.io/gengo/types"
 )
 
@@ -25,6 +29,13 @@ type unsafeConversionArbitrator struct {
 	processedPairs           map[ConversionPair]unsafeConversionDecision
 	manualConversionsTracker *ManualConversionsTracker
 	functionTagName          string
+	// platformSizes are the sizes of the target platforms, if any; when set, builtin types with
+	// different names are deemed to have the same memory layout iff they're the same kind of number,
+	// and have the same size and alignment on all target platforms.
+	platformSizes []gotypes.Sizes
+	// transformsValue, if set, returns true for struct members whose values don't just get copied
+	// by conversions, e.g. because of tags; structs with such members can't be cast.
+	transformsValue func(member *types.Member) bool
 }
 
 type unsafeConversionDecision int
@@ -42,11 +53,37 @@ const (
 	possible
 )
 
-func newUnsafeConversionArbitrator(manualConversionsTracker *ManualConversionsTracker) *unsafeConversionArbitrator {
+func newUnsafeConversionArbitrator(manualConversionsTracker *ManualConversionsTracker, targetPlatforms []string) (*unsafeConversionArbitrator, error) {
+	platformSizes, err := sizesForPlatforms(targetPlatforms)
+	if err != nil {
+		return nil, err
+	}
+
 	return &unsafeConversionArbitrator{
 		processedPairs:           make(map[ConversionPair]unsafeConversionDecision),
 		manualConversionsTracker: manualConversionsTracker,
+		platformSizes:            platformSizes,
+	}, nil
+}
+
+// sizesForPlatforms returns the sizes for the given platforms, of the form "<GOOS>/<GOARCH>".
+func sizesForPlatforms(platforms []string) ([]gotypes.Sizes, error) {
+	platformSizes := make([]gotypes.Sizes, 0, len(platforms))
+	for _, platform := range platforms {
+		split := strings.Split(platform, "/")
+		if len(split) != 2 {
+			return nil, fmt.Errorf("invalid target platform %q, expected <GOOS>/<GOARCH>", platform)
+		}
+		if !knownOS[split[0]] {
+			return nil, fmt.Errorf("unknown operating system for target platform %q", platform)
+		}
+		sizes := gotypes.SizesFor("gc", split[1])
+		if sizes == nil {
+			return nil, fmt.Errorf("unknown architecture for target platform %q", platform)
+		}
+		platformSizes = append(platformSizes, sizes)
 	}
+	return platformSizes, nil
 }
 
 // canUseUnsafeConversion returns true iff x can be converted to y using an unsafe conversion.
@@ -113,6 +150,9 @@ func (a *unsafeConversionArbitrator) canUseUnsafeRecursive(x, y *types.Type, alr
 			}
 			for i, inMember := range in.Members {
 				outMember := out.Members[i]
+				if a.transformsValue != nil && (a.transformsValue(&inMember) || a.transformsValue(&outMember)) {
+					return notPossibleTwoWay
+				}
 				if decision := a.canUseUnsafeConversionWithCaching(inMember.Type, outMember.Type, alreadyVisitedTypes); decision != possible {
 					return decision
 				}
@@ -130,7 +170,7 @@ func (a *unsafeConversionArbitrator) canUseUnsafeRecursive(x, y *types.Type, alr
 			// same type.
 			return notPossibleTwoWay
 		case types.Builtin:
-			if in.Name.Name == out.Name.Name {
+			if in.Name.Name == out.Name.Name || a.sameLayoutOnAllPlatforms(in, out) {
 				return possible
 			}
 			return notPossibleTwoWay
@@ -145,3 +185,37 @@ func min(a, b unsafeConversionDecision) unsafeConversionDecision {
 	}
 	return b
 }
+
+// sameLayoutOnAllPlatforms returns true iff builtins x and y are the same kind of number, and have
+// the same size and alignment on all the target platforms. Always false if there are no target platforms.
+func (a *unsafeConversionArbitrator) sameLayoutOnAllPlatforms(x, y *types.Type) bool {
+	if len(a.platformSizes) == 0 {
+		return false
+	}
+
+	xBasic, yBasic := basicType(x), basicType(y)
+	if xBasic == nil || yBasic == nil {
+		return false
+	}
+	const numberKinds = gotypes.IsInteger | gotypes.IsUnsigned | gotypes.IsFloat | gotypes.IsComplex
+	if xBasic.Info()&numberKinds == 0 || xBasic.Info()&numberKinds != yBasic.Info()&numberKinds {
+		return false
+	}
+
+	for _, sizes := range a.platformSizes {
+		if sizes.Sizeof(xBasic) != sizes.Sizeof(yBasic) || sizes.Alignof(xBasic) != sizes.Alignof(yBasic) {
+			return false
+		}
+	}
+	return true
+}
+
+// basicType returns the go/types equivalent of builtin t, if any.
+func basicType(t *types.Type) *gotypes.Basic {
+	object := gotypes.Universe.Lookup(t.Name.Name)
+	if object == nil {
+		return nil
+	}
+	basic, _ := object.Type().(*gotypes.Basic)
+	return basic
+}
@@ -0,0 +1,20 @@
+package generator
+
+import "testing"
+
+func TestSizesForPlatforms(t *testing.T) {
+	for _, platforms := range [][]string{
+		{"linux/amd64"},
+		{"linux/arm", "darwin/arm64", "windows/386", "js/wasm"},
+	} {
+		if sizes, err := sizesForPlatforms(platforms); err != nil || len(sizes) != len(platforms) {
+			t.Errorf("expected sizes for %v, got %v, %v", platforms, sizes, err)
+		}
+	}
+
+	for _, platform := range []string{"linux", "linux/amd64/v3", "linux/nope", "nope/amd64", "/amd64", "linux_arm/amd64"} {
+		if _, err := sizesForPlatforms([]string{platform}); err == nil {
+			t.Errorf("expected an error for %q", platform)
+		}
+	}
+}
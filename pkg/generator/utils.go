@@ -53,8 +53,14 @@ const (
 )
 
 func conversionFunctionNameTemplate(namer string) string {
+	return functionNameTemplate(conversionFunctionPrefix, namer)
+}
+
+// functionNameTemplate is the template of the name of conversion functions starting with prefix,
+// see Options.PrivateFunctionPrefix.
+func functionNameTemplate(prefix, namer string) string {
 	return fmt.Sprintf("%s%s.inType|%s%s_To_%s.outType|%s%s",
-		conversionFunctionPrefix, snippetDelimiter, namer, snippetDelimiter, snippetDelimiter, namer, snippetDelimiter)
+		prefix, snippetDelimiter, namer, snippetDelimiter, snippetDelimiter, namer, snippetDelimiter)
 }
 
 func argsFromType(inType, outType *types.Type) generator.Args {
@@ -131,11 +137,21 @@ func isCopyOnlyFunction(function *types.Type, functionTagName string) bool {
 	return functionHasTag(function, functionTagName, "copy-only")
 }
 
-// ConversionFunctionName returns the name of the conversion function for in to out.
+// ConversionFunctionName returns the name of the public conversion function that the generator
+// emits for in to out, e.g. "Convert_v1_Foo_To_v2_Foo"; see PrivateConversionFunctionName for the
+// private function it wraps. It doesn't need a Generator, so that tools can predict generated
+// functions' names without running generation.
 func ConversionFunctionName(in, out *types.Type) string {
 	return conversionFunctionName(in, out, ConversionNamer(), &bytes.Buffer{})
 }
 
+// PrivateConversionFunctionName returns the name of the private conversion function that the
+// generator emits for in to out, with the given Options.PrivateFunctionPrefix; e.g.
+// "autoConvert_v1_Foo_To_v2_Foo" with DefaultPrivateFunctionPrefix.
+func PrivateConversionFunctionName(prefix string, in, out *types.Type) string {
+	return prefix + strings.TrimPrefix(ConversionFunctionName(in, out), conversionFunctionPrefix)
+}
+
 func conversionFunctionName(in, out *types.Type, conversionNamer *namer.NameStrategy, buffer *bytes.Buffer) string {
 	namerName := "conversion"
 	tmpl, err := template.New(fmt.Sprintf("conversion function name from %s to %s", in.Name, out.Name)).
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	gotypes "go/types"
+
+	"k8s.io/gengo/types"
+)
+
+// valueInput returns true iff the public conversion function from inType to outType takes its
+// input by value rather than by pointer: if it's a manual function that does, or else if it's
+// generated for a small enough struct, see Options.ValueInputMaxSize.
+func (g *Generator) valueInput(inType, outType *types.Type) bool {
+	if function, found := g.preexists(inType, outType); found {
+		return takesValueInput(function)
+	}
+	return g.smallStruct(inType)
+}
+
+// takesValueInput returns true iff the given conversion function takes its input by value.
+func takesValueInput(function *types.Type) bool {
+	parameters := function.Underlying.Signature.Parameters
+	return len(parameters) != 0 && parameters[0].Kind != types.Pointer
+}
+
+// smallStruct returns true iff t is a struct small enough for the public conversion functions
+// generated from it to take it by value, see Options.ValueInputMaxSize.
+func (g *Generator) smallStruct(t *types.Type) bool {
+	if g.Options.ValueInputMaxSize <= 0 || t.Kind != types.Struct {
+		return false
+	}
+	if small, present := g.smallStructs[t]; present {
+		return small
+	}
+
+	sizes := g.unsafeConversionArbitrator.platformSizes
+	if len(sizes) == 0 {
+		sizes = []gotypes.Sizes{gotypes.SizesFor("gc", "amd64")}
+	}
+	goType, known := sizedType(t)
+	small := known
+	for _, platformSizes := range sizes {
+		small = small && platformSizes.Sizeof(goType) <= g.Options.ValueInputMaxSize
+	}
+	g.smallStructs[t] = small
+	return small
+}
+
+// sizedType returns a go/types type with the same size and alignment as t, if known.
+func sizedType(t *types.Type) (gotypes.Type, bool) {
+	switch t.Kind {
+	case types.Builtin:
+		if basic := basicType(t); basic != nil {
+			return basic, true
+		}
+		if t.Name == errorName {
+			return gotypes.Universe.Lookup("error").Type(), true
+		}
+	case types.Alias:
+		return sizedType(t.Underlying)
+	case types.Pointer, types.Map, types.Chan, types.Func:
+		return gotypes.Typ[gotypes.UnsafePointer], true
+	case types.Slice:
+		return gotypes.NewSlice(gotypes.Typ[gotypes.Int]), true
+	case types.Interface:
+		return gotypes.NewInterfaceType(nil, nil), true
+	case types.Array:
+		if elem, known := sizedType(t.Elem); known {
+			return gotypes.NewArray(elem, t.Len), true
+		}
+	case types.Struct:
+		fields := make([]*gotypes.Var, 0, len(t.Members))
+		for i, member := range t.Members {
+			memberType, known := sizedType(member.Type)
+			if !known {
+				return nil, false
+			}
+			fields = append(fields, gotypes.NewField(token.NoPos, nil, fmt.Sprintf("f%d", i), memberType, false))
+		}
+		return gotypes.NewStruct(fields, nil), true
+	}
+	return nil, false
+}
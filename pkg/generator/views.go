@@ -0,0 +1,36 @@
+package generator
+
+import (
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/types"
+)
+
+// viewFunctionNameTemplate renders the name of the view function from $.inType$, e.g. "View_v1_Foo".
+const viewFunctionNameTemplate = "View_$.inType|" + publicImportTrackingNamer + "$"
+
+// viewable returns true iff a view function gets generated from inType to outType, see
+// Options.ViewFunctions: iff they have the same memory layout, and their fields are converted as
+// is, without mappings.
+func (g *Generator) viewable(inType, outType *types.Type) bool {
+	if !g.Options.ViewFunctions || g.noPublicFun(inType) || g.noPublicFun(outType) {
+		return false
+	}
+	return g.fieldMappings(inType, outType) == nil && g.useUnsafeConversion(inType, outType)
+}
+
+// writeViewFunction writes the view function from inType to outType, if any, see
+// Options.ViewFunctions.
+func (g *Generator) writeViewFunction(inType, outType *types.Type, sw *generator.SnippetWriter) {
+	if !g.viewable(inType, outType) {
+		return
+	}
+	g.explainConversionf(inType, outType, "view function generated, with an unsafe cast")
+
+	args := argsFromType(inType, outType).With("Pointer", types.Ref("unsafe", "Pointer"))
+	sw.Do("// "+viewFunctionNameTemplate+" returns in as a *$.outType|"+rawNamer+"$, without copying it, as both types have\n", args)
+	sw.Do("// the same memory layout. The result aliases in: it must only be read, and only as long as in\n", nil)
+	sw.Do("// isn't modified. Use the conversion functions to get a copy.\n", nil)
+	sw.Do("func "+viewFunctionNameTemplate+"(in *$.inType|"+rawNamer+"$) *$.outType|"+rawNamer+"$ {\n", args)
+	sw.Do("return (*$.outType|"+rawNamer+"$)($.Pointer|"+rawNamer+"$(in))\n", args)
+	sw.Do("}\n\n", nil)
+}